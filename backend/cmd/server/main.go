@@ -22,6 +22,9 @@ func main() {
 
 	// Initialize configuration
 	cfg := config.Load()
+	if cfg.EncryptionKey == "" {
+		log.Fatal("ENCRYPTION_KEY is not set. Installs created before it existed must set it to their JWT_SECRET.")
+	}
 
 	// Initialize database connection
 	db, err := database.Connect(cfg)
@@ -53,8 +56,28 @@ func main() {
 	worker.Start()
 	defer worker.Stop()
 
+	// Shared connection pools to project databases
+	projectPools := services.NewProjectPools(db, cfg)
+	projectPools.Start()
+	defer projectPools.Stop()
+
+	// Start database quota enforcement
+	quotaService := services.NewQuotaService(db, cfg, projectPools)
+	quotaService.Start()
+	defer quotaService.Stop()
+
+	// Start scheduled database backups
+	backupService := services.NewBackupService(db, cfg)
+	backupService.Start()
+	defer backupService.Stop()
+
+	// Audit log retention
+	auditService := services.NewAuditService(db)
+	auditService.Start()
+	defer auditService.Stop()
+
 	// Initialize and start server
-	app := routes.Setup(db, cfg, redisService)
+	app := routes.Setup(db, cfg, redisService, projectPools)
 
 	port := os.Getenv("PORT")
 	if port == "" {
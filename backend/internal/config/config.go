@@ -8,6 +8,7 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 )
 
 // Config holds all application configuration
@@ -15,6 +16,10 @@ type Config struct {
 	// App
 	AppEnv   string
 	AppDebug bool
+	AppURL   string // Public URL of the panel, used in emailed links
+
+	// Proxies allowed to report the client address in X-Forwarded-For
+	TrustedProxies []string
 
 	// Database
 	DBHost     string
@@ -23,9 +28,23 @@ type Config struct {
 	DBUser     string
 	DBPassword string
 
-	// JWT
-	JWTSecret      string
-	JWTExpiryHours int
+	// Privileged account used to provision project databases
+	MySQLRootUser     string
+	MySQLRootPassword string
+
+	// PostgreSQL server for projects using the pgsql engine
+	PostgresHost     string
+	PostgresPort     string
+	PostgresUser     string
+	PostgresPassword string
+
+	// JWT access tokens are short-lived and renewed with a refresh token cookie
+	JWTSecret          string
+	AccessTokenMinutes int
+	RefreshTokenDays   int
+
+	// Encryption key for secrets stored in the database
+	EncryptionKey string
 
 	// Redis
 	RedisHost     string
@@ -37,11 +56,46 @@ type Config struct {
 	ProjectDomain string
 	ACMEEmail     string
 
+	// OpenID Connect single sign-on (enabled in settings)
+	OIDCIssuer         string
+	OIDCClientID       string
+	OIDCClientSecret   string
+	OIDCRedirectURL    string
+	OIDCAllowedDomains string // Comma-separated email domains, empty allows any
+	OIDCRoleClaim      string // Claim holding groups or roles
+	OIDCAdminValues    string // Comma-separated claim values that map to admin
+
+	// LDAP bind login (enabled in settings)
+	LDAPURL          string
+	LDAPStartTLS     bool
+	LDAPBindDN       string
+	LDAPBindPassword string
+	LDAPBaseDN       string
+	LDAPUserFilter   string // %s is replaced with the escaped login name
+	LDAPAdminGroups  string // Comma-separated group DNs that map to admin
+
+	// Mail ("smtp", or "log" to print messages instead of sending them)
+	MailDriver   string
+	MailHost     string
+	MailPort     string
+	MailUsername string
+	MailPassword string
+	MailFrom     string
+
 	// Docker
 	DockerSocket   string
 	ProjectsPath   string
 	TemplatesPath  string
 	DockerNetwork  string
+
+	// Database backups (local directory, or S3-compatible when an endpoint is set)
+	BackupPath        string
+	BackupS3Endpoint  string
+	BackupS3Region    string
+	BackupS3Bucket    string
+	BackupS3AccessKey string
+	BackupS3SecretKey string
+	BackupS3UseSSL    bool
 }
 
 // Load reads configuration from environment variables
@@ -50,6 +104,10 @@ func Load() *Config {
 		// App
 		AppEnv:   getEnv("APP_ENV", "production"),
 		AppDebug: getEnvBool("APP_DEBUG", false),
+		AppURL:   getEnv("APP_URL", "https://"+getEnv("BASE_DOMAIN", "localhost")),
+
+		// Docker's default address pool, which holds paas-network unless configured otherwise
+		TrustedProxies: getEnvList("TRUSTED_PROXIES", "172.16.0.0/12"),
 
 		// Database
 		DBHost:     getEnv("MYSQL_HOST", "mysql"),
@@ -58,9 +116,21 @@ func Load() *Config {
 		DBUser:     getEnv("MYSQL_USER", "paas"),
 		DBPassword: getEnv("MYSQL_PASSWORD", ""),
 
+		MySQLRootUser:     getEnv("MYSQL_ROOT_USER", "root"),
+		MySQLRootPassword: getEnv("MYSQL_ROOT_PASSWORD", ""),
+
+		PostgresHost:     getEnv("POSTGRES_HOST", "paas-postgres"),
+		PostgresPort:     getEnv("POSTGRES_PORT", "5432"),
+		PostgresUser:     getEnv("POSTGRES_USER", "postgres"),
+		PostgresPassword: getEnv("POSTGRES_PASSWORD", ""),
+
 		// JWT
-		JWTSecret:      getEnv("JWT_SECRET", "change-this-secret"),
-		JWTExpiryHours: getEnvInt("JWT_EXPIRY_HOURS", 24),
+		JWTSecret:          getEnv("JWT_SECRET", "change-this-secret"),
+		AccessTokenMinutes: getEnvInt("JWT_ACCESS_TOKEN_MINUTES", 15),
+		RefreshTokenDays:   getEnvInt("JWT_REFRESH_TOKEN_DAYS", 30),
+
+		// Encryption (required, main refuses to start without it)
+		EncryptionKey: getEnv("ENCRYPTION_KEY", ""),
 
 		// Redis
 		RedisHost:     getEnv("REDIS_HOST", "paas-redis"),
@@ -72,11 +142,46 @@ func Load() *Config {
 		ProjectDomain: getEnv("PROJECT_DOMAIN", getEnv("BASE_DOMAIN", "localhost")),
 		ACMEEmail:     getEnv("ACME_EMAIL", "admin@localhost"),
 
+		// OpenID Connect
+		OIDCIssuer:         getEnv("OIDC_ISSUER", ""),
+		OIDCClientID:       getEnv("OIDC_CLIENT_ID", ""),
+		OIDCClientSecret:   getEnv("OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectURL:    getEnv("OIDC_REDIRECT_URL", getEnv("APP_URL", "https://"+getEnv("BASE_DOMAIN", "localhost"))+"/api/auth/oidc/callback"),
+		OIDCAllowedDomains: getEnv("OIDC_ALLOWED_DOMAINS", ""),
+		OIDCRoleClaim:      getEnv("OIDC_ROLE_CLAIM", "groups"),
+		OIDCAdminValues:    getEnv("OIDC_ADMIN_VALUES", ""),
+
+		// LDAP
+		LDAPURL:          getEnv("LDAP_URL", ""),
+		LDAPStartTLS:     getEnvBool("LDAP_START_TLS", false),
+		LDAPBindDN:       getEnv("LDAP_BIND_DN", ""),
+		LDAPBindPassword: getEnv("LDAP_BIND_PASSWORD", ""),
+		LDAPBaseDN:       getEnv("LDAP_BASE_DN", ""),
+		LDAPUserFilter:   getEnv("LDAP_USER_FILTER", "(|(mail=%s)(uid=%s))"),
+		LDAPAdminGroups:  getEnv("LDAP_ADMIN_GROUPS", ""),
+
+		// Mail
+		MailDriver:   getEnv("MAIL_DRIVER", "log"),
+		MailHost:     getEnv("MAIL_HOST", ""),
+		MailPort:     getEnv("MAIL_PORT", "587"),
+		MailUsername: getEnv("MAIL_USERNAME", ""),
+		MailPassword: getEnv("MAIL_PASSWORD", ""),
+		MailFrom:     getEnv("MAIL_FROM", "no-reply@"+getEnv("BASE_DOMAIN", "localhost")),
+
 		// Docker
 		DockerSocket:  getEnv("DOCKER_SOCKET", "/var/run/docker.sock"),
 		ProjectsPath:  getEnv("PROJECTS_PATH", "/app/storage/projects"),
 		TemplatesPath: getEnv("TEMPLATES_PATH", "/app/docker/templates"),
 		DockerNetwork: getEnv("DOCKER_NETWORK", "paas-network"),
+
+		// Backups
+		BackupPath:        getEnv("BACKUP_PATH", "/app/storage/backups"),
+		BackupS3Endpoint:  getEnv("BACKUP_S3_ENDPOINT", ""),
+		BackupS3Region:    getEnv("BACKUP_S3_REGION", "us-east-1"),
+		BackupS3Bucket:    getEnv("BACKUP_S3_BUCKET", "paas-backups"),
+		BackupS3AccessKey: getEnv("BACKUP_S3_ACCESS_KEY", ""),
+		BackupS3SecretKey: getEnv("BACKUP_S3_SECRET_KEY", ""),
+		BackupS3UseSSL:    getEnvBool("BACKUP_S3_USE_SSL", false),
 	}
 }
 
@@ -97,6 +202,16 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvList(key, defaultValue string) []string {
+	var list []string
+	for _, item := range strings.Split(getEnv(key, defaultValue), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
 func getEnvBool(key string, defaultValue bool) bool {
 	if value := os.Getenv(key); value != "" {
 		if boolValue, err := strconv.ParseBool(value); err == nil {
@@ -53,6 +53,16 @@ func Migrate(db *gorm.DB) error {
 		&models.Setting{},
 		&models.ResourceLog{},
 		&models.Feedback{},
+		&models.Addon{},
+		&models.Backup{},
+		&models.Session{},
+		&models.PasswordReset{},
+		&models.RecoveryCode{},
+		&models.LoginAttempt{},
+		&models.AuditEvent{},
+		&models.Class{},
+		&models.RoleDefinition{},
+		&models.ProjectMember{},
 	)
 	if err != nil {
 		return fmt.Errorf("migration failed: %w", err)
@@ -74,6 +84,25 @@ func Seed(db *gorm.DB, cfg *config.Config) error {
 		{Key: "memory_limit_mb", Value: "512", Description: "Memory limit per container (MB)", Type: "int"},
 		{Key: "base_domain", Value: cfg.BaseDomain, Description: "Base domain for subdomains", Type: "string"},
 		{Key: "project_domain", Value: cfg.ProjectDomain, Description: "Dedicated domain for student projects", Type: "string"},
+		{Key: "db_quota_mb", Value: "0", Description: "Database size quota per project (MB, 0=unlimited)", Type: "int"},
+		{Key: "db_quota_warn_percent", Value: "80", Description: "Warn when database usage reaches this percent of quota", Type: "int"},
+		{Key: "query_timeout_seconds", Value: "30", Description: "Maximum run time of SQL console queries (seconds)", Type: "int"},
+		{Key: "query_max_rows", Value: "1000", Description: "Maximum rows returned per SQL console result set", Type: "int"},
+		{Key: "db_pool_max_open", Value: "5", Description: "Maximum open connections per project database", Type: "int"},
+		{Key: "db_pool_idle_minutes", Value: "10", Description: "Minutes before an unused project connection pool is closed", Type: "int"},
+		{Key: "backup_interval_hours", Value: "24", Description: "Hours between automatic database backups (0=disabled)", Type: "int"},
+		{Key: "backup_retention", Value: "7", Description: "Backups kept per project and reason (oldest are deleted)", Type: "int"},
+		{Key: "password_min_length", Value: "8", Description: "Minimum length of passwords chosen by users", Type: "int"},
+		{Key: "password_reset_minutes", Value: "60", Description: "Minutes a password reset link stays valid", Type: "int"},
+		{Key: "auth_password_enabled", Value: "true", Description: "Allow signing in with local passwords (always on for the superadmin)", Type: "bool"},
+		{Key: "auth_oidc_enabled", Value: "false", Description: "Allow signing in with OpenID Connect (configured through OIDC_* variables)", Type: "bool"},
+		{Key: "auth_ldap_enabled", Value: "false", Description: "Allow signing in with LDAP (configured through LDAP_* variables)", Type: "bool"},
+		{Key: "login_backoff_after", Value: "3", Description: "Failed logins per email before each further attempt is delayed (doubling)", Type: "int"},
+		{Key: "login_ip_backoff_after", Value: "20", Description: "Failed logins per IP address before each further attempt is delayed (doubling)", Type: "int"},
+		{Key: "login_lockout_threshold", Value: "10", Description: "Failed logins before the account is locked (0=never)", Type: "int"},
+		{Key: "login_lockout_minutes", Value: "15", Description: "Minutes an account stays locked after too many failed logins", Type: "int"},
+		{Key: "audit_retention_days", Value: "180", Description: "Days audit log entries and failed logins are kept (0=forever)", Type: "int"},
+		{Key: "require_2fa_admins", Value: "false", Description: "Require two-factor authentication for admin and superadmin accounts", Type: "bool"},
 	}
 
 	for _, setting := range defaultSettings {
@@ -85,6 +114,17 @@ func Seed(db *gorm.DB, cfg *config.Config) error {
 		}
 	}
 
+	// Create built-in roles if not exists; their permissions may be changed later
+	for _, role := range models.DefaultRoles {
+		var existing models.RoleDefinition
+		if db.Where("name = ?", role.Name).First(&existing).Error != nil {
+			role.BuiltIn = true
+			if err := db.Create(&role).Error; err != nil {
+				log.Printf("Warning: failed to create role %s: %v", role.Name, err)
+			}
+		}
+	}
+
 	log.Println("✅ Database seeding completed")
 	return nil
 }
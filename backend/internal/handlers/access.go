@@ -0,0 +1,50 @@
+// ===========================================
+// Access Helpers
+// ===========================================
+// Resolve the project of a request and turn
+// access errors into responses
+// ===========================================
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/laravel-paas/backend/internal/models"
+	"github.com/laravel-paas/backend/internal/services"
+)
+
+// errInvalidProjectID is returned for a malformed :id parameter
+var errInvalidProjectID = errors.New("invalid project ID")
+
+// resolveProject loads the project in the :id parameter if the current user may
+// perform the action on it
+func resolveProject(c *fiber.Ctx, access *services.AccessService, action services.ProjectAction, preload ...string) (*models.Project, error) {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return nil, errInvalidProjectID
+	}
+
+	userID := c.Locals("user_id").(uint)
+	role := c.Locals("role").(string)
+	return access.ResolveProject(userID, role, uint(id), action, preload...)
+}
+
+// projectAccessStatus is the HTTP status of a resolveProject error
+func projectAccessStatus(err error) int {
+	switch err {
+	case errInvalidProjectID:
+		return fiber.StatusBadRequest
+	case services.ErrProjectNotFound:
+		return fiber.StatusNotFound
+	case services.ErrProjectForbidden:
+		return fiber.StatusForbidden
+	}
+	return fiber.StatusInternalServerError
+}
+
+// projectAccessError answers a failed resolveProject
+func projectAccessError(c *fiber.Ctx, err error) error {
+	return c.Status(projectAccessStatus(err)).JSON(fiber.Map{"error": err.Error()})
+}
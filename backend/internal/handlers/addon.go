@@ -0,0 +1,225 @@
+// ===========================================
+// Add-on Handler
+// ===========================================
+// Attaches and detaches service containers
+// (Redis, Meilisearch, Mailpit) to projects
+// ===========================================
+package handlers
+
+import (
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/laravel-paas/backend/internal/config"
+	"github.com/laravel-paas/backend/internal/models"
+	"github.com/laravel-paas/backend/internal/services"
+	"gorm.io/gorm"
+)
+
+// AddonHandler handles project add-on endpoints
+type AddonHandler struct {
+	db            *gorm.DB
+	cfg           *config.Config
+	dockerService *services.DockerService
+	addonService  *services.AddonService
+	access        *services.AccessService
+}
+
+// NewAddonHandler creates a new add-on handler
+func NewAddonHandler(db *gorm.DB, cfg *config.Config) *AddonHandler {
+	return &AddonHandler{
+		db:            db,
+		cfg:           cfg,
+		dockerService: services.NewDockerService(cfg),
+		addonService:  services.NewAddonService(cfg),
+		access:        services.NewAccessService(db),
+	}
+}
+
+// AttachAddonRequest represents add-on attach payload
+type AttachAddonRequest struct {
+	Type models.AddonType `json:"type"`
+}
+
+// getProjectForUser fetches the project if the user may perform the action on it
+func (h *AddonHandler) getProjectForUser(c *fiber.Ctx, action services.ProjectAction) (*models.Project, error) {
+	return resolveProject(c, h.access, action)
+}
+
+// List returns the add-ons attached to a project
+func (h *AddonHandler) List(c *fiber.Ctx) error {
+	project, err := h.getProjectForUser(c, services.ProjectRead)
+	if err != nil {
+		return projectAccessError(c, err)
+	}
+
+	var addons []models.Addon
+	if err := h.db.Where("project_id = ?", project.ID).Order("created_at").Find(&addons).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch add-ons"})
+	}
+
+	// Viewers see the add-ons but not the Mailpit login
+	if h.access.CanAccessProject(c.Locals("user_id").(uint), c.Locals("role").(string), project, services.ProjectWrite) {
+		for i := range addons {
+			if addons[i].Type != models.AddonMailpit || addons[i].Secret == "" {
+				continue
+			}
+			user, password, err := h.addonService.MailpitLogin(&addons[i])
+			if err != nil {
+				continue
+			}
+			addons[i].UIUser, addons[i].UIPassword = user, password
+		}
+	}
+
+	return c.JSON(fiber.Map{"data": addons})
+}
+
+// Attach creates an add-on and provisions its container in the background
+func (h *AddonHandler) Attach(c *fiber.Ctx) error {
+	project, err := h.getProjectForUser(c, services.ProjectWrite)
+	if err != nil {
+		return projectAccessError(c, err)
+	}
+
+	var req AttachAddonRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	if !services.ValidAddonType(req.Type) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid add-on type (allowed: redis, meilisearch, mailpit)",
+		})
+	}
+
+	var count int64
+	h.db.Model(&models.Addon{}).Where("project_id = ? AND type = ?", project.ID, req.Type).Count(&count)
+	if count > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Add-on already attached"})
+	}
+
+	addon, err := h.addonService.NewAddon(project, req.Type)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to prepare add-on"})
+	}
+
+	if err := h.db.Create(addon).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create add-on"})
+	}
+
+	// Image pulls can take a while
+	go h.provision(*project, *addon)
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message": "Add-on is being provisioned",
+		"addon":   addon,
+	})
+}
+
+// provision starts the add-on container and points the running app at it
+func (h *AddonHandler) provision(project models.Project, addon models.Addon) {
+	projectDomain := GetSetting(h.db, "project_domain", h.cfg.ProjectDomain)
+
+	if err := h.addonService.Provision(&project, &addon, projectDomain); err != nil {
+		log.Printf("❌ Failed to provision %s add-on for project #%d: %v", addon.Type, project.ID, err)
+		h.db.Model(&addon).Updates(map[string]interface{}{
+			"status":    models.AddonStatusFailed,
+			"error_log": err.Error(),
+		})
+		return
+	}
+
+	h.db.Model(&addon).Updates(map[string]interface{}{
+		"status":    models.AddonStatusRunning,
+		"error_log": nil,
+	})
+
+	vars, err := h.addonService.EnvVars(&addon)
+	if err != nil {
+		log.Printf("❌ Failed to build %s add-on env for project #%d: %v", addon.Type, project.ID, err)
+		return
+	}
+	h.applyEnv(&project, vars)
+}
+
+// SecureMailpit gives Mailpit add-ons created before the web UI had a login
+// a password and recreates their containers with it
+func (h *AddonHandler) SecureMailpit() {
+	var addons []models.Addon
+	h.db.Where("type = ? AND (secret = '' OR secret IS NULL)", models.AddonMailpit).Find(&addons)
+
+	for i := range addons {
+		addon := &addons[i]
+		var project models.Project
+		if err := h.db.First(&project, addon.ProjectID).Error; err != nil {
+			continue
+		}
+
+		secret, err := h.addonService.NewSecret()
+		if err != nil {
+			log.Printf("❌ Failed to generate Mailpit password for project #%d: %v", project.ID, err)
+			continue
+		}
+		if err := h.db.Model(addon).Update("secret", secret).Error; err != nil {
+			continue
+		}
+		addon.Secret = secret
+
+		projectDomain := GetSetting(h.db, "project_domain", h.cfg.ProjectDomain)
+		if err := h.addonService.Provision(&project, addon, projectDomain); err != nil {
+			log.Printf("❌ Failed to secure Mailpit add-on for project #%d: %v", project.ID, err)
+			h.db.Model(addon).Updates(map[string]interface{}{
+				"status":    models.AddonStatusFailed,
+				"error_log": err.Error(),
+			})
+		}
+	}
+}
+
+// Detach removes an add-on container and its data
+func (h *AddonHandler) Detach(c *fiber.Ctx) error {
+	project, err := h.getProjectForUser(c, services.ProjectWrite)
+	if err != nil {
+		return projectAccessError(c, err)
+	}
+
+	var addon models.Addon
+	if err := h.db.Where("project_id = ?", project.ID).First(&addon, c.Params("addonId")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Add-on not found"})
+	}
+
+	if err := h.addonService.Remove(&addon); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := h.db.Delete(&addon).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete add-on"})
+	}
+
+	// Switch the app back to built-in drivers
+	restarted := h.applyEnv(project, services.DetachedEnvVars(addon.Type))
+
+	return c.JSON(fiber.Map{
+		"message":   "Add-on detached successfully",
+		"restarted": restarted,
+	})
+}
+
+// applyEnv writes add-on keys into the project's .env and restarts a running container.
+// Projects that were never deployed pick the keys up on their first deploy.
+func (h *AddonHandler) applyEnv(project *models.Project, vars []services.EnvVar) bool {
+	if err := h.dockerService.UpdateEnvKeys(project.Subdomain, vars); err != nil {
+		return false
+	}
+
+	if project.ContainerID == nil {
+		return false
+	}
+
+	if err := h.dockerService.ApplyEnvFile(*project.ContainerID, project.Subdomain); err != nil {
+		log.Printf("⚠️  Failed to apply add-on env to project #%d: %v", project.ID, err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,176 @@
+// ===========================================
+// Audit Handler
+// ===========================================
+// Browse and export the audit log (admin)
+// ===========================================
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/laravel-paas/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxAuditExport caps the rows of a CSV export
+	maxAuditExport = 50000
+	// maxAuditLimit caps the events returned per page
+	maxAuditLimit = 200
+)
+
+// AuditHandler handles audit log endpoints
+type AuditHandler struct {
+	db *gorm.DB
+}
+
+// NewAuditHandler creates a new audit handler
+func NewAuditHandler(db *gorm.DB) *AuditHandler {
+	return &AuditHandler{db: db}
+}
+
+// audit names the action of the current request for the audit log. The Audit
+// middleware writes the event with the actor and result once the handler returns.
+func audit(c *fiber.Ctx, action, targetType string, targetID interface{}, summary string) {
+	c.Locals("audit", &models.AuditEvent{
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   fmt.Sprint(targetID),
+		Summary:    summary,
+	})
+}
+
+// List returns audit events, newest first. With format=csv the matching events
+// are exported as a CSV file instead.
+func (h *AuditHandler) List(c *fiber.Ctx) error {
+	query, err := h.filter(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if c.Query("format") == "csv" {
+		return h.exportCSV(c, query)
+	}
+
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 50
+	}
+	if limit > maxAuditLimit {
+		limit = maxAuditLimit
+	}
+	offset := (page - 1) * limit
+
+	var events []models.AuditEvent
+	var total int64
+
+	query.Count(&total)
+
+	if err := query.Offset(offset).Limit(limit).Order("created_at DESC").Find(&events).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch audit log",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data":  events,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+// filter applies the query string filters
+func (h *AuditHandler) filter(c *fiber.Ctx) (*gorm.DB, error) {
+	query := h.db.Model(&models.AuditEvent{})
+
+	if actor := c.Query("actor"); actor != "" {
+		query = query.Where("actor_email LIKE ?", "%"+actor+"%")
+	}
+	if action := c.Query("action"); action != "" {
+		query = query.Where("action LIKE ?", "%"+action+"%")
+	}
+	if targetType := c.Query("target_type"); targetType != "" {
+		query = query.Where("target_type = ?", targetType)
+	}
+	if targetID := c.Query("target_id"); targetID != "" {
+		query = query.Where("target_id = ?", targetID)
+	}
+	if result := c.Query("result"); result != "" {
+		query = query.Where("result = ?", result)
+	}
+	if from := c.Query("from"); from != "" {
+		date, err := time.ParseInLocation("2006-01-02", from, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid from date, expected YYYY-MM-DD")
+		}
+		query = query.Where("created_at >= ?", date)
+	}
+	if to := c.Query("to"); to != "" {
+		date, err := time.ParseInLocation("2006-01-02", to, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid to date, expected YYYY-MM-DD")
+		}
+		query = query.Where("created_at < ?", date.AddDate(0, 0, 1))
+	}
+
+	return query, nil
+}
+
+// exportCSV writes the matching events as a CSV download
+func (h *AuditHandler) exportCSV(c *fiber.Ctx, query *gorm.DB) error {
+	var events []models.AuditEvent
+	if err := query.Order("created_at DESC").Limit(maxAuditExport).Find(&events).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to export audit log",
+		})
+	}
+
+	filename := fmt.Sprintf("audit_%s.csv", time.Now().Format("20060102_150405"))
+	c.Set("Content-Type", "text/csv")
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+
+	w := csv.NewWriter(c)
+	w.Write([]string{"time", "actor_id", "actor_email", "actor_role", "action", "target_type", "target_id", "result", "status_code", "ip_address", "user_agent", "summary"})
+	for _, event := range events {
+		actorID := ""
+		if event.ActorID != nil {
+			actorID = strconv.FormatUint(uint64(*event.ActorID), 10)
+		}
+		w.Write([]string{
+			event.CreatedAt.Format(time.RFC3339),
+			actorID,
+			csvSafe(event.ActorEmail),
+			event.ActorRole,
+			csvSafe(event.Action),
+			event.TargetType,
+			csvSafe(event.TargetID),
+			event.Result,
+			strconv.Itoa(event.StatusCode),
+			event.IPAddress,
+			csvSafe(event.UserAgent),
+			csvSafe(event.Summary),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// csvSafe keeps spreadsheet apps from running user-controlled values as formulas
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
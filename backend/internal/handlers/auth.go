@@ -6,27 +6,55 @@
 package handlers
 
 import (
+	"fmt"
+	"log"
+	"strconv"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/laravel-paas/backend/internal/config"
 	"github.com/laravel-paas/backend/internal/models"
+	"github.com/laravel-paas/backend/internal/services"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
 // AuthHandler handles authentication endpoints
 type AuthHandler struct {
-	db  *gorm.DB
-	cfg *config.Config
+	db           *gorm.DB
+	cfg          *config.Config
+	redisService *services.RedisService
+	tokens       *services.TokenService
+	mailer       services.Mailer
+	oidc         *services.OIDCProvider
+	ldap         *services.LDAPAuthenticator
+	twoFactor    *services.TwoFactorService
+	guard        *services.LoginGuard
+	access       *services.AccessService
 }
 
 // NewAuthHandler creates a new auth handler
-func NewAuthHandler(db *gorm.DB, cfg *config.Config) *AuthHandler {
-	return &AuthHandler{db: db, cfg: cfg}
+func NewAuthHandler(db *gorm.DB, cfg *config.Config, redisService *services.RedisService, mailer services.Mailer) *AuthHandler {
+	return &AuthHandler{
+		db:           db,
+		cfg:          cfg,
+		redisService: redisService,
+		tokens:       services.NewTokenService(db, cfg, redisService),
+		mailer:       mailer,
+		oidc:         services.NewOIDCProvider(cfg),
+		ldap:         services.NewLDAPAuthenticator(cfg),
+		twoFactor:    services.NewTwoFactorService(db, cfg, redisService),
+		guard:        services.NewLoginGuard(db, redisService),
+		access:       services.NewAccessService(db),
+	}
 }
 
+// dummyPasswordHash is checked for unknown emails so they take as long as known ones
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("unknown-user-placeholder"), bcrypt.DefaultCost)
+
+// refreshCookie holds the refresh token, out of reach of scripts
+const refreshCookie = "refresh_token"
+
 // LoginRequest represents login payload
 type LoginRequest struct {
 	Email    string `json:"email"`
@@ -35,8 +63,9 @@ type LoginRequest struct {
 
 // LoginResponse represents successful login response
 type LoginResponse struct {
-	Token string       `json:"token"`
-	User  *models.User `json:"user"`
+	Token     string       `json:"token"`
+	ExpiresIn int64        `json:"expires_in"` // Seconds until the access token expires
+	User      *models.User `json:"user"`
 }
 
 // Login authenticates user and returns JWT token
@@ -55,9 +84,44 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 		})
 	}
 
-	// Find user by email
+	// Brute-force protection applies to every provider
+	attempt := services.LoginAttemptInfo{Email: req.Email, IPAddress: c.IP(), UserAgent: c.Get("User-Agent")}
+	if blocked := h.guardBlocks(c, attempt); blocked != nil {
+		return blocked
+	}
+
+	// Directory accounts first; unknown users fall through to local passwords
+	if h.ldapEnabled() {
+		identity, err := h.ldap.Authenticate(req.Email, req.Password)
+		if err == nil {
+			user, err := h.provisionExternal(identity, services.MapRole(identity.Groups, h.cfg.LDAPAdminGroups))
+			if err != nil {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+					"error": err.Error(),
+				})
+			}
+			return h.signIn(c, user)
+		}
+		if err != services.ErrLDAPInvalidCredentials {
+			log.Printf("LDAP login failed: %v", err)
+		}
+	}
+
+	// Find user by email; unknown emails still pay for a bcrypt comparison
 	var user models.User
 	if err := h.db.Where("email = ?", req.Email).First(&user).Error; err != nil {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(req.Password))
+		h.guard.RecordFailure(attempt, nil, services.FailureInvalidCredentials)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid email or password",
+		})
+	}
+
+	// The superadmin keeps password login so a broken provider cannot lock everyone out.
+	// Checked before the password so the answer does not reveal whether it was right.
+	if !h.passwordEnabled() && user.Role != models.RoleSuperAdmin {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(req.Password))
+		h.guard.RecordFailure(attempt, &user.ID, services.FailureInvalidCredentials)
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "Invalid email or password",
 		})
@@ -65,37 +129,129 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 
 	// Verify password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		h.guard.RecordFailure(attempt, &user.ID, services.FailureInvalidCredentials)
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "Invalid email or password",
 		})
 	}
 
-	// Generate JWT token
-	claims := jwt.MapClaims{
-		"user_id": user.ID,
-		"email":   user.Email,
-		"role":    user.Role,
-		"exp":     time.Now().Add(time.Duration(h.cfg.JWTExpiryHours) * time.Hour).Unix(),
+	return h.signIn(c, &user)
+}
+
+// guardBlocks answers attempts that must wait or target a locked account; nil lets them go ahead
+func (h *AuthHandler) guardBlocks(c *fiber.Ctx, attempt services.LoginAttemptInfo) error {
+	if wait := h.guard.Wait(attempt); wait > 0 {
+		seconds := int(wait.Round(time.Second).Seconds())
+		if seconds < 1 {
+			seconds = 1
+		}
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(seconds))
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+			"error": fmt.Sprintf("Too many failed login attempts, try again in %d seconds", seconds),
+		})
+	}
+	if h.guard.Locked(attempt.Email) {
+		return c.Status(fiber.StatusLocked).JSON(fiber.Map{
+			"error": "Account is temporarily locked after too many failed logins",
+		})
 	}
+	return nil
+}
+
+// signIn finishes a login whose first factor checked out. Accounts with two-factor
+// authentication get a challenge token to exchange, together with a code, for a session.
+func (h *AuthHandler) signIn(c *fiber.Ctx, user *models.User) error {
+	if user.TwoFactorEnabled {
+		challenge, err := h.twoFactor.CreateChallenge(user.ID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to start two-factor login",
+			})
+		}
+		return c.JSON(fiber.Map{
+			"two_factor_required": true,
+			"challenge_token":     challenge,
+		})
+	}
+
+	return h.startSession(c, user)
+}
+
+// startSession answers with an access token and sets the refresh cookie
+func (h *AuthHandler) startSession(c *fiber.Ctx, user *models.User) error {
+	user.TwoFactorSetupRequired = services.TwoFactorSetupRequired(h.db, user)
+	user.Permissions = h.access.PermissionList(string(user.Role))
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(h.cfg.JWTSecret))
+	// Start a session: short-lived access token plus refresh token cookie
+	pair, err := h.tokens.StartSession(user, sessionInfo(c))
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to generate token",
 		})
 	}
+	h.setRefreshCookie(c, pair.RefreshToken, pair.Session.ExpiresAt)
+
+	// Failures are forgotten only once every factor checked out
+	h.guard.RecordSuccess(user.Email)
 
 	return c.JSON(LoginResponse{
-		Token: tokenString,
-		User:  &user,
+		Token:     pair.AccessToken,
+		ExpiresIn: int64(time.Until(pair.AccessExpiry).Seconds()),
+		User:      user,
+	})
+}
+
+// Refresh issues a new access token from the refresh token cookie and rotates it
+func (h *AuthHandler) Refresh(c *fiber.Ctx) error {
+	refreshToken := c.Cookies(refreshCookie)
+	if refreshToken == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Missing refresh token",
+		})
+	}
+
+	pair, err := h.tokens.Refresh(refreshToken, sessionInfo(c))
+	if err != nil {
+		h.clearRefreshCookie(c)
+		if err == services.ErrInvalidRefreshToken || err == services.ErrRefreshTokenReused {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to refresh token",
+		})
+	}
+
+	// Concurrent refreshes within the grace period keep the current cookie
+	if pair.RefreshToken != "" {
+		h.setRefreshCookie(c, pair.RefreshToken, pair.Session.ExpiresAt)
+	}
+
+	return c.JSON(fiber.Map{
+		"token":      pair.AccessToken,
+		"expires_in": int64(time.Until(pair.AccessExpiry).Seconds()),
 	})
 }
 
-// Logout invalidates user session (client should discard token)
+// Logout revokes the token used for the request and ends its session
 func (h *AuthHandler) Logout(c *fiber.Ctx) error {
-	// In a stateless JWT setup, logout is handled client-side
-	// For enhanced security, you could implement a token blacklist using Redis
+	jti, _ := c.Locals("jti").(string)
+	sessionID, _ := c.Locals("session_id").(string)
+	expiresAt, _ := c.Locals("token_expires_at").(time.Time)
+
+	if err := h.tokens.Revoke(jti, expiresAt); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to revoke token",
+		})
+	}
+	if err := h.tokens.EndSession(sessionID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to end session",
+		})
+	}
+	h.clearRefreshCookie(c)
+
 	return c.JSON(fiber.Map{
 		"message": "Logged out successfully",
 	})
@@ -111,6 +267,93 @@ func (h *AuthHandler) Me(c *fiber.Ctx) error {
 			"error": "User not found",
 		})
 	}
+	user.TwoFactorSetupRequired = services.TwoFactorSetupRequired(h.db, &user)
+	user.Permissions = h.access.PermissionList(string(user.Role))
 
 	return c.JSON(user)
 }
+
+// Sessions lists the signed-in devices of the current user
+func (h *AuthHandler) Sessions(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	sessionID, _ := c.Locals("session_id").(string)
+
+	sessions, err := h.tokens.ActiveSessions(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch sessions",
+		})
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].TokenID == sessionID
+	}
+
+	return c.JSON(sessions)
+}
+
+// RevokeSession signs the current user out of one of their sessions
+func (h *AuthHandler) RevokeSession(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	sessionID, _ := c.Locals("session_id").(string)
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid session ID",
+		})
+	}
+
+	var session models.Session
+	if err := h.db.Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).First(&session).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Session not found",
+		})
+	}
+
+	if err := h.tokens.RevokeSession(&session); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to revoke session",
+		})
+	}
+	if session.TokenID == sessionID {
+		h.clearRefreshCookie(c)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Session revoked",
+	})
+}
+
+// sessionInfo describes the client making the request
+func sessionInfo(c *fiber.Ctx) services.SessionInfo {
+	return services.SessionInfo{
+		IPAddress: c.IP(),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+	}
+}
+
+// setRefreshCookie stores the refresh token where only auth endpoints receive it
+func (h *AuthHandler) setRefreshCookie(c *fiber.Ctx, token string, expires time.Time) {
+	c.Cookie(&fiber.Cookie{
+		Name:     refreshCookie,
+		Value:    token,
+		Path:     "/api/auth",
+		Expires:  expires,
+		HTTPOnly: true,
+		Secure:   c.Protocol() == "https",
+		SameSite: fiber.CookieSameSiteStrictMode,
+	})
+}
+
+// clearRefreshCookie removes the refresh token from the browser
+func (h *AuthHandler) clearRefreshCookie(c *fiber.Ctx) {
+	c.Cookie(&fiber.Cookie{
+		Name:     refreshCookie,
+		Value:    "",
+		Path:     "/api/auth",
+		Expires:  time.Unix(0, 0),
+		HTTPOnly: true,
+		Secure:   c.Protocol() == "https",
+		SameSite: fiber.CookieSameSiteStrictMode,
+	})
+}
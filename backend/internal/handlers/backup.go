@@ -0,0 +1,176 @@
+// ===========================================
+// Database Backup Handler
+// ===========================================
+// Lists, downloads and restores project
+// database backups
+// ===========================================
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/laravel-paas/backend/internal/config"
+	"github.com/laravel-paas/backend/internal/models"
+	"github.com/laravel-paas/backend/internal/services"
+	"gorm.io/gorm"
+)
+
+// BackupHandler handles database backup endpoints
+type BackupHandler struct {
+	db            *gorm.DB
+	cfg           *config.Config
+	backupService *services.BackupService
+	redisService  *services.RedisService
+	access        *services.AccessService
+}
+
+// NewBackupHandler creates a new backup handler
+func NewBackupHandler(db *gorm.DB, cfg *config.Config, redisService *services.RedisService) *BackupHandler {
+	return &BackupHandler{
+		db:            db,
+		cfg:           cfg,
+		backupService: services.NewBackupService(db, cfg),
+		redisService:  redisService,
+		access:        services.NewAccessService(db),
+	}
+}
+
+// getProjectForUser fetches the project if the user may perform the action on it
+func (h *BackupHandler) getProjectForUser(c *fiber.Ctx, action services.ProjectAction) (*models.Project, error) {
+	return resolveProject(c, h.access, action)
+}
+
+// getBackup fetches a backup belonging to the project
+func (h *BackupHandler) getBackup(c *fiber.Ctx, project *models.Project) (*models.Backup, error) {
+	backupID, err := strconv.ParseUint(c.Params("backupId"), 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup ID")
+	}
+
+	var backup models.Backup
+	if err := h.db.Where("project_id = ?", project.ID).First(&backup, backupID).Error; err != nil {
+		return nil, fmt.Errorf("backup not found")
+	}
+
+	return &backup, nil
+}
+
+// List returns the backups of a project, newest first
+func (h *BackupHandler) List(c *fiber.Ctx) error {
+	project, err := h.getProjectForUser(c, services.ProjectRead)
+	if err != nil {
+		return projectAccessError(c, err)
+	}
+
+	var backups []models.Backup
+	h.db.Where("project_id = ?", project.ID).Order("created_at DESC, id DESC").Find(&backups)
+
+	return c.JSON(fiber.Map{
+		"backups":        backups,
+		"interval_hours": GetSetting(h.db, "backup_interval_hours", "24"),
+		"retention":      GetSetting(h.db, "backup_retention", "7"),
+	})
+}
+
+// Create takes a manual backup
+func (h *BackupHandler) Create(c *fiber.Ctx) error {
+	project, err := h.getProjectForUser(c, services.ProjectWrite)
+	if err != nil {
+		return projectAccessError(c, err)
+	}
+
+	backup, err := h.backupService.Backup(project, models.BackupManual)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Backup failed: " + err.Error()})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(backup)
+}
+
+// Download streams a backup as a gzipped SQL file
+func (h *BackupHandler) Download(c *fiber.Ctx) error {
+	project, err := h.getProjectForUser(c, services.ProjectRead)
+	if err != nil {
+		return projectAccessError(c, err)
+	}
+
+	backup, err := h.getBackup(c, project)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	r, err := h.backupService.Open(backup)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	filename := fmt.Sprintf("%s_%s_%s.sql.gz", project.DatabaseName, backup.CreatedAt.Format("20060102_150405"), backup.Reason)
+	c.Set("Content-Type", "application/gzip")
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+
+	// Fiber closes the reader once the body has been sent
+	return c.SendStream(r)
+}
+
+// Restore replaces the project database with a backup in the background.
+// Progress is reported through the database import status endpoint.
+func (h *BackupHandler) Restore(c *fiber.Ctx) error {
+	project, err := h.getProjectForUser(c, services.ProjectWrite)
+	if err != nil {
+		return projectAccessError(c, err)
+	}
+
+	backup, err := h.getBackup(c, project)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	audit(c, "backup.restore", "project", project.ID, fmt.Sprintf("Restored backup #%d taken %s", backup.ID, backup.CreatedAt.Format("2006-01-02 15:04")))
+
+	if backup.Engine != project.DatabaseEngine {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Backup was taken from a different database engine"})
+	}
+
+	job, err := services.NewImportJob(project.ID, fmt.Sprintf("backup #%d", backup.ID), backup.SizeBytes)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := services.SaveImportJob(h.redisService, job); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to queue restore"})
+	}
+
+	go h.runRestore(*project, *backup, job)
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message": "Restore started",
+		"job_id":  job.ID,
+		"job":     job,
+	})
+}
+
+// runRestore restores a backup and publishes progress as an import job
+func (h *BackupHandler) runRestore(project models.Project, backup models.Backup, job *services.ImportJob) {
+	save := func(job *services.ImportJob) {
+		if err := services.SaveImportJob(h.redisService, job); err != nil {
+			log.Printf("Failed to save restore job %s: %v", job.ID, err)
+		}
+	}
+
+	if err := h.backupService.Restore(&project, &backup, job, save); err != nil {
+		log.Printf("Restore of backup #%d for project %s failed: %v", backup.ID, project.Name, err)
+
+		// Failures before the import started have not been recorded on the job yet
+		if job.Status != services.ImportFailed {
+			now := time.Now()
+			job.Status = services.ImportFailed
+			job.Error = err.Error()
+			job.FinishedAt = &now
+			save(job)
+		}
+	}
+}
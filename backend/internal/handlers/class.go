@@ -0,0 +1,439 @@
+// ===========================================
+// Class Handler
+// ===========================================
+// Classes (cohorts), their teachers, students
+// and join codes
+// ===========================================
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/laravel-paas/backend/internal/models"
+	"github.com/laravel-paas/backend/internal/services"
+	"gorm.io/gorm"
+)
+
+// ClassHandler handles class endpoints
+type ClassHandler struct {
+	db     *gorm.DB
+	access *services.AccessService
+}
+
+// NewClassHandler creates a new class handler
+func NewClassHandler(db *gorm.DB) *ClassHandler {
+	return &ClassHandler{db: db, access: services.NewAccessService(db)}
+}
+
+// ClassRequest represents class create/update payload. Limit overrides are
+// admin only; a negative value clears the override.
+type ClassRequest struct {
+	Name              string `json:"name"`
+	Description       *string `json:"description"`
+	JoinEnabled       *bool   `json:"join_enabled"`
+	MaxProjects       *int    `json:"max_projects"`
+	ProjectExpiryDays *int    `json:"project_expiry_days"`
+	CPULimitPercent   *int    `json:"cpu_limit_percent"`
+	MemoryLimitMB     *int    `json:"memory_limit_mb"`
+}
+
+// ClassMembersRequest represents a list of users to add to a class
+type ClassMembersRequest struct {
+	UserIDs []uint   `json:"user_ids"`
+	Emails  []string `json:"emails"`
+}
+
+// JoinClassRequest represents join code payload
+type JoinClassRequest struct {
+	Code string `json:"code"`
+}
+
+// List returns the classes the user may see: all of them, or those they teach
+func (h *ClassHandler) List(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	role := c.Locals("role").(string)
+
+	query := h.db.Preload("Teachers").Scopes(h.access.ClassScope(userID, role, "read"))
+
+	var classes []models.Class
+	if err := query.Order("name").Find(&classes).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch classes",
+		})
+	}
+
+	for i := range classes {
+		h.db.Table("class_members").Where("class_id = ?", classes[i].ID).Count(&classes[i].StudentCount)
+	}
+
+	return c.JSON(classes)
+}
+
+// Get returns a class with its teachers and students
+func (h *ClassHandler) Get(c *fiber.Ctx) error {
+	class, err := h.getClass(c, "read")
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := h.db.Preload("Teachers").Preload("Students").First(class, class.ID).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch class",
+		})
+	}
+	class.StudentCount = int64(len(class.Students))
+
+	return c.JSON(fiber.Map{
+		"class": class,
+		// What the settings and overrides add up to for a student only in this class
+		"limits": services.ClassLimits(h.db, class),
+	})
+}
+
+// Create adds a new class with a fresh join code
+func (h *ClassHandler) Create(c *fiber.Ctx) error {
+	var req ClassRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Name is required",
+		})
+	}
+
+	code, err := services.GenerateJoinCode(h.db)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	class := models.Class{
+		Name:        strings.TrimSpace(req.Name),
+		JoinCode:    code,
+		JoinEnabled: req.JoinEnabled == nil || *req.JoinEnabled,
+	}
+	if req.Description != nil {
+		class.Description = *req.Description
+	}
+	applyOverride(&class.MaxProjects, req.MaxProjects)
+	applyOverride(&class.ProjectExpiryDays, req.ProjectExpiryDays)
+	applyOverride(&class.CPULimitPercent, req.CPULimitPercent)
+	applyOverride(&class.MemoryLimitMB, req.MemoryLimitMB)
+
+	if err := h.db.Create(&class).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create class",
+		})
+	}
+
+	audit(c, "class.create", "class", class.ID, "Created class "+class.Name)
+
+	return c.Status(fiber.StatusCreated).JSON(class)
+}
+
+// Update changes a class. Teachers may rename their classes and toggle joining;
+// limit overrides need class.manage.
+func (h *ClassHandler) Update(c *fiber.Ctx) error {
+	class, err := h.getClass(c, "write")
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var req ClassRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	overrides := req.MaxProjects != nil || req.ProjectExpiryDays != nil || req.CPULimitPercent != nil || req.MemoryLimitMB != nil
+	if overrides && !h.access.Can(c.Locals("role").(string), models.PermClassManage) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "You do not have permission to change class limits",
+		})
+	}
+
+	if strings.TrimSpace(req.Name) != "" {
+		class.Name = strings.TrimSpace(req.Name)
+	}
+	if req.Description != nil {
+		class.Description = *req.Description
+	}
+	if req.JoinEnabled != nil {
+		class.JoinEnabled = *req.JoinEnabled
+	}
+	applyOverride(&class.MaxProjects, req.MaxProjects)
+	applyOverride(&class.ProjectExpiryDays, req.ProjectExpiryDays)
+	applyOverride(&class.CPULimitPercent, req.CPULimitPercent)
+	applyOverride(&class.MemoryLimitMB, req.MemoryLimitMB)
+
+	audit(c, "class.update", "class", class.ID, "Updated class "+class.Name)
+
+	if err := h.db.Save(class).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update class",
+		})
+	}
+
+	return c.JSON(class)
+}
+
+// Delete removes a class; its students and their projects stay
+func (h *ClassHandler) Delete(c *fiber.Ctx) error {
+	class, err := h.getClass(c, "write")
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	audit(c, "class.delete", "class", class.ID, "Deleted class "+class.Name)
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(class).Association("Teachers").Clear(); err != nil {
+			return err
+		}
+		if err := tx.Model(class).Association("Students").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(class).Error
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete class",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Class deleted successfully",
+	})
+}
+
+// SetTeachers replaces the teachers of a class
+func (h *ClassHandler) SetTeachers(c *fiber.Ctx) error {
+	class, err := h.getClass(c, "write")
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var req ClassMembersRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	teachers := []models.User{}
+	if len(req.UserIDs) > 0 {
+		h.db.Where("id IN ? AND role IN ?", req.UserIDs, []models.Role{models.RoleTeacher, models.RoleAdmin}).Find(&teachers)
+		if len(teachers) != len(req.UserIDs) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Only teachers and admins can teach a class",
+			})
+		}
+	}
+
+	names := make([]string, len(teachers))
+	for i, teacher := range teachers {
+		names[i] = teacher.Email
+	}
+	audit(c, "class.teachers", "class", class.ID, fmt.Sprintf("Teachers of %s: %s", class.Name, strings.Join(names, ", ")))
+
+	if err := h.db.Model(class).Association("Teachers").Replace(teachers); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update teachers",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Teachers updated",
+	})
+}
+
+// AddStudents enrolls students by ID or email. It needs class.manage: class
+// membership grants teachers access to the student, so teachers rely on the join code.
+func (h *ClassHandler) AddStudents(c *fiber.Ctx) error {
+	class, err := h.getClass(c, "write")
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var req ClassMembersRequest
+	if err := c.BodyParser(&req); err != nil || (len(req.UserIDs) == 0 && len(req.Emails) == 0) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "User IDs or emails are required",
+		})
+	}
+
+	var students []models.User
+	query := h.db.Where("role = ?", models.RoleStudent)
+	if len(req.UserIDs) > 0 {
+		query = query.Where("id IN ?", req.UserIDs)
+	} else {
+		query = query.Where("email IN ?", req.Emails)
+	}
+	query.Find(&students)
+
+	if len(students) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "No matching students found",
+		})
+	}
+
+	audit(c, "class.students.add", "class", class.ID, fmt.Sprintf("Added %d students to %s", len(students), class.Name))
+
+	if err := h.db.Model(class).Association("Students").Append(students); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to add students",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": fmt.Sprintf("%d students added", len(students)),
+		"added":   len(students),
+	})
+}
+
+// RemoveStudent takes a student out of a class
+func (h *ClassHandler) RemoveStudent(c *fiber.Ctx) error {
+	class, err := h.getClass(c, "write")
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	studentID, err := strconv.ParseUint(c.Params("userId"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+
+	audit(c, "class.students.remove", "class", class.ID, fmt.Sprintf("Removed user %d from %s", studentID, class.Name))
+
+	if err := h.db.Model(class).Association("Students").Delete(&models.User{ID: uint(studentID)}); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to remove student",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Student removed from class",
+	})
+}
+
+// RegenerateJoinCode replaces the join code, e.g. after it leaked
+func (h *ClassHandler) RegenerateJoinCode(c *fiber.Ctx) error {
+	class, err := h.getClass(c, "write")
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	code, err := services.GenerateJoinCode(h.db)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := h.db.Model(class).Update("join_code", code).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update join code",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"join_code": code,
+	})
+}
+
+// ListOwn returns the classes the current user is enrolled in
+func (h *ClassHandler) ListOwn(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var classes []models.Class
+	if err := h.db.Preload("Teachers").
+		Joins("JOIN class_members ON class_members.class_id = classes.id").
+		Where("class_members.user_id = ?", userID).
+		Order("name").
+		Find(&classes).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch classes",
+		})
+	}
+
+	// The join code is for teachers to hand out
+	for i := range classes {
+		classes[i].JoinCode = ""
+	}
+
+	return c.JSON(fiber.Map{
+		"classes": classes,
+		"limits":  services.EffectiveLimits(h.db, userID),
+	})
+}
+
+// Join enrolls the current student with a class join code
+func (h *ClassHandler) Join(c *fiber.Ctx) error {
+	var req JoinClassRequest
+	if err := c.BodyParser(&req); err != nil || strings.TrimSpace(req.Code) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Join code is required",
+		})
+	}
+
+	var class models.Class
+	code := strings.ToUpper(strings.TrimSpace(req.Code))
+	if err := h.db.Where("join_code = ? AND join_enabled = ?", code, true).First(&class).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Invalid or disabled join code",
+		})
+	}
+
+	student := models.User{ID: c.Locals("user_id").(uint)}
+	if err := h.db.Model(&class).Association("Students").Append(&student); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to join class",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Joined " + class.Name,
+		"class":   fiber.Map{"id": class.ID, "name": class.Name},
+	})
+}
+
+// getClass fetches a class the current user may read or write
+func (h *ClassHandler) getClass(c *fiber.Ctx, action string) (*models.Class, error) {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid class ID")
+	}
+
+	userID := c.Locals("user_id").(uint)
+	role := c.Locals("role").(string)
+
+	var class models.Class
+	if err := h.db.Scopes(h.access.ClassScope(userID, role, action)).First(&class, id).Error; err != nil {
+		return nil, fmt.Errorf("class not found")
+	}
+
+	return &class, nil
+}
+
+// applyOverride sets or, for negative values, clears a limit override
+func applyOverride(field **int, value *int) {
+	if value == nil {
+		return
+	}
+	if *value < 0 {
+		*field = nil
+		return
+	}
+	v := *value
+	*field = &v
+}
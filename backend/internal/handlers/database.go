@@ -6,8 +6,14 @@
 package handlers
 
 import (
+	"bufio"
+	"compress/gzip"
+	"context"
 	"database/sql"
 	"fmt"
+	"log"
+	"mime/multipart"
+	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -15,6 +21,7 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/laravel-paas/backend/internal/config"
 	"github.com/laravel-paas/backend/internal/models"
+	"github.com/laravel-paas/backend/internal/services"
 	"gorm.io/gorm"
 
 	_ "github.com/go-sql-driver/mysql"
@@ -22,13 +29,32 @@ import (
 
 // DatabaseHandler handles database management endpoints
 type DatabaseHandler struct {
-	db  *gorm.DB
-	cfg *config.Config
+	db            *gorm.DB
+	cfg           *config.Config
+	secrets       *services.SecretBox
+	dockerService *services.DockerService
+	provisioners  *services.Provisioners
+	quotaService  *services.QuotaService
+	backupService *services.BackupService
+	redisService  *services.RedisService
+	projectPools  *services.ProjectPools
+	access        *services.AccessService
 }
 
 // NewDatabaseHandler creates a new database handler
-func NewDatabaseHandler(db *gorm.DB, cfg *config.Config) *DatabaseHandler {
-	return &DatabaseHandler{db: db, cfg: cfg}
+func NewDatabaseHandler(db *gorm.DB, cfg *config.Config, redisService *services.RedisService, projectPools *services.ProjectPools) *DatabaseHandler {
+	return &DatabaseHandler{
+		db:            db,
+		cfg:           cfg,
+		secrets:       services.NewSecretBox(cfg),
+		dockerService: services.NewDockerService(cfg),
+		provisioners:  services.NewProvisioners(cfg),
+		quotaService:  services.NewQuotaService(db, cfg, projectPools),
+		backupService: services.NewBackupService(db, cfg),
+		redisService:  redisService,
+		projectPools:  projectPools,
+		access:        services.NewAccessService(db),
+	}
 }
 
 // TableInfo represents table metadata
@@ -50,89 +76,225 @@ type ColumnInfo struct {
 	Extra    string  `json:"extra"`
 }
 
-// QueryResult represents SQL query result
-type QueryResult struct {
-	Columns      []string                 `json:"columns"`
-	Rows         []map[string]interface{} `json:"rows"`
-	RowsAffected int64                    `json:"rows_affected"`
-	Duration     string                   `json:"duration"`
+// dialect returns the SQL dialect of a project's database engine
+func (h *DatabaseHandler) dialect(project *models.Project) services.Dialect {
+	return services.DialectFor(h.cfg, project.DatabaseEngine)
 }
 
-// connectToProjectDB connects to a student's project database
+// connectToProjectDB returns the shared connection pool of a student's project
+// database. The pool is owned by ProjectPools and must not be closed.
 func (h *DatabaseHandler) connectToProjectDB(project *models.Project) (*sql.DB, error) {
-	dsn := fmt.Sprintf("%s:%s@tcp(paas-mysql:3306)/%s?parseTime=true",
-		project.DatabaseName,
-		project.DatabaseName,
-		project.DatabaseName,
-	)
-	return sql.Open("mysql", dsn)
+	return h.projectPools.Get(project)
 }
 
-// getProjectForUser fetches project and validates ownership
-func (h *DatabaseHandler) getProjectForUser(c *fiber.Ctx) (*models.Project, error) {
-	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
-	if err != nil {
-		return nil, fmt.Errorf("invalid project ID")
+// queryTimeout returns the configured limit for project database queries
+func (h *DatabaseHandler) queryTimeout() time.Duration {
+	timeout, _ := strconv.Atoi(GetSetting(h.db, "query_timeout_seconds", "30"))
+	if timeout <= 0 {
+		timeout = 30
 	}
+	return time.Duration(timeout) * time.Second
+}
+
+// queryContext bounds a request's queries, including the wait for a pooled connection
+func (h *DatabaseHandler) queryContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), h.queryTimeout())
+}
 
-	userID := c.Locals("user_id").(uint)
-	role := c.Locals("role").(string)
+// listTableNames returns the names of all tables in a project database
+func (h *DatabaseHandler) listTableNames(db *sql.DB, project *models.Project) ([]string, error) {
+	return services.NewDatabaseDumper(db, h.dialect(project), project.DatabaseName).Tables()
+}
 
-	var project models.Project
-	query := h.db
+// getProjectForUser fetches the project if the user may perform the action on it
+func (h *DatabaseHandler) getProjectForUser(c *fiber.Ctx, action services.ProjectAction) (*models.Project, error) {
+	return resolveProject(c, h.access, action)
+}
 
-	// Students can only access their own projects
-	if role == string(models.RoleStudent) {
-		query = query.Where("user_id = ?", userID)
+// GetCredentials returns database credentials
+func (h *DatabaseHandler) GetCredentials(c *fiber.Ctx) error {
+	project, err := h.getProjectForUser(c, services.ProjectWrite)
+	if err != nil {
+		return projectAccessError(c, err)
 	}
 
-	if err := query.First(&project, id).Error; err != nil {
-		return nil, fmt.Errorf("project not found")
+	password, err := services.ProjectDatabasePassword(h.secrets, project)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to read database password"})
 	}
 
-	return &project, nil
+	dialect := h.dialect(project)
+	return c.JSON(fiber.Map{
+		"engine":     dialect.Engine(),
+		"connection": dialect.LaravelConnection(),
+		"host":       dialect.Host(),
+		"port":       dialect.Port(),
+		"database":   project.DatabaseName,
+		"username":   project.DatabaseName,
+		"password":   password,
+	})
 }
 
-// GetCredentials returns database credentials
-func (h *DatabaseHandler) GetCredentials(c *fiber.Ctx) error {
-	project, err := h.getProjectForUser(c)
+// RotatePassword generates a new database password and applies it everywhere:
+// the database server, the stored secret, the project's .env and the running container
+func (h *DatabaseHandler) RotatePassword(c *fiber.Ctx) error {
+	project, err := h.getProjectForUser(c, services.ProjectWrite)
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		return projectAccessError(c, err)
+	}
+
+	audit(c, "database.rotate_password", "project", project.ID, "Rotated password of "+project.DatabaseName)
+
+	password, err := services.GenerateDatabasePassword()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate password"})
+	}
+
+	encrypted, err := h.secrets.Encrypt(password)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to encrypt password"})
+	}
+
+	oldPassword, err := services.ProjectDatabasePassword(h.secrets, project)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to read database password"})
+	}
+
+	provisioner := h.provisioners.For(project.DatabaseEngine)
+	if err := provisioner.SetPassword(project.DatabaseName, password); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// The stored secret must keep matching the server, put the old password back
+	if err := h.db.Model(project).Update("db_password", encrypted).Error; err != nil {
+		if rollbackErr := provisioner.SetPassword(project.DatabaseName, oldPassword); rollbackErr != nil {
+			log.Printf("❌ Failed to roll back the password of project #%d: %v", project.ID, rollbackErr)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save password"})
+	}
+
+	// Pooled connections were opened with the old password
+	h.projectPools.Invalidate(project.ID)
+
+	if err := h.dockerService.UpdateEnvKeys(project.Subdomain, []services.EnvVar{
+		{Key: "DB_PASSWORD", Value: password},
+	}); err != nil {
+		// A running app still uses the old password and can no longer connect
+		if project.ContainerID != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Password rotated but the .env file could not be updated: " + err.Error(),
+			})
+		}
+		// Not deployed yet: the next deploy writes the new password into .env
+		return c.JSON(fiber.Map{
+			"message":   "Password rotated. It will be applied on the next deploy.",
+			"restarted": false,
+		})
+	}
+
+	restarted := false
+	if project.ContainerID != nil {
+		if err := h.dockerService.ApplyEnvFile(*project.ContainerID, project.Subdomain); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Password rotated but the container could not be restarted: " + err.Error(),
+			})
+		}
+		restarted = true
 	}
 
 	return c.JSON(fiber.Map{
-		"host":     "paas-mysql",
-		"port":     3306,
-		"database": project.DatabaseName,
-		"username": project.DatabaseName,
-		"password": project.DatabaseName,
+		"message":   "Password rotated successfully",
+		"restarted": restarted,
 	})
 }
 
+// GetUsage returns database size against quota. Privileges are only changed by
+// the background enforcer.
+func (h *DatabaseHandler) GetUsage(c *fiber.Ctx) error {
+	project, err := h.getProjectForUser(c, services.ProjectRead)
+	if err != nil {
+		return projectAccessError(c, err)
+	}
+
+	usage, err := h.quotaService.Usage(project)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(usage)
+}
+
+// ListOrphans returns databases on the server that no project references (admin only)
+func (h *DatabaseHandler) ListOrphans(c *fiber.Ctx) error {
+	var known []string
+	if err := h.db.Unscoped().Model(&models.Project{}).Pluck("database_name", &known).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch projects"})
+	}
+
+	orphans := []services.OrphanDatabase{}
+	for _, provisioner := range h.provisioners.All() {
+		found, err := provisioner.ListOrphans(known)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		orphans = append(orphans, found...)
+	}
+
+	return c.JSON(fiber.Map{"data": orphans})
+}
+
+// DropOrphan removes a database left over from a deleted project (admin only).
+// The engine is selected with ?engine=mysql|postgres (default mysql).
+func (h *DatabaseHandler) DropOrphan(c *fiber.Ctx) error {
+	engine := models.DatabaseEngine(c.Query("engine", string(models.EngineMySQL)))
+	if !services.ValidEngine(engine) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid database engine"})
+	}
+	provisioner := h.provisioners.For(engine)
+
+	name := c.Params("name")
+	if err := provisioner.ValidateDatabaseName(name); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	audit(c, "database.drop_orphan", "database", name, fmt.Sprintf("Dropped orphaned %s database %s", engine, name))
+
+	var count int64
+	h.db.Unscoped().Model(&models.Project{}).Where("database_name = ?", name).Count(&count)
+	if count > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Database belongs to a project"})
+	}
+
+	exists, err := provisioner.Exists(name)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	if !exists {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Database not found"})
+	}
+
+	if err := provisioner.Drop(name); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"message": "Database dropped successfully"})
+}
+
 // ListTables returns all tables in the database
 func (h *DatabaseHandler) ListTables(c *fiber.Ctx) error {
-	project, err := h.getProjectForUser(c)
+	project, err := h.getProjectForUser(c, services.ProjectRead)
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		return projectAccessError(c, err)
 	}
 
 	db, err := h.connectToProjectDB(project)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to connect to database"})
 	}
-	defer db.Close()
 
-	rows, err := db.Query(`
-		SELECT 
-			TABLE_NAME,
-			TABLE_ROWS,
-			ROUND(((DATA_LENGTH + INDEX_LENGTH) / 1024), 2) AS size_kb,
-			ENGINE,
-			CREATE_TIME
-		FROM information_schema.TABLES 
-		WHERE TABLE_SCHEMA = ?
-		ORDER BY TABLE_NAME
-	`, project.DatabaseName)
+	ctx, cancel := h.queryContext()
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, h.dialect(project).ListTablesQuery(), project.DatabaseName)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -158,9 +320,9 @@ func (h *DatabaseHandler) ListTables(c *fiber.Ctx) error {
 
 // GetTableStructure returns columns for a table
 func (h *DatabaseHandler) GetTableStructure(c *fiber.Ctx) error {
-	project, err := h.getProjectForUser(c)
+	project, err := h.getProjectForUser(c, services.ProjectRead)
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		return projectAccessError(c, err)
 	}
 
 	tableName := c.Params("table")
@@ -172,23 +334,24 @@ func (h *DatabaseHandler) GetTableStructure(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to connect"})
 	}
-	defer db.Close()
-
-	rows, err := db.Query(`
-		SELECT 
-			COLUMN_NAME,
-			COLUMN_TYPE,
-			IS_NULLABLE,
-			COLUMN_KEY,
-			COLUMN_DEFAULT,
-			EXTRA
-		FROM information_schema.COLUMNS 
-		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?
-		ORDER BY ORDINAL_POSITION
-	`, project.DatabaseName, tableName)
+
+	ctx, cancel := h.queryContext()
+	defer cancel()
+
+	columns, err := h.tableColumns(ctx, db, project, tableName)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
+
+	return c.JSON(fiber.Map{"columns": columns})
+}
+
+// tableColumns returns the column metadata of a table
+func (h *DatabaseHandler) tableColumns(ctx context.Context, db *sql.DB, project *models.Project, tableName string) ([]ColumnInfo, error) {
+	rows, err := db.QueryContext(ctx, h.dialect(project).ColumnsQuery(), project.DatabaseName, tableName)
+	if err != nil {
+		return nil, err
+	}
 	defer rows.Close()
 
 	var columns []ColumnInfo
@@ -202,66 +365,121 @@ func (h *DatabaseHandler) GetTableStructure(c *fiber.Ctx) error {
 		columns = append(columns, col)
 	}
 
-	return c.JSON(fiber.Map{"columns": columns})
+	return columns, rows.Err()
 }
 
-// GetTableData returns rows from a table with pagination
+// GetTableData returns rows from a table. Query parameters:
+//   - sort=col:asc,other:desc
+//   - filter=col:op:value (repeatable; op is eq, like, gt, lt or is_null)
+//   - search=text across text columns
+//   - page/limit, or cursor for keyset pagination (pass an empty cursor for the first page)
 func (h *DatabaseHandler) GetTableData(c *fiber.Ctx) error {
-	project, err := h.getProjectForUser(c)
+	project, err := h.getProjectForUser(c, services.ProjectRead)
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		return projectAccessError(c, err)
 	}
 
-	tableName := c.Params("table")
 	page, _ := strconv.Atoi(c.Query("page", "1"))
 	limit, _ := strconv.Atoi(c.Query("limit", "50"))
-	offset := (page - 1) * limit
-
-	// Validate table name (prevent SQL injection)
-	if !isValidIdentifier(tableName) {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid table name"})
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > maxBrowseLimit {
+		limit = 50
 	}
+	offset := (page - 1) * limit
 
 	db, err := h.connectToProjectDB(project)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to connect"})
 	}
-	defer db.Close()
 
-	// Get total count
-	var total int64
-	db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM `%s`", tableName)).Scan(&total)
+	ctx, cancel := h.queryContext()
+	defer cancel()
 
-	// Get data
-	query := fmt.Sprintf("SELECT * FROM `%s` LIMIT %d OFFSET %d", tableName, limit, offset)
-	rows, err := db.Query(query)
+	// Column names are checked against information_schema, not escaped
+	table, err := h.loadRowTable(ctx, db, project, c.Params("table"))
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
-	defer rows.Close()
-
-	columns, _ := rows.Columns()
-	var data []map[string]interface{}
 
-	for rows.Next() {
-		values := make([]interface{}, len(columns))
-		valuePtrs := make([]interface{}, len(columns))
-		for i := range values {
-			valuePtrs[i] = &values[i]
+	q := newBrowseQuery(table)
+	if err := q.sortBy(c.Query("sort")); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	for _, spec := range c.Context().QueryArgs().PeekMulti("filter") {
+		if err := q.filter(string(spec)); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 		}
+	}
+	if search := strings.TrimSpace(c.Query("search")); search != "" {
+		q.search(search)
+	}
 
-		rows.Scan(valuePtrs...)
+	quotedTable := table.dialect.QuoteIdent(table.name)
+	keyset := c.Context().QueryArgs().Has("cursor")
 
-		row := make(map[string]interface{})
-		for i, col := range columns {
-			val := values[i]
-			if b, ok := val.([]byte); ok {
-				row[col] = string(b)
-			} else {
-				row[col] = val
+	if keyset {
+		// Keyset pagination skips COUNT(*) and OFFSET scans on large tables
+		if len(table.primaryKey) == 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Keyset pagination requires a primary key, use page instead"})
+		}
+		if cursor := c.Query("cursor"); cursor != "" {
+			values, err := decodeCursor(cursor)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+			}
+			if err := q.after(values); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 			}
 		}
-		data = append(data, row)
+
+		// Fetch one extra row to know whether there is a next page
+		query := fmt.Sprintf("SELECT * FROM %s%s%s LIMIT %d", quotedTable, q.whereClause(), q.orderClause(), limit+1)
+		rows, err := db.QueryContext(ctx, query, q.args...)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		defer rows.Close()
+
+		columns, _ := rows.Columns()
+		data, err := scanRowMaps(rows)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		var nextCursor string
+		hasMore := len(data) > limit
+		if hasMore {
+			data = data[:limit]
+			nextCursor = q.encodeCursor(data[len(data)-1])
+		}
+
+		return c.JSON(fiber.Map{
+			"columns":     columns,
+			"rows":        data,
+			"limit":       limit,
+			"has_more":    hasMore,
+			"next_cursor": nextCursor,
+		})
+	}
+
+	// Get total count of matching rows
+	var total int64
+	db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s%s", quotedTable, q.whereClause()), q.args...).Scan(&total)
+
+	// Get data
+	query := fmt.Sprintf("SELECT * FROM %s%s%s LIMIT %d OFFSET %d", quotedTable, q.whereClause(), q.orderClause(), limit, offset)
+	rows, err := db.QueryContext(ctx, query, q.args...)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	defer rows.Close()
+
+	columns, _ := rows.Columns()
+	data, err := scanRowMaps(rows)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
 	return c.JSON(fiber.Map{
@@ -273,15 +491,19 @@ func (h *DatabaseHandler) GetTableData(c *fiber.Ctx) error {
 	})
 }
 
-// ExecuteQuery runs a SQL query
+// ExecuteQueryRequest is the SQL console payload
 type ExecuteQueryRequest struct {
-	Query string `json:"query"`
+	Query    string `json:"query"`
+	ReadOnly bool   `json:"read_only"`
 }
 
+// ExecuteQuery runs one or more statements from the SQL console. Each statement
+// produces a result set; read-only mode rejects writes and runs inside a
+// read-only transaction.
 func (h *DatabaseHandler) ExecuteQuery(c *fiber.Ctx) error {
-	project, err := h.getProjectForUser(c)
+	project, err := h.getProjectForUser(c, services.ProjectRead)
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		return projectAccessError(c, err)
 	}
 
 	var req ExecuteQueryRequest
@@ -289,161 +511,153 @@ func (h *DatabaseHandler) ExecuteQuery(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
 	}
 
-	query := strings.TrimSpace(req.Query)
-	if query == "" {
+	if strings.TrimSpace(req.Query) == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Query is required"})
 	}
-
-	// Block dangerous operations
-	upperQuery := strings.ToUpper(query)
-	if strings.Contains(upperQuery, "DROP DATABASE") ||
-		strings.Contains(upperQuery, "CREATE DATABASE") ||
-		strings.Contains(upperQuery, "GRANT") ||
-		strings.Contains(upperQuery, "REVOKE") {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "This operation is not allowed"})
+	// Writes need write access to the project; read-only mode is enforced by the runner
+	if !req.ReadOnly && !h.access.CanAccessProject(c.Locals("user_id").(uint), c.Locals("role").(string), project, services.ProjectWrite) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You can only run read-only queries on this project"})
+	}
+	// Read-only queries cannot change anything and are not audited
+	if !req.ReadOnly {
+		audit(c, "database.query", "project", project.ID, req.Query)
 	}
 
 	db, err := h.connectToProjectDB(project)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to connect"})
 	}
-	defer db.Close()
 
-	start := time.Now()
+	maxRows, _ := strconv.Atoi(GetSetting(h.db, "query_max_rows", "1000"))
 
-	// Check if it's a SELECT query
-	if strings.HasPrefix(upperQuery, "SELECT") || strings.HasPrefix(upperQuery, "SHOW") || strings.HasPrefix(upperQuery, "DESCRIBE") {
-		rows, err := db.Query(query)
-		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
-		}
-		defer rows.Close()
-
-		columns, _ := rows.Columns()
-		var data []map[string]interface{}
+	start := time.Now()
+	results, err := services.NewQueryRunner(db, h.dialect(project)).Run(req.Query, services.QueryOptions{
+		ReadOnly: req.ReadOnly,
+		Timeout:  h.queryTimeout(),
+		MaxRows:  maxRows,
+	})
+	if results == nil {
+		results = []services.QueryResult{}
+	}
 
-		for rows.Next() {
-			values := make([]interface{}, len(columns))
-			valuePtrs := make([]interface{}, len(columns))
-			for i := range values {
-				valuePtrs[i] = &values[i]
+	if err != nil {
+		status := fiber.StatusBadRequest
+		response := fiber.Map{"error": err.Error(), "results": results}
+		if qerr, ok := err.(*services.QueryError); ok {
+			if qerr.Forbidden {
+				status = fiber.StatusForbidden
 			}
-			rows.Scan(valuePtrs...)
-
-			row := make(map[string]interface{})
-			for i, col := range columns {
-				val := values[i]
-				if b, ok := val.([]byte); ok {
-					row[col] = string(b)
-				} else {
-					row[col] = val
-				}
+			if qerr.Index >= 0 {
+				response["statement"] = qerr.Index + 1
+				response["line"] = qerr.Line
 			}
-			data = append(data, row)
 		}
-
-		return c.JSON(QueryResult{
-			Columns:  columns,
-			Rows:     data,
-			Duration: time.Since(start).String(),
-		})
-	}
-
-	// Execute non-SELECT query
-	result, err := db.Exec(query)
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		return c.Status(status).JSON(response)
 	}
 
-	affected, _ := result.RowsAffected()
-	return c.JSON(QueryResult{
-		RowsAffected: affected,
-		Duration:     time.Since(start).String(),
+	return c.JSON(fiber.Map{
+		"results":   results,
+		"read_only": req.ReadOnly,
+		"duration":  time.Since(start).String(),
 	})
 }
 
-// ExportDatabase exports database as SQL
+// exportFormats maps supported export formats to content type and file extension
+var exportFormats = map[string][2]string{
+	"sql":     {"application/sql", "sql"},
+	"sql.gz":  {"application/gzip", "sql.gz"},
+	"csv-zip": {"application/zip", "zip"},
+}
+
+// ExportDatabase streams the database as SQL, gzipped SQL or zipped CSV.
+// ?format=sql|sql.gz|csv-zip (default sql), ?tables=a,b limits the tables.
 func (h *DatabaseHandler) ExportDatabase(c *fiber.Ctx) error {
-	project, err := h.getProjectForUser(c)
+	project, err := h.getProjectForUser(c, services.ProjectRead)
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		return projectAccessError(c, err)
+	}
+
+	format := c.Query("format", "sql")
+	formatInfo, ok := exportFormats[format]
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid format (allowed: sql, sql.gz, csv-zip)"})
+	}
+
+	var tables []string
+	for _, name := range strings.Split(c.Query("tables"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			tables = append(tables, name)
+		}
 	}
 
 	db, err := h.connectToProjectDB(project)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to connect"})
 	}
-	defer db.Close()
 
-	var sqlDump strings.Builder
-	sqlDump.WriteString(fmt.Sprintf("-- Database Export: %s\n", project.DatabaseName))
-	sqlDump.WriteString(fmt.Sprintf("-- Generated: %s\n\n", time.Now().Format(time.RFC3339)))
+	dumper := services.NewDatabaseDumper(db, h.dialect(project), project.DatabaseName)
+	if len(tables) > 0 {
+		if err := dumper.ValidateTables(tables); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
 
-	// Get all tables
-	tables, _ := db.Query("SHOW TABLES")
-	defer tables.Close()
+	filename := fmt.Sprintf("%s_%s.%s", project.DatabaseName, time.Now().Format("20060102_150405"), formatInfo[1])
+	c.Set("Content-Type", formatInfo[0])
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
 
-	for tables.Next() {
-		var tableName string
-		tables.Scan(&tableName)
+	// The body is written after the handler returns, on the shared pool
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		opts := services.DumpOptions{Tables: tables, Flush: w.Flush}
 
-		// Get CREATE TABLE statement
-		var tbl, createStmt string
-		db.QueryRow(fmt.Sprintf("SHOW CREATE TABLE `%s`", tableName)).Scan(&tbl, &createStmt)
-		sqlDump.WriteString(fmt.Sprintf("-- Table: %s\n", tableName))
-		sqlDump.WriteString(fmt.Sprintf("DROP TABLE IF EXISTS `%s`;\n", tableName))
-		sqlDump.WriteString(createStmt + ";\n\n")
+		var err error
+		switch format {
+		case "sql":
+			if err = dumper.WriteSQL(w, opts); err != nil {
+				fmt.Fprintf(w, "\n-- Export aborted: %s\n", err.Error())
+			}
 
-		// Get table data
-		rows, err := db.Query(fmt.Sprintf("SELECT * FROM `%s`", tableName))
-		if err != nil {
-			continue
-		}
+		case "sql.gz":
+			gz := gzip.NewWriter(w)
+			opts.Flush = func() error {
+				if err := gz.Flush(); err != nil {
+					return err
+				}
+				return w.Flush()
+			}
+			err = dumper.WriteSQL(gz, opts)
+			gz.Close()
 
-		columns, _ := rows.Columns()
-		if len(columns) == 0 {
-			rows.Close()
-			continue
+		case "csv-zip":
+			err = dumper.WriteCSVZip(w, opts)
 		}
 
-		for rows.Next() {
-			values := make([]interface{}, len(columns))
-			valuePtrs := make([]interface{}, len(columns))
-			for i := range values {
-				valuePtrs[i] = &values[i]
-			}
-			rows.Scan(valuePtrs...)
-
-			var vals []string
-			for _, v := range values {
-				if v == nil {
-					vals = append(vals, "NULL")
-				} else if b, ok := v.([]byte); ok {
-					vals = append(vals, fmt.Sprintf("'%s'", escapeSQLString(string(b))))
-				} else {
-					vals = append(vals, fmt.Sprintf("'%v'", v))
-				}
-			}
-			sqlDump.WriteString(fmt.Sprintf("INSERT INTO `%s` VALUES (%s);\n", tableName, strings.Join(vals, ", ")))
+		if err != nil {
+			log.Printf("❌ Export of %s failed: %v", project.DatabaseName, err)
 		}
-		rows.Close()
-		sqlDump.WriteString("\n")
-	}
+		w.Flush()
+	})
 
-	c.Set("Content-Type", "application/sql")
-	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s_%s.sql", project.DatabaseName, time.Now().Format("20060102_150405")))
-	return c.SendString(sqlDump.String())
+	return nil
 }
 
-// ImportDatabase imports SQL file
+// ImportRequest is the JSON body for importing pasted SQL
 type ImportRequest struct {
 	SQL string `json:"sql"`
 }
 
+// ImportDatabase imports SQL into the project database. A multipart "file"
+// upload (.sql or .sql.gz) runs in the background and returns a job to poll;
+// a JSON "sql" body runs synchronously.
 func (h *DatabaseHandler) ImportDatabase(c *fiber.Ctx) error {
-	project, err := h.getProjectForUser(c)
+	project, err := h.getProjectForUser(c, services.ProjectWrite)
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		return projectAccessError(c, err)
+	}
+
+	if file, err := c.FormFile("file"); err == nil {
+		audit(c, "database.import", "project", project.ID, "Imported file "+file.Filename)
+		return h.startImportJob(c, project, file)
 	}
 
 	var req ImportRequest
@@ -451,87 +665,145 @@ func (h *DatabaseHandler) ImportDatabase(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
 	}
 
-	if req.SQL == "" {
+	if strings.TrimSpace(req.SQL) == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "SQL content is required"})
 	}
+	audit(c, "database.import", "project", project.ID, fmt.Sprintf("Imported %d bytes of SQL", len(req.SQL)))
 
 	db, err := h.connectToProjectDB(project)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to connect"})
 	}
-	defer db.Close()
 
-	// Split by semicolons and execute each statement
-	statements := strings.Split(req.SQL, ";")
-	successCount := 0
-	var errors []string
+	job, err := services.NewImportJob(project.ID, "", int64(len(req.SQL)))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
 
-	for _, stmt := range statements {
-		stmt = strings.TrimSpace(stmt)
-		if stmt == "" || strings.HasPrefix(stmt, "--") {
-			continue
-		}
+	services.NewDatabaseImporter(db, h.dialect(project)).ImportString(req.SQL, job, nil)
 
-		// Block dangerous operations
-		upperStmt := strings.ToUpper(stmt)
-		if strings.Contains(upperStmt, "DROP DATABASE") ||
-			strings.Contains(upperStmt, "CREATE DATABASE") {
-			errors = append(errors, "Blocked: DROP/CREATE DATABASE not allowed")
-			continue
-		}
-
-		if _, err := db.Exec(stmt); err != nil {
-			errors = append(errors, fmt.Sprintf("Error: %s", err.Error()))
-		} else {
-			successCount++
-		}
+	errors := make([]string, 0, len(job.Errors))
+	for _, e := range job.Errors {
+		errors = append(errors, fmt.Sprintf("Line %d: %s", e.Line, e.Error))
+	}
+	if job.Status == services.ImportFailed && len(job.Errors) == 0 {
+		errors = append(errors, job.Error)
 	}
 
 	return c.JSON(fiber.Map{
-		"success":    len(errors) == 0,
-		"statements": successCount,
+		"success":    job.Status == services.ImportCompleted && len(job.Errors) == 0,
+		"statements": job.Executed,
 		"errors":     errors,
+		"job":        job,
 	})
 }
 
-// ResetDatabase drops all tables
-func (h *DatabaseHandler) ResetDatabase(c *fiber.Ctx) error {
-	project, err := h.getProjectForUser(c)
+// startImportJob stores an uploaded dump and imports it in the background
+func (h *DatabaseHandler) startImportJob(c *fiber.Ctx, project *models.Project, file *multipart.FileHeader) error {
+	name := strings.ToLower(file.Filename)
+	if !strings.HasSuffix(name, ".sql") && !strings.HasSuffix(name, ".sql.gz") && !strings.HasSuffix(name, ".gz") {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Only .sql and .sql.gz files are supported"})
+	}
+
+	tmp, err := os.CreateTemp("", "paas-import-*")
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to store upload"})
 	}
+	tmp.Close()
 
-	db, err := h.connectToProjectDB(project)
+	if err := c.SaveFile(file, tmp.Name()); err != nil {
+		os.Remove(tmp.Name())
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to store upload"})
+	}
+
+	job, err := services.NewImportJob(project.ID, file.Filename, file.Size)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to connect"})
+		os.Remove(tmp.Name())
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
-	defer db.Close()
 
-	// Get all tables
-	rows, _ := db.Query("SHOW TABLES")
-	defer rows.Close()
+	if err := services.SaveImportJob(h.redisService, job); err != nil {
+		os.Remove(tmp.Name())
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to queue import"})
+	}
 
-	var tables []string
-	for rows.Next() {
-		var table string
-		rows.Scan(&table)
-		tables = append(tables, table)
+	go h.runImportJob(*project, job, tmp.Name())
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message": "Import started",
+		"job_id":  job.ID,
+		"job":     job,
+	})
+}
+
+// runImportJob executes an import job and publishes its progress
+func (h *DatabaseHandler) runImportJob(project models.Project, job *services.ImportJob, path string) {
+	defer os.Remove(path)
+
+	save := func(job *services.ImportJob) {
+		if err := services.SaveImportJob(h.redisService, job); err != nil {
+			log.Printf("Failed to save import job %s: %v", job.ID, err)
+		}
+	}
+
+	db, err := h.connectToProjectDB(&project)
+	if err != nil {
+		now := time.Now()
+		job.Status = services.ImportFailed
+		job.Error = "Failed to connect"
+		job.FinishedAt = &now
+		save(job)
+		return
 	}
 
-	// Disable foreign key checks
-	db.Exec("SET FOREIGN_KEY_CHECKS = 0")
+	if err := services.NewDatabaseImporter(db, h.dialect(&project)).ImportFile(path, job, save); err != nil {
+		log.Printf("Import job %s for project %s failed: %v", job.ID, project.Name, err)
+	}
+}
 
-	// Drop all tables
-	for _, table := range tables {
-		db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS `%s`", table))
+// GetImportStatus returns the progress of an import job
+func (h *DatabaseHandler) GetImportStatus(c *fiber.Ctx) error {
+	project, err := h.getProjectForUser(c, services.ProjectRead)
+	if err != nil {
+		return projectAccessError(c, err)
+	}
+
+	var job services.ImportJob
+	if err := h.redisService.GetCache(services.ImportJobKey(c.Params("jobId")), &job); err != nil || job.ProjectID != project.ID {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Import job not found"})
+	}
+
+	return c.JSON(job)
+}
+
+// ResetDatabase drops all tables after taking a backup
+func (h *DatabaseHandler) ResetDatabase(c *fiber.Ctx) error {
+	project, err := h.getProjectForUser(c, services.ProjectWrite)
+	if err != nil {
+		return projectAccessError(c, err)
 	}
 
-	// Re-enable foreign key checks
-	db.Exec("SET FOREIGN_KEY_CHECKS = 1")
+	audit(c, "database.reset", "project", project.ID, "Reset database "+project.DatabaseName)
+
+	backup, err := h.backupService.Backup(project, models.BackupPreReset)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to back up database before reset: " + err.Error()})
+	}
+
+	db, err := h.connectToProjectDB(project)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to connect"})
+	}
+
+	dropped, err := services.DropAllTables(db, h.dialect(project), project.DatabaseName)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
 
 	return c.JSON(fiber.Map{
-		"success": true,
-		"dropped": len(tables),
+		"success":   true,
+		"dropped":   dropped,
+		"backup_id": backup.ID,
 	})
 }
 
@@ -544,11 +816,3 @@ func isValidIdentifier(s string) bool {
 	}
 	return len(s) > 0 && len(s) < 64
 }
-
-func escapeSQLString(s string) string {
-	s = strings.ReplaceAll(s, "\\", "\\\\")
-	s = strings.ReplaceAll(s, "'", "\\'")
-	s = strings.ReplaceAll(s, "\n", "\\n")
-	s = strings.ReplaceAll(s, "\r", "\\r")
-	return s
-}
@@ -0,0 +1,285 @@
+// ===========================================
+// Table Data Browsing
+// ===========================================
+// Sorting, filtering, search and keyset
+// pagination for the table data view
+// ===========================================
+package handlers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/laravel-paas/backend/internal/models"
+)
+
+const (
+	// maxBrowseLimit caps the rows returned per page
+	maxBrowseLimit = 500
+	// cursorTimeLayout keeps time values comparable on both engines
+	cursorTimeLayout = "2006-01-02 15:04:05.999999"
+)
+
+// orderColumn is one ORDER BY term
+type orderColumn struct {
+	name string
+	desc bool
+}
+
+// browseQuery collects the WHERE and ORDER BY parts of a table data request.
+// Every column name has been checked against the table's columns.
+type browseQuery struct {
+	table *rowTable
+	where []string
+	args  []interface{}
+	order []orderColumn
+}
+
+func newBrowseQuery(table *rowTable) *browseQuery {
+	return &browseQuery{table: table}
+}
+
+// arg adds a parameter and returns its placeholder
+func (b *browseQuery) arg(value interface{}) string {
+	b.args = append(b.args, value)
+	return b.table.dialect.Placeholder(len(b.args))
+}
+
+// column returns the quoted name of an existing column
+func (b *browseQuery) column(name string) (string, error) {
+	if _, ok := b.table.columns[name]; !ok {
+		return "", fmt.Errorf("Unknown column: %s", name)
+	}
+	return b.table.dialect.QuoteIdent(name), nil
+}
+
+// like builds a pattern match; PostgreSQL needs a cast for non-text columns
+// and ILIKE to match MySQL's case-insensitive collations
+func (b *browseQuery) like(quoted, pattern string, insensitive bool) string {
+	if b.table.dialect.Engine() == models.EnginePostgres {
+		op := "LIKE"
+		if insensitive {
+			op = "ILIKE"
+		}
+		return fmt.Sprintf("CAST(%s AS TEXT) %s %s", quoted, op, b.arg(pattern))
+	}
+	return fmt.Sprintf("%s LIKE %s", quoted, b.arg(pattern))
+}
+
+// sortBy parses "col:asc,other:desc". Primary key columns are appended as a
+// tie-breaker so pages are stable and keyset cursors are unique.
+func (b *browseQuery) sortBy(spec string) error {
+	seen := make(map[string]bool)
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, dir, _ := strings.Cut(part, ":")
+		if _, err := b.column(name); err != nil {
+			return err
+		}
+
+		var desc bool
+		switch strings.ToLower(dir) {
+		case "", "asc":
+		case "desc":
+			desc = true
+		default:
+			return fmt.Errorf("Invalid sort direction: %s", dir)
+		}
+
+		if !seen[name] {
+			b.order = append(b.order, orderColumn{name: name, desc: desc})
+			seen[name] = true
+		}
+	}
+
+	for _, name := range b.table.primaryKey {
+		if !seen[name] {
+			b.order = append(b.order, orderColumn{name: name})
+		}
+	}
+	return nil
+}
+
+// filter parses "col:op:value" with op one of eq, like, gt, lt, is_null.
+// is_null takes an optional false to match non-null values.
+func (b *browseQuery) filter(spec string) error {
+	parts := strings.SplitN(spec, ":", 3)
+	if len(parts) < 2 {
+		return fmt.Errorf("Invalid filter: %s (expected column:operator:value)", spec)
+	}
+
+	quoted, err := b.column(parts[0])
+	if err != nil {
+		return err
+	}
+
+	op := parts[1]
+	if op == "is_null" {
+		if len(parts) == 3 && parts[2] == "false" {
+			b.where = append(b.where, quoted+" IS NOT NULL")
+		} else {
+			b.where = append(b.where, quoted+" IS NULL")
+		}
+		return nil
+	}
+
+	if len(parts) < 3 {
+		return fmt.Errorf("Filter %s on %s requires a value", op, parts[0])
+	}
+	value := parts[2]
+
+	switch op {
+	case "eq":
+		b.where = append(b.where, fmt.Sprintf("%s = %s", quoted, b.arg(value)))
+	case "gt":
+		b.where = append(b.where, fmt.Sprintf("%s > %s", quoted, b.arg(value)))
+	case "lt":
+		b.where = append(b.where, fmt.Sprintf("%s < %s", quoted, b.arg(value)))
+	case "like":
+		// Plain values match anywhere; explicit wildcards are kept as given
+		if !strings.ContainsAny(value, "%_") {
+			value = "%" + value + "%"
+		}
+		b.where = append(b.where, b.like(quoted, value, false))
+	default:
+		return fmt.Errorf("Invalid filter operator: %s", op)
+	}
+	return nil
+}
+
+// search matches text anywhere in the table's text columns
+func (b *browseQuery) search(text string) {
+	pattern := "%" + escapeLike(text) + "%"
+
+	var terms []string
+	for _, name := range b.table.names {
+		if !isTextColumn(b.table.columns[name].Type) {
+			continue
+		}
+		terms = append(terms, b.like(b.table.dialect.QuoteIdent(name), pattern, true))
+	}
+
+	if len(terms) == 0 {
+		// No text columns: nothing can match
+		b.where = append(b.where, "1 = 0")
+		return
+	}
+	b.where = append(b.where, "("+strings.Join(terms, " OR ")+")")
+}
+
+// after restricts rows to those following the cursor in sort order. Mixed
+// directions rule out row value comparison, so the condition is expanded:
+// (a > ?) OR (a = ? AND b > ?) ...
+func (b *browseQuery) after(cursor []interface{}) error {
+	if len(cursor) != len(b.order) {
+		return fmt.Errorf("Cursor does not match the sort order")
+	}
+
+	var terms []string
+	for i, col := range b.order {
+		var parts []string
+		for j := 0; j < i; j++ {
+			if cursor[j] == nil {
+				return fmt.Errorf("Keyset pagination cannot continue past NULL values of %s, use page instead", b.order[j].name)
+			}
+			parts = append(parts, fmt.Sprintf("%s = %s", b.table.dialect.QuoteIdent(b.order[j].name), b.arg(cursor[j])))
+		}
+
+		if cursor[i] == nil {
+			return fmt.Errorf("Keyset pagination cannot continue past NULL values of %s, use page instead", col.name)
+		}
+		op := ">"
+		if col.desc {
+			op = "<"
+		}
+		parts = append(parts, fmt.Sprintf("%s %s %s", b.table.dialect.QuoteIdent(col.name), op, b.arg(cursor[i])))
+		terms = append(terms, "("+strings.Join(parts, " AND ")+")")
+	}
+
+	b.where = append(b.where, "("+strings.Join(terms, " OR ")+")")
+	return nil
+}
+
+// whereClause returns " WHERE ..." or an empty string
+func (b *browseQuery) whereClause() string {
+	if len(b.where) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(b.where, " AND ")
+}
+
+// orderClause returns " ORDER BY ..." or an empty string
+func (b *browseQuery) orderClause() string {
+	if len(b.order) == 0 {
+		return ""
+	}
+	terms := make([]string, len(b.order))
+	for i, col := range b.order {
+		terms[i] = b.table.dialect.QuoteIdent(col.name)
+		if col.desc {
+			terms[i] += " DESC"
+		}
+	}
+	return " ORDER BY " + strings.Join(terms, ", ")
+}
+
+// encodeCursor captures the sort values of the last row of a page
+func (b *browseQuery) encodeCursor(row map[string]interface{}) string {
+	values := make([]interface{}, len(b.order))
+	for i, col := range b.order {
+		value := row[col.name]
+		if t, ok := value.(time.Time); ok {
+			value = t.Format(cursorTimeLayout)
+		}
+		values[i] = value
+	}
+	data, _ := json.Marshal(values)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor parses a cursor produced by encodeCursor
+func decodeCursor(cursor string) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid cursor")
+	}
+
+	// Keep numbers as text so large IDs stay exact
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var values []interface{}
+	if err := decoder.Decode(&values); err != nil {
+		return nil, fmt.Errorf("Invalid cursor")
+	}
+	for i, v := range values {
+		if n, ok := v.(json.Number); ok {
+			values[i] = n.String()
+		}
+	}
+	return values, nil
+}
+
+// isTextColumn reports character and JSON column types
+func isTextColumn(colType string) bool {
+	t := strings.ToLower(colType)
+	for _, text := range []string{"char", "text", "enum", "set(", "json", "uuid"} {
+		if strings.Contains(t, text) {
+			return true
+		}
+	}
+	return false
+}
+
+// escapeLike escapes LIKE wildcards so search text matches literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
@@ -0,0 +1,413 @@
+// ===========================================
+// Database Row Editing
+// ===========================================
+// Inserts, updates and deletes single rows of
+// project tables with parameterized statements
+// ===========================================
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/laravel-paas/backend/internal/models"
+	"github.com/laravel-paas/backend/internal/services"
+)
+
+// RowRequest identifies a row by primary key and carries column values
+type RowRequest struct {
+	Key    map[string]interface{} `json:"key"`
+	Values map[string]interface{} `json:"values"`
+}
+
+// rowTable is the metadata needed to edit rows of one table
+type rowTable struct {
+	name       string
+	names      []string // column names in table order
+	columns    map[string]ColumnInfo
+	primaryKey []string
+	dialect    services.Dialect
+}
+
+// loadRowTable validates the table name and loads its columns
+func (h *DatabaseHandler) loadRowTable(ctx context.Context, db *sql.DB, project *models.Project, tableName string) (*rowTable, error) {
+	if !isValidIdentifier(tableName) {
+		return nil, fmt.Errorf("Invalid table name")
+	}
+
+	columns, err := h.tableColumns(ctx, db, project, tableName)
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("Table not found")
+	}
+
+	table := &rowTable{
+		name:    tableName,
+		columns: make(map[string]ColumnInfo, len(columns)),
+		dialect: h.dialect(project),
+	}
+	for _, col := range columns {
+		table.names = append(table.names, col.Name)
+		table.columns[col.Name] = col
+		if col.Key == "PRI" {
+			table.primaryKey = append(table.primaryKey, col.Name)
+		}
+	}
+	return table, nil
+}
+
+// checkColumns ensures every name is a valid, existing column
+func (t *rowTable) checkColumns(values map[string]interface{}) error {
+	for name := range values {
+		if !isValidIdentifier(name) {
+			return fmt.Errorf("Invalid column name: %s", name)
+		}
+		if _, ok := t.columns[name]; !ok {
+			return fmt.Errorf("Unknown column: %s", name)
+		}
+	}
+	return nil
+}
+
+// checkKey ensures the key names exactly the primary key columns
+func (t *rowTable) checkKey(key map[string]interface{}) error {
+	if len(t.primaryKey) == 0 {
+		return fmt.Errorf("Table %s has no primary key, rows cannot be identified", t.name)
+	}
+	if len(key) != len(t.primaryKey) {
+		return fmt.Errorf("Key must contain exactly the primary key columns: %s", strings.Join(t.primaryKey, ", "))
+	}
+	for _, name := range t.primaryKey {
+		if _, ok := key[name]; !ok {
+			return fmt.Errorf("Key must contain exactly the primary key columns: %s", strings.Join(t.primaryKey, ", "))
+		}
+	}
+	return nil
+}
+
+// param converts a JSON value to a driver argument for the column type
+func (t *rowTable) param(column string, value interface{}) interface{} {
+	colType := strings.ToLower(t.columns[column].Type)
+
+	switch v := value.(type) {
+	case float64:
+		// JSON numbers are floats; keep integers exact
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int64(v)
+		}
+		return v
+	case bool:
+		if strings.Contains(colType, "bool") {
+			return v
+		}
+		if v {
+			return 1
+		}
+		return 0
+	case map[string]interface{}, []interface{}:
+		// JSON columns
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+	return value
+}
+
+// where builds "a = ? AND b = ?" for the key, numbering placeholders from n
+func (t *rowTable) where(key map[string]interface{}, n int) (string, []interface{}) {
+	var parts []string
+	var args []interface{}
+	for _, name := range t.primaryKey {
+		parts = append(parts, fmt.Sprintf("%s = %s", t.dialect.QuoteIdent(name), t.dialect.Placeholder(n)))
+		args = append(args, t.param(name, key[name]))
+		n++
+	}
+	return strings.Join(parts, " AND "), args
+}
+
+// fetch returns the row with the given key, or nil when it does not exist
+func (t *rowTable) fetch(ctx context.Context, tx *sql.Tx, key map[string]interface{}) (map[string]interface{}, error) {
+	where, args := t.where(key, 1)
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s WHERE %s", t.dialect.QuoteIdent(t.name), where), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result, err := scanRowMaps(rows)
+	if err != nil || len(result) == 0 {
+		return nil, err
+	}
+	return result[0], nil
+}
+
+// sortedNames returns map keys in a stable order
+func sortedNames(values map[string]interface{}) []string {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// scanRowMaps reads all rows as column -> value maps
+func scanRowMaps(rows *sql.Rows) ([]map[string]interface{}, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var data []map[string]interface{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		valuePtrs := make([]interface{}, len(columns))
+		for i := range values {
+			valuePtrs[i] = &values[i]
+		}
+		if err := rows.Scan(valuePtrs...); err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]interface{}, len(columns))
+		for i, col := range columns {
+			if b, ok := values[i].([]byte); ok {
+				row[col] = string(b)
+			} else {
+				row[col] = values[i]
+			}
+		}
+		data = append(data, row)
+	}
+	return data, rows.Err()
+}
+
+// parseRowRequest loads the project, table and request body shared by row endpoints
+func (h *DatabaseHandler) parseRowRequest(ctx context.Context, c *fiber.Ctx) (*sql.DB, *rowTable, *RowRequest, error) {
+	project, err := h.getProjectForUser(c, services.ProjectWrite)
+	if err != nil {
+		return nil, nil, nil, fiber.NewError(projectAccessStatus(err), err.Error())
+	}
+
+	var req RowRequest
+	if err := c.BodyParser(&req); err != nil {
+		return nil, nil, nil, fiber.NewError(fiber.StatusBadRequest, "Invalid request")
+	}
+
+	db, err := h.connectToProjectDB(project)
+	if err != nil {
+		return nil, nil, nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to connect")
+	}
+
+	table, err := h.loadRowTable(ctx, db, project, c.Params("table"))
+	if err != nil {
+		return nil, nil, nil, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := table.checkColumns(req.Values); err != nil {
+		return nil, nil, nil, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if err := table.checkColumns(req.Key); err != nil {
+		return nil, nil, nil, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return db, table, &req, nil
+}
+
+// rowError writes an error returned by parseRowRequest
+func rowError(c *fiber.Ctx, err error) error {
+	if ferr, ok := err.(*fiber.Error); ok {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+}
+
+// InsertRow inserts a row and returns it as stored
+func (h *DatabaseHandler) InsertRow(c *fiber.Ctx) error {
+	ctx, cancel := h.queryContext()
+	defer cancel()
+
+	db, table, req, err := h.parseRowRequest(ctx, c)
+	if err != nil {
+		return rowError(c, err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	defer tx.Rollback()
+
+	quotedTable := table.dialect.QuoteIdent(table.name)
+	postgres := table.dialect.Engine() == models.EnginePostgres
+
+	var query string
+	var args []interface{}
+	if len(req.Values) == 0 {
+		query = fmt.Sprintf("INSERT INTO %s DEFAULT VALUES", quotedTable)
+		if !postgres {
+			query = fmt.Sprintf("INSERT INTO %s () VALUES ()", quotedTable)
+		}
+	} else {
+		var columns, placeholders []string
+		for i, name := range sortedNames(req.Values) {
+			columns = append(columns, table.dialect.QuoteIdent(name))
+			placeholders = append(placeholders, table.dialect.Placeholder(i+1))
+			args = append(args, table.param(name, req.Values[name]))
+		}
+		query = fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", quotedTable, strings.Join(columns, ", "), strings.Join(placeholders, ", "))
+	}
+
+	var row map[string]interface{}
+	if postgres {
+		rows, err := tx.QueryContext(ctx, query+" RETURNING *", args...)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		inserted, err := scanRowMaps(rows)
+		rows.Close()
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		if len(inserted) > 0 {
+			row = inserted[0]
+		}
+	} else {
+		result, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		// Read the row back by primary key, using the generated ID when it was not given
+		if len(table.primaryKey) > 0 {
+			key := make(map[string]interface{})
+			for _, name := range table.primaryKey {
+				if value, ok := req.Values[name]; ok {
+					key[name] = value
+				} else if table.columns[name].Extra == "auto_increment" {
+					id, _ := result.LastInsertId()
+					key[name] = float64(id)
+				}
+			}
+			if len(key) == len(table.primaryKey) {
+				if row, err = table.fetch(ctx, tx, key); err != nil {
+					return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+				}
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"row": row})
+}
+
+// UpdateRow updates the row identified by its primary key and returns it
+func (h *DatabaseHandler) UpdateRow(c *fiber.Ctx) error {
+	ctx, cancel := h.queryContext()
+	defer cancel()
+
+	db, table, req, err := h.parseRowRequest(ctx, c)
+	if err != nil {
+		return rowError(c, err)
+	}
+
+	if err := table.checkKey(req.Key); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if len(req.Values) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "No values to update"})
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	defer tx.Rollback()
+
+	var sets []string
+	var args []interface{}
+	for i, name := range sortedNames(req.Values) {
+		sets = append(sets, fmt.Sprintf("%s = %s", table.dialect.QuoteIdent(name), table.dialect.Placeholder(i+1)))
+		args = append(args, table.param(name, req.Values[name]))
+	}
+	where, whereArgs := table.where(req.Key, len(args)+1)
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s", table.dialect.QuoteIdent(table.name), strings.Join(sets, ", "), where)
+	if _, err := tx.ExecContext(ctx, query, append(args, whereArgs...)...); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// The key may have been changed by the update itself
+	newKey := make(map[string]interface{}, len(req.Key))
+	for name, value := range req.Key {
+		if updated, ok := req.Values[name]; ok {
+			value = updated
+		}
+		newKey[name] = value
+	}
+
+	// MySQL reports 0 affected rows when values are unchanged, so check existence instead
+	row, err := table.fetch(ctx, tx, newKey)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	if row == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Row not found"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"row": row})
+}
+
+// DeleteRow deletes the row identified by its primary key and returns it
+func (h *DatabaseHandler) DeleteRow(c *fiber.Ctx) error {
+	ctx, cancel := h.queryContext()
+	defer cancel()
+
+	db, table, req, err := h.parseRowRequest(ctx, c)
+	if err != nil {
+		return rowError(c, err)
+	}
+
+	if err := table.checkKey(req.Key); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	defer tx.Rollback()
+
+	row, err := table.fetch(ctx, tx, req.Key)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	if row == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Row not found"})
+	}
+
+	where, args := table.where(req.Key, 1)
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE %s", table.dialect.QuoteIdent(table.name), where), args...); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"row": row})
+}
@@ -0,0 +1,505 @@
+// ===========================================
+// Schema Designer
+// ===========================================
+// Structured endpoints to create and alter
+// tables, with DDL preview and migrations
+// ===========================================
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/laravel-paas/backend/internal/models"
+	"github.com/laravel-paas/backend/internal/services"
+)
+
+// tableIndexes returns the indexes of a table
+func (h *DatabaseHandler) tableIndexes(ctx context.Context, db *sql.DB, project *models.Project, tableName string) ([]services.IndexSpec, error) {
+	rows, err := db.QueryContext(ctx, h.dialect(project).IndexesQuery(), project.DatabaseName, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var indexes []services.IndexSpec
+	for rows.Next() {
+		var name, column string
+		var unique, primary bool
+		if err := rows.Scan(&name, &column, &unique, &primary); err != nil {
+			return nil, err
+		}
+		// Rows are ordered by index, one per column
+		if n := len(indexes); n > 0 && indexes[n-1].Name == name {
+			indexes[n-1].Columns = append(indexes[n-1].Columns, column)
+			continue
+		}
+		indexes = append(indexes, services.IndexSpec{Name: name, Columns: []string{column}, Unique: unique, Primary: primary})
+	}
+	return indexes, rows.Err()
+}
+
+// tableForeignKeys returns the foreign keys of a table
+func (h *DatabaseHandler) tableForeignKeys(ctx context.Context, db *sql.DB, project *models.Project, tableName string) ([]services.ForeignKeySpec, error) {
+	rows, err := db.QueryContext(ctx, h.dialect(project).ForeignKeysQuery(), project.DatabaseName, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []services.ForeignKeySpec
+	for rows.Next() {
+		var name, column, refTable, refColumn, onDelete, onUpdate string
+		if err := rows.Scan(&name, &column, &refTable, &refColumn, &onDelete, &onUpdate); err != nil {
+			return nil, err
+		}
+		if n := len(keys); n > 0 && keys[n-1].Name == name {
+			keys[n-1].Columns = append(keys[n-1].Columns, column)
+			keys[n-1].ReferencedColumns = append(keys[n-1].ReferencedColumns, refColumn)
+			continue
+		}
+		keys = append(keys, services.ForeignKeySpec{
+			Name:              name,
+			Columns:           []string{column},
+			ReferencedTable:   refTable,
+			ReferencedColumns: []string{refColumn},
+			OnDelete:          onDelete,
+			OnUpdate:          onUpdate,
+		})
+	}
+	return keys, rows.Err()
+}
+
+// GetSchema returns every table with its columns, keys, indexes and
+// relationships. ?format=dbml or ?format=mermaid returns diagram text instead.
+func (h *DatabaseHandler) GetSchema(c *fiber.Ctx) error {
+	project, err := h.getProjectForUser(c, services.ProjectRead)
+	if err != nil {
+		return projectAccessError(c, err)
+	}
+
+	format := c.Query("format", "json")
+	if format != "json" && format != "dbml" && format != "mermaid" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid format (json, dbml or mermaid)"})
+	}
+
+	db, err := h.connectToProjectDB(project)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to connect to database"})
+	}
+
+	ctx, cancel := h.queryContext()
+	defer cancel()
+
+	dialect := h.dialect(project)
+	rows, err := db.QueryContext(ctx, dialect.ListTablesQuery(), project.DatabaseName)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	var names []string
+	for rows.Next() {
+		var name, engine string
+		var count int64
+		var sizeKB float64
+		var created sql.NullTime
+		if err := rows.Scan(&name, &count, &sizeKB, &engine, &created); err != nil {
+			continue
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+
+	schema := services.DatabaseSchema{
+		Database: project.DatabaseName,
+		Engine:   string(dialect.Engine()),
+		Tables:   []services.SchemaTable{},
+	}
+	for _, name := range names {
+		table := services.SchemaTable{
+			Name:        name,
+			PrimaryKey:  []string{},
+			Indexes:     []services.IndexSpec{},
+			ForeignKeys: []services.ForeignKeySpec{},
+		}
+
+		columns, err := h.tableColumns(ctx, db, project, name)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		for _, col := range columns {
+			table.Columns = append(table.Columns, services.SchemaColumn{
+				Name:          col.Name,
+				Type:          col.Type,
+				Nullable:      col.Nullable,
+				Default:       col.Default,
+				AutoIncrement: col.Extra == "auto_increment",
+			})
+		}
+
+		indexes, err := h.tableIndexes(ctx, db, project, name)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		for _, index := range indexes {
+			if index.Primary {
+				table.PrimaryKey = index.Columns
+			}
+			table.Indexes = append(table.Indexes, index)
+		}
+
+		keys, err := h.tableForeignKeys(ctx, db, project, name)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		table.ForeignKeys = append(table.ForeignKeys, keys...)
+
+		schema.Tables = append(schema.Tables, table)
+	}
+	schema.BuildRelationships()
+
+	switch format {
+	case "dbml":
+		c.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+		return c.SendString(schema.DBML())
+	case "mermaid":
+		c.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+		return c.SendString(schema.Mermaid())
+	}
+	return c.JSON(schema)
+}
+
+// prepareSchemaChange validates a change against the current schema and
+// records the definitions it replaces
+func (h *DatabaseHandler) prepareSchemaChange(ctx context.Context, db *sql.DB, project *models.Project, change *services.SchemaChange) error {
+	change.Normalize()
+	if err := change.Validate(); err != nil {
+		return err
+	}
+	for _, name := range change.Identifiers() {
+		if !isValidIdentifier(name) {
+			return fmt.Errorf("Invalid identifier: %s", name)
+		}
+	}
+
+	if change.Action == services.SchemaCreateTable {
+		columns, err := h.tableColumns(ctx, db, project, change.Table)
+		if err != nil {
+			return err
+		}
+		if len(columns) > 0 {
+			return fmt.Errorf("Table %s already exists", change.Table)
+		}
+		return nil
+	}
+
+	table, err := h.loadRowTable(ctx, db, project, change.Table)
+	if err != nil {
+		return err
+	}
+	previousColumn := func(name string) (*services.ColumnSpec, error) {
+		col, ok := table.columns[name]
+		if !ok {
+			return nil, fmt.Errorf("Unknown column: %s", name)
+		}
+		spec := services.ColumnSpecFromCatalog(col.Name, col.Type, col.Nullable, col.Default, col.Key, col.Extra)
+		return &spec, nil
+	}
+	requireColumns := func(names []string) error {
+		for _, name := range names {
+			if _, ok := table.columns[name]; !ok {
+				return fmt.Errorf("Unknown column: %s", name)
+			}
+		}
+		return nil
+	}
+
+	switch change.Action {
+	case services.SchemaAddColumn:
+		if _, ok := table.columns[change.Column.Name]; ok {
+			return fmt.Errorf("Column %s already exists", change.Column.Name)
+		}
+
+	case services.SchemaModifyColumn, services.SchemaDropColumn:
+		if change.PreviousColumn, err = previousColumn(change.Name); err != nil {
+			return err
+		}
+
+	case services.SchemaAddIndex, services.SchemaDropIndex:
+		indexes, err := h.tableIndexes(ctx, db, project, change.Table)
+		if err != nil {
+			return err
+		}
+		name := change.Name
+		if change.Action == services.SchemaAddIndex {
+			if err := requireColumns(change.Index.Columns); err != nil {
+				return err
+			}
+			name = change.Index.Name
+		}
+
+		for i := range indexes {
+			if indexes[i].Name != name {
+				continue
+			}
+			if change.Action == services.SchemaAddIndex {
+				return fmt.Errorf("Index %s already exists", name)
+			}
+			if indexes[i].Primary {
+				return fmt.Errorf("The primary key cannot be dropped")
+			}
+			change.PreviousIndex = &indexes[i]
+		}
+		if change.Action == services.SchemaDropIndex && change.PreviousIndex == nil {
+			return fmt.Errorf("Index %s not found", name)
+		}
+
+	case services.SchemaAddForeignKey:
+		fk := change.ForeignKey
+		if err := requireColumns(fk.Columns); err != nil {
+			return err
+		}
+		refColumns, err := h.tableColumns(ctx, db, project, fk.ReferencedTable)
+		if err != nil {
+			return err
+		}
+		if len(refColumns) == 0 {
+			return fmt.Errorf("Referenced table %s not found", fk.ReferencedTable)
+		}
+		for _, name := range fk.ReferencedColumns {
+			found := false
+			for _, col := range refColumns {
+				found = found || col.Name == name
+			}
+			if !found {
+				return fmt.Errorf("Unknown column %s in %s", name, fk.ReferencedTable)
+			}
+		}
+
+	case services.SchemaDropForeignKey:
+		keys, err := h.tableForeignKeys(ctx, db, project, change.Table)
+		if err != nil {
+			return err
+		}
+		for i := range keys {
+			if keys[i].Name == change.Name {
+				change.PreviousForeignKey = &keys[i]
+			}
+		}
+		if change.PreviousForeignKey == nil {
+			return fmt.Errorf("Foreign key %s not found", change.Name)
+		}
+	}
+
+	return nil
+}
+
+// applySchemaChange validates the change and runs its DDL, or only returns
+// the DDL with ?dry_run=true
+func (h *DatabaseHandler) applySchemaChange(c *fiber.Ctx, change *services.SchemaChange) error {
+	project, err := h.getProjectForUser(c, services.ProjectWrite)
+	if err != nil {
+		return projectAccessError(c, err)
+	}
+
+	db, err := h.connectToProjectDB(project)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to connect"})
+	}
+
+	ctx, cancel := h.queryContext()
+	defer cancel()
+
+	if err := h.prepareSchemaChange(ctx, db, project, change); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	dialect := h.dialect(project)
+	statements, err := services.SchemaDDL(dialect, change)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// The equivalent migration, so the change can be committed to the repository
+	migration := fiber.Map{}
+	if filename, content, err := services.LaravelMigration(change, time.Now()); err == nil {
+		migration = fiber.Map{"filename": filename, "content": content}
+	}
+
+	if c.Query("dry_run") == "true" {
+		return c.JSON(fiber.Map{"statements": statements, "migration": migration, "dry_run": true})
+	}
+
+	// PostgreSQL DDL is transactional; MySQL commits each statement implicitly
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range statements {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":      err.Error(),
+				"statement":  stmt,
+				"statements": statements,
+			})
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"statements": statements, "migration": migration, "dry_run": false})
+}
+
+// CreateTableRequest is the payload of CreateTable
+type CreateTableRequest struct {
+	Name    string                `json:"name"`
+	Columns []services.ColumnSpec `json:"columns"`
+}
+
+// CreateTable creates a table from column definitions
+func (h *DatabaseHandler) CreateTable(c *fiber.Ctx) error {
+	var req CreateTableRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	return h.applySchemaChange(c, &services.SchemaChange{
+		Action:  services.SchemaCreateTable,
+		Table:   req.Name,
+		Columns: req.Columns,
+	})
+}
+
+// AddColumn adds a column to a table
+func (h *DatabaseHandler) AddColumn(c *fiber.Ctx) error {
+	var col services.ColumnSpec
+	if err := c.BodyParser(&col); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	return h.applySchemaChange(c, &services.SchemaChange{
+		Action: services.SchemaAddColumn,
+		Table:  c.Params("table"),
+		Column: &col,
+	})
+}
+
+// ModifyColumn changes the type, nullability or default of a column
+func (h *DatabaseHandler) ModifyColumn(c *fiber.Ctx) error {
+	var col services.ColumnSpec
+	if err := c.BodyParser(&col); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	return h.applySchemaChange(c, &services.SchemaChange{
+		Action: services.SchemaModifyColumn,
+		Table:  c.Params("table"),
+		Name:   c.Params("column"),
+		Column: &col,
+	})
+}
+
+// DropColumn removes a column from a table
+func (h *DatabaseHandler) DropColumn(c *fiber.Ctx) error {
+	return h.applySchemaChange(c, &services.SchemaChange{
+		Action: services.SchemaDropColumn,
+		Table:  c.Params("table"),
+		Name:   c.Params("column"),
+	})
+}
+
+// AddIndex creates an index on a table
+func (h *DatabaseHandler) AddIndex(c *fiber.Ctx) error {
+	var index services.IndexSpec
+	if err := c.BodyParser(&index); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	index.Primary = false
+
+	return h.applySchemaChange(c, &services.SchemaChange{
+		Action: services.SchemaAddIndex,
+		Table:  c.Params("table"),
+		Index:  &index,
+	})
+}
+
+// DropIndex removes an index from a table
+func (h *DatabaseHandler) DropIndex(c *fiber.Ctx) error {
+	return h.applySchemaChange(c, &services.SchemaChange{
+		Action: services.SchemaDropIndex,
+		Table:  c.Params("table"),
+		Name:   c.Params("index"),
+	})
+}
+
+// AddForeignKey adds a foreign key constraint to a table
+func (h *DatabaseHandler) AddForeignKey(c *fiber.Ctx) error {
+	var fk services.ForeignKeySpec
+	if err := c.BodyParser(&fk); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	return h.applySchemaChange(c, &services.SchemaChange{
+		Action:     services.SchemaAddForeignKey,
+		Table:      c.Params("table"),
+		ForeignKey: &fk,
+	})
+}
+
+// DropForeignKey removes a foreign key constraint from a table
+func (h *DatabaseHandler) DropForeignKey(c *fiber.Ctx) error {
+	return h.applySchemaChange(c, &services.SchemaChange{
+		Action: services.SchemaDropForeignKey,
+		Table:  c.Params("table"),
+		Name:   c.Params("name"),
+	})
+}
+
+// GenerateMigration returns a Laravel migration equivalent to a schema change
+// that has not been applied yet. The body is a services.SchemaChange; with
+// ?download=true the PHP file is sent as an attachment.
+func (h *DatabaseHandler) GenerateMigration(c *fiber.Ctx) error {
+	project, err := h.getProjectForUser(c, services.ProjectRead)
+	if err != nil {
+		return projectAccessError(c, err)
+	}
+
+	var change services.SchemaChange
+	if err := c.BodyParser(&change); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	db, err := h.connectToProjectDB(project)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to connect"})
+	}
+
+	ctx, cancel := h.queryContext()
+	defer cancel()
+
+	if err := h.prepareSchemaChange(ctx, db, project, &change); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	filename, content, err := services.LaravelMigration(&change, time.Now())
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if c.Query("download") == "true" {
+		c.Attachment(filename)
+		c.Set(fiber.HeaderContentType, "application/x-php")
+		return c.SendString(content)
+	}
+
+	return c.JSON(fiber.Map{
+		"filename": filename,
+		"path":     "database/migrations/" + filename,
+		"content":  content,
+	})
+}
@@ -0,0 +1,334 @@
+// ===========================================
+// Project Member Handler
+// ===========================================
+// Collaborators on a project and their
+// email invitations
+// ===========================================
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/laravel-paas/backend/internal/config"
+	"github.com/laravel-paas/backend/internal/models"
+	"github.com/laravel-paas/backend/internal/services"
+	"gorm.io/gorm"
+)
+
+// MemberHandler handles project member and invitation endpoints
+type MemberHandler struct {
+	db     *gorm.DB
+	cfg    *config.Config
+	mailer services.Mailer
+	access *services.AccessService
+}
+
+// NewMemberHandler creates a new project member handler
+func NewMemberHandler(db *gorm.DB, cfg *config.Config, mailer services.Mailer) *MemberHandler {
+	return &MemberHandler{
+		db:     db,
+		cfg:    cfg,
+		mailer: mailer,
+		access: services.NewAccessService(db),
+	}
+}
+
+// InviteMemberRequest represents invitation payload
+type InviteMemberRequest struct {
+	Email string            `json:"email"`
+	Role  models.MemberRole `json:"role"`
+}
+
+// UpdateMemberRequest represents member role change payload
+type UpdateMemberRequest struct {
+	Role models.MemberRole `json:"role"`
+}
+
+// invitableRoles are the roles a collaborator can be given; the owner is the project's user
+var invitableRoles = map[models.MemberRole]bool{
+	models.MemberDeveloper: true,
+	models.MemberViewer:    true,
+}
+
+// List returns the owner, members and pending invitations of a project
+func (h *MemberHandler) List(c *fiber.Ctx) error {
+	project, err := resolveProject(c, h.access, services.ProjectRead, "User")
+	if err != nil {
+		return projectAccessError(c, err)
+	}
+
+	var members []models.ProjectMember
+	if err := h.db.Preload("User").Where("project_id = ?", project.ID).Order("created_at").Find(&members).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch members",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"owner":      project.User,
+		"data":       members,
+		"can_manage": h.canManage(c, project),
+	})
+}
+
+// Invite adds a pending member and emails the invitation
+func (h *MemberHandler) Invite(c *fiber.Ctx) error {
+	project, err := resolveProject(c, h.access, services.ProjectDelete, "User")
+	if err != nil {
+		return projectAccessError(c, err)
+	}
+
+	var req InviteMemberRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	address, err := mail.ParseAddress(strings.TrimSpace(req.Email))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid email address",
+		})
+	}
+	email := address.Address
+
+	if !invitableRoles[req.Role] {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Role must be developer or viewer",
+		})
+	}
+	if strings.EqualFold(email, project.User.Email) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "The owner is already a member",
+		})
+	}
+
+	var count int64
+	h.db.Model(&models.ProjectMember{}).Where("project_id = ? AND email = ?", project.ID, email).Count(&count)
+	if count > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "This email is already invited",
+		})
+	}
+
+	member := models.ProjectMember{
+		ProjectID:   project.ID,
+		Email:       email,
+		Role:        req.Role,
+		InvitedByID: c.Locals("user_id").(uint),
+	}
+	if err := h.db.Create(&member).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to invite member",
+		})
+	}
+
+	audit(c, "project.member.invite", "project", project.ID, fmt.Sprintf("Invited %s as %s", email, req.Role))
+
+	inviter, _ := c.Locals("email").(string)
+	body := fmt.Sprintf("Hello,\n\n"+
+		"%s invited you to collaborate on the Laravel PaaS project %s as %s.\n"+
+		"Sign in with this email address and accept the invitation on your projects page:\n\n%s\n",
+		inviter, project.Name, req.Role, h.cfg.AppURL+"/projects")
+
+	go func() {
+		if err := h.mailer.Send(email, "Invitation to "+project.Name, body); err != nil {
+			log.Printf("Failed to send project invitation #%d: %v", member.ID, err)
+		}
+	}()
+
+	return c.Status(fiber.StatusCreated).JSON(member)
+}
+
+// Update changes the role of a member
+func (h *MemberHandler) Update(c *fiber.Ctx) error {
+	project, err := resolveProject(c, h.access, services.ProjectDelete)
+	if err != nil {
+		return projectAccessError(c, err)
+	}
+
+	member, err := h.getMember(c, project)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var req UpdateMemberRequest
+	if err := c.BodyParser(&req); err != nil || !invitableRoles[req.Role] {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Role must be developer or viewer",
+		})
+	}
+
+	audit(c, "project.member.update", "project", project.ID, fmt.Sprintf("Role of %s: %s -> %s", member.Email, member.Role, req.Role))
+
+	member.Role = req.Role
+	if err := h.db.Save(member).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update member",
+		})
+	}
+
+	return c.JSON(member)
+}
+
+// Remove revokes a member or invitation. Members may also remove themselves.
+func (h *MemberHandler) Remove(c *fiber.Ctx) error {
+	project, err := resolveProject(c, h.access, services.ProjectRead)
+	if err != nil {
+		return projectAccessError(c, err)
+	}
+
+	member, err := h.getMember(c, project)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	userID := c.Locals("user_id").(uint)
+	leaving := member.UserID != nil && *member.UserID == userID
+	if !leaving && !h.canManage(c, project) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": services.ErrProjectForbidden.Error(),
+		})
+	}
+
+	audit(c, "project.member.remove", "project", project.ID, fmt.Sprintf("Removed %s (%s)", member.Email, member.Role))
+
+	if err := h.db.Delete(member).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to remove member",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Member removed",
+	})
+}
+
+// ListInvitations returns the pending invitations for the current user's email
+func (h *MemberHandler) ListInvitations(c *fiber.Ctx) error {
+	email, err := h.currentEmail(c)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var invitations []models.ProjectMember
+	if err := h.db.Preload("Project").Preload("InvitedBy").
+		Where("email = ? AND accepted_at IS NULL", email).
+		Order("created_at DESC").Find(&invitations).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch invitations",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": invitations,
+	})
+}
+
+// AcceptInvitation makes the current user a member of the inviting project
+func (h *MemberHandler) AcceptInvitation(c *fiber.Ctx) error {
+	invitation, err := h.getInvitation(c)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	userID := c.Locals("user_id").(uint)
+
+	// An earlier invitation to a previous email of the user may already be accepted
+	var count int64
+	h.db.Model(&models.ProjectMember{}).Where("project_id = ? AND user_id = ?", invitation.ProjectID, userID).Count(&count)
+	if count > 0 {
+		h.db.Delete(invitation)
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "You are already a member of this project",
+		})
+	}
+
+	now := time.Now()
+	invitation.UserID = &userID
+	invitation.AcceptedAt = &now
+	if err := h.db.Save(invitation).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to accept invitation",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":    "Invitation accepted",
+		"project_id": invitation.ProjectID,
+	})
+}
+
+// DeclineInvitation deletes a pending invitation of the current user
+func (h *MemberHandler) DeclineInvitation(c *fiber.Ctx) error {
+	invitation, err := h.getInvitation(c)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := h.db.Delete(invitation).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to decline invitation",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Invitation declined",
+	})
+}
+
+// canManage reports whether the current user may manage the members of the project
+func (h *MemberHandler) canManage(c *fiber.Ctx, project *models.Project) bool {
+	userID := c.Locals("user_id").(uint)
+	role := c.Locals("role").(string)
+	return h.access.CanAccessProject(userID, role, project, services.ProjectDelete)
+}
+
+// getMember fetches the member in the :memberId parameter of the project
+func (h *MemberHandler) getMember(c *fiber.Ctx, project *models.Project) (*models.ProjectMember, error) {
+	id, err := strconv.ParseUint(c.Params("memberId"), 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid member ID")
+	}
+
+	var member models.ProjectMember
+	if err := h.db.Where("project_id = ?", project.ID).First(&member, id).Error; err != nil {
+		return nil, fmt.Errorf("member not found")
+	}
+	return &member, nil
+}
+
+// getInvitation fetches a pending invitation addressed to the current user
+func (h *MemberHandler) getInvitation(c *fiber.Ctx) (*models.ProjectMember, error) {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid invitation ID")
+	}
+
+	email, err := h.currentEmail(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var invitation models.ProjectMember
+	if err := h.db.Where("email = ? AND accepted_at IS NULL", email).First(&invitation, id).Error; err != nil {
+		return nil, fmt.Errorf("invitation not found")
+	}
+	return &invitation, nil
+}
+
+// currentEmail returns the current email of the signed-in user; the token may predate a change
+func (h *MemberHandler) currentEmail(c *fiber.Ctx) (string, error) {
+	var user models.User
+	if err := h.db.Select("email").First(&user, c.Locals("user_id").(uint)).Error; err != nil {
+		return "", fmt.Errorf("user not found")
+	}
+	return user.Email, nil
+}
@@ -0,0 +1,195 @@
+// ===========================================
+// Password Handler
+// ===========================================
+// Self-service password change and the
+// emailed password reset flow
+// ===========================================
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/laravel-paas/backend/internal/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ChangePasswordRequest represents password change payload
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// ForgotPasswordRequest represents reset link request payload
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// ResetPasswordRequest represents password reset payload
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// ChangePassword sets a new password after checking the current one.
+// Every session is signed out and a fresh one is started for this device.
+func (h *AuthHandler) ChangePassword(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var req ChangePasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	var user models.User
+	if err := h.db.First(&user, userID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.CurrentPassword)); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Current password is incorrect",
+		})
+	}
+	if req.NewPassword == req.CurrentPassword {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "New password must differ from the current one",
+		})
+	}
+	if err := h.validatePassword(req.NewPassword); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := h.setPassword(&user, req.NewPassword); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to change password",
+		})
+	}
+
+	// Reload for the new token version
+	if err := h.db.First(&user, userID).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to change password",
+		})
+	}
+	return h.startSession(c, &user)
+}
+
+// ForgotPassword emails a reset link. The response is the same whether or not
+// the email belongs to an account.
+func (h *AuthHandler) ForgotPassword(c *fiber.Ctx) error {
+	var req ForgotPasswordRequest
+	if err := c.BodyParser(&req); err != nil || req.Email == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Email is required",
+		})
+	}
+
+	response := fiber.Map{
+		"message": "If an account exists for this email, a reset link has been sent",
+	}
+
+	var user models.User
+	if err := h.db.Where("email = ?", req.Email).First(&user).Error; err != nil {
+		return c.JSON(response)
+	}
+
+	token, expiresAt, err := h.tokens.CreatePasswordReset(&user)
+	if err != nil {
+		log.Printf("Failed to create password reset for user %d: %v", user.ID, err)
+		return c.JSON(response)
+	}
+
+	link := h.cfg.AppURL + "/reset-password?token=" + url.QueryEscape(token)
+	body := fmt.Sprintf("Hello %s,\n\n"+
+		"A password reset was requested for your Laravel PaaS account.\n"+
+		"Open this link to choose a new password:\n\n%s\n\n"+
+		"The link expires at %s and can be used once.\n"+
+		"If you did not request a reset, you can ignore this email.\n",
+		user.Name, link, expiresAt.Format("2006-01-02 15:04 MST"))
+
+	// Send in the background so response time does not reveal whether the account exists
+	go func() {
+		if err := h.mailer.Send(user.Email, "Reset your password", body); err != nil {
+			log.Printf("Failed to send password reset email to user %d: %v", user.ID, err)
+		}
+	}()
+
+	return c.JSON(response)
+}
+
+// ResetPassword sets a new password using an emailed reset token
+func (h *AuthHandler) ResetPassword(c *fiber.Ctx) error {
+	var req ResetPasswordRequest
+	if err := c.BodyParser(&req); err != nil || req.Token == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Token and password are required",
+		})
+	}
+	if err := h.validatePassword(req.Password); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	user, err := h.tokens.ConsumePasswordReset(req.Token)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := h.setPassword(user, req.Password); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to reset password",
+		})
+	}
+
+	// Proving access to the mailbox lifts a lockout
+	if err := h.guard.Unlock(user); err != nil {
+		log.Printf("Failed to unlock user %d after password reset: %v", user.ID, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Password reset successfully, please sign in",
+	})
+}
+
+// validatePassword enforces the configured minimum length
+func (h *AuthHandler) validatePassword(password string) error {
+	minLength, _ := strconv.Atoi(GetSetting(h.db, "password_min_length", "8"))
+	if len(password) < minLength {
+		return fmt.Errorf("Password must be at least %d characters", minLength)
+	}
+	// bcrypt ignores everything past 72 bytes
+	if len(password) > 72 {
+		return fmt.Errorf("Password must be at most 72 characters")
+	}
+	return nil
+}
+
+// setPassword stores a new password, clears the forced change and signs the user out everywhere
+func (h *AuthHandler) setPassword(user *models.User, password string) error {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	if err := h.db.Model(user).Updates(map[string]interface{}{
+		"password":             string(hashed),
+		"must_change_password": false,
+	}).Error; err != nil {
+		return err
+	}
+
+	return h.tokens.RevokeAllForUser(user.ID)
+}
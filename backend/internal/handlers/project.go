@@ -7,6 +7,7 @@ package handlers
 
 import (
 	"fmt"
+	"log"
 	"strconv"
 	"strings"
 	"time"
@@ -24,16 +25,26 @@ type ProjectHandler struct {
 	db            *gorm.DB
 	cfg           *config.Config
 	dockerService *services.DockerService
+	provisioners  *services.Provisioners
+	addonService  *services.AddonService
+	backupService *services.BackupService
 	redisService  *services.RedisService
+	projectPools  *services.ProjectPools
+	access        *services.AccessService
 }
 
 // NewProjectHandler creates a new project handler
-func NewProjectHandler(db *gorm.DB, cfg *config.Config, redisService *services.RedisService) *ProjectHandler {
+func NewProjectHandler(db *gorm.DB, cfg *config.Config, redisService *services.RedisService, projectPools *services.ProjectPools) *ProjectHandler {
 	return &ProjectHandler{
 		db:            db,
 		cfg:           cfg,
 		dockerService: services.NewDockerService(cfg),
+		provisioners:  services.NewProvisioners(cfg),
+		addonService:  services.NewAddonService(cfg),
+		backupService: services.NewBackupService(db, cfg),
 		redisService:  redisService,
+		projectPools:  projectPools,
+		access:        services.NewAccessService(db),
 	}
 }
 
@@ -44,28 +55,38 @@ type CreateProjectRequest struct {
 	Branch       string `json:"branch"`
 	DatabaseName string `json:"database_name"`
 	QueueEnabled bool   `json:"queue_enabled"`
+
+	// DatabaseEngine is mysql (default) or postgres
+	DatabaseEngine models.DatabaseEngine `json:"database_engine"`
 }
 
-// ListOwn returns user's own projects
+// ListOwn returns user's own projects and those shared with them
 func (h *ProjectHandler) ListOwn(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uint)
 
 	var projects []models.Project
-	if err := h.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&projects).Error; err != nil {
+	if err := h.db.Where("user_id = ? OR id IN (?)", userID, services.SharedProjectIDs(h.db, userID)).
+		Order("created_at DESC").Find(&projects).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to fetch projects",
 		})
 	}
 
 	h.populateURLs(projects)
+	for i := range projects {
+		projects[i].MemberRole = h.access.MemberRole(userID, &projects[i])
+	}
 
 	return c.JSON(fiber.Map{
 		"data": projects,
 	})
 }
 
-// ListAll returns all projects (admin only)
+// ListAll returns all projects the user may read, e.g. those of a teacher's classes
 func (h *ProjectHandler) ListAll(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	role := c.Locals("role").(string)
+
 	page, _ := strconv.Atoi(c.Query("page", "1"))
 	limit, _ := strconv.Atoi(c.Query("limit", "10"))
 	status := c.Query("status", "")
@@ -76,7 +97,7 @@ func (h *ProjectHandler) ListAll(c *fiber.Ctx) error {
 	var projects []models.Project
 	var total int64
 
-	query := h.db.Model(&models.Project{}).Preload("User")
+	query := h.db.Model(&models.Project{}).Preload("User").Scopes(h.access.ProjectScope(userID, role, services.ProjectRead))
 
 	if status != "" {
 		query = query.Where("status = ?", status)
@@ -106,50 +127,35 @@ func (h *ProjectHandler) ListAll(c *fiber.Ctx) error {
 
 // Get returns a single project
 func (h *ProjectHandler) Get(c *fiber.Ctx) error {
-	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	project, err := resolveProject(c, h.access, services.ProjectRead, "User")
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid project ID",
-		})
-	}
-
-	userID := c.Locals("user_id").(uint)
-	role := c.Locals("role").(string)
-
-	var project models.Project
-	query := h.db.Preload("User")
-
-	// Students can only see their own projects
-	if role == string(models.RoleStudent) {
-		query = query.Where("user_id = ?", userID)
+		return projectAccessError(c, err)
 	}
 
-	if err := query.First(&project, id).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Project not found",
-		})
-	}
-
-	h.populateURL(&project)
+	h.populateURL(project)
 
 	return c.JSON(project)
 }
 
 // UpdateProjectRequest represents project update payload
 type UpdateProjectRequest struct {
-	PHPVersion   string `json:"php_version"`
-	QueueEnabled *bool  `json:"queue_enabled"`
+	PHPVersion   string  `json:"php_version"`
+	QueueEnabled *bool   `json:"queue_enabled"`
+	AppEnv       *string `json:"app_env"`
+	AppDebug     *bool   `json:"app_debug"`
+	DBQuotaMB    *int    `json:"db_quota_mb"` // Admin only, negative clears the override
+}
+
+// allowedAppEnvs lists the APP_ENV values a project may override
+var allowedAppEnvs = map[string]bool{
+	"production": true,
+	"staging":    true,
+	"local":      true,
+	"testing":    true,
 }
 
 // Update modifies project settings
 func (h *ProjectHandler) Update(c *fiber.Ctx) error {
-	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid project ID",
-		})
-	}
-
 	var req UpdateProjectRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -157,20 +163,9 @@ func (h *ProjectHandler) Update(c *fiber.Ctx) error {
 		})
 	}
 
-	userID := c.Locals("user_id").(uint)
-	role := c.Locals("role").(string)
-
-	var project models.Project
-	query := h.db
-
-	if role == string(models.RoleStudent) {
-		query = query.Where("user_id = ?", userID)
-	}
-
-	if err := query.First(&project, id).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Project not found",
-		})
+	project, err := resolveProject(c, h.access, services.ProjectWrite)
+	if err != nil {
+		return projectAccessError(c, err)
 	}
 
 	updates := map[string]interface{}{}
@@ -185,8 +180,36 @@ func (h *ProjectHandler) Update(c *fiber.Ctx) error {
 		updates["queue_enabled"] = *req.QueueEnabled
 	}
 
+	// APP_ENV / APP_DEBUG overrides are applied on the next deploy
+	if req.AppEnv != nil {
+		if !allowedAppEnvs[*req.AppEnv] {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid app_env (allowed: production, staging, local, testing)",
+			})
+		}
+		updates["app_env"] = *req.AppEnv
+	}
+
+	if req.AppDebug != nil {
+		updates["app_debug"] = *req.AppDebug
+	}
+
+	// Per-project database quota override (admin only)
+	if req.DBQuotaMB != nil {
+		if !h.access.Can(c.Locals("role").(string), models.PermProjectQuota) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Only admins can change the database quota",
+			})
+		}
+		if *req.DBQuotaMB < 0 {
+			updates["db_quota_mb"] = nil
+		} else {
+			updates["db_quota_mb"] = *req.DBQuotaMB
+		}
+	}
+
 	if len(updates) > 0 {
-		if err := h.db.Model(&project).Updates(updates).Error; err != nil {
+		if err := h.db.Model(project).Updates(updates).Error; err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"error": "Failed to update project",
 			})
@@ -214,6 +237,21 @@ func (h *ProjectHandler) Create(c *fiber.Ctx) error {
 			"error": "Name, GitHub URL, and database name are required",
 		})
 	}
+
+	if req.DatabaseEngine == "" {
+		req.DatabaseEngine = models.EngineMySQL
+	}
+	if !services.ValidEngine(req.DatabaseEngine) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid database engine (allowed: mysql, postgres)",
+		})
+	}
+
+	if err := h.provisioners.For(req.DatabaseEngine).ValidateDatabaseName(req.DatabaseName); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
 	
 	// Default branch to main if empty
 	branch := req.Branch
@@ -223,11 +261,12 @@ func (h *ProjectHandler) Create(c *fiber.Ctx) error {
 
 	userID := c.Locals("user_id").(uint)
 
-	// Check project limit
-	maxProjects, _ := strconv.Atoi(GetSetting(h.db, "max_projects_per_user", "3"))
+	// Check project limit; class overrides take precedence over the global settings.
+	// Only owned projects count, not those shared with the user.
+	limits := services.EffectiveLimits(h.db, userID)
 	var projectCount int64
 	h.db.Model(&models.Project{}).Where("user_id = ?", userID).Count(&projectCount)
-	if int(projectCount) >= maxProjects {
+	if int(projectCount) >= limits.MaxProjects {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"error": "Project limit reached",
 		})
@@ -277,6 +316,18 @@ func (h *ProjectHandler) Create(c *fiber.Ctx) error {
 		DatabaseName: req.DatabaseName,
 		Status:       models.StatusPending,
 		QueueEnabled: req.QueueEnabled,
+
+		DatabaseEngine: req.DatabaseEngine,
+	}
+
+	// Resource limits and expiry are fixed when the project is created
+	cpuLimit := float64(limits.CPULimitPercent) / 100
+	memoryLimit := fmt.Sprintf("%dm", limits.MemoryLimitMB)
+	project.CPULimit = &cpuLimit
+	project.MemoryLimit = &memoryLimit
+	if limits.ProjectExpiryDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, limits.ProjectExpiryDays)
+		project.ExpiresAt = &expiresAt
 	}
 
 	if err := h.db.Create(&project).Error; err != nil {
@@ -338,17 +389,54 @@ func (h *ProjectHandler) deployProject(project *models.Project) {
 		"php_version":     finalPHPVersion,
 	})
 
-	// Step 3: Create database
-	if err := h.dockerService.CreateDatabase(project.DatabaseName); err != nil {
+	// Step 3: Create database with a per-project random password
+	passwordChanged, err := h.dockerService.EnsureDatabasePassword(project)
+	if err != nil {
+		h.updateProjectError(project, "Failed to generate database password: "+err.Error())
+		return
+	}
+
+	dbPassword, err := h.dockerService.DatabasePassword(project)
+	if err != nil {
+		h.updateProjectError(project, "Failed to read database password: "+err.Error())
+		return
+	}
+
+	if err := h.provisioners.For(project.DatabaseEngine).Create(project.DatabaseName, dbPassword); err != nil {
 		h.updateProjectError(project, "Failed to create database: "+err.Error())
 		return
 	}
 
+	if passwordChanged {
+		h.db.Model(project).Update("db_password", project.DBPassword)
+	}
+
+	// Provisioning re-grants all privileges, keep writes revoked while over quota
+	if project.DBWritesRevoked {
+		if err := h.provisioners.For(project.DatabaseEngine).RevokeWrites(project.DatabaseName); err != nil {
+			h.updateProjectError(project, "Failed to apply database quota: "+err.Error())
+			return
+		}
+	}
+
 	
 	var oldContainerID *string
 	if project.ContainerID != nil {
 		oldHelp := *project.ContainerID
 		oldContainerID = &oldHelp
+
+		// Snapshot the database before the new release runs its migrations
+		if _, err := h.backupService.Backup(project, models.BackupPreDeploy); err != nil {
+			log.Printf("⚠️  Pre-deploy backup failed for project #%d: %v", project.ID, err)
+		}
+	}
+
+	// Persist the APP_KEY once so redeploys don't invalidate sessions and encrypted data
+	if changed, err := h.dockerService.EnsureAppKey(project); err != nil {
+		h.updateProjectError(project, "Failed to prepare APP_KEY: "+err.Error())
+		return
+	} else if changed {
+		h.db.Model(project).Update("app_key", project.AppKey)
 	}
 
 	projectDomain := GetSetting(h.db, "project_domain", h.cfg.ProjectDomain)
@@ -400,30 +488,13 @@ func (h *ProjectHandler) updateProjectError(project *models.Project, errorMsg st
 
 // Redeploy rebuilds and restarts a project
 func (h *ProjectHandler) Redeploy(c *fiber.Ctx) error {
-	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	project, err := resolveProject(c, h.access, services.ProjectWrite)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid project ID",
-		})
-	}
-
-	userID := c.Locals("user_id").(uint)
-	role := c.Locals("role").(string)
-
-	var project models.Project
-	query := h.db
-
-	if role == string(models.RoleStudent) {
-		query = query.Where("user_id = ?", userID)
-	}
-
-	if err := query.First(&project, id).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Project not found",
-		})
+		return projectAccessError(c, err)
 	}
 
 	// Enqueue redeployment job to Redis
+	userID := c.Locals("user_id").(uint)
 	if err := h.redisService.EnqueueDeployment(project.ID, userID, "redeploy"); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to queue redeployment: " + err.Error(),
@@ -441,26 +512,21 @@ func (h *ProjectHandler) Redeploy(c *fiber.Ctx) error {
 
 // Delete removes a project
 func (h *ProjectHandler) Delete(c *fiber.Ctx) error {
-	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	project, err := resolveProject(c, h.access, services.ProjectDelete)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid project ID",
-		})
+		return projectAccessError(c, err)
 	}
 
-	userID := c.Locals("user_id").(uint)
-	role := c.Locals("role").(string)
-
-	var project models.Project
-	query := h.db
+	audit(c, "project.delete", "project", project.ID, fmt.Sprintf("Deleted project %s (%s)", project.Name, project.Subdomain))
 
-	if role == string(models.RoleStudent) {
-		query = query.Where("user_id = ?", userID)
-	}
+	// Close pooled connections first, PostgreSQL refuses to drop a database in use
+	h.projectPools.Invalidate(project.ID)
 
-	if err := query.First(&project, id).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Project not found",
+	// Drop the database before removing anything else: on failure the project is
+	// left untouched and the delete can be retried
+	if err := h.provisioners.For(project.DatabaseEngine).Drop(project.DatabaseName); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to drop project database: " + err.Error(),
 		})
 	}
 
@@ -478,11 +544,21 @@ func (h *ProjectHandler) Delete(c *fiber.Ctx) error {
 	// Remove project files
 	h.dockerService.CleanupProject(project.Subdomain)
 
-	// Drop database
-	h.dockerService.DropDatabase(project.DatabaseName)
+	// Remove add-on containers and their data
+	var addons []models.Addon
+	h.db.Where("project_id = ?", project.ID).Find(&addons)
+	for i := range addons {
+		if err := h.addonService.Remove(&addons[i]); err != nil {
+			log.Printf("⚠️  Failed to remove add-on %s: %v", addons[i].ContainerName, err)
+		}
+	}
+
+	h.db.Where("project_id = ?", project.ID).Delete(&models.Addon{})
+	h.db.Where("project_id = ?", project.ID).Delete(&models.ProjectMember{})
+	h.backupService.DeleteAll(project)
 
 	// Hard delete project record (not soft delete) to free up database_name and subdomain
-	if err := h.db.Unscoped().Delete(&project).Error; err != nil {
+	if err := h.db.Unscoped().Delete(project).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to delete project",
 		})
@@ -495,27 +571,9 @@ func (h *ProjectHandler) Delete(c *fiber.Ctx) error {
 
 // Logs streams container logs
 func (h *ProjectHandler) Logs(c *fiber.Ctx) error {
-	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	project, err := resolveProject(c, h.access, services.ProjectRead)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid project ID",
-		})
-	}
-
-	userID := c.Locals("user_id").(uint)
-	role := c.Locals("role").(string)
-
-	var project models.Project
-	query := h.db
-
-	if role == string(models.RoleStudent) {
-		query = query.Where("user_id = ?", userID)
-	}
-
-	if err := query.First(&project, id).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Project not found",
-		})
+		return projectAccessError(c, err)
 	}
 
 	if project.ContainerID == nil {
@@ -539,27 +597,9 @@ func (h *ProjectHandler) Logs(c *fiber.Ctx) error {
 
 // Stats returns project resource usage
 func (h *ProjectHandler) Stats(c *fiber.Ctx) error {
-	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	project, err := resolveProject(c, h.access, services.ProjectRead)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid project ID",
-		})
-	}
-
-	userID := c.Locals("user_id").(uint)
-	role := c.Locals("role").(string)
-
-	var project models.Project
-	query := h.db
-
-	if role == string(models.RoleStudent) {
-		query = query.Where("user_id = ?", userID)
-	}
-
-	if err := query.First(&project, id).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Project not found",
-		})
+		return projectAccessError(c, err)
 	}
 
 	if project.ContainerID == nil {
@@ -585,23 +625,9 @@ type RunArtisanRequest struct {
 
 // RunArtisan executes an artisan command
 func (h *ProjectHandler) RunArtisan(c *fiber.Ctx) error {
-	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	project, err := resolveProject(c, h.access, services.ProjectWrite)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
-	}
-
-	userID := c.Locals("user_id").(uint)
-	role := c.Locals("role").(string)
-
-	var project models.Project
-	query := h.db
-
-	if role == string(models.RoleStudent) {
-		query = query.Where("user_id = ?", userID)
-	}
-
-	if err := query.First(&project, id).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Project not found"})
+		return projectAccessError(c, err)
 	}
 
 	if project.ContainerID == nil {
@@ -616,6 +642,7 @@ func (h *ProjectHandler) RunArtisan(c *fiber.Ctx) error {
 	if req.Command == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Command is required"})
 	}
+	audit(c, "project.artisan", "project", project.ID, "php artisan "+req.Command)
 
 	// Execute command
 	output, err := h.dockerService.ExecLaravelCommand(*project.ContainerID, req.Command)
@@ -634,25 +661,12 @@ func (h *ProjectHandler) RunArtisan(c *fiber.Ctx) error {
 	})
 }
 
-// GetEnv returns the .env file content
+// GetEnv returns the .env file content. It holds DB_PASSWORD and APP_KEY, so
+// viewers are refused like for any other write.
 func (h *ProjectHandler) GetEnv(c *fiber.Ctx) error {
-	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	project, err := resolveProject(c, h.access, services.ProjectWrite)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
-	}
-
-	userID := c.Locals("user_id").(uint)
-	role := c.Locals("role").(string)
-
-	var project models.Project
-	query := h.db
-
-	if role == string(models.RoleStudent) {
-		query = query.Where("user_id = ?", userID)
-	}
-
-	if err := query.First(&project, id).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Project not found"})
+		return projectAccessError(c, err)
 	}
 
 	content, err := h.dockerService.GetEnvFile(project.Subdomain)
@@ -672,23 +686,9 @@ type UpdateEnvRequest struct {
 
 // UpdateEnv updates the .env file content
 func (h *ProjectHandler) UpdateEnv(c *fiber.Ctx) error {
-	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	project, err := resolveProject(c, h.access, services.ProjectWrite)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
-	}
-
-	userID := c.Locals("user_id").(uint)
-	role := c.Locals("role").(string)
-
-	var project models.Project
-	query := h.db
-
-	if role == string(models.RoleStudent) {
-		query = query.Where("user_id = ?", userID)
-	}
-
-	if err := query.First(&project, id).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Project not found"})
+		return projectAccessError(c, err)
 	}
 
 	var req UpdateEnvRequest
@@ -696,6 +696,9 @@ func (h *ProjectHandler) UpdateEnv(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
 	}
 
+	// The file holds credentials, only record that it changed
+	audit(c, "project.env.update", "project", project.ID, ".env file updated")
+
 	if err := h.dockerService.SaveEnvFile(project.Subdomain, req.Content); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save .env file"})
 	}
@@ -726,15 +729,20 @@ func (h *ProjectHandler) UpdateEnv(c *fiber.Ctx) error {
 	})
 }
 
-// AdminStats returns overview statistics
+// AdminStats returns overview statistics of the projects and students the user may see
 func (h *ProjectHandler) AdminStats(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	role := c.Locals("role").(string)
+
 	var totalProjects int64
 	var runningProjects int64
 	var totalStudents int64
 
-	h.db.Model(&models.Project{}).Count(&totalProjects)
-	h.db.Model(&models.Project{}).Where("status = ?", models.StatusRunning).Count(&runningProjects)
-	h.db.Model(&models.User{}).Where("role = ?", models.RoleStudent).Count(&totalStudents)
+	scope := h.access.ProjectScope(userID, role, services.ProjectRead)
+	h.db.Model(&models.Project{}).Scopes(scope).Count(&totalProjects)
+	h.db.Model(&models.Project{}).Scopes(scope).Where("status = ?", models.StatusRunning).Count(&runningProjects)
+	h.db.Model(&models.User{}).Scopes(h.access.UserScope(userID, role, "read")).
+		Where("role = ?", models.RoleStudent).Count(&totalStudents)
 
 	return c.JSON(fiber.Map{
 		"total_projects":   totalProjects,
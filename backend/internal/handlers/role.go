@@ -0,0 +1,212 @@
+// ===========================================
+// Role Handler
+// ===========================================
+// Roles as named, editable permission sets
+// ===========================================
+package handlers
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/laravel-paas/backend/internal/models"
+	"github.com/laravel-paas/backend/internal/services"
+	"gorm.io/gorm"
+)
+
+// RoleHandler handles role endpoints
+type RoleHandler struct {
+	db *gorm.DB
+}
+
+// NewRoleHandler creates a new role handler
+func NewRoleHandler(db *gorm.DB) *RoleHandler {
+	return &RoleHandler{db: db}
+}
+
+// RoleRequest represents role create/update payload
+type RoleRequest struct {
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	Permissions []models.Permission `json:"permissions"`
+}
+
+// roleNamePattern keeps role names usable in URLs, tokens and the role column
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,19}$`)
+
+// List returns all roles with their user counts and the permission catalogue
+func (h *RoleHandler) List(c *fiber.Ctx) error {
+	var roles []models.RoleDefinition
+	if err := h.db.Order("id").Find(&roles).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch roles",
+		})
+	}
+
+	for i := range roles {
+		h.db.Model(&models.User{}).Where("role = ?", roles[i].Name).Count(&roles[i].UserCount)
+	}
+
+	return c.JSON(fiber.Map{
+		"data":        roles,
+		"permissions": models.AllPermissions,
+	})
+}
+
+// Create adds a custom role
+func (h *RoleHandler) Create(c *fiber.Ctx) error {
+	var req RoleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	req.Name = strings.ToLower(strings.TrimSpace(req.Name))
+	if !roleNamePattern.MatchString(req.Name) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Role name must be 2-20 lowercase letters, digits, dashes or underscores",
+		})
+	}
+	if req.Permissions == nil {
+		req.Permissions = []models.Permission{}
+	}
+	if err := validatePermissions(req.Permissions); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	var count int64
+	h.db.Model(&models.RoleDefinition{}).Where("name = ?", req.Name).Count(&count)
+	if count > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Role already exists",
+		})
+	}
+
+	role := models.RoleDefinition{
+		Name:        models.Role(req.Name),
+		Description: req.Description,
+		Permissions: req.Permissions,
+	}
+	if err := h.db.Create(&role).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create role",
+		})
+	}
+	services.InvalidatePermissions()
+
+	audit(c, "role.create", "role", role.ID, fmt.Sprintf("Created role %s %v", role.Name, role.Permissions))
+
+	return c.Status(fiber.StatusCreated).JSON(role)
+}
+
+// Update changes the description and permissions of a role. Names are fixed
+// because users reference roles by name; the superadmin role keeps full access.
+func (h *RoleHandler) Update(c *fiber.Ctx) error {
+	role, err := h.getRole(c)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var req RoleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if role.Name == models.RoleSuperAdmin {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Cannot change the superadmin role",
+		})
+	}
+	if req.Permissions == nil {
+		req.Permissions = []models.Permission{}
+	}
+	if err := validatePermissions(req.Permissions); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	audit(c, "role.update", "role", role.ID, fmt.Sprintf("Permissions of %s: %v -> %v", role.Name, role.Permissions, req.Permissions))
+
+	role.Description = req.Description
+	role.Permissions = req.Permissions
+	if err := h.db.Save(role).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update role",
+		})
+	}
+	services.InvalidatePermissions()
+
+	return c.JSON(role)
+}
+
+// Delete removes a custom role that no user has
+func (h *RoleHandler) Delete(c *fiber.Ctx) error {
+	role, err := h.getRole(c)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if role.BuiltIn {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Built-in roles cannot be deleted",
+		})
+	}
+
+	var users int64
+	h.db.Model(&models.User{}).Where("role = ?", role.Name).Count(&users)
+	if users > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": fmt.Sprintf("Role is assigned to %d users", users),
+		})
+	}
+
+	audit(c, "role.delete", "role", role.ID, "Deleted role "+string(role.Name))
+
+	if err := h.db.Delete(role).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete role",
+		})
+	}
+	services.InvalidatePermissions()
+
+	return c.JSON(fiber.Map{
+		"message": "Role deleted successfully",
+	})
+}
+
+// getRole fetches the role in the :id parameter
+func (h *RoleHandler) getRole(c *fiber.Ctx) (*models.RoleDefinition, error) {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid role ID")
+	}
+
+	var role models.RoleDefinition
+	if err := h.db.First(&role, id).Error; err != nil {
+		return nil, fmt.Errorf("role not found")
+	}
+	return &role, nil
+}
+
+// validatePermissions rejects unknown permissions; the wildcard is reserved for superadmin
+func validatePermissions(permissions []models.Permission) error {
+	known := make(map[models.Permission]bool, len(models.AllPermissions))
+	for _, info := range models.AllPermissions {
+		known[info.Name] = true
+	}
+	for _, permission := range permissions {
+		if !known[permission] {
+			return fmt.Errorf("unknown permission %q", permission)
+		}
+	}
+	return nil
+}
@@ -6,6 +6,10 @@
 package handlers
 
 import (
+	"fmt"
+	"sort"
+	"strings"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/laravel-paas/backend/internal/models"
 	"gorm.io/gorm"
@@ -56,6 +60,16 @@ func (h *SettingHandler) Update(c *fiber.Ctx) error {
 		})
 	}
 
+	// Record what changed, old and new value
+	var changes []string
+	for key, value := range req.Settings {
+		if old := GetSetting(h.db, key, ""); old != value {
+			changes = append(changes, fmt.Sprintf("%s: %q -> %q", key, old, value))
+		}
+	}
+	sort.Strings(changes)
+	audit(c, "settings.update", "setting", "", strings.Join(changes, "; "))
+
 	// Update each setting
 	for key, value := range req.Settings {
 		result := h.db.Model(&models.Setting{}).
@@ -0,0 +1,138 @@
+// ===========================================
+// Single Sign-On Handler
+// ===========================================
+// OpenID Connect login and the provider
+// toggles shown on the login page
+// ===========================================
+package handlers
+
+import (
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/laravel-paas/backend/internal/models"
+	"github.com/laravel-paas/backend/internal/services"
+)
+
+// oidcStateTTL is how long a user has to finish signing in at the provider
+const oidcStateTTL = 10 * time.Minute
+
+// Providers lists the login methods available on the login page
+func (h *AuthHandler) Providers(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"password": h.passwordEnabled(),
+		"oidc":     h.oidcEnabled(),
+		"ldap":     h.ldapEnabled(),
+	})
+}
+
+// OIDCLogin redirects the browser to the identity provider
+func (h *AuthHandler) OIDCLogin(c *fiber.Ctx) error {
+	if !h.oidcEnabled() {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Single sign-on is not enabled",
+		})
+	}
+
+	authURL, state, login, err := h.oidc.Begin(c.Context())
+	if err != nil {
+		log.Printf("OIDC login failed: %v", err)
+		return c.Redirect(loginErrorURL("Identity provider is unavailable"))
+	}
+	if err := h.redisService.SetCache(oidcStateKey(state), login, oidcStateTTL); err != nil {
+		return c.Redirect(loginErrorURL("Failed to start sign-in"))
+	}
+
+	return c.Redirect(authURL)
+}
+
+// OIDCCallback completes the login started by OIDCLogin. The session is handed
+// to the frontend through the refresh cookie, never in the URL.
+func (h *AuthHandler) OIDCCallback(c *fiber.Ctx) error {
+	if !h.oidcEnabled() {
+		return c.Redirect(loginErrorURL("Single sign-on is not enabled"))
+	}
+	if msg := c.Query("error_description", c.Query("error")); msg != "" {
+		return c.Redirect(loginErrorURL(msg))
+	}
+
+	// States are single use
+	state := c.Query("state")
+	var login services.OIDCLogin
+	if state == "" || h.redisService.GetCache(oidcStateKey(state), &login) != nil {
+		return c.Redirect(loginErrorURL("Sign-in expired, please try again"))
+	}
+	h.redisService.DeleteCache(oidcStateKey(state))
+
+	identity, err := h.oidc.Complete(c.Context(), c.Query("code"), &login)
+	if err != nil {
+		log.Printf("OIDC callback failed: %v", err)
+		return c.Redirect(loginErrorURL(err.Error()))
+	}
+
+	user, err := h.provisionExternal(identity, services.MapRole(identity.Groups, h.cfg.OIDCAdminValues))
+	if err != nil {
+		return c.Redirect(loginErrorURL(err.Error()))
+	}
+
+	// The second factor is still asked for; the challenge token is single use and short-lived
+	if user.TwoFactorEnabled {
+		challenge, err := h.twoFactor.CreateChallenge(user.ID)
+		if err != nil {
+			return c.Redirect(loginErrorURL("Failed to start two-factor login"))
+		}
+		return c.Redirect("/login?challenge=" + url.QueryEscape(challenge))
+	}
+
+	pair, err := h.tokens.StartSession(user, sessionInfo(c))
+	if err != nil {
+		return c.Redirect(loginErrorURL("Failed to start session"))
+	}
+	h.setRefreshCookie(c, pair.RefreshToken, pair.Session.ExpiresAt)
+
+	return c.Redirect("/login?sso=success")
+}
+
+// provisionExternal finds or creates the user of an external identity. A role
+// change signs out the user's existing sessions, whose tokens carry the old role.
+func (h *AuthHandler) provisionExternal(identity *services.ExternalIdentity, role models.Role) (*models.User, error) {
+	user, roleChanged, err := services.ProvisionUser(h.db, identity, role)
+	if err != nil {
+		return nil, err
+	}
+	if roleChanged {
+		if err := h.tokens.RevokeAllForUser(user.ID); err != nil {
+			return nil, err
+		}
+		if err := h.db.First(user, user.ID).Error; err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+// passwordEnabled reports whether local passwords may be used to sign in
+func (h *AuthHandler) passwordEnabled() bool {
+	return GetSetting(h.db, "auth_password_enabled", "true") == "true"
+}
+
+// oidcEnabled reports whether OpenID Connect is switched on and configured
+func (h *AuthHandler) oidcEnabled() bool {
+	return GetSetting(h.db, "auth_oidc_enabled", "false") == "true" && h.oidc.Configured()
+}
+
+// ldapEnabled reports whether LDAP is switched on and configured
+func (h *AuthHandler) ldapEnabled() bool {
+	return GetSetting(h.db, "auth_ldap_enabled", "false") == "true" && h.ldap.Configured()
+}
+
+func oidcStateKey(state string) string {
+	return "oidc_state:" + state
+}
+
+// loginErrorURL sends the browser back to the login page with a message
+func loginErrorURL(msg string) string {
+	return "/login?sso_error=" + url.QueryEscape(msg)
+}
@@ -52,6 +52,8 @@ func (h *SystemHandler) GetStats(c *fiber.Ctx) error {
 
 // PruneSystem cleans up unused docker images/containers
 func (h *SystemHandler) PruneSystem(c *fiber.Ctx) error {
+	audit(c, "system.prune", "system", "", "Pruned unused Docker images")
+
 	err := h.dockerService.PruneImages()
 	if err != nil {
 		return err
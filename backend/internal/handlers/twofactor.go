@@ -0,0 +1,206 @@
+// ===========================================
+// Two-Factor Handler
+// ===========================================
+// TOTP enrollment for the current user and
+// the second step of two-factor logins
+// ===========================================
+package handlers
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/laravel-paas/backend/internal/models"
+	"github.com/laravel-paas/backend/internal/services"
+)
+
+// TwoFactorCodeRequest carries a TOTP or recovery code
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// TwoFactorChallengeRequest represents the second login step payload
+type TwoFactorChallengeRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+}
+
+// TwoFactorStatus reports whether 2FA is on, required, and how many recovery codes are left
+func (h *AuthHandler) TwoFactorStatus(c *fiber.Ctx) error {
+	user, err := h.currentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"enabled":                  user.TwoFactorEnabled,
+		"required":                 user.IsAdmin() && GetSetting(h.db, "require_2fa_admins", "false") == "true",
+		"recovery_codes_remaining": h.twoFactor.RemainingRecoveryCodes(user.ID),
+	})
+}
+
+// TwoFactorSetup generates a secret and QR code to scan with an authenticator app
+func (h *AuthHandler) TwoFactorSetup(c *fiber.Ctx) error {
+	user, err := h.currentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
+	setup, err := h.twoFactor.Setup(user)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(setup)
+}
+
+// TwoFactorVerify confirms the first code from the app, turns 2FA on and returns
+// the recovery codes. The access token is reissued without the enrollment restriction.
+func (h *AuthHandler) TwoFactorVerify(c *fiber.Ctx) error {
+	user, err := h.currentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil || req.Code == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Code is required",
+		})
+	}
+
+	codes, err := h.twoFactor.Enable(user, req.Code)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	user.TwoFactorEnabled = true
+	sessionID, _ := c.Locals("session_id").(string)
+	token, expiry, err := h.tokens.Issue(user, sessionID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Two-factor enabled but failed to issue token",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":        "Two-factor authentication enabled",
+		"recovery_codes": codes,
+		"token":          token,
+		"expires_in":     int64(time.Until(expiry).Seconds()),
+	})
+}
+
+// TwoFactorDisable turns 2FA off after checking a current code
+func (h *AuthHandler) TwoFactorDisable(c *fiber.Ctx) error {
+	user, err := h.currentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
+	if user.IsAdmin() && GetSetting(h.db, "require_2fa_admins", "false") == "true" {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Two-factor authentication is required for your role",
+		})
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil || !h.twoFactor.Verify(user, req.Code) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": services.ErrInvalidTwoFactorCode.Error(),
+		})
+	}
+
+	if err := h.twoFactor.Disable(user); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to disable two-factor authentication",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Two-factor authentication disabled",
+	})
+}
+
+// TwoFactorRecoveryCodes replaces the recovery codes after checking a current code
+func (h *AuthHandler) TwoFactorRecoveryCodes(c *fiber.Ctx) error {
+	user, err := h.currentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil || !h.twoFactor.Verify(user, req.Code) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": services.ErrInvalidTwoFactorCode.Error(),
+		})
+	}
+
+	codes, err := h.twoFactor.RegenerateRecoveryCodes(user)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"recovery_codes": codes,
+	})
+}
+
+// TwoFactorChallenge completes a login with the challenge token and a TOTP or recovery code
+func (h *AuthHandler) TwoFactorChallenge(c *fiber.Ctx) error {
+	var req TwoFactorChallengeRequest
+	if err := c.BodyParser(&req); err != nil || req.Code == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Challenge token and code are required",
+		})
+	}
+
+	// Wrong codes count toward the same backoff and lockout as wrong passwords
+	pending, err := h.twoFactor.ChallengeUser(req.ChallengeToken)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	attempt := services.LoginAttemptInfo{Email: pending.Email, IPAddress: c.IP(), UserAgent: c.Get("User-Agent")}
+	if blocked := h.guardBlocks(c, attempt); blocked != nil {
+		return blocked
+	}
+
+	user, err := h.twoFactor.ResolveChallenge(req.ChallengeToken, req.Code)
+	if err != nil {
+		if err == services.ErrInvalidTwoFactorCode || err == services.ErrInvalidChallenge {
+			h.guard.RecordFailure(attempt, &pending.ID, services.FailureInvalidTwoFactor)
+		}
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return h.startSession(c, user)
+}
+
+// currentUser loads the signed-in user
+func (h *AuthHandler) currentUser(c *fiber.Ctx) (*models.User, error) {
+	var user models.User
+	if err := h.db.First(&user, c.Locals("user_id").(uint)).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
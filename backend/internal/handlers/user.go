@@ -12,7 +12,9 @@ import (
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/laravel-paas/backend/internal/config"
 	"github.com/laravel-paas/backend/internal/models"
+	"github.com/laravel-paas/backend/internal/services"
 	"github.com/xuri/excelize/v2"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
@@ -20,12 +22,22 @@ import (
 
 // UserHandler handles user management endpoints
 type UserHandler struct {
-	db *gorm.DB
+	db        *gorm.DB
+	tokens    *services.TokenService
+	twoFactor *services.TwoFactorService
+	guard     *services.LoginGuard
+	access    *services.AccessService
 }
 
 // NewUserHandler creates a new user handler
-func NewUserHandler(db *gorm.DB) *UserHandler {
-	return &UserHandler{db: db}
+func NewUserHandler(db *gorm.DB, cfg *config.Config, redisService *services.RedisService) *UserHandler {
+	return &UserHandler{
+		db:        db,
+		tokens:    services.NewTokenService(db, cfg, redisService),
+		twoFactor: services.NewTwoFactorService(db, cfg, redisService),
+		guard:     services.NewLoginGuard(db, redisService),
+		access:    services.NewAccessService(db),
+	}
 }
 
 // CreateUserRequest represents user creation payload
@@ -48,7 +60,7 @@ func (h *UserHandler) List(c *fiber.Ctx) error {
 	var users []models.User
 	var total int64
 
-	query := h.db.Model(&models.User{})
+	query := h.db.Model(&models.User{}).Scopes(h.userScope(c, "read"))
 
 	// Filter by role if specified
 	if role != "" {
@@ -88,7 +100,7 @@ func (h *UserHandler) Get(c *fiber.Ctx) error {
 	}
 
 	var user models.User
-	if err := h.db.Preload("Projects").First(&user, id).Error; err != nil {
+	if err := h.db.Scopes(h.userScope(c, "read")).Preload("Projects").First(&user, id).Error; err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "User not found",
 		})
@@ -141,23 +153,23 @@ func (h *UserHandler) Create(c *fiber.Ctx) error {
 		role = models.RoleStudent
 	}
 
-	// Only superadmin can create admins
-	currentRole := c.Locals("role").(string)
-	if role == models.RoleAdmin && currentRole != string(models.RoleSuperAdmin) {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": "Only superadmin can create admin users",
+	if status, err := h.checkRoleAssignment(c, role); err != nil {
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
 		})
 	}
 
 	// Get creator ID
 	creatorID := c.Locals("user_id").(uint)
 
+	// The password was chosen or seen by an admin, so the user picks their own on first login
 	user := models.User{
-		Email:     req.Email,
-		Password:  string(hashedPassword),
-		Name:      req.Name,
-		Role:      role,
-		CreatedBy: &creatorID,
+		Email:              req.Email,
+		Password:           string(hashedPassword),
+		Name:               req.Name,
+		Role:               role,
+		MustChangePassword: true,
+		CreatedBy:          &creatorID,
 	}
 
 	if err := h.db.Create(&user).Error; err != nil {
@@ -166,6 +178,8 @@ func (h *UserHandler) Create(c *fiber.Ctx) error {
 		})
 	}
 
+	audit(c, "user.create", "user", user.ID, fmt.Sprintf("Created %s %s", user.Role, user.Email))
+
 	// Return user with plain password (only on creation)
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"user":     user,
@@ -183,7 +197,7 @@ func (h *UserHandler) Update(c *fiber.Ctx) error {
 	}
 
 	var user models.User
-	if err := h.db.First(&user, id).Error; err != nil {
+	if err := h.db.Scopes(h.userScope(c, "write")).First(&user, id).Error; err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "User not found",
 		})
@@ -196,8 +210,45 @@ func (h *UserHandler) Update(c *fiber.Ctx) error {
 		})
 	}
 
+	// New credentials would hand over the account: staff accounts need user.admins
+	// and only the superadmin changes its own
+	self := user.ID == c.Locals("user_id").(uint)
+	credentials := req.Password != "" || (req.Email != "" && req.Email != user.Email)
+	if credentials && !self && (user.Role == models.RoleSuperAdmin || !h.canManageAccount(c, &user)) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "You do not have permission to change the email or password of this user",
+		})
+	}
+
+	var changes []string
+	defer func() {
+		audit(c, "user.update", "user", user.ID, fmt.Sprintf("Updated %s %v", user.Email, changes))
+	}()
+
+	// Role changes follow the same rules as creation, for the old and the new role;
+	// the superadmin keeps its role
+	revoke := false
+	if req.Role != "" && req.Role != user.Role {
+		if user.Role == models.RoleSuperAdmin {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Cannot change superadmin role",
+			})
+		}
+		for _, role := range []models.Role{user.Role, req.Role} {
+			if status, err := h.checkRoleAssignment(c, role); err != nil {
+				return c.Status(status).JSON(fiber.Map{
+					"error": err.Error(),
+				})
+			}
+		}
+		changes = append(changes, fmt.Sprintf("role %s -> %s", user.Role, req.Role))
+		user.Role = req.Role
+		revoke = true
+	}
+
 	// Update fields
-	if req.Name != "" {
+	if req.Name != "" && req.Name != user.Name {
+		changes = append(changes, "name")
 		user.Name = req.Name
 	}
 	if req.Email != "" && req.Email != user.Email {
@@ -207,6 +258,7 @@ func (h *UserHandler) Update(c *fiber.Ctx) error {
 				"error": "Email already exists",
 			})
 		}
+		changes = append(changes, fmt.Sprintf("email %s -> %s", user.Email, req.Email))
 		user.Email = req.Email
 	}
 	if req.Password != "" {
@@ -216,7 +268,11 @@ func (h *UserHandler) Update(c *fiber.Ctx) error {
 				"error": "Failed to hash password",
 			})
 		}
+		changes = append(changes, "password")
 		user.Password = string(hashedPassword)
+		// Admin-set passwords are temporary unless admins set their own
+		user.MustChangePassword = !self
+		revoke = true
 	}
 
 	if err := h.db.Save(&user).Error; err != nil {
@@ -225,6 +281,15 @@ func (h *UserHandler) Update(c *fiber.Ctx) error {
 		})
 	}
 
+	// Tokens carry the role and were issued against the old password
+	if revoke {
+		if err := h.tokens.RevokeAllForUser(user.ID); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "User updated but failed to revoke sessions",
+			})
+		}
+	}
+
 	return c.JSON(user)
 }
 
@@ -238,12 +303,14 @@ func (h *UserHandler) Delete(c *fiber.Ctx) error {
 	}
 
 	var user models.User
-	if err := h.db.First(&user, id).Error; err != nil {
+	if err := h.db.Scopes(h.userScope(c, "write")).First(&user, id).Error; err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "User not found",
 		})
 	}
 
+	audit(c, "user.delete", "user", user.ID, fmt.Sprintf("Deleted %s %s", user.Role, user.Email))
+
 	// Prevent deleting superadmin
 	if user.Role == models.RoleSuperAdmin {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
@@ -258,11 +325,188 @@ func (h *UserHandler) Delete(c *fiber.Ctx) error {
 		})
 	}
 
+	// Deleted users no longer collaborate on other projects
+	h.db.Where("user_id = ? OR email = ?", user.ID, user.Email).Delete(&models.ProjectMember{})
+
+	if err := h.tokens.RevokeAllForUser(user.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "User deleted but failed to revoke sessions",
+		})
+	}
+
 	return c.JSON(fiber.Map{
 		"message": "User deleted successfully",
 	})
 }
 
+// SignOut revokes every token of a user, signing them out everywhere
+func (h *UserHandler) SignOut(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+
+	var user models.User
+	if err := h.db.Scopes(h.userScope(c, "write")).First(&user, id).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
+	// Staff accounts are managed by users with user.admins
+	if !h.canManageAccount(c, &user) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "You do not have permission to sign out staff users",
+		})
+	}
+
+	audit(c, "user.sign_out", "user", user.ID, "Signed out "+user.Email+" from all sessions")
+
+	if err := h.tokens.RevokeAllForUser(user.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to revoke sessions",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "User signed out from all sessions",
+	})
+}
+
+// ResetTwoFactor turns off two-factor authentication for a user who lost their device
+func (h *UserHandler) ResetTwoFactor(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+
+	var user models.User
+	if err := h.db.Scopes(h.userScope(c, "write")).First(&user, id).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
+	// Staff accounts are managed by users with user.admins
+	if !h.canManageAccount(c, &user) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "You do not have permission to reset two-factor authentication for staff users",
+		})
+	}
+
+	audit(c, "user.reset_2fa", "user", user.ID, "Reset two-factor authentication of "+user.Email)
+
+	if err := h.twoFactor.Disable(&user); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to reset two-factor authentication",
+		})
+	}
+
+	// Whoever holds the lost device should not keep a session either
+	if err := h.tokens.RevokeAllForUser(user.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to revoke sessions",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Two-factor authentication reset",
+	})
+}
+
+// Unlock lifts a lockout caused by too many failed logins
+func (h *UserHandler) Unlock(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+
+	var user models.User
+	if err := h.db.Scopes(h.userScope(c, "write")).First(&user, id).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
+	audit(c, "user.unlock", "user", user.ID, "Unlocked "+user.Email)
+
+	if err := h.guard.Unlock(&user); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to unlock user",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "User unlocked",
+	})
+}
+
+// userScope limits a query to the users the current user may read or write
+func (h *UserHandler) userScope(c *fiber.Ctx, action string) func(*gorm.DB) *gorm.DB {
+	return h.access.UserScope(c.Locals("user_id").(uint), c.Locals("role").(string), action)
+}
+
+// checkRoleAssignment reports whether the current user may give accounts the role
+func (h *UserHandler) checkRoleAssignment(c *fiber.Ctx, role models.Role) (int, error) {
+	var count int64
+	h.db.Model(&models.RoleDefinition{}).Where("name = ?", role).Count(&count)
+	if count == 0 || role == models.RoleSuperAdmin {
+		return fiber.StatusBadRequest, fmt.Errorf("invalid role")
+	}
+	if !h.access.CanAssignRole(c.Locals("role").(string), string(role)) {
+		return fiber.StatusForbidden, fmt.Errorf("you do not have permission to assign the %s role", role)
+	}
+	return 0, nil
+}
+
+// canManageAccount reports whether the current user may sign out, reset or change
+// the credentials of the user
+func (h *UserHandler) canManageAccount(c *fiber.Ctx, user *models.User) bool {
+	return !h.access.Privileged(string(user.Role)) || h.access.Can(c.Locals("role").(string), models.PermUserAdmins)
+}
+
+// LoginAttempts lists failed sign-ins, newest first
+func (h *UserHandler) LoginAttempts(c *fiber.Ctx) error {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "20"))
+	email := c.Query("email", "")
+	ip := c.Query("ip", "")
+
+	offset := (page - 1) * limit
+
+	var attempts []models.LoginAttempt
+	var total int64
+
+	query := h.db.Model(&models.LoginAttempt{})
+	if email != "" {
+		query = query.Where("email LIKE ?", "%"+email+"%")
+	}
+	if ip != "" {
+		query = query.Where("ip_address = ?", ip)
+	}
+
+	query.Count(&total)
+
+	if err := query.Offset(offset).Limit(limit).Order("created_at DESC").Find(&attempts).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch login attempts",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data":  attempts,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
 // ImportExcel imports users from Excel file
 func (h *UserHandler) ImportExcel(c *fiber.Ctx) error {
 	file, err := c.FormFile("file")
@@ -337,11 +581,12 @@ func (h *UserHandler) ImportExcel(c *fiber.Ctx) error {
 		hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 
 		user := models.User{
-			Email:     email,
-			Name:      name,
-			Password:  string(hashedPassword),
-			Role:      models.RoleStudent,
-			CreatedBy: &creatorID,
+			Email:              email,
+			Name:               name,
+			Password:           string(hashedPassword),
+			Role:               models.RoleStudent,
+			MustChangePassword: true,
+			CreatedBy:          &creatorID,
 		}
 
 		if err := h.db.Create(&user).Error; err != nil {
@@ -357,6 +602,8 @@ func (h *UserHandler) ImportExcel(c *fiber.Ctx) error {
 		})
 	}
 
+	audit(c, "user.import", "user", "", fmt.Sprintf("Imported %d students from %s, %d rows rejected", len(created), file.Filename, len(errors)))
+
 	return c.JSON(fiber.Map{
 		"created": created,
 		"errors":  errors,
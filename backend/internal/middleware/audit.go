@@ -0,0 +1,154 @@
+// ===========================================
+// Audit Middleware
+// ===========================================
+// Writes an audit event for administrative
+// and destructive requests after they ran
+// ===========================================
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/laravel-paas/backend/internal/models"
+	"github.com/laravel-paas/backend/internal/services"
+)
+
+// maxAuditBody is the largest JSON body copied into an audit summary
+const maxAuditBody = 64 * 1024
+
+// sensitiveFields are redacted from audited request bodies when a key contains one of them
+var sensitiveFields = []string{"password", "token", "secret", "code"}
+
+// Audit middleware records every DELETE, every change made by staff (roles with
+// more than self-service permissions) and any request a handler described with
+// an audit event in Locals("audit"). It must run after JWTAuth so the actor is known.
+func Audit(audit *services.AuditService, access *services.AccessService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		err := c.Next()
+
+		explicit, _ := c.Locals("audit").(*models.AuditEvent)
+		if explicit == nil && !shouldAudit(c, access) {
+			return err
+		}
+
+		event := &models.AuditEvent{}
+		if explicit != nil {
+			*event = *explicit
+		}
+		if event.Action == "" {
+			event.Action = c.Method() + " " + c.Route().Path
+		}
+		if event.TargetType == "" {
+			event.TargetType, event.TargetID = routeTarget(c)
+		}
+		if event.Summary == "" {
+			event.Summary = summarizeBody(c)
+		}
+
+		if userID, ok := c.Locals("user_id").(uint); ok {
+			event.ActorID = &userID
+		}
+		event.ActorEmail, _ = c.Locals("email").(string)
+		event.ActorRole, _ = c.Locals("role").(string)
+		event.IPAddress = c.IP()
+		event.UserAgent = c.Get("User-Agent")
+
+		event.StatusCode = c.Response().StatusCode()
+		if err != nil {
+			event.StatusCode = fiber.StatusInternalServerError
+			if fiberErr, ok := err.(*fiber.Error); ok {
+				event.StatusCode = fiberErr.Code
+			}
+		}
+		event.Result = "success"
+		if event.StatusCode >= 400 {
+			event.Result = "failure"
+		}
+
+		audit.Record(event)
+		return err
+	}
+}
+
+// shouldAudit picks the requests recorded without a handler asking for it
+func shouldAudit(c *fiber.Ctx, access *services.AccessService) bool {
+	if strings.HasPrefix(c.Path(), "/api/auth/") {
+		return false
+	}
+	switch c.Method() {
+	case fiber.MethodDelete:
+		return true
+	case fiber.MethodPost, fiber.MethodPut, fiber.MethodPatch:
+		role, _ := c.Locals("role").(string)
+		return access.Privileged(role)
+	}
+	return false
+}
+
+// routeTarget derives the target from the route, e.g. /api/admin/users/:id -> user, 7
+func routeTarget(c *fiber.Ctx) (string, string) {
+	path := strings.TrimPrefix(c.Route().Path, "/api")
+	path = strings.TrimPrefix(path, "/admin")
+	segment := strings.Split(strings.Trim(path, "/"), "/")[0]
+	targetType := strings.TrimSuffix(segment, "s")
+
+	targetID := c.Params("id")
+	if targetID == "" {
+		targetID = c.Params("name")
+	}
+	return targetType, targetID
+}
+
+// summarizeBody returns the JSON request body with secrets redacted, or a short
+// description of other bodies
+func summarizeBody(c *fiber.Ctx) string {
+	// Bodies are streamed, only read the ones that will be recorded
+	length := c.Request().Header.ContentLength()
+	if length == 0 {
+		return ""
+	}
+	if !strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEApplicationJSON) || length > maxAuditBody {
+		return fmt.Sprintf("[%s body, %d bytes]", c.Get(fiber.HeaderContentType), length)
+	}
+
+	body := c.Body()
+
+	var payload interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return fmt.Sprintf("[invalid JSON body, %d bytes]", len(body))
+	}
+	redacted, _ := json.Marshal(redact(payload))
+	return string(redacted)
+}
+
+// redact replaces the values of sensitive keys at any depth
+func redact(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, inner := range v {
+			if isSensitive(key) {
+				v[key] = "[redacted]"
+			} else {
+				v[key] = redact(inner)
+			}
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = redact(v[i])
+		}
+	}
+	return value
+}
+
+func isSensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, field := range sensitiveFields {
+		if strings.Contains(key, field) {
+			return true
+		}
+	}
+	return false
+}
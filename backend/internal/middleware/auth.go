@@ -10,18 +10,26 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/laravel-paas/backend/internal/models"
+	"github.com/laravel-paas/backend/internal/services"
 )
 
 // JWTClaims defines the JWT payload structure
 type JWTClaims struct {
-	UserID uint   `json:"user_id"`
-	Email  string `json:"email"`
-	Role   string `json:"role"`
+	UserID       uint   `json:"user_id"`
+	Email        string `json:"email"`
+	Role         string `json:"role"`
+	TokenVersion uint   `json:"ver"`
+	SessionID    string `json:"sid"`
+	// MustChangePassword and TwoFactorSetup limit the token to the auth endpoints
+	// until the password is changed or two-factor authentication is enrolled
+	MustChangePassword bool `json:"mcp"`
+	TwoFactorSetup     bool `json:"tfa"`
 	jwt.RegisteredClaims
 }
 
-// JWTAuth middleware validates JWT tokens
-func JWTAuth(secret string) fiber.Handler {
+// JWTAuth middleware validates JWT tokens and rejects revoked ones
+func JWTAuth(secret string, tokens *services.TokenService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		// Get token from Authorization header
 		authHeader := c.Get("Authorization")
@@ -60,34 +68,58 @@ func JWTAuth(secret string) fiber.Handler {
 			})
 		}
 
+		// Reject tokens revoked on logout, with their session or by a newer token version
+		if tokens.IsRevoked(claims.UserID, claims.ID, claims.SessionID, claims.TokenVersion) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Token has been revoked",
+			})
+		}
+
 		c.Locals("user_id", claims.UserID)
 		c.Locals("email", claims.Email)
 		c.Locals("role", claims.Role)
+		c.Locals("jti", claims.ID)
+		c.Locals("session_id", claims.SessionID)
+		c.Locals("must_change_password", claims.MustChangePassword)
+		c.Locals("two_factor_setup_required", claims.TwoFactorSetup)
+		if claims.ExpiresAt != nil {
+			c.Locals("token_expires_at", claims.ExpiresAt.Time)
+		}
 
 		return c.Next()
 	}
 }
 
-// RequireAdmin middleware ensures user has admin privileges
-func RequireAdmin() fiber.Handler {
+// RequireAccountReady middleware blocks accounts that must change their password
+// or enroll in two-factor authentication from everything but the auth endpoints
+func RequireAccountReady() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		role := c.Locals("role").(string)
-		if role != "superadmin" && role != "admin" {
+		if strings.HasPrefix(c.Path(), "/api/auth/") {
+			return c.Next()
+		}
+
+		if mustChange, _ := c.Locals("must_change_password").(bool); mustChange {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Password change required",
+			})
+		}
+		if setup, _ := c.Locals("two_factor_setup_required").(bool); setup {
 			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-				"error": "Admin access required",
+				"error": "Two-factor authentication setup required",
 			})
 		}
 		return c.Next()
 	}
 }
 
-// RequireSuperAdmin middleware ensures user is superadmin
-func RequireSuperAdmin() fiber.Handler {
+// RequirePermission middleware lets a request through when the user's role
+// holds any of the permissions
+func RequirePermission(access *services.AccessService, permissions ...models.Permission) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		role := c.Locals("role").(string)
-		if role != "superadmin" {
+		if !access.Can(role, permissions...) {
 			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-				"error": "Superadmin access required",
+				"error": "You do not have permission to do this",
 			})
 		}
 		return c.Next()
@@ -0,0 +1,36 @@
+// ===========================================
+// Body Limit Middleware
+// ===========================================
+// Caps request bodies per route. The server
+// streams bodies, so nothing is read before this
+// ===========================================
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
+// BodyLimit rejects requests whose body is larger than limit bytes. Requests
+// matched by skip are left to a route-level limit. Chunked bodies have no
+// length to check and are refused.
+func BodyLimit(limit int, skip func(c *fiber.Ctx) bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if skip != nil && skip(c) {
+			return c.Next()
+		}
+
+		length := c.Request().Header.ContentLength()
+		if length < 0 {
+			return c.Status(fiber.StatusLengthRequired).JSON(fiber.Map{
+				"error": "Content-Length is required",
+			})
+		}
+		if length > limit {
+			return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+				"error": "Request body is too large",
+			})
+		}
+
+		return c.Next()
+	}
+}
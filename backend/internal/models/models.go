@@ -21,22 +21,33 @@ type Role string
 const (
 	RoleSuperAdmin Role = "superadmin"
 	RoleAdmin      Role = "admin"
+	RoleTeacher    Role = "teacher" // Manages the students of their classes
 	RoleStudent    Role = "student"
 )
 
 // User represents a system user (admin or student)
 type User struct {
-	ID        uint           `gorm:"primaryKey" json:"id"`
-	Email     string         `gorm:"uniqueIndex;size:255;not null" json:"email"`
-	Password  string         `gorm:"size:255;not null" json:"-"` // Never expose password
-	Name      string         `gorm:"size:255;not null" json:"name"`
-	Role      Role           `gorm:"size:20;not null;default:student" json:"role"`
-	CreatedBy *uint          `json:"created_by,omitempty"`
-	Creator   *User          `gorm:"foreignKey:CreatedBy" json:"creator,omitempty"`
-	Projects  []Project      `gorm:"foreignKey:UserID" json:"projects,omitempty"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID                 uint           `gorm:"primaryKey" json:"id"`
+	Email              string         `gorm:"uniqueIndex;size:255;not null" json:"email"`
+	Password           string         `gorm:"size:255;not null" json:"-"` // Never expose password
+	Name               string         `gorm:"size:255;not null" json:"name"`
+	Role               Role           `gorm:"size:20;not null;default:student" json:"role"`
+	MustChangePassword bool           `gorm:"not null;default:false" json:"must_change_password"`
+	AuthProvider       string         `gorm:"size:20;not null;default:local" json:"auth_provider"` // local, oidc, ldap
+	TwoFactorEnabled   bool           `gorm:"not null;default:false" json:"two_factor_enabled"`
+	TOTPSecret         string         `gorm:"column:totp_secret;size:255" json:"-"` // Encrypted, set during enrollment
+	TokenVersion       uint           `gorm:"not null;default:0" json:"-"` // Bumped to revoke all issued tokens
+	LockedUntil        *time.Time     `json:"locked_until"` // Set after too many failed logins
+	CreatedBy          *uint          `json:"created_by,omitempty"`
+	Creator            *User          `gorm:"foreignKey:CreatedBy" json:"creator,omitempty"`
+	Projects           []Project      `gorm:"foreignKey:UserID" json:"projects,omitempty"`
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+	DeletedAt          gorm.DeletedAt `gorm:"index" json:"-"`
+	// TwoFactorSetupRequired is computed on sign-in: the role requires 2FA that is not enrolled yet
+	TwoFactorSetupRequired bool `gorm:"-" json:"two_factor_setup_required"`
+	// Permissions of the role, sent to the frontend on sign-in
+	Permissions []Permission `gorm:"-" json:"permissions,omitempty"`
 }
 
 // ===========================================
@@ -54,6 +65,14 @@ const (
 	StatusStopped  ProjectStatus = "stopped"
 )
 
+// DatabaseEngine represents the database server backing a project
+type DatabaseEngine string
+
+const (
+	EngineMySQL    DatabaseEngine = "mysql"
+	EnginePostgres DatabaseEngine = "postgres"
+)
+
 // Project represents a deployed Laravel application
 type Project struct {
 	ID           uint           `gorm:"primaryKey" json:"id"`
@@ -64,6 +83,8 @@ type Project struct {
 	Branch       string         `gorm:"size:200;not null;default:main" json:"branch"`
 	Subdomain    string         `gorm:"uniqueIndex;size:100;not null" json:"subdomain"`
 	DatabaseName string         `gorm:"uniqueIndex;size:100;not null" json:"database_name"`
+	DBPassword   string         `gorm:"column:db_password;size:255" json:"-"` // Encrypted
+	DatabaseEngine DatabaseEngine `gorm:"size:20;not null;default:mysql" json:"database_engine"`
 	Status       ProjectStatus  `gorm:"size:20;not null;default:pending;index:idx_status_active" json:"status"`
 	ContainerID  *string        `gorm:"size:100" json:"container_id,omitempty"`
 	Port         *int           `json:"port,omitempty"`
@@ -74,18 +95,88 @@ type Project struct {
 	PHPVersion     string `gorm:"size:20" json:"php_version,omitempty"`
 	IsManualVersion bool  `gorm:"default:false" json:"is_manual_version"`
 	QueueEnabled    bool  `gorm:"default:false" json:"queue_enabled"` // Enables worker process
+
+	// Laravel environment (platform-managed keys merged into .env on deploy)
+	AppKey   string `gorm:"size:255" json:"-"` // Encrypted, generated once per project
+	AppEnv   string `gorm:"size:20;not null;default:production" json:"app_env"`
+	AppDebug bool   `gorm:"default:false" json:"app_debug"`
 	
 	// Resource limits (override defaults)
 	CPULimit    *float64 `json:"cpu_limit,omitempty"`
 	MemoryLimit *string  `gorm:"size:20" json:"memory_limit,omitempty"`
+	DBQuotaMB   *int     `gorm:"column:db_quota_mb" json:"db_quota_mb,omitempty"`
+
+	// Set while the database is over quota and INSERT/UPDATE are revoked
+	DBWritesRevoked bool `gorm:"default:false" json:"db_writes_revoked"`
 	
 	ExpiresAt *time.Time     `json:"expires_at,omitempty"`
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index:idx_status_active" json:"-"`
 
-	// Virtual field for frontend
-	URL string `gorm:"-" json:"url,omitempty"`
+	// Attached add-on services
+	Addons []Addon `gorm:"foreignKey:ProjectID" json:"addons,omitempty"`
+
+	// Virtual fields for frontend
+	URL        string     `gorm:"-" json:"url,omitempty"`
+	MemberRole MemberRole `gorm:"-" json:"member_role,omitempty"` // The current user's role on the project
+}
+
+// ===========================================
+// ProjectMember Model
+// ===========================================
+
+// MemberRole is what a collaborator may do on a project
+type MemberRole string
+
+const (
+	MemberOwner     MemberRole = "owner"     // The project's user; manages members and may delete it
+	MemberDeveloper MemberRole = "developer" // Redeploys, runs commands, edits env and writes to the database
+	MemberViewer    MemberRole = "viewer"    // Sees the project, its logs, stats and database
+)
+
+// ProjectMember is a collaborator invited to a project by email. The invitation
+// stays pending until the user with that email accepts it.
+type ProjectMember struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	ProjectID   uint       `gorm:"not null;uniqueIndex:idx_project_member_email" json:"project_id"`
+	Project     *Project   `gorm:"foreignKey:ProjectID" json:"project,omitempty"`
+	Email       string     `gorm:"size:255;not null;uniqueIndex:idx_project_member_email" json:"email"`
+	UserID      *uint      `gorm:"index" json:"user_id,omitempty"` // Set on acceptance
+	User        *User      `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Role        MemberRole `gorm:"size:20;not null" json:"role"`
+	InvitedByID uint       `gorm:"not null" json:"invited_by_id"`
+	InvitedBy   *User      `gorm:"foreignKey:InvitedByID" json:"invited_by,omitempty"`
+	AcceptedAt  *time.Time `json:"accepted_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// ===========================================
+// Class Model
+// ===========================================
+
+// Class is a cohort of students managed by its teachers
+type Class struct {
+	ID          uint   `gorm:"primaryKey" json:"id"`
+	Name        string `gorm:"size:255;not null" json:"name"`
+	Description string `gorm:"size:1000" json:"description"`
+	JoinCode    string `gorm:"uniqueIndex;size:20;not null" json:"join_code"`
+	JoinEnabled bool   `gorm:"not null" json:"join_enabled"`
+
+	// Overrides of the global settings for members; nil uses the setting
+	MaxProjects       *int `json:"max_projects"`
+	ProjectExpiryDays *int `json:"project_expiry_days"`
+	CPULimitPercent   *int `gorm:"column:cpu_limit_percent" json:"cpu_limit_percent"`
+	MemoryLimitMB     *int `gorm:"column:memory_limit_mb" json:"memory_limit_mb"`
+
+	Teachers  []User    `gorm:"many2many:class_teachers" json:"teachers,omitempty"`
+	Students  []User    `gorm:"many2many:class_members" json:"students,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Virtual field for lists
+	StudentCount int64 `gorm:"-" json:"student_count"`
 }
 
 // ===========================================
@@ -115,6 +206,138 @@ type ResourceLog struct {
 	RecordedAt time.Time `gorm:"index" json:"recorded_at"`
 }
 
+// ===========================================
+// Addon Model
+// ===========================================
+
+// AddonType represents a kind of attachable service
+type AddonType string
+
+const (
+	AddonRedis       AddonType = "redis"
+	AddonMeilisearch AddonType = "meilisearch"
+	AddonMailpit     AddonType = "mailpit"
+)
+
+// AddonStatus represents provisioning state of an add-on
+type AddonStatus string
+
+const (
+	AddonStatusProvisioning AddonStatus = "provisioning"
+	AddonStatusRunning      AddonStatus = "running"
+	AddonStatusFailed       AddonStatus = "failed"
+)
+
+// Addon is a sidecar service container attached to a project
+type Addon struct {
+	ID            uint        `gorm:"primaryKey" json:"id"`
+	ProjectID     uint        `gorm:"not null;uniqueIndex:idx_addon_project_type" json:"project_id"`
+	Type          AddonType   `gorm:"size:20;not null;uniqueIndex:idx_addon_project_type" json:"type"`
+	ContainerName string      `gorm:"size:150;not null" json:"container_name"`
+	Secret        string      `gorm:"size:255" json:"-"` // Encrypted password / master key
+	Status        AddonStatus `gorm:"size:20;not null;default:provisioning" json:"status"`
+	ErrorLog      *string     `gorm:"type:text" json:"error_log,omitempty"`
+	CreatedAt     time.Time   `json:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+
+	// Mailpit web UI login, only filled in for members who can change the project
+	UIUser     string `gorm:"-" json:"ui_user,omitempty"`
+	UIPassword string `gorm:"-" json:"ui_password,omitempty"`
+}
+
+// BackupReason describes why a backup was taken
+type BackupReason string
+
+const (
+	BackupScheduled  BackupReason = "scheduled"
+	BackupManual     BackupReason = "manual"
+	BackupPreReset   BackupReason = "pre-reset"
+	BackupPreDeploy  BackupReason = "pre-deploy"
+	BackupPreRestore BackupReason = "pre-restore"
+)
+
+// Backup is a gzipped SQL snapshot of a project database
+type Backup struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	ProjectID uint           `gorm:"not null;index" json:"project_id"`
+	Engine    DatabaseEngine `gorm:"size:10;not null" json:"engine"`
+	Reason    BackupReason   `gorm:"size:20;not null" json:"reason"`
+	Storage   string         `gorm:"size:10;not null" json:"storage"` // local, s3
+	Key       string         `gorm:"size:255;not null" json:"-"`      // File path or object key
+	SizeBytes int64          `json:"size_bytes"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// ===========================================
+// Session Model
+// ===========================================
+
+// Session is a signed-in device holding a refresh token.
+// Only hashes of refresh tokens are stored.
+type Session struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	UserID       uint       `gorm:"not null;index" json:"user_id"`
+	TokenID      string     `gorm:"uniqueIndex;size:32;not null" json:"-"` // Public id in refresh and access tokens
+	RefreshHash  string     `gorm:"size:64;not null" json:"-"`
+	PreviousHash string     `gorm:"size:64" json:"-"` // Refresh token replaced by the last rotation
+	RotatedAt    *time.Time `json:"-"`
+	Device       string     `gorm:"size:100" json:"device"`
+	IPAddress    string     `gorm:"size:45" json:"ip_address"`
+	UserAgent    string     `gorm:"size:500" json:"user_agent"`
+	LastUsedAt   time.Time  `json:"last_used_at"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	RevokedAt    *time.Time `gorm:"index" json:"-"`
+	CreatedAt    time.Time  `json:"created_at"`
+	Current      bool       `gorm:"-" json:"current"`
+}
+
+// RecoveryCode is a one-time two-factor backup code; only its hash is stored
+type RecoveryCode struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	CodeHash  string     `gorm:"size:64;not null" json:"-"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// LoginAttempt records a failed sign-in for admins to review
+type LoginAttempt struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Email     string    `gorm:"size:255;not null;index" json:"email"`
+	UserID    *uint     `gorm:"index" json:"user_id"` // Nil when the email has no account
+	IPAddress string    `gorm:"size:45;index" json:"ip_address"`
+	UserAgent string    `gorm:"size:255" json:"user_agent"`
+	Reason    string    `gorm:"size:30;not null" json:"reason"` // invalid_credentials, invalid_2fa_code, account_locked
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+// AuditEvent records an administrative or destructive action
+type AuditEvent struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	ActorID    *uint     `gorm:"index" json:"actor_id"`
+	ActorEmail string    `gorm:"size:255" json:"actor_email"`
+	ActorRole  string    `gorm:"size:20" json:"actor_role"`
+	Action     string    `gorm:"size:100;not null;index" json:"action"` // e.g. project.delete
+	TargetType string    `gorm:"size:50;index" json:"target_type"`
+	TargetID   string    `gorm:"size:100;index" json:"target_id"`
+	IPAddress  string    `gorm:"size:45" json:"ip_address"`
+	UserAgent  string    `gorm:"size:255" json:"user_agent"`
+	Summary    string    `gorm:"type:text" json:"summary"` // Request details, secrets redacted
+	Result     string    `gorm:"size:20;not null;index" json:"result"` // success, failure
+	StatusCode int       `json:"status_code"`
+	CreatedAt  time.Time `gorm:"index" json:"created_at"`
+}
+
+// PasswordReset is a single-use password reset token; only its hash is stored
+type PasswordReset struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	TokenHash string     `gorm:"uniqueIndex;size:64;not null" json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
 // ===========================================
 // Helper Methods
 // ===========================================
@@ -124,6 +347,11 @@ func (u *User) IsAdmin() bool {
 	return u.Role == RoleSuperAdmin || u.Role == RoleAdmin
 }
 
+// IsTeacher checks if user manages classes without admin privileges
+func (u *User) IsTeacher() bool {
+	return u.Role == RoleTeacher
+}
+
 // IsSuperAdmin checks if user is superadmin
 func (u *User) IsSuperAdmin() bool {
 	return u.Role == RoleSuperAdmin
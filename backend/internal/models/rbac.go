@@ -0,0 +1,158 @@
+// ===========================================
+// Roles and Permissions
+// ===========================================
+// A role is a named set of permissions stored
+// in the database; users reference it by name
+// ===========================================
+package models
+
+import "time"
+
+// Permission allows one kind of action. Project permissions come in scopes:
+// own (projects the user owns), class (projects of the students the user
+// teaches) and any (all projects).
+type Permission string
+
+const (
+	PermProjectCreate      Permission = "project.create"
+	PermProjectReadOwn     Permission = "project.read.own"
+	PermProjectReadClass   Permission = "project.read.class"
+	PermProjectReadAny     Permission = "project.read.any"
+	PermProjectWriteOwn    Permission = "project.write.own"
+	PermProjectWriteClass  Permission = "project.write.class"
+	PermProjectWriteAny    Permission = "project.write.any"
+	PermProjectDeleteOwn   Permission = "project.delete.own"
+	PermProjectDeleteClass Permission = "project.delete.class"
+	PermProjectDeleteAny   Permission = "project.delete.any"
+	PermProjectQuota       Permission = "project.quota" // Override the database quota of a project
+
+	PermDatabaseQuery   Permission = "database.query"   // Use the SQL console
+	PermDatabaseOrphans Permission = "database.orphans" // List and drop databases of deleted projects
+
+	PermUserReadClass  Permission = "user.read.class"
+	PermUserReadAny    Permission = "user.read.any"
+	PermUserWriteClass Permission = "user.write.class"
+	PermUserWriteAny   Permission = "user.write.any"
+	PermUserCreate     Permission = "user.create"
+	PermUserDelete     Permission = "user.delete"
+	PermUserAdmins     Permission = "user.admins" // Assign roles and manage staff accounts
+
+	PermClassJoin     Permission = "class.join"
+	PermClassReadOwn  Permission = "class.read.own" // Classes the user teaches
+	PermClassReadAny  Permission = "class.read.any"
+	PermClassWriteOwn Permission = "class.write.own"
+	PermClassWriteAny Permission = "class.write.any"
+	PermClassManage   Permission = "class.manage" // Create and delete classes, assign teachers and limits, enroll any student
+
+	PermSettingsRead   Permission = "settings.read"
+	PermSettingsWrite  Permission = "settings.write"
+	PermAuditRead      Permission = "audit.read"
+	PermFeedbackManage Permission = "feedback.manage"
+	PermSystemRead     Permission = "system.read"
+	PermSystemPrune    Permission = "system.prune"
+	PermRolesManage    Permission = "roles.manage"
+
+	// PermAll grants every permission, including ones added later
+	PermAll Permission = "*"
+)
+
+// PermissionInfo describes a permission for the role editor
+type PermissionInfo struct {
+	Name        Permission `json:"name"`
+	Description string     `json:"description"`
+}
+
+// AllPermissions lists every permission with a description, in display order
+var AllPermissions = []PermissionInfo{
+	{PermProjectCreate, "Create projects"},
+	{PermProjectReadOwn, "View own projects, their logs and databases"},
+	{PermProjectReadClass, "View projects of students in taught classes"},
+	{PermProjectReadAny, "View all projects"},
+	{PermProjectWriteOwn, "Change, redeploy and run commands in own projects"},
+	{PermProjectWriteClass, "Change projects of students in taught classes"},
+	{PermProjectWriteAny, "Change all projects"},
+	{PermProjectDeleteOwn, "Delete own projects"},
+	{PermProjectDeleteClass, "Delete projects of students in taught classes"},
+	{PermProjectDeleteAny, "Delete all projects"},
+	{PermProjectQuota, "Override the database quota of a project"},
+	{PermDatabaseQuery, "Use the SQL console"},
+	{PermDatabaseOrphans, "List and drop databases of deleted projects"},
+	{PermUserReadClass, "View students of taught classes"},
+	{PermUserReadAny, "View all users"},
+	{PermUserWriteClass, "Edit, sign out and unlock students of taught classes"},
+	{PermUserWriteAny, "Edit, sign out and unlock all users"},
+	{PermUserCreate, "Create and import users"},
+	{PermUserDelete, "Delete users"},
+	{PermUserAdmins, "Assign roles and manage staff accounts"},
+	{PermClassJoin, "Join classes with a join code"},
+	{PermClassReadOwn, "View taught classes"},
+	{PermClassReadAny, "View all classes"},
+	{PermClassWriteOwn, "Manage students and join codes of taught classes"},
+	{PermClassWriteAny, "Manage students and join codes of all classes"},
+	{PermClassManage, "Create and delete classes, assign teachers and limits, enroll students by email"},
+	{PermSettingsRead, "View system settings"},
+	{PermSettingsWrite, "Change system settings"},
+	{PermAuditRead, "View the audit log"},
+	{PermFeedbackManage, "Read and answer feedback"},
+	{PermSystemRead, "View server, queue and Docker statistics"},
+	{PermSystemPrune, "Prune unused Docker resources"},
+	{PermRolesManage, "Create and edit roles"},
+}
+
+// RoleDefinition is a named set of permissions
+type RoleDefinition struct {
+	ID          uint         `gorm:"primaryKey" json:"id"`
+	Name        Role         `gorm:"uniqueIndex;size:20;not null" json:"name"`
+	Description string       `gorm:"size:255" json:"description"`
+	Permissions []Permission `gorm:"serializer:json;type:text" json:"permissions"`
+	BuiltIn     bool         `gorm:"not null;default:false" json:"built_in"` // Built-in roles cannot be renamed or deleted
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+
+	// Virtual field for lists
+	UserCount int64 `gorm:"-" json:"user_count"`
+}
+
+// TableName stores role definitions in "roles"
+func (RoleDefinition) TableName() string {
+	return "roles"
+}
+
+// DefaultRoles are created on first start; admins may change their permissions later
+var DefaultRoles = []RoleDefinition{
+	{
+		Name:        RoleSuperAdmin,
+		Description: "Full access",
+		Permissions: []Permission{PermAll},
+	},
+	{
+		Name:        RoleAdmin,
+		Description: "Manages users, classes, projects and the platform",
+		Permissions: []Permission{
+			PermProjectCreate, PermProjectReadAny, PermProjectWriteAny, PermProjectDeleteAny, PermProjectQuota,
+			PermDatabaseQuery, PermDatabaseOrphans,
+			PermUserReadAny, PermUserWriteAny, PermUserCreate, PermUserDelete,
+			PermClassReadAny, PermClassWriteAny, PermClassManage,
+			PermSettingsRead, PermSettingsWrite, PermAuditRead, PermFeedbackManage, PermSystemRead, PermSystemPrune,
+		},
+	},
+	{
+		Name:        RoleTeacher,
+		Description: "Manages the students and projects of their classes",
+		Permissions: []Permission{
+			PermProjectCreate, PermProjectReadOwn, PermProjectWriteOwn, PermProjectDeleteOwn,
+			PermProjectReadClass, PermProjectWriteClass, PermProjectDeleteClass,
+			PermDatabaseQuery,
+			PermUserReadClass, PermUserWriteClass,
+			PermClassReadOwn, PermClassWriteOwn,
+		},
+	},
+	{
+		Name:        RoleStudent,
+		Description: "Deploys and manages their own projects",
+		Permissions: []Permission{
+			PermProjectCreate, PermProjectReadOwn, PermProjectWriteOwn, PermProjectDeleteOwn,
+			PermDatabaseQuery, PermClassJoin,
+		},
+	},
+}
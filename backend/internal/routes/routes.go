@@ -6,6 +6,8 @@
 package routes
 
 import (
+	"strings"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
@@ -13,15 +15,29 @@ import (
 	"github.com/laravel-paas/backend/internal/config"
 	"github.com/laravel-paas/backend/internal/handlers"
 	"github.com/laravel-paas/backend/internal/middleware"
+	"github.com/laravel-paas/backend/internal/models"
 	"github.com/laravel-paas/backend/internal/services"
 	"gorm.io/gorm"
 )
 
+// importBodyLimit is the largest database dump accepted by the import route
+const importBodyLimit = 100 * 1024 * 1024
+
 // Setup initializes the Fiber app with all routes
-func Setup(db *gorm.DB, cfg *config.Config, redisService *services.RedisService) *fiber.App {
+func Setup(db *gorm.DB, cfg *config.Config, redisService *services.RedisService, projectPools *services.ProjectPools) *fiber.App {
 	app := fiber.New(fiber.Config{
 		ErrorHandler: handlers.ErrorHandler,
 		AppName:      "Laravel PaaS API",
+		// Bodies are read on demand so the BodyLimit middleware can refuse them
+		// first, and uploaded dumps are spooled to disk instead of memory
+		StreamRequestBody:            true,
+		DisablePreParseMultipartForm: true,
+		// Requests arrive through Traefik; sessions record the client address.
+		// X-Forwarded-For is only read from Traefik, others could forge it.
+		ProxyHeader:             fiber.HeaderXForwardedFor,
+		EnableIPValidation:      true,
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          cfg.TrustedProxies,
 	})
 
 	// ===========================================
@@ -29,6 +45,11 @@ func Setup(db *gorm.DB, cfg *config.Config, redisService *services.RedisService)
 	// ===========================================
 	app.Use(recover.New())
 	app.Use(logger.New())
+	// Only database imports go above Fiber's default, with their own limit
+	app.Use(middleware.BodyLimit(fiber.DefaultBodyLimit, func(c *fiber.Ctx) bool {
+		return c.Method() == fiber.MethodPost && strings.HasPrefix(c.Path(), "/api/projects/") &&
+			strings.HasSuffix(c.Path(), "/database/import")
+	}))
 	app.Use(cors.New(cors.Config{
 		AllowOrigins: "*",
 		AllowMethods: "GET,POST,PUT,DELETE,OPTIONS",
@@ -48,13 +69,23 @@ func Setup(db *gorm.DB, cfg *config.Config, redisService *services.RedisService)
 	api := app.Group("/api")
 
 	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(db, cfg)
-	userHandler := handlers.NewUserHandler(db)
-	projectHandler := handlers.NewProjectHandler(db, cfg, redisService)
+	mailer := services.NewMailer(cfg)
+	authHandler := handlers.NewAuthHandler(db, cfg, redisService, mailer)
+	userHandler := handlers.NewUserHandler(db, cfg, redisService)
+	tokenService := services.NewTokenService(db, cfg, redisService)
+	projectHandler := handlers.NewProjectHandler(db, cfg, redisService, projectPools)
 	settingHandler := handlers.NewSettingHandler(db)
 	dockerService := services.NewDockerService(cfg)
 	systemHandler := handlers.NewSystemHandler(dockerService)
 	feedbackHandler := handlers.NewFeedbackHandler(db)
+	databaseHandler := handlers.NewDatabaseHandler(db, cfg, redisService, projectPools)
+	addonHandler := handlers.NewAddonHandler(db, cfg)
+	go addonHandler.SecureMailpit()
+	backupHandler := handlers.NewBackupHandler(db, cfg, redisService)
+	auditHandler := handlers.NewAuditHandler(db)
+	classHandler := handlers.NewClassHandler(db)
+	roleHandler := handlers.NewRoleHandler(db)
+	memberHandler := handlers.NewMemberHandler(db, cfg, mailer)
 
 	// ===========================================
 	// Subdomain Proxy for Student Projects
@@ -66,60 +97,119 @@ func Setup(db *gorm.DB, cfg *config.Config, redisService *services.RedisService)
 	// -----------------------------
 	auth := api.Group("/auth")
 	auth.Post("/login", authHandler.Login)
+	auth.Post("/refresh", authHandler.Refresh)
+	auth.Post("/password/forgot", authHandler.ForgotPassword)
+	auth.Post("/password/reset", authHandler.ResetPassword)
+	auth.Get("/providers", authHandler.Providers)
+	auth.Get("/oidc/login", authHandler.OIDCLogin)
+	auth.Get("/oidc/callback", authHandler.OIDCCallback)
+	auth.Post("/2fa/challenge", authHandler.TwoFactorChallenge)
 
 	// -----------------------------
 	// Protected Routes
 	// -----------------------------
-	protected := api.Group("", middleware.JWTAuth(cfg.JWTSecret))
-	
+	// Every route below is limited to the permissions of the user's role
+	accessService := services.NewAccessService(db)
+	can := func(permissions ...models.Permission) fiber.Handler {
+		return middleware.RequirePermission(accessService, permissions...)
+	}
+
+	protected := api.Group("", middleware.JWTAuth(cfg.JWTSecret, tokenService), middleware.RequireAccountReady(), middleware.Audit(services.NewAuditService(db), accessService))
+
 	// Auth (protected)
 	protected.Post("/auth/logout", authHandler.Logout)
 	protected.Get("/auth/me", authHandler.Me)
+	protected.Post("/auth/password", authHandler.ChangePassword)
+	protected.Get("/auth/sessions", authHandler.Sessions)
+	protected.Get("/auth/2fa", authHandler.TwoFactorStatus)
+	protected.Post("/auth/2fa/setup", authHandler.TwoFactorSetup)
+	protected.Post("/auth/2fa/verify", authHandler.TwoFactorVerify)
+	protected.Post("/auth/2fa/disable", authHandler.TwoFactorDisable)
+	protected.Post("/auth/2fa/recovery-codes", authHandler.TwoFactorRecoveryCodes)
+	protected.Delete("/auth/sessions/:id", authHandler.RevokeSession)
 
 	// Feedback (common)
 	protected.Post("/feedback", feedbackHandler.Create)
 	protected.Get("/feedback", feedbackHandler.ListOwn)
 
+	// Classes (students)
+	protected.Get("/classes", classHandler.ListOwn)
+	protected.Post("/classes/join", can(models.PermClassJoin), classHandler.Join)
+
+	// Project invitations
+	protected.Get("/invitations", memberHandler.ListInvitations)
+	protected.Post("/invitations/:id/accept", memberHandler.AcceptInvitation)
+	protected.Delete("/invitations/:id", memberHandler.DeclineInvitation)
+
 	// -----------------------------
 	// Admin Routes
 	// -----------------------------
-	admin := protected.Group("/admin", middleware.RequireAdmin())
+	// Handlers narrow .class and .own permissions to the user's classes
+	admin := protected.Group("/admin")
 
 	// User management
-	admin.Get("/users", userHandler.List)
-	admin.Post("/users", userHandler.Create)
-	admin.Post("/users/import", userHandler.ImportExcel)
-	admin.Get("/users/:id", userHandler.Get)
-	admin.Put("/users/:id", userHandler.Update)
-	admin.Delete("/users/:id", userHandler.Delete)
+	admin.Get("/users", can(models.PermUserReadClass, models.PermUserReadAny), userHandler.List)
+	admin.Post("/users", can(models.PermUserCreate), userHandler.Create)
+	admin.Post("/users/import", can(models.PermUserCreate), userHandler.ImportExcel)
+	admin.Get("/users/:id", can(models.PermUserReadClass, models.PermUserReadAny), userHandler.Get)
+	admin.Put("/users/:id", can(models.PermUserWriteClass, models.PermUserWriteAny), userHandler.Update)
+	admin.Delete("/users/:id", can(models.PermUserDelete), userHandler.Delete)
+	admin.Post("/users/:id/sign-out", can(models.PermUserWriteClass, models.PermUserWriteAny), userHandler.SignOut)
+	admin.Delete("/users/:id/2fa", can(models.PermUserWriteClass, models.PermUserWriteAny), userHandler.ResetTwoFactor)
+	admin.Post("/users/:id/unlock", can(models.PermUserWriteClass, models.PermUserWriteAny), userHandler.Unlock)
+	admin.Get("/login-attempts", can(models.PermUserReadAny), userHandler.LoginAttempts)
+
+	// Roles
+	admin.Get("/roles", can(models.PermRolesManage, models.PermUserAdmins, models.PermUserCreate, models.PermUserWriteAny), roleHandler.List)
+	admin.Post("/roles", can(models.PermRolesManage), roleHandler.Create)
+	admin.Put("/roles/:id", can(models.PermRolesManage), roleHandler.Update)
+	admin.Delete("/roles/:id", can(models.PermRolesManage), roleHandler.Delete)
+
+	// Projects
+	admin.Get("/projects", can(models.PermProjectReadClass, models.PermProjectReadAny), projectHandler.ListAll)
+	admin.Get("/stats", can(models.PermProjectReadClass, models.PermProjectReadAny), projectHandler.AdminStats)
+
+	// Classes
+	admin.Get("/classes", can(models.PermClassReadOwn, models.PermClassReadAny), classHandler.List)
+	admin.Post("/classes", can(models.PermClassManage), classHandler.Create)
+	admin.Get("/classes/:id", can(models.PermClassReadOwn, models.PermClassReadAny), classHandler.Get)
+	admin.Put("/classes/:id", can(models.PermClassWriteOwn, models.PermClassWriteAny), classHandler.Update)
+	admin.Delete("/classes/:id", can(models.PermClassManage), classHandler.Delete)
+	admin.Put("/classes/:id/teachers", can(models.PermClassManage), classHandler.SetTeachers)
+	admin.Post("/classes/:id/students", can(models.PermClassManage), classHandler.AddStudents)
+	admin.Delete("/classes/:id/students/:userId", can(models.PermClassWriteOwn, models.PermClassWriteAny), classHandler.RemoveStudent)
+	admin.Post("/classes/:id/join-code", can(models.PermClassWriteOwn, models.PermClassWriteAny), classHandler.RegenerateJoinCode)
 
 	// Settings
-	admin.Get("/settings", settingHandler.List)
-	admin.Put("/settings", settingHandler.Update)
+	admin.Get("/settings", can(models.PermSettingsRead), settingHandler.List)
+	admin.Put("/settings", can(models.PermSettingsWrite), settingHandler.Update)
 
-	// Admin project overview
-	admin.Get("/projects", projectHandler.ListAll)
-	admin.Get("/stats", projectHandler.AdminStats)
-	
-	// Feedback management (Admin)
-	admin.Get("/feedback", feedbackHandler.ListAll)
-	admin.Put("/feedback/:id/status", feedbackHandler.UpdateStatus)
-	admin.Delete("/feedback/:id", feedbackHandler.Delete)
+	// Audit log
+	admin.Get("/audit", can(models.PermAuditRead), auditHandler.List)
+
+	// Feedback management
+	admin.Get("/feedback", can(models.PermFeedbackManage), feedbackHandler.ListAll)
+	admin.Put("/feedback/:id/status", can(models.PermFeedbackManage), feedbackHandler.UpdateStatus)
+	admin.Delete("/feedback/:id", can(models.PermFeedbackManage), feedbackHandler.Delete)
 	
-	// Queue statistics (admin only)
-	admin.Get("/queue/stats", projectHandler.GetQueueStats)
-	admin.Get("/projects/stats", projectHandler.GetProjectsStats)
+	// Queue statistics
+	admin.Get("/queue/stats", can(models.PermSystemRead), projectHandler.GetQueueStats)
+	admin.Get("/projects/stats", can(models.PermSystemRead), projectHandler.GetProjectsStats)
+
+	// Orphaned project databases
+	admin.Get("/databases/orphans", can(models.PermDatabaseOrphans), databaseHandler.ListOrphans)
+	admin.Delete("/databases/orphans/:name", can(models.PermDatabaseOrphans), databaseHandler.DropOrphan)
 
 	// System monitoring (PaaS style)
-	admin.Get("/system/stats", systemHandler.GetStats)
-	admin.Post("/system/prune", systemHandler.PruneSystem)
+	admin.Get("/system/stats", can(models.PermSystemRead), systemHandler.GetStats)
+	admin.Post("/system/prune", can(models.PermSystemPrune), systemHandler.PruneSystem)
 
 	// -----------------------------
 	// Project Routes (Students)
 	// -----------------------------
 	projects := protected.Group("/projects")
 	projects.Get("/", projectHandler.ListOwn)
-	projects.Post("/", projectHandler.Create)
+	projects.Post("/", can(models.PermProjectCreate), projectHandler.Create)
 	projects.Get("/:id", projectHandler.Get)
 	projects.Put("/:id", projectHandler.Update)
 	projects.Post("/:id/redeploy", projectHandler.Redeploy)
@@ -130,18 +220,48 @@ func Setup(db *gorm.DB, cfg *config.Config, redisService *services.RedisService)
 	projects.Get("/:id/env", projectHandler.GetEnv)
 	projects.Put("/:id/env", projectHandler.UpdateEnv)
 
+	// Collaborators
+	projects.Get("/:id/members", memberHandler.List)
+	projects.Post("/:id/members", memberHandler.Invite)
+	projects.Put("/:id/members/:memberId", memberHandler.Update)
+	projects.Delete("/:id/members/:memberId", memberHandler.Remove)
+
+	// Add-on services
+	projects.Get("/:id/addons", addonHandler.List)
+	projects.Post("/:id/addons", addonHandler.Attach)
+	projects.Delete("/:id/addons/:addonId", addonHandler.Detach)
+
 	// -----------------------------
 	// Database Management Routes
 	// -----------------------------
-	databaseHandler := handlers.NewDatabaseHandler(db, cfg)
 	projects.Get("/:id/database/credentials", databaseHandler.GetCredentials)
+	projects.Post("/:id/database/rotate-password", databaseHandler.RotatePassword)
+	projects.Get("/:id/database/usage", databaseHandler.GetUsage)
 	projects.Get("/:id/database/tables", databaseHandler.ListTables)
+	projects.Get("/:id/database/schema", databaseHandler.GetSchema)
 	projects.Get("/:id/database/tables/:table", databaseHandler.GetTableStructure)
 	projects.Get("/:id/database/tables/:table/data", databaseHandler.GetTableData)
-	projects.Post("/:id/database/query", databaseHandler.ExecuteQuery)
+	projects.Post("/:id/database/tables/:table/rows", databaseHandler.InsertRow)
+	projects.Put("/:id/database/tables/:table/rows", databaseHandler.UpdateRow)
+	projects.Delete("/:id/database/tables/:table/rows", databaseHandler.DeleteRow)
+	projects.Post("/:id/database/tables", databaseHandler.CreateTable)
+	projects.Post("/:id/database/tables/:table/columns", databaseHandler.AddColumn)
+	projects.Put("/:id/database/tables/:table/columns/:column", databaseHandler.ModifyColumn)
+	projects.Delete("/:id/database/tables/:table/columns/:column", databaseHandler.DropColumn)
+	projects.Post("/:id/database/tables/:table/indexes", databaseHandler.AddIndex)
+	projects.Delete("/:id/database/tables/:table/indexes/:index", databaseHandler.DropIndex)
+	projects.Post("/:id/database/tables/:table/foreign-keys", databaseHandler.AddForeignKey)
+	projects.Delete("/:id/database/tables/:table/foreign-keys/:name", databaseHandler.DropForeignKey)
+	projects.Post("/:id/database/migration", databaseHandler.GenerateMigration)
+	projects.Post("/:id/database/query", can(models.PermDatabaseQuery), databaseHandler.ExecuteQuery)
 	projects.Get("/:id/database/export", databaseHandler.ExportDatabase)
-	projects.Post("/:id/database/import", databaseHandler.ImportDatabase)
+	projects.Post("/:id/database/import", middleware.BodyLimit(importBodyLimit, nil), databaseHandler.ImportDatabase)
+	projects.Get("/:id/database/import/:jobId", databaseHandler.GetImportStatus)
 	projects.Post("/:id/database/reset", databaseHandler.ResetDatabase)
+	projects.Get("/:id/database/backups", backupHandler.List)
+	projects.Post("/:id/database/backups", backupHandler.Create)
+	projects.Get("/:id/database/backups/:backupId/download", backupHandler.Download)
+	projects.Post("/:id/database/backups/:backupId/restore", backupHandler.Restore)
 
 	return app
 }
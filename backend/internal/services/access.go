@@ -0,0 +1,303 @@
+// ===========================================
+// Access Control
+// ===========================================
+// Role permissions, project collaborators and
+// the project access resolver shared by all
+// project endpoints
+// ===========================================
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/laravel-paas/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// ProjectAction is what a user wants to do with a project
+type ProjectAction string
+
+const (
+	ProjectRead   ProjectAction = "read"   // View it, its logs, stats, env and database
+	ProjectWrite  ProjectAction = "write"  // Change it, redeploy, run commands, write to its database
+	ProjectDelete ProjectAction = "delete" // Delete it and manage its members
+)
+
+// memberActions are the actions each collaborator role allows; deleting stays with the owner
+var memberActions = map[models.MemberRole][]ProjectAction{
+	models.MemberDeveloper: {ProjectRead, ProjectWrite},
+	models.MemberViewer:    {ProjectRead},
+}
+
+// memberRolesFor returns the collaborator roles that allow the action
+func memberRolesFor(action ProjectAction) []models.MemberRole {
+	roles := []models.MemberRole{}
+	for role, actions := range memberActions {
+		for _, allowed := range actions {
+			if allowed == action {
+				roles = append(roles, role)
+			}
+		}
+	}
+	return roles
+}
+
+var (
+	// ErrProjectNotFound is returned for projects the user cannot see, so their existence is not revealed
+	ErrProjectNotFound = errors.New("project not found")
+	// ErrProjectForbidden is returned when the user can see the project but not perform the action
+	ErrProjectForbidden = errors.New("you do not have permission to do this on this project")
+)
+
+// permissionCacheTTL bounds how long other processes may act on outdated roles
+const permissionCacheTTL = time.Minute
+
+type cachedPermissions struct {
+	permissions map[models.Permission]bool
+	loadedAt    time.Time
+}
+
+// The cache is shared by every AccessService so role changes apply everywhere at once
+var (
+	permissionCache   = map[string]cachedPermissions{}
+	permissionCacheMu sync.RWMutex
+)
+
+// selfServicePermissions are what any signed-in user may hold without being staff
+var selfServicePermissions = map[models.Permission]bool{
+	models.PermProjectCreate:    true,
+	models.PermProjectReadOwn:   true,
+	models.PermProjectWriteOwn:  true,
+	models.PermProjectDeleteOwn: true,
+	models.PermDatabaseQuery:    true,
+	models.PermClassJoin:        true,
+}
+
+// AccessService answers authorization questions
+type AccessService struct {
+	db *gorm.DB
+}
+
+// NewAccessService creates a new access service
+func NewAccessService(db *gorm.DB) *AccessService {
+	return &AccessService{db: db}
+}
+
+// InvalidatePermissions drops cached role permissions after roles change
+func InvalidatePermissions() {
+	permissionCacheMu.Lock()
+	permissionCache = map[string]cachedPermissions{}
+	permissionCacheMu.Unlock()
+}
+
+// Permissions returns the permissions of a role. Unknown roles have none.
+func (s *AccessService) Permissions(role string) map[models.Permission]bool {
+	permissionCacheMu.RLock()
+	cached, ok := permissionCache[role]
+	permissionCacheMu.RUnlock()
+	if ok && time.Since(cached.loadedAt) < permissionCacheTTL {
+		return cached.permissions
+	}
+
+	permissions := map[models.Permission]bool{}
+	var definition models.RoleDefinition
+	if err := s.db.Where("name = ?", role).First(&definition).Error; err == nil {
+		for _, permission := range definition.Permissions {
+			permissions[permission] = true
+		}
+	}
+
+	permissionCacheMu.Lock()
+	permissionCache[role] = cachedPermissions{permissions: permissions, loadedAt: time.Now()}
+	permissionCacheMu.Unlock()
+	return permissions
+}
+
+// PermissionList returns the permissions of a role, with the wildcard expanded
+func (s *AccessService) PermissionList(role string) []models.Permission {
+	list := []models.Permission{}
+	for _, info := range models.AllPermissions {
+		if s.Can(role, info.Name) {
+			list = append(list, info.Name)
+		}
+	}
+	return list
+}
+
+// Can reports whether the role holds any of the permissions
+func (s *AccessService) Can(role string, permissions ...models.Permission) bool {
+	granted := s.Permissions(role)
+	if granted[models.PermAll] {
+		return true
+	}
+	for _, permission := range permissions {
+		if granted[permission] {
+			return true
+		}
+	}
+	return false
+}
+
+// Privileged reports whether the role holds anything beyond self-service permissions.
+// Managing accounts with such a role requires user.admins.
+func (s *AccessService) Privileged(role string) bool {
+	for permission := range s.Permissions(role) {
+		if !selfServicePermissions[permission] {
+			return true
+		}
+	}
+	return false
+}
+
+// scopes returns which scopes of a scoped permission like "project.read" the role holds
+func (s *AccessService) scopes(role, prefix string) (own, class, all bool) {
+	all = s.Can(role, models.Permission(prefix+".any"))
+	class = s.Can(role, models.Permission(prefix+".class"))
+	own = s.Can(role, models.Permission(prefix+".own"))
+	return own, class, all
+}
+
+// SharedProjectIDs is a subquery of the projects the user collaborates on with
+// one of the given roles, or with any role when none are given
+func SharedProjectIDs(db *gorm.DB, userID uint, roles ...models.MemberRole) *gorm.DB {
+	query := db.Model(&models.ProjectMember{}).Select("project_id").
+		Where("user_id = ? AND accepted_at IS NOT NULL", userID)
+	if len(roles) > 0 {
+		query = query.Where("role IN ?", roles)
+	}
+	return query
+}
+
+// MemberRole returns the user's collaborator role on the project, empty if none
+func (s *AccessService) MemberRole(userID uint, project *models.Project) models.MemberRole {
+	if project.UserID == userID {
+		return models.MemberOwner
+	}
+
+	var member models.ProjectMember
+	if err := s.db.Where("project_id = ? AND user_id = ? AND accepted_at IS NOT NULL", project.ID, userID).
+		First(&member).Error; err != nil {
+		return ""
+	}
+	return member.Role
+}
+
+// ProjectScope limits a project query to the projects the user may perform the action on.
+// The .own scope covers projects the user owns or collaborates on with a fitting role.
+func (s *AccessService) ProjectScope(userID uint, role string, action ProjectAction) func(*gorm.DB) *gorm.DB {
+	own, class, all := s.scopes(role, "project."+string(action))
+	memberRoles := memberRolesFor(action)
+	return func(query *gorm.DB) *gorm.DB {
+		if all {
+			return query
+		}
+
+		conditions := []string{}
+		args := []interface{}{}
+		if own {
+			conditions = append(conditions, "user_id = ?")
+			args = append(args, userID)
+			if len(memberRoles) > 0 {
+				conditions = append(conditions, "id IN (?)")
+				args = append(args, SharedProjectIDs(s.db, userID, memberRoles...))
+			}
+		}
+		if class {
+			conditions = append(conditions, "user_id IN (?)")
+			args = append(args, ClassStudentIDs(s.db, userID))
+		}
+		if len(conditions) == 0 {
+			return query.Where("1 = 0")
+		}
+		return query.Where("("+strings.Join(conditions, " OR ")+")", args...)
+	}
+}
+
+// CanAccessProject reports whether the user may perform the action on the project
+func (s *AccessService) CanAccessProject(userID uint, role string, project *models.Project, action ProjectAction) bool {
+	own, class, all := s.scopes(role, "project."+string(action))
+	switch {
+	case all:
+		return true
+	case own && project.UserID == userID:
+		return true
+	case class && TeachesStudent(s.db, userID, project.UserID):
+		return true
+	case own:
+		memberRole := s.MemberRole(userID, project)
+		for _, allowed := range memberActions[memberRole] {
+			if allowed == action {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ResolveProject loads a project for an action. Projects the user cannot even
+// read are reported as not found.
+func (s *AccessService) ResolveProject(userID uint, role string, projectID uint, action ProjectAction, preload ...string) (*models.Project, error) {
+	query := s.db
+	for _, association := range preload {
+		query = query.Preload(association)
+	}
+
+	var project models.Project
+	if err := query.First(&project, projectID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrProjectNotFound
+		}
+		return nil, fmt.Errorf("failed to load project: %w", err)
+	}
+
+	if !s.CanAccessProject(userID, role, &project, ProjectRead) {
+		return nil, ErrProjectNotFound
+	}
+	if action != ProjectRead && !s.CanAccessProject(userID, role, &project, action) {
+		return nil, ErrProjectForbidden
+	}
+	project.MemberRole = s.MemberRole(userID, &project)
+	return &project, nil
+}
+
+// UserScope limits a user query to the accounts the user may read or write
+func (s *AccessService) UserScope(userID uint, role string, action string) func(*gorm.DB) *gorm.DB {
+	_, class, all := s.scopes(role, "user."+action)
+	return func(query *gorm.DB) *gorm.DB {
+		switch {
+		case all:
+			return query
+		case class:
+			return query.Where("id IN (?)", ClassStudentIDs(s.db, userID))
+		}
+		return query.Where("1 = 0")
+	}
+}
+
+// ClassScope limits a class query to the classes the user may read or write
+func (s *AccessService) ClassScope(userID uint, role string, action string) func(*gorm.DB) *gorm.DB {
+	own, _, all := s.scopes(role, "class."+action)
+	return func(query *gorm.DB) *gorm.DB {
+		switch {
+		case all:
+			return query
+		case own:
+			return query.Where("id IN (?)", s.db.Table("class_teachers").Select("class_id").Where("user_id = ?", userID))
+		}
+		return query.Where("1 = 0")
+	}
+}
+
+// CanAssignRole reports whether users of actorRole may give accounts the role.
+// Staff roles need user.admins; self-service roles like student need the
+// permission to create or edit any account.
+func (s *AccessService) CanAssignRole(actorRole, role string) bool {
+	if s.Can(actorRole, models.PermUserAdmins) {
+		return true
+	}
+	return !s.Privileged(role) && s.Can(actorRole, models.PermUserCreate, models.PermUserWriteAny)
+}
@@ -0,0 +1,233 @@
+// ===========================================
+// Add-on Service
+// ===========================================
+// Runs sidecar service containers (Redis,
+// Meilisearch, Mailpit) attached to a project
+// ===========================================
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+
+	"github.com/laravel-paas/backend/internal/config"
+	"github.com/laravel-paas/backend/internal/models"
+)
+
+// mailpitImage is pinned so a new release cannot change the UI or its authentication
+const mailpitImage = "axllent/mailpit:v1.21.8"
+
+// MailpitUser is the login name of the Mailpit web UI, the password is the add-on secret
+const MailpitUser = "mailpit"
+
+// AddonService manages add-on containers
+type AddonService struct {
+	cfg     *config.Config
+	secrets *SecretBox
+}
+
+// NewAddonService creates a new add-on service
+func NewAddonService(cfg *config.Config) *AddonService {
+	return &AddonService{cfg: cfg, secrets: NewSecretBox(cfg)}
+}
+
+// ValidAddonType reports whether t is a supported add-on
+func ValidAddonType(t models.AddonType) bool {
+	return t == models.AddonRedis || t == models.AddonMeilisearch || t == models.AddonMailpit
+}
+
+// AddonContainerName returns the container name of a project's add-on
+func AddonContainerName(subdomain string, t models.AddonType) string {
+	return fmt.Sprintf("paas-addon-%s-%s", subdomain, t)
+}
+
+// NewAddon prepares an add-on record with a generated, encrypted secret
+func (s *AddonService) NewAddon(project *models.Project, t models.AddonType) (*models.Addon, error) {
+	addon := &models.Addon{
+		ProjectID:     project.ID,
+		Type:          t,
+		ContainerName: AddonContainerName(project.Subdomain, t),
+		Status:        models.AddonStatusProvisioning,
+	}
+
+	secret, err := s.NewSecret()
+	if err != nil {
+		return nil, err
+	}
+	addon.Secret = secret
+
+	return addon, nil
+}
+
+// NewSecret generates an encrypted add-on secret
+func (s *AddonService) NewSecret() (string, error) {
+	secret, err := GenerateDatabasePassword()
+	if err != nil {
+		return "", err
+	}
+	return s.secrets.Encrypt(secret)
+}
+
+// MailpitLogin returns the credentials of a Mailpit add-on's web UI
+func (s *AddonService) MailpitLogin(addon *models.Addon) (string, string, error) {
+	secret, err := s.secret(addon)
+	if err != nil {
+		return "", "", err
+	}
+	return MailpitUser, secret, nil
+}
+
+// Provision starts the add-on container on the platform network, replacing any previous one
+func (s *AddonService) Provision(project *models.Project, addon *models.Addon, projectDomain string) error {
+	secret, err := s.secret(addon)
+	if err != nil {
+		return err
+	}
+
+	exec.Command("docker", "rm", "-f", addon.ContainerName).Run()
+
+	args := []string{
+		"run", "-d",
+		"--name", addon.ContainerName,
+		"--network", s.cfg.DockerNetwork,
+		"--restart", "unless-stopped",
+		"--label", "com.paas.addon=true",
+		"--label", fmt.Sprintf("com.paas.addon.project=%s", project.Subdomain),
+	}
+
+	switch addon.Type {
+	case models.AddonRedis:
+		args = append(args,
+			"--memory", "128m",
+			"-v", addon.ContainerName+":/data",
+			"redis:7-alpine",
+			"redis-server", "--requirepass", secret, "--appendonly", "yes",
+		)
+
+	case models.AddonMeilisearch:
+		args = append(args,
+			"--memory", "256m",
+			"-e", "MEILI_ENV=production",
+			"-e", "MEILI_MASTER_KEY="+secret,
+			"-e", "MEILI_NO_ANALYTICS=true",
+			"-v", addon.ContainerName+":/meili_data",
+			"getmeili/meilisearch:v1.7",
+		)
+
+	case models.AddonMailpit:
+		// Web UI published at mail-<subdomain>.<project domain>, behind a login
+		if secret == "" {
+			return fmt.Errorf("mailpit add-on has no UI password")
+		}
+		router := fmt.Sprintf("%s-mailpit", project.Subdomain)
+		args = append(args,
+			"--memory", "64m",
+			"-e", fmt.Sprintf("MP_UI_AUTH=%s:%s", MailpitUser, secret),
+			"--label", "traefik.enable=true",
+			"--label", fmt.Sprintf("traefik.http.routers.%s.rule=Host(`mail-%s.%s`)", router, project.Subdomain, projectDomain),
+			"--label", fmt.Sprintf("traefik.http.services.%s.loadbalancer.server.port=8025", router),
+			mailpitImage,
+		)
+
+	default:
+		return fmt.Errorf("unsupported add-on type: %s", addon.Type)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("docker", args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("docker run failed: %s", stderr.String())
+	}
+
+	return nil
+}
+
+// Remove deletes the add-on container and its data volume
+func (s *AddonService) Remove(addon *models.Addon) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command("docker", "rm", "-f", addon.ContainerName)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to remove %s: %s", addon.ContainerName, stderr.String())
+	}
+
+	// Mailpit has no volume; a missing volume is not an error
+	exec.Command("docker", "volume", "rm", addon.ContainerName).Run()
+	return nil
+}
+
+// EnvVars returns the .env keys a Laravel app needs to use the add-on
+func (s *AddonService) EnvVars(addon *models.Addon) ([]EnvVar, error) {
+	return addonEnvVars(s.secrets, addon)
+}
+
+// DetachedEnvVars returns safe fallbacks for the keys set by an add-on after it is removed
+func DetachedEnvVars(t models.AddonType) []EnvVar {
+	switch t {
+	case models.AddonRedis:
+		return []EnvVar{
+			{Key: "CACHE_DRIVER", Value: "file"},
+			{Key: "CACHE_STORE", Value: "file"},
+			{Key: "SESSION_DRIVER", Value: "file"},
+		}
+	case models.AddonMeilisearch:
+		return []EnvVar{{Key: "SCOUT_DRIVER", Value: "collection"}}
+	case models.AddonMailpit:
+		return []EnvVar{{Key: "MAIL_MAILER", Value: "log"}}
+	}
+	return nil
+}
+
+// addonEnvVars builds the env keys of an add-on. Shared with createEnvFile.
+func addonEnvVars(secrets *SecretBox, addon *models.Addon) ([]EnvVar, error) {
+	secret := ""
+	if addon.Secret != "" {
+		decrypted, err := secrets.Decrypt(addon.Secret)
+		if err != nil {
+			return nil, err
+		}
+		secret = decrypted
+	}
+
+	switch addon.Type {
+	case models.AddonRedis:
+		return []EnvVar{
+			{Key: "REDIS_CLIENT", Value: "phpredis"},
+			{Key: "REDIS_HOST", Value: addon.ContainerName},
+			{Key: "REDIS_PORT", Value: "6379"},
+			{Key: "REDIS_PASSWORD", Value: secret},
+			{Key: "CACHE_DRIVER", Value: "redis"},
+			{Key: "CACHE_STORE", Value: "redis"},
+			{Key: "SESSION_DRIVER", Value: "redis"},
+		}, nil
+
+	case models.AddonMeilisearch:
+		return []EnvVar{
+			{Key: "SCOUT_DRIVER", Value: "meilisearch"},
+			{Key: "MEILISEARCH_HOST", Value: fmt.Sprintf("http://%s:7700", addon.ContainerName)},
+			{Key: "MEILISEARCH_KEY", Value: secret},
+		}, nil
+
+	case models.AddonMailpit:
+		return []EnvVar{
+			{Key: "MAIL_MAILER", Value: "smtp"},
+			{Key: "MAIL_HOST", Value: addon.ContainerName},
+			{Key: "MAIL_PORT", Value: "1025"},
+			{Key: "MAIL_USERNAME", Value: "null"},
+			{Key: "MAIL_PASSWORD", Value: "null"},
+			{Key: "MAIL_ENCRYPTION", Value: "null"},
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported add-on type: %s", addon.Type)
+}
+
+// secret decrypts the add-on secret (empty for add-ons without one)
+func (s *AddonService) secret(addon *models.Addon) (string, error) {
+	if addon.Secret == "" {
+		return "", nil
+	}
+	return s.secrets.Decrypt(addon.Secret)
+}
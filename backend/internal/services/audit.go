@@ -0,0 +1,79 @@
+// ===========================================
+// Audit Log Service
+// ===========================================
+// Stores audit events and prunes them (and
+// failed login records) after the retention
+// ===========================================
+package services
+
+import (
+	"log"
+	"time"
+
+	"github.com/laravel-paas/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// AuditService writes audit events and applies the retention period
+type AuditService struct {
+	db       *gorm.DB
+	interval time.Duration
+	running  bool
+}
+
+// NewAuditService creates a new audit service
+func NewAuditService(db *gorm.DB) *AuditService {
+	return &AuditService{db: db, interval: 6 * time.Hour}
+}
+
+// Record stores an audit event. Failures are logged so the action itself is not affected.
+func (s *AuditService) Record(event *models.AuditEvent) {
+	event.Action = truncate(event.Action, 100)
+	event.TargetID = truncate(event.TargetID, 100)
+	event.UserAgent = truncate(event.UserAgent, 255)
+	event.Summary = truncate(event.Summary, 4000)
+	if err := s.db.Create(event).Error; err != nil {
+		log.Printf("❌ Failed to record audit event %s: %v", event.Action, err)
+	}
+}
+
+// Start begins pruning old entries in the background
+func (s *AuditService) Start() {
+	if s.running {
+		return
+	}
+	s.running = true
+	log.Println("📜 Audit log retention started")
+
+	go func() {
+		for s.running {
+			s.Prune()
+			time.Sleep(s.interval)
+		}
+	}()
+}
+
+// Stop stops the pruning loop
+func (s *AuditService) Stop() {
+	s.running = false
+}
+
+// Prune deletes audit events and failed login records older than the retention period
+func (s *AuditService) Prune() {
+	days := getSettingInt(s.db, "audit_retention_days", 180)
+	if days <= 0 {
+		return
+	}
+	cutoff := time.Now().AddDate(0, 0, -days)
+
+	result := s.db.Where("created_at < ?", cutoff).Delete(&models.AuditEvent{})
+	if result.Error != nil {
+		log.Printf("❌ Failed to prune audit log: %v", result.Error)
+	} else if result.RowsAffected > 0 {
+		log.Printf("📜 Pruned %d audit events older than %d days", result.RowsAffected, days)
+	}
+
+	if err := s.db.Where("created_at < ?", cutoff).Delete(&models.LoginAttempt{}).Error; err != nil {
+		log.Printf("❌ Failed to prune login attempts: %v", err)
+	}
+}
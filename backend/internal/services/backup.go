@@ -0,0 +1,315 @@
+// ===========================================
+// Database Backup Service
+// ===========================================
+// Snapshots project databases on a schedule and
+// before destructive operations, with retention
+// ===========================================
+package services
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"time"
+
+	"github.com/laravel-paas/backend/internal/config"
+	"github.com/laravel-paas/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// BackupService creates, prunes and restores project database backups
+type BackupService struct {
+	db       *gorm.DB
+	cfg      *config.Config
+	secrets  *SecretBox
+	store    BackupStore
+	storeErr error
+	interval time.Duration
+	running  bool
+}
+
+// NewBackupService creates a new backup service
+func NewBackupService(db *gorm.DB, cfg *config.Config) *BackupService {
+	store, err := NewBackupStore(cfg)
+	if err != nil {
+		log.Printf("❌ Backup storage unavailable: %v", err)
+	}
+
+	return &BackupService{
+		db:       db,
+		cfg:      cfg,
+		secrets:  NewSecretBox(cfg),
+		store:    store,
+		storeErr: err,
+		interval: 15 * time.Minute,
+	}
+}
+
+// Start periodically backs up projects whose last scheduled backup is due
+func (b *BackupService) Start() {
+	if b.running {
+		return
+	}
+	b.running = true
+	log.Println("💾 Database backup scheduler started")
+
+	go func() {
+		for b.running {
+			b.RunScheduled()
+			time.Sleep(b.interval)
+		}
+	}()
+}
+
+// Stop stops the scheduler
+func (b *BackupService) Stop() {
+	b.running = false
+}
+
+// RunScheduled backs up every project whose last scheduled backup is older than the interval
+func (b *BackupService) RunScheduled() {
+	hours := getSettingInt(b.db, "backup_interval_hours", 24)
+	if hours <= 0 {
+		return
+	}
+	due := time.Now().Add(-time.Duration(hours) * time.Hour)
+
+	// Only projects whose database was provisioned: the password is saved once the
+	// database exists, and older projects without one have a container by then
+	var projects []models.Project
+	if err := b.db.Where("db_password <> '' OR container_id IS NOT NULL").Find(&projects).Error; err != nil {
+		log.Printf("❌ Backup scheduler failed to load projects: %v", err)
+		return
+	}
+
+	for i := range projects {
+		project := &projects[i]
+
+		var last models.Backup
+		err := b.db.Where("project_id = ? AND reason = ?", project.ID, models.BackupScheduled).
+			Order("created_at DESC").First(&last).Error
+		if err == nil && last.CreatedAt.After(due) {
+			continue
+		}
+
+		if _, err := b.Backup(project, models.BackupScheduled); err != nil {
+			log.Printf("❌ Scheduled backup failed for project #%d: %v", project.ID, err)
+		}
+	}
+}
+
+// Backup dumps the project database as gzipped SQL into backup storage
+func (b *BackupService) Backup(project *models.Project, reason models.BackupReason) (*models.Backup, error) {
+	if b.storeErr != nil {
+		return nil, b.storeErr
+	}
+
+	db, err := OpenProjectDatabase(b.cfg, b.secrets, project)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	defer db.Close()
+
+	key := fmt.Sprintf("%s/%s-%s.sql.gz", project.DatabaseName, time.Now().UTC().Format("20060102-150405"), reason)
+	dumper := NewDatabaseDumper(db, DialectFor(b.cfg, project.DatabaseEngine), project.DatabaseName)
+
+	// Stream the dump straight into storage
+	pr, pw := io.Pipe()
+	go func() {
+		gz := gzip.NewWriter(pw)
+		err := dumper.WriteSQL(gz, DumpOptions{})
+		if err == nil {
+			err = gz.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	size, err := b.store.Put(key, pr)
+	pr.CloseWithError(err)
+	if err != nil {
+		b.store.Delete(key)
+		return nil, err
+	}
+
+	backup := &models.Backup{
+		ProjectID: project.ID,
+		Engine:    project.DatabaseEngine,
+		Reason:    reason,
+		Storage:   b.store.Name(),
+		Key:       key,
+		SizeBytes: size,
+	}
+	if err := b.db.Create(backup).Error; err != nil {
+		b.store.Delete(key)
+		return nil, err
+	}
+
+	log.Printf("💾 Backed up project #%d database (%s, %d bytes)", project.ID, reason, size)
+	b.prune(project, reason)
+	return backup, nil
+}
+
+// prune deletes the oldest backups of a reason beyond the retention setting, so
+// pre-deploy or pre-restore snapshots never push out the scheduled ones
+func (b *BackupService) prune(project *models.Project, reason models.BackupReason) {
+	retention := getSettingInt(b.db, "backup_retention", 7)
+	if retention <= 0 {
+		return
+	}
+
+	// MySQL rejects OFFSET without LIMIT
+	var expired []models.Backup
+	err := b.db.Where("project_id = ? AND reason = ?", project.ID, reason).
+		Order("created_at DESC, id DESC").Limit(math.MaxInt32).Offset(retention).
+		Find(&expired).Error
+	if err != nil {
+		log.Printf("❌ Failed to list expired backups for project #%d: %v", project.ID, err)
+		return
+	}
+
+	for i := range expired {
+		if err := b.Delete(&expired[i]); err != nil {
+			log.Printf("❌ Failed to delete expired backup #%d: %v", expired[i].ID, err)
+		}
+	}
+}
+
+// Open returns a reader over the gzipped backup file
+func (b *BackupService) Open(backup *models.Backup) (io.ReadCloser, error) {
+	if b.storeErr != nil {
+		return nil, b.storeErr
+	}
+	if backup.Storage != b.store.Name() {
+		return nil, fmt.Errorf("backup is kept in %s storage, which is not configured", backup.Storage)
+	}
+	return b.store.Open(backup.Key)
+}
+
+// Delete removes a backup file and its record
+func (b *BackupService) Delete(backup *models.Backup) error {
+	if b.storeErr == nil && backup.Storage == b.store.Name() {
+		if err := b.store.Delete(backup.Key); err != nil {
+			return err
+		}
+	}
+	return b.db.Delete(backup).Error
+}
+
+// DeleteAll removes every backup of a project
+func (b *BackupService) DeleteAll(project *models.Project) {
+	var backups []models.Backup
+	b.db.Where("project_id = ?", project.ID).Find(&backups)
+	for i := range backups {
+		if err := b.Delete(&backups[i]); err != nil {
+			log.Printf("❌ Failed to delete backup #%d: %v", backups[i].ID, err)
+		}
+	}
+}
+
+// Restore replaces the project database with a backup. A pre-restore snapshot
+// is taken first so the restore itself can be undone.
+func (b *BackupService) Restore(project *models.Project, backup *models.Backup, job *ImportJob, progress func(*ImportJob)) error {
+	if backup.Engine != project.DatabaseEngine {
+		return fmt.Errorf("backup was taken from a %s database", backup.Engine)
+	}
+
+	// Download first: the snapshot below may prune the backup being restored
+	path, err := b.download(backup)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+
+	if _, err := b.Backup(project, models.BackupPreRestore); err != nil {
+		return fmt.Errorf("failed to snapshot database before restore: %w", err)
+	}
+
+	db, err := OpenProjectDatabase(b.cfg, b.secrets, project)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer db.Close()
+
+	dialect := DialectFor(b.cfg, project.DatabaseEngine)
+	if _, err := DropAllTables(db, dialect, project.DatabaseName); err != nil {
+		return fmt.Errorf("failed to clear database: %w", err)
+	}
+
+	return NewDatabaseImporter(db, dialect).ImportFile(path, job, progress)
+}
+
+// download copies a backup into a temporary file
+func (b *BackupService) download(backup *models.Backup) (string, error) {
+	r, err := b.Open(backup)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	tmp, err := os.CreateTemp("", "paas-restore-*.sql.gz")
+	if err != nil {
+		return "", err
+	}
+
+	_, err = io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to download backup: %w", err)
+	}
+	return tmp.Name(), nil
+}
+
+// OpenProjectDatabase connects to a project database with the project's own credentials
+func OpenProjectDatabase(cfg *config.Config, secrets *SecretBox, project *models.Project) (*sql.DB, error) {
+	password, err := ProjectDatabasePassword(secrets, project)
+	if err != nil {
+		return nil, err
+	}
+
+	dialect := DialectFor(cfg, project.DatabaseEngine)
+	return sql.Open(dialect.DriverName(), dialect.DSN(project.DatabaseName, project.DatabaseName, password))
+}
+
+// DropAllTables drops every table of a project database and returns how many were dropped
+func DropAllTables(db *sql.DB, dialect Dialect, database string) (int, error) {
+	tables, err := NewDatabaseDumper(db, dialect, database).Tables()
+	if err != nil {
+		return 0, err
+	}
+
+	// Session settings only apply to one connection
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	if dialect.Engine() == models.EnginePostgres {
+		// CASCADE drops dependent constraints and views
+		for _, table := range tables {
+			if _, err := conn.ExecContext(ctx, fmt.Sprintf("DROP TABLE IF EXISTS %s CASCADE", dialect.QuoteIdent(table))); err != nil {
+				return 0, err
+			}
+		}
+		return len(tables), nil
+	}
+
+	conn.ExecContext(ctx, "SET FOREIGN_KEY_CHECKS = 0")
+	defer conn.ExecContext(ctx, "SET FOREIGN_KEY_CHECKS = 1")
+
+	for _, table := range tables {
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf("DROP TABLE IF EXISTS %s", dialect.QuoteIdent(table))); err != nil {
+			return 0, err
+		}
+	}
+	return len(tables), nil
+}
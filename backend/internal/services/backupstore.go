@@ -0,0 +1,166 @@
+// ===========================================
+// Backup Storage
+// ===========================================
+// Stores backup files in a local directory or
+// an S3-compatible object store (e.g. MinIO)
+// ===========================================
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/laravel-paas/backend/internal/config"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// BackupStore persists backup files by key
+type BackupStore interface {
+	// Name identifies the store on backup records ("local" or "s3")
+	Name() string
+	Put(key string, r io.Reader) (int64, error)
+	Open(key string) (io.ReadCloser, error)
+	Delete(key string) error
+}
+
+// NewBackupStore returns the S3 store when an endpoint is configured, else the local store
+func NewBackupStore(cfg *config.Config) (BackupStore, error) {
+	if cfg.BackupS3Endpoint != "" {
+		return newS3BackupStore(cfg)
+	}
+	return &localBackupStore{root: cfg.BackupPath}, nil
+}
+
+// ===========================================
+// Local Directory
+// ===========================================
+
+type localBackupStore struct {
+	root string
+}
+
+func (s *localBackupStore) Name() string { return "local" }
+
+// path resolves a key inside the backup directory
+func (s *localBackupStore) path(key string) (string, error) {
+	path := filepath.Join(s.root, filepath.FromSlash(key))
+	if !strings.HasPrefix(path, filepath.Clean(s.root)+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid backup key: %s", key)
+	}
+	return path, nil
+}
+
+func (s *localBackupStore) Put(key string, r io.Reader) (int64, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return 0, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0640)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create backup file: %w", err)
+	}
+
+	size, err := io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return 0, err
+	}
+	return size, nil
+}
+
+func (s *localBackupStore) Open(key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+func (s *localBackupStore) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// ===========================================
+// S3-Compatible Object Store
+// ===========================================
+
+type s3BackupStore struct {
+	client *minio.Client
+	bucket string
+	region string
+}
+
+func newS3BackupStore(cfg *config.Config) (*s3BackupStore, error) {
+	client, err := minio.New(cfg.BackupS3Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.BackupS3AccessKey, cfg.BackupS3SecretKey, ""),
+		Secure: cfg.BackupS3UseSSL,
+		Region: cfg.BackupS3Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid S3 backup configuration: %w", err)
+	}
+	return &s3BackupStore{client: client, bucket: cfg.BackupS3Bucket, region: cfg.BackupS3Region}, nil
+}
+
+func (s *s3BackupStore) Name() string { return "s3" }
+
+// ensureBucket creates the bucket on first use
+func (s *s3BackupStore) ensureBucket(ctx context.Context) error {
+	exists, err := s.client.BucketExists(ctx, s.bucket)
+	if err != nil {
+		return fmt.Errorf("failed to reach S3 backup store: %w", err)
+	}
+	if !exists {
+		if err := s.client.MakeBucket(ctx, s.bucket, minio.MakeBucketOptions{Region: s.region}); err != nil {
+			return fmt.Errorf("failed to create bucket %s: %w", s.bucket, err)
+		}
+	}
+	return nil
+}
+
+func (s *s3BackupStore) Put(key string, r io.Reader) (int64, error) {
+	ctx := context.Background()
+	if err := s.ensureBucket(ctx); err != nil {
+		return 0, err
+	}
+
+	// Size -1 streams the upload in multipart chunks
+	info, err := s.client.PutObject(ctx, s.bucket, key, r, -1, minio.PutObjectOptions{
+		ContentType: "application/gzip",
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to upload backup: %w", err)
+	}
+	return info.Size, nil
+}
+
+func (s *s3BackupStore) Open(key string) (io.ReadCloser, error) {
+	obj, err := s.client.GetObject(context.Background(), s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download backup: %w", err)
+	}
+	return obj, nil
+}
+
+func (s *s3BackupStore) Delete(key string) error {
+	return s.client.RemoveObject(context.Background(), s.bucket, key, minio.RemoveObjectOptions{})
+}
@@ -0,0 +1,144 @@
+// ===========================================
+// Classes
+// ===========================================
+// Class membership, teacher scoping and the
+// per-class overrides of global limits
+// ===========================================
+package services
+
+import (
+	"crypto/rand"
+	"fmt"
+
+	"github.com/laravel-paas/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// joinCodeAlphabet leaves out characters that are easily confused
+const joinCodeAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+
+// Limits are the limits that apply to a user's projects
+type Limits struct {
+	MaxProjects       int `json:"max_projects"`
+	ProjectExpiryDays int `json:"project_expiry_days"` // 0 = never
+	CPULimitPercent   int `json:"cpu_limit_percent"`
+	MemoryLimitMB     int `json:"memory_limit_mb"`
+}
+
+// EffectiveLimits returns the global settings overridden by the user's classes.
+// A student in several classes gets the most generous override of each limit.
+func EffectiveLimits(db *gorm.DB, userID uint) Limits {
+	limits := GlobalLimits(db)
+
+	var classes []models.Class
+	db.Joins("JOIN class_members ON class_members.class_id = classes.id").
+		Where("class_members.user_id = ?", userID).
+		Find(&classes)
+
+	var maxProjects, expiry, cpu, memory []int
+	for _, class := range classes {
+		if class.MaxProjects != nil {
+			maxProjects = append(maxProjects, *class.MaxProjects)
+		}
+		if class.ProjectExpiryDays != nil {
+			expiry = append(expiry, *class.ProjectExpiryDays)
+		}
+		if class.CPULimitPercent != nil {
+			cpu = append(cpu, *class.CPULimitPercent)
+		}
+		if class.MemoryLimitMB != nil {
+			memory = append(memory, *class.MemoryLimitMB)
+		}
+	}
+
+	if len(maxProjects) > 0 {
+		limits.MaxProjects = maxOf(maxProjects)
+	}
+	if len(expiry) > 0 {
+		limits.ProjectExpiryDays = maxOf(expiry)
+		for _, days := range expiry {
+			if days == 0 {
+				limits.ProjectExpiryDays = 0
+			}
+		}
+	}
+	if len(cpu) > 0 {
+		limits.CPULimitPercent = maxOf(cpu)
+	}
+	if len(memory) > 0 {
+		limits.MemoryLimitMB = maxOf(memory)
+	}
+	return limits
+}
+
+// GlobalLimits returns the limits from the settings, before any class override
+func GlobalLimits(db *gorm.DB) Limits {
+	return Limits{
+		MaxProjects:       getSettingInt(db, "max_projects_per_user", 3),
+		ProjectExpiryDays: getSettingInt(db, "project_expiry_days", 30),
+		CPULimitPercent:   getSettingInt(db, "cpu_limit_percent", 50),
+		MemoryLimitMB:     getSettingInt(db, "memory_limit_mb", 512),
+	}
+}
+
+// ClassLimits returns the global limits overridden by a single class
+func ClassLimits(db *gorm.DB, class *models.Class) Limits {
+	limits := GlobalLimits(db)
+	if class.MaxProjects != nil {
+		limits.MaxProjects = *class.MaxProjects
+	}
+	if class.ProjectExpiryDays != nil {
+		limits.ProjectExpiryDays = *class.ProjectExpiryDays
+	}
+	if class.CPULimitPercent != nil {
+		limits.CPULimitPercent = *class.CPULimitPercent
+	}
+	if class.MemoryLimitMB != nil {
+		limits.MemoryLimitMB = *class.MemoryLimitMB
+	}
+	return limits
+}
+
+// ClassStudentIDs is a subquery of the students in the classes a teacher teaches
+func ClassStudentIDs(db *gorm.DB, teacherID uint) *gorm.DB {
+	return db.Table("class_members").Select("class_members.user_id").
+		Joins("JOIN class_teachers ON class_teachers.class_id = class_members.class_id").
+		Where("class_teachers.user_id = ?", teacherID)
+}
+
+// TeachesStudent reports whether the student is in one of the teacher's classes
+func TeachesStudent(db *gorm.DB, teacherID, studentID uint) bool {
+	var count int64
+	ClassStudentIDs(db, teacherID).Where("class_members.user_id = ?", studentID).Count(&count)
+	return count > 0
+}
+
+// GenerateJoinCode returns a random class join code like "K7RM2X9Q" that is not in use
+func GenerateJoinCode(db *gorm.DB) (string, error) {
+	for attempt := 0; attempt < 5; attempt++ {
+		b := make([]byte, 8)
+		if _, err := rand.Read(b); err != nil {
+			return "", fmt.Errorf("failed to generate join code: %w", err)
+		}
+		for i := range b {
+			b[i] = joinCodeAlphabet[int(b[i])%len(joinCodeAlphabet)]
+		}
+
+		var count int64
+		db.Model(&models.Class{}).Where("join_code = ?", string(b)).Count(&count)
+		if count == 0 {
+			return string(b), nil
+		}
+	}
+	return "", fmt.Errorf("failed to generate a unique join code")
+}
+
+func maxOf(values []int) int {
+	result := values[0]
+	for _, v := range values[1:] {
+		if v > result {
+			result = v
+		}
+	}
+	return result
+}
@@ -0,0 +1,800 @@
+// ===========================================
+// Database Dumper
+// ===========================================
+// Streams project databases as SQL (mysqldump
+// compatible for MySQL) or as zipped CSV files
+// ===========================================
+package services
+
+import (
+	"archive/zip"
+	"bufio"
+	"database/sql"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/laravel-paas/backend/internal/models"
+)
+
+// dumpBatchRows is the number of rows per extended INSERT statement
+const dumpBatchRows = 100
+
+// definerPattern matches DEFINER clauses, which students cannot restore without SUPER
+var definerPattern = regexp.MustCompile("DEFINER=`[^`]*`@`[^`]*` ")
+
+// serialPattern extracts the sequence of a serial column default
+var serialPattern = regexp.MustCompile(`^nextval\('([^']+)'::regclass\)$`)
+
+// DumpOptions selects what a dump contains
+type DumpOptions struct {
+	// Tables limits the dump to these tables. Empty dumps all tables, views and triggers.
+	Tables []string
+	// Flush is called after each chunk so data reaches the client while dumping
+	Flush func() error
+}
+
+// DatabaseDumper writes the contents of one project database
+type DatabaseDumper struct {
+	db       *sql.DB
+	dialect  Dialect
+	database string
+}
+
+// NewDatabaseDumper creates a dumper over an open project database connection
+func NewDatabaseDumper(db *sql.DB, dialect Dialect, database string) *DatabaseDumper {
+	return &DatabaseDumper{db: db, dialect: dialect, database: database}
+}
+
+// Tables returns the names of all base tables
+func (d *DatabaseDumper) Tables() ([]string, error) {
+	rows, err := d.db.Query(d.dialect.ListTablesQuery(), d.database)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var name, engine string
+		var tableRows int64
+		var sizeKB float64
+		var created sql.NullTime
+		if err := rows.Scan(&name, &tableRows, &sizeKB, &engine, &created); err != nil {
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+	return tables, rows.Err()
+}
+
+// ValidateTables checks that every requested table exists
+func (d *DatabaseDumper) ValidateTables(requested []string) error {
+	tables, err := d.Tables()
+	if err != nil {
+		return err
+	}
+
+	known := make(map[string]bool, len(tables))
+	for _, t := range tables {
+		known[t] = true
+	}
+	for _, t := range requested {
+		if !known[t] {
+			return fmt.Errorf("table %q does not exist", t)
+		}
+	}
+	return nil
+}
+
+// WriteSQL streams a SQL dump that can be re-imported into the same engine
+func (d *DatabaseDumper) WriteSQL(out io.Writer, opts DumpOptions) error {
+	w := bufio.NewWriterSize(out, 64*1024)
+	flush := func() error {
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if opts.Flush != nil {
+			return opts.Flush()
+		}
+		return nil
+	}
+
+	tables, full, err := d.selectTables(opts)
+	if err != nil {
+		return err
+	}
+
+	postgres := d.dialect.Engine() == models.EnginePostgres
+
+	fmt.Fprintf(w, "-- Laravel PaaS SQL dump\n")
+	fmt.Fprintf(w, "-- Database: %s\n", d.database)
+	fmt.Fprintf(w, "-- Engine: %s\n", d.dialect.Engine())
+	fmt.Fprintf(w, "-- Generated: %s\n\n", time.Now().Format(time.RFC3339))
+
+	if postgres {
+		fmt.Fprintf(w, "SET client_encoding = 'UTF8';\n")
+		fmt.Fprintf(w, "SET standard_conforming_strings = on;\n\n")
+	} else {
+		fmt.Fprintf(w, "SET NAMES utf8mb4;\n")
+		fmt.Fprintf(w, "SET FOREIGN_KEY_CHECKS = 0;\n")
+		fmt.Fprintf(w, "SET UNIQUE_CHECKS = 0;\n")
+		fmt.Fprintf(w, "SET SQL_MODE = 'NO_AUTO_VALUE_ON_ZERO';\n\n")
+	}
+
+	// PostgreSQL foreign keys, indexes and sequence positions are applied after all data
+	var deferred []string
+
+	for _, table := range tables {
+		quoted := d.dialect.QuoteIdent(table)
+
+		fmt.Fprintf(w, "--\n-- Table structure for %s\n--\n\n", quoted)
+
+		if postgres {
+			ddl, after, err := d.postgresTable(table)
+			if err != nil {
+				return fmt.Errorf("failed to read structure of %s: %w", table, err)
+			}
+			fmt.Fprintf(w, "DROP TABLE IF EXISTS %s CASCADE;\n%s\n\n", quoted, ddl)
+			deferred = append(deferred, after...)
+		} else {
+			ddl, err := d.showCreate(fmt.Sprintf("SHOW CREATE TABLE %s", quoted), 1)
+			if err != nil {
+				return fmt.Errorf("failed to read structure of %s: %w", table, err)
+			}
+			fmt.Fprintf(w, "DROP TABLE IF EXISTS %s;\n%s;\n\n", quoted, ddl)
+		}
+
+		fmt.Fprintf(w, "--\n-- Data for %s\n--\n\n", quoted)
+		if err := d.writeInserts(w, table, flush); err != nil {
+			return fmt.Errorf("failed to dump data of %s: %w", table, err)
+		}
+		w.WriteString("\n")
+
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(deferred) > 0 {
+		fmt.Fprintf(w, "--\n-- Indexes, foreign keys and sequences\n--\n\n")
+		for _, stmt := range deferred {
+			fmt.Fprintf(w, "%s;\n", stmt)
+		}
+		w.WriteString("\n")
+	}
+
+	if full {
+		if err := d.writeViews(w); err != nil {
+			return fmt.Errorf("failed to dump views: %w", err)
+		}
+		if err := d.writeTriggers(w); err != nil {
+			return fmt.Errorf("failed to dump triggers: %w", err)
+		}
+	}
+
+	if !postgres {
+		fmt.Fprintf(w, "SET UNIQUE_CHECKS = 1;\n")
+		fmt.Fprintf(w, "SET FOREIGN_KEY_CHECKS = 1;\n")
+	}
+	fmt.Fprintf(w, "\n-- Dump completed: %s\n", time.Now().Format(time.RFC3339))
+
+	return flush()
+}
+
+// WriteCSVZip streams a zip archive with one CSV file (with header row) per table
+func (d *DatabaseDumper) WriteCSVZip(out io.Writer, opts DumpOptions) error {
+	tables, _, err := d.selectTables(opts)
+	if err != nil {
+		return err
+	}
+
+	archive := zip.NewWriter(out)
+
+	for _, table := range tables {
+		file, err := archive.Create(table + ".csv")
+		if err != nil {
+			return err
+		}
+
+		if err := d.writeCSV(file, table); err != nil {
+			return fmt.Errorf("failed to dump data of %s: %w", table, err)
+		}
+
+		if err := archive.Flush(); err != nil {
+			return err
+		}
+		if opts.Flush != nil {
+			if err := opts.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return archive.Close()
+}
+
+// selectTables resolves the tables to dump and whether this is a full dump
+func (d *DatabaseDumper) selectTables(opts DumpOptions) ([]string, bool, error) {
+	if len(opts.Tables) == 0 {
+		tables, err := d.Tables()
+		return tables, true, err
+	}
+
+	if err := d.ValidateTables(opts.Tables); err != nil {
+		return nil, false, err
+	}
+	return opts.Tables, false, nil
+}
+
+// writeInserts writes table rows as extended INSERT statements
+func (d *DatabaseDumper) writeInserts(w *bufio.Writer, table string, flush func() error) error {
+	quoted := d.dialect.QuoteIdent(table)
+
+	rows, err := d.db.Query(fmt.Sprintf("SELECT * FROM %s", quoted))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return err
+	}
+
+	generated, overriding, err := d.generatedColumns(table)
+	if err != nil {
+		return err
+	}
+
+	// Generated columns are computed again on import and cannot be written
+	var columns []string
+	var written []int
+	for i, ct := range columnTypes {
+		if generated[ct.Name()] {
+			continue
+		}
+		columns = append(columns, d.dialect.QuoteIdent(ct.Name()))
+		written = append(written, i)
+	}
+	if len(columns) == 0 {
+		return rows.Err()
+	}
+
+	insert := fmt.Sprintf("INSERT INTO %s (%s) VALUES\n", quoted, strings.Join(columns, ", "))
+	if overriding {
+		// GENERATED ALWAYS identity columns only accept the dumped values this way
+		insert = fmt.Sprintf("INSERT INTO %s (%s) OVERRIDING SYSTEM VALUE VALUES\n", quoted, strings.Join(columns, ", "))
+	}
+
+	values := make([]interface{}, len(columnTypes))
+	pointers := make([]interface{}, len(columnTypes))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+
+	batch := 0
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return err
+		}
+
+		if batch == 0 {
+			w.WriteString(insert)
+		} else {
+			w.WriteString(",\n")
+		}
+
+		w.WriteString("(")
+		for n, i := range written {
+			if n > 0 {
+				w.WriteString(", ")
+			}
+			w.WriteString(d.literal(columnTypes[i].DatabaseTypeName(), values[i]))
+		}
+		w.WriteString(")")
+
+		batch++
+		if batch == dumpBatchRows {
+			w.WriteString(";\n")
+			batch = 0
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if batch > 0 {
+		w.WriteString(";\n")
+	}
+
+	return rows.Err()
+}
+
+// generatedColumns returns the generated columns of a table, and whether it has a
+// GENERATED ALWAYS identity column (PostgreSQL)
+func (d *DatabaseDumper) generatedColumns(table string) (map[string]bool, bool, error) {
+	generated := map[string]bool{}
+
+	if d.dialect.Engine() != models.EnginePostgres {
+		// EXTRA is also DEFAULT_GENERATED for expression defaults, which are writable
+		names, err := d.queryStrings(`
+			SELECT COLUMN_NAME FROM information_schema.COLUMNS
+			WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?
+				AND (EXTRA LIKE '%VIRTUAL GENERATED%' OR EXTRA LIKE '%STORED GENERATED%' OR EXTRA LIKE '%PERSISTENT GENERATED%')
+		`, d.database, table)
+		if err != nil {
+			return nil, false, err
+		}
+		for _, name := range names {
+			generated[name] = true
+		}
+		return generated, false, nil
+	}
+
+	rows, err := d.db.Query(`
+		SELECT attname, attidentity, attgenerated
+		FROM pg_attribute
+		WHERE attrelid = $1::regclass AND attnum > 0 AND NOT attisdropped
+	`, d.dialect.QuoteIdent("public")+"."+d.dialect.QuoteIdent(table))
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	overriding := false
+	for rows.Next() {
+		var name, identity, kind string
+		if err := rows.Scan(&name, &identity, &kind); err != nil {
+			return nil, false, err
+		}
+		if kind != "" {
+			generated[name] = true
+		}
+		if identity == "a" {
+			overriding = true
+		}
+	}
+	return generated, overriding, rows.Err()
+}
+
+// writeCSV writes all rows of a table as CSV. NULL is written as an empty field.
+func (d *DatabaseDumper) writeCSV(out io.Writer, table string) error {
+	rows, err := d.db.Query(fmt.Sprintf("SELECT * FROM %s", d.dialect.QuoteIdent(table)))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return err
+	}
+
+	w := csv.NewWriter(out)
+
+	header := make([]string, len(columnTypes))
+	for i, ct := range columnTypes {
+		header[i] = ct.Name()
+	}
+	if err := w.Write(header); err != nil {
+		return err
+	}
+
+	values := make([]interface{}, len(columnTypes))
+	pointers := make([]interface{}, len(columnTypes))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+
+	record := make([]string, len(columnTypes))
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return err
+		}
+		for i, v := range values {
+			record[i] = d.text(columnTypes[i].DatabaseTypeName(), v)
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+// writeViews writes CREATE VIEW statements (after all tables they may reference)
+func (d *DatabaseDumper) writeViews(w *bufio.Writer) error {
+	if d.dialect.Engine() == models.EnginePostgres {
+		rows, err := d.db.Query(`
+			SELECT c.relname, pg_get_viewdef(c.oid, true)
+			FROM pg_class c
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			WHERE n.nspname = 'public' AND c.relkind = 'v'
+			ORDER BY c.oid
+		`)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var name, definition string
+			if err := rows.Scan(&name, &definition); err != nil {
+				return err
+			}
+			quoted := d.dialect.QuoteIdent(name)
+			fmt.Fprintf(w, "--\n-- View %s\n--\n\n", quoted)
+			fmt.Fprintf(w, "DROP VIEW IF EXISTS %s CASCADE;\nCREATE VIEW %s AS\n%s\n\n",
+				quoted, quoted, strings.TrimSpace(definition))
+		}
+		return rows.Err()
+	}
+
+	names, err := d.queryStrings("SELECT TABLE_NAME FROM information_schema.VIEWS WHERE TABLE_SCHEMA = ? ORDER BY TABLE_NAME", d.database)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		quoted := d.dialect.QuoteIdent(name)
+		ddl, err := d.showCreate(fmt.Sprintf("SHOW CREATE VIEW %s", quoted), 1)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "--\n-- View %s\n--\n\n", quoted)
+		fmt.Fprintf(w, "DROP VIEW IF EXISTS %s;\n%s;\n\n", quoted, definerPattern.ReplaceAllString(ddl, ""))
+	}
+	return nil
+}
+
+// writeTriggers writes triggers (and for PostgreSQL their functions).
+// MySQL trigger bodies are wrapped in DELIMITER ;; like mysqldump does.
+func (d *DatabaseDumper) writeTriggers(w *bufio.Writer) error {
+	if d.dialect.Engine() == models.EnginePostgres {
+		functions, err := d.queryStrings(`
+			SELECT DISTINCT pg_get_functiondef(p.oid)
+			FROM pg_trigger t
+			JOIN pg_proc p ON p.oid = t.tgfoid
+			JOIN pg_namespace pn ON pn.oid = p.pronamespace
+			WHERE NOT t.tgisinternal AND pn.nspname = 'public'
+		`)
+		if err != nil {
+			return err
+		}
+		triggers, err := d.queryStrings(`
+			SELECT pg_get_triggerdef(t.oid)
+			FROM pg_trigger t
+			JOIN pg_class c ON c.oid = t.tgrelid
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			WHERE NOT t.tgisinternal AND n.nspname = 'public'
+			ORDER BY t.tgname
+		`)
+		if err != nil {
+			return err
+		}
+
+		if len(triggers) > 0 {
+			fmt.Fprintf(w, "--\n-- Triggers\n--\n\n")
+		}
+		for _, fn := range functions {
+			fmt.Fprintf(w, "%s;\n\n", strings.TrimSpace(fn))
+		}
+		for _, trigger := range triggers {
+			fmt.Fprintf(w, "%s;\n", trigger)
+		}
+		w.WriteString("\n")
+		return nil
+	}
+
+	names, err := d.queryStrings("SELECT TRIGGER_NAME FROM information_schema.TRIGGERS WHERE TRIGGER_SCHEMA = ? ORDER BY TRIGGER_NAME", d.database)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		quoted := d.dialect.QuoteIdent(name)
+		ddl, err := d.showCreate(fmt.Sprintf("SHOW CREATE TRIGGER %s", quoted), 2)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "--\n-- Trigger %s\n--\n\n", quoted)
+		fmt.Fprintf(w, "DROP TRIGGER IF EXISTS %s;\nDELIMITER ;;\n%s;;\nDELIMITER ;\n\n", quoted, definerPattern.ReplaceAllString(ddl, ""))
+	}
+	return nil
+}
+
+// postgresTable rebuilds CREATE TABLE for PostgreSQL. It returns statements
+// (foreign keys, indexes, sequence positions) that must run after all data.
+func (d *DatabaseDumper) postgresTable(table string) (string, []string, error) {
+	quoted := d.dialect.QuoteIdent(table)
+	regclass := d.dialect.QuoteIdent("public") + "." + quoted
+
+	rows, err := d.db.Query(`
+		SELECT a.attname, format_type(a.atttypid, a.atttypmod), a.attnotnull,
+			pg_get_expr(ad.adbin, ad.adrelid), a.attidentity, a.attgenerated
+		FROM pg_attribute a
+		LEFT JOIN pg_attrdef ad ON ad.adrelid = a.attrelid AND ad.adnum = a.attnum
+		WHERE a.attrelid = $1::regclass AND a.attnum > 0 AND NOT a.attisdropped
+		ORDER BY a.attnum
+	`, regclass)
+	if err != nil {
+		return "", nil, err
+	}
+	defer rows.Close()
+
+	var before, definitions, after []string
+	for rows.Next() {
+		var name, dataType, identity, generated string
+		var notNull bool
+		var defaultValue sql.NullString
+		if err := rows.Scan(&name, &dataType, &notNull, &defaultValue, &identity, &generated); err != nil {
+			return "", nil, err
+		}
+
+		column := d.dialect.QuoteIdent(name)
+		def := fmt.Sprintf("  %s %s", column, dataType)
+
+		switch {
+		case generated == "s":
+			// The expression is stored as the column default
+			def += " GENERATED ALWAYS AS (" + defaultValue.String + ") STORED"
+		case identity == "a":
+			def += " GENERATED ALWAYS AS IDENTITY"
+			after = append(after, fmt.Sprintf("SELECT setval(pg_get_serial_sequence(%s, %s), COALESCE(MAX(%s), 0) + 1, false) FROM %s",
+				d.dialect.QuoteString(quoted), d.dialect.QuoteString(name), column, quoted))
+		case identity == "d":
+			def += " GENERATED BY DEFAULT AS IDENTITY"
+			after = append(after, fmt.Sprintf("SELECT setval(pg_get_serial_sequence(%s, %s), COALESCE(MAX(%s), 0) + 1, false) FROM %s",
+				d.dialect.QuoteString(quoted), d.dialect.QuoteString(name), column, quoted))
+		case defaultValue.Valid:
+			def += " DEFAULT " + defaultValue.String
+			// serial columns: the sequence must exist before the table
+			if m := serialPattern.FindStringSubmatch(defaultValue.String); m != nil {
+				before = append(before, fmt.Sprintf("CREATE SEQUENCE IF NOT EXISTS %s;", m[1]))
+				after = append(after,
+					fmt.Sprintf("ALTER SEQUENCE %s OWNED BY %s.%s", m[1], quoted, column),
+					fmt.Sprintf("SELECT setval(%s, COALESCE(MAX(%s), 0) + 1, false) FROM %s", d.dialect.QuoteString(m[1]), column, quoted),
+				)
+			}
+		}
+		if notNull {
+			def += " NOT NULL"
+		}
+		definitions = append(definitions, def)
+	}
+	if err := rows.Err(); err != nil {
+		return "", nil, err
+	}
+
+	// Constraints: keys and checks inline, foreign keys after all tables exist
+	constraintRows, err := d.db.Query(`
+		SELECT conname, pg_get_constraintdef(oid), contype
+		FROM pg_constraint
+		WHERE conrelid = $1::regclass
+		ORDER BY contype, conname
+	`, regclass)
+	if err != nil {
+		return "", nil, err
+	}
+	defer constraintRows.Close()
+
+	for constraintRows.Next() {
+		var name, definition, kind string
+		if err := constraintRows.Scan(&name, &definition, &kind); err != nil {
+			return "", nil, err
+		}
+		if kind == "f" {
+			after = append(after, fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s %s", quoted, d.dialect.QuoteIdent(name), definition))
+			continue
+		}
+		definitions = append(definitions, fmt.Sprintf("  CONSTRAINT %s %s", d.dialect.QuoteIdent(name), definition))
+	}
+	if err := constraintRows.Err(); err != nil {
+		return "", nil, err
+	}
+
+	// Indexes that are not backing a constraint
+	indexes, err := d.queryStrings(`
+		SELECT pg_get_indexdef(i.indexrelid)
+		FROM pg_index i
+		WHERE i.indrelid = $1::regclass
+			AND NOT EXISTS (SELECT 1 FROM pg_constraint c WHERE c.conindid = i.indexrelid)
+	`, regclass)
+	if err != nil {
+		return "", nil, err
+	}
+	after = append(after, indexes...)
+
+	ddl := strings.Join(before, "\n")
+	if ddl != "" {
+		ddl += "\n"
+	}
+	ddl += fmt.Sprintf("CREATE TABLE %s (\n%s\n);", quoted, strings.Join(definitions, ",\n"))
+
+	return ddl, after, nil
+}
+
+// CreateTableStatement returns the DDL of a single table
+func (d *DatabaseDumper) CreateTableStatement(table string) (string, error) {
+	if d.dialect.Engine() == models.EnginePostgres {
+		ddl, _, err := d.postgresTable(table)
+		return ddl, err
+	}
+	return d.showCreate(fmt.Sprintf("SHOW CREATE TABLE %s", d.dialect.QuoteIdent(table)), 1)
+}
+
+// showCreate runs a MySQL SHOW CREATE statement and returns the given column
+func (d *DatabaseDumper) showCreate(query string, column int) (string, error) {
+	rows, err := d.db.Query(query)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	if column >= len(columns) {
+		return "", fmt.Errorf("unexpected result for %s", query)
+	}
+
+	values := make([]sql.NullString, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("no result for %s", query)
+	}
+	if err := rows.Scan(pointers...); err != nil {
+		return "", err
+	}
+	return values[column].String, nil
+}
+
+// queryStrings returns the first column of every row
+func (d *DatabaseDumper) queryStrings(query string, args ...interface{}) ([]string, error) {
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		result = append(result, value)
+	}
+	return result, rows.Err()
+}
+
+// ===========================================
+// Literals
+// ===========================================
+
+// literal formats a scanned value as a SQL literal of its column type
+func (d *DatabaseDumper) literal(typeName string, v interface{}) string {
+	if v == nil {
+		return "NULL"
+	}
+
+	typeName = strings.ToUpper(typeName)
+	postgres := d.dialect.Engine() == models.EnginePostgres
+
+	switch value := v.(type) {
+	case bool:
+		if value {
+			return "TRUE"
+		}
+		return "FALSE"
+	case int64:
+		return strconv.FormatInt(value, 10)
+	case float64:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	case time.Time:
+		return d.dialect.QuoteString(formatDumpTime(typeName, value, postgres))
+	case []byte:
+		if isBinaryType(typeName) {
+			if postgres {
+				return fmt.Sprintf(`'\x%s'::bytea`, hex.EncodeToString(value))
+			}
+			if len(value) == 0 {
+				return "''"
+			}
+			return "0x" + hex.EncodeToString(value)
+		}
+		if isNumericType(typeName) && isNumber(string(value)) {
+			return string(value)
+		}
+		return d.dialect.QuoteString(string(value))
+	case string:
+		if isNumericType(typeName) && isNumber(value) {
+			return value
+		}
+		return d.dialect.QuoteString(value)
+	}
+
+	return d.dialect.QuoteString(fmt.Sprintf("%v", v))
+}
+
+// text formats a scanned value for CSV output
+func (d *DatabaseDumper) text(typeName string, v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return formatDumpTime(strings.ToUpper(typeName), value, d.dialect.Engine() == models.EnginePostgres)
+	case []byte:
+		if isBinaryType(strings.ToUpper(typeName)) {
+			return "0x" + hex.EncodeToString(value)
+		}
+		return string(value)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// formatDumpTime formats dates the way the engine parses them back. PostgreSQL
+// times are scanned in year 0, which it would reject, so only the clock is kept.
+func formatDumpTime(typeName string, t time.Time, postgres bool) string {
+	if t.IsZero() && !postgres {
+		if typeName == "DATE" {
+			return "0000-00-00"
+		}
+		return "0000-00-00 00:00:00"
+	}
+
+	switch typeName {
+	case "DATE":
+		return t.Format("2006-01-02")
+	case "TIME":
+		return t.Format("15:04:05.999999")
+	case "TIMETZ":
+		return t.Format("15:04:05.999999-07:00")
+	case "TIMESTAMPTZ":
+		return t.Format("2006-01-02 15:04:05.999999-07:00")
+	}
+	return t.Format("2006-01-02 15:04:05.999999")
+}
+
+// isNumericType reports column types that are written unquoted
+func isNumericType(typeName string) bool {
+	typeName = strings.TrimPrefix(typeName, "UNSIGNED ")
+	switch typeName {
+	case "TINYINT", "SMALLINT", "MEDIUMINT", "INT", "INTEGER", "BIGINT", "DECIMAL", "FLOAT", "DOUBLE", "YEAR",
+		"INT2", "INT4", "INT8", "NUMERIC", "FLOAT4", "FLOAT8", "OID":
+		return true
+	}
+	return false
+}
+
+// isBinaryType reports column types written as hex literals
+func isBinaryType(typeName string) bool {
+	switch typeName {
+	case "BLOB", "TINYBLOB", "MEDIUMBLOB", "LONGBLOB", "BINARY", "VARBINARY", "BIT", "GEOMETRY", "BYTEA":
+		return true
+	}
+	return false
+}
+
+// isNumber rejects values like NaN or Infinity that must stay quoted
+func isNumber(s string) bool {
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil && !strings.ContainsAny(s, "nNiI")
+}
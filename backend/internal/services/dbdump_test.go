@@ -0,0 +1,34 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatDumpTime(t *testing.T) {
+	clock := time.Date(0, 1, 1, 12, 30, 0, 0, time.UTC)
+	zoned := time.Date(0, 1, 1, 12, 30, 5, 250000000, time.FixedZone("", 2*3600))
+	stamp := time.Date(2024, 3, 9, 8, 5, 1, 0, time.UTC)
+
+	tests := []struct {
+		typeName string
+		value    time.Time
+		postgres bool
+		want     string
+	}{
+		{"TIME", clock, true, "12:30:00"},
+		{"TIMETZ", zoned, true, "12:30:05.25+02:00"},
+		{"DATE", stamp, true, "2024-03-09"},
+		{"TIMESTAMP", stamp, true, "2024-03-09 08:05:01"},
+		{"TIMESTAMPTZ", stamp, true, "2024-03-09 08:05:01+00:00"},
+		{"DATETIME", stamp, false, "2024-03-09 08:05:01"},
+		{"DATETIME", time.Time{}, false, "0000-00-00 00:00:00"},
+		{"DATE", time.Time{}, false, "0000-00-00"},
+	}
+
+	for _, tt := range tests {
+		if got := formatDumpTime(tt.typeName, tt.value, tt.postgres); got != tt.want {
+			t.Errorf("formatDumpTime(%s, %v) = %q, want %q", tt.typeName, tt.value, got, tt.want)
+		}
+	}
+}
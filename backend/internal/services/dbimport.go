@@ -0,0 +1,365 @@
+// ===========================================
+// Database Importer
+// ===========================================
+// Executes SQL scripts (plain or gzipped) against
+// project databases with progress reporting
+// ===========================================
+package services
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/laravel-paas/backend/internal/models"
+	"github.com/laravel-paas/backend/internal/sqlparse"
+)
+
+const (
+	// importJobTTL is how long finished import jobs can be queried
+	importJobTTL = 24 * time.Hour
+	// maxImportErrors caps the errors kept on a job
+	maxImportErrors = 50
+	// importProgressInterval throttles progress updates
+	importProgressInterval = time.Second
+)
+
+// ImportStatus represents the state of an import job
+type ImportStatus string
+
+const (
+	ImportQueued    ImportStatus = "queued"
+	ImportRunning   ImportStatus = "running"
+	ImportCompleted ImportStatus = "completed"
+	ImportFailed    ImportStatus = "failed"
+)
+
+// ImportError describes a failed statement
+type ImportError struct {
+	Line      int    `json:"line"`
+	Statement string `json:"statement"`
+	Error     string `json:"error"`
+}
+
+// ImportJob tracks an import; it is stored in Redis while running
+type ImportJob struct {
+	ID              string        `json:"id"`
+	ProjectID       uint          `json:"project_id"`
+	Filename        string        `json:"filename,omitempty"`
+	Status          ImportStatus  `json:"status"`
+	Transactional   bool          `json:"transactional"`
+	TotalBytes      int64         `json:"total_bytes"`
+	BytesRead       int64         `json:"bytes_read"`
+	TotalStatements int           `json:"total_statements"`
+	Executed        int           `json:"executed"`
+	Skipped         int           `json:"skipped"`
+	Progress        float64       `json:"progress"` // percent
+	Errors          []ImportError `json:"errors"`
+	Error           string        `json:"error,omitempty"`
+	StartedAt       time.Time     `json:"started_at"`
+	FinishedAt      *time.Time    `json:"finished_at,omitempty"`
+}
+
+// NewImportJob creates a queued job with a random ID
+func NewImportJob(projectID uint, filename string, size int64) (*ImportJob, error) {
+	id := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, id); err != nil {
+		return nil, fmt.Errorf("failed to generate job id: %w", err)
+	}
+
+	return &ImportJob{
+		ID:         hex.EncodeToString(id),
+		ProjectID:  projectID,
+		Filename:   filename,
+		Status:     ImportQueued,
+		TotalBytes: size,
+		Errors:     []ImportError{},
+		StartedAt:  time.Now(),
+	}, nil
+}
+
+// ImportJobKey returns the Redis key of an import job
+func ImportJobKey(id string) string {
+	return "import_job:" + id
+}
+
+// SaveImportJob stores the job state in Redis
+func SaveImportJob(redisService *RedisService, job *ImportJob) error {
+	return redisService.SetCache(ImportJobKey(job.ID), job, importJobTTL)
+}
+
+// DatabaseImporter runs SQL scripts against one project database
+type DatabaseImporter struct {
+	db      *sql.DB
+	dialect Dialect
+}
+
+// NewDatabaseImporter creates an importer over an open project database connection
+func NewDatabaseImporter(db *sql.DB, dialect Dialect) *DatabaseImporter {
+	return &DatabaseImporter{db: db, dialect: dialect}
+}
+
+// scriptSource opens the script for one pass and reports consumed input bytes
+type scriptSource func() (io.Reader, func() int64, io.Closer, error)
+
+// ImportFile imports a SQL file from disk; gzip is detected from its content
+func (i *DatabaseImporter) ImportFile(path string, job *ImportJob, progress func(*ImportJob)) error {
+	return i.run(func() (io.Reader, func() int64, io.Closer, error) {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		counter := &countingReader{r: f}
+		br := bufio.NewReader(counter)
+		var r io.Reader = br
+
+		if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+			gz, err := gzip.NewReader(br)
+			if err != nil {
+				f.Close()
+				return nil, nil, nil, fmt.Errorf("invalid gzip file: %w", err)
+			}
+			r = gz
+		}
+
+		return r, func() int64 { return counter.n }, f, nil
+	}, job, progress)
+}
+
+// ImportString imports a script held in memory
+func (i *DatabaseImporter) ImportString(script string, job *ImportJob, progress func(*ImportJob)) error {
+	return i.run(func() (io.Reader, func() int64, io.Closer, error) {
+		r := strings.NewReader(script)
+		return r, func() int64 { return r.Size() - int64(r.Len()) }, io.NopCloser(nil), nil
+	}, job, progress)
+}
+
+// run validates the script in a first pass, then executes it. Scripts without
+// statements that commit implicitly run in a single transaction, so a failure
+// leaves the database untouched. Otherwise failed statements are reported and skipped.
+func (i *DatabaseImporter) run(open scriptSource, job *ImportJob, progress func(*ImportJob)) error {
+	notify := func() {
+		if progress != nil {
+			progress(job)
+		}
+	}
+
+	fail := func(err error) error {
+		now := time.Now()
+		job.Status = ImportFailed
+		job.Error = err.Error()
+		job.FinishedAt = &now
+		notify()
+		return err
+	}
+
+	job.Status = ImportRunning
+	notify()
+
+	// Pass 1: parse everything before touching the database
+	total, transactional, err := i.scan(open)
+	if err != nil {
+		return fail(err)
+	}
+	job.TotalStatements = total
+	job.Transactional = transactional
+	notify()
+
+	// Pass 2: execute on a single connection so session settings carry over
+	ctx := context.Background()
+	conn, err := i.db.Conn(ctx)
+	if err != nil {
+		return fail(fmt.Errorf("failed to connect: %w", err))
+	}
+	defer conn.Close()
+	defer discardConn(conn)
+
+	var tx *sql.Tx
+	exec := func(query string) error {
+		_, err := conn.ExecContext(ctx, query)
+		return err
+	}
+	if transactional {
+		if tx, err = conn.BeginTx(ctx, nil); err != nil {
+			return fail(fmt.Errorf("failed to start transaction: %w", err))
+		}
+		exec = func(query string) error {
+			_, err := tx.ExecContext(ctx, query)
+			return err
+		}
+	}
+
+	r, bytesRead, closer, err := open()
+	if err != nil {
+		return fail(err)
+	}
+	defer closer.Close()
+
+	postgres := i.dialect.Engine() == models.EnginePostgres
+	splitter := sqlparse.NewSplitter(r, splitOptions(i.dialect))
+	lastProgress := time.Now()
+	processed := 0
+
+	for {
+		stmt, err := splitter.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if tx != nil {
+				tx.Rollback()
+			}
+			return fail(err)
+		}
+
+		class := sqlparse.Classify(stmt.SQL, splitOptions(i.dialect))
+		keyword := class.Keyword
+		upper := strings.ToUpper(stmt.SQL)
+
+		switch {
+		case class.Forbidden != "":
+			job.Skipped++
+			i.addError(job, stmt, "Blocked: "+class.Forbidden)
+
+		case postgres && isMySQLSessionStatement(keyword, upper):
+			// Session settings from MySQL dumps have no PostgreSQL equivalent
+			job.Skipped++
+
+		default:
+			if err := exec(stmt.SQL); err != nil {
+				if tx != nil {
+					tx.Rollback()
+					i.addError(job, stmt, err.Error())
+					return fail(fmt.Errorf("line %d: %s (import rolled back)", stmt.Line, err.Error()))
+				}
+				i.addError(job, stmt, err.Error())
+			} else {
+				job.Executed++
+			}
+		}
+
+		processed++
+		if time.Since(lastProgress) >= importProgressInterval {
+			job.BytesRead = bytesRead()
+			job.Progress = percent(processed, job.TotalStatements)
+			notify()
+			lastProgress = time.Now()
+		}
+	}
+
+	if tx != nil {
+		if err := tx.Commit(); err != nil {
+			return fail(fmt.Errorf("failed to commit import: %w", err))
+		}
+	}
+
+	now := time.Now()
+	job.Status = ImportCompleted
+	job.BytesRead = bytesRead()
+	job.Progress = 100
+	job.FinishedAt = &now
+	notify()
+	return nil
+}
+
+// scan counts statements and decides whether the script can run in a transaction
+func (i *DatabaseImporter) scan(open scriptSource) (int, bool, error) {
+	r, _, closer, err := open()
+	if err != nil {
+		return 0, false, err
+	}
+	defer closer.Close()
+
+	postgres := i.dialect.Engine() == models.EnginePostgres
+	transactional := true
+	total := 0
+
+	splitter := sqlparse.NewSplitter(r, splitOptions(i.dialect))
+	for {
+		stmt, err := splitter.Next()
+		if err == io.EOF {
+			return total, transactional, nil
+		}
+		if err != nil {
+			return 0, false, err
+		}
+		total++
+
+		switch sqlparse.FirstKeyword(stmt.SQL) {
+		case "BEGIN", "START", "COMMIT", "ROLLBACK", "SAVEPOINT":
+			// The script manages its own transactions
+			transactional = false
+		case "CREATE", "ALTER", "DROP", "TRUNCATE", "RENAME", "LOCK", "UNLOCK", "GRANT", "REVOKE":
+			// MySQL commits implicitly on DDL, PostgreSQL DDL is transactional
+			if !postgres {
+				transactional = false
+			}
+		}
+	}
+}
+
+// addError records a failed statement, keeping at most maxImportErrors
+func (i *DatabaseImporter) addError(job *ImportJob, stmt sqlparse.Statement, message string) {
+	if len(job.Errors) >= maxImportErrors {
+		return
+	}
+
+	text := stmt.SQL
+	if len(text) > 200 {
+		text = text[:200] + "..."
+	}
+	job.Errors = append(job.Errors, ImportError{Line: stmt.Line, Statement: text, Error: message})
+}
+
+// splitOptions returns the lexical rules of the dialect's engine
+func splitOptions(dialect Dialect) sqlparse.Options {
+	if dialect.Engine() == models.EnginePostgres {
+		return sqlparse.Postgres()
+	}
+	return sqlparse.MySQL()
+}
+
+// isMySQLSessionStatement reports MySQL-only statements found in dumps
+func isMySQLSessionStatement(keyword, upper string) bool {
+	switch keyword {
+	case "LOCK", "UNLOCK", "DELIMITER":
+		return true
+	case "SET":
+		return strings.Contains(upper, "FOREIGN_KEY_CHECKS") ||
+			strings.Contains(upper, "UNIQUE_CHECKS") ||
+			strings.Contains(upper, "SQL_MODE") ||
+			strings.Contains(upper, "NAMES") ||
+			strings.Contains(upper, "TIME_ZONE") ||
+			strings.Contains(upper, "@")
+	}
+	return strings.HasPrefix(upper, "/*!")
+}
+
+// percent returns done/total as a percentage
+func percent(done, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+	return float64(done) * 100 / float64(total)
+}
+
+// countingReader counts bytes read from the underlying reader
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,285 @@
+// ===========================================
+// SQL Dialects
+// ===========================================
+// Engine-specific connection details, quoting
+// and catalog queries for project databases
+// ===========================================
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/laravel-paas/backend/internal/config"
+	"github.com/laravel-paas/backend/internal/models"
+	_ "github.com/lib/pq"
+)
+
+// Dialect describes how to talk to a project database engine.
+// Catalog queries take the database name as first argument (and the
+// table name as second where relevant) for every engine.
+type Dialect interface {
+	Engine() models.DatabaseEngine
+	DriverName() string
+	DSN(database, user, password string) string
+	Host() string
+	Port() int
+	LaravelConnection() string
+	QuoteIdent(name string) string
+	QuoteString(value string) string
+	Placeholder(n int) string
+
+	// ListTablesQuery returns name, rows, size in KB, engine, created
+	ListTablesQuery() string
+	// ColumnsQuery returns name, type, nullable (YES/NO), key (PRI/UNI/MUL), default, extra
+	ColumnsQuery() string
+	// IndexesQuery returns index name, column, unique, primary; one row per column in index order
+	IndexesQuery() string
+	// ForeignKeysQuery returns constraint name, column, referenced table, referenced column,
+	// delete rule and update rule; one row per column in key order
+	ForeignKeysQuery() string
+	// StatementTimeoutQuery limits how long the server runs each statement of the session
+	StatementTimeoutQuery(timeout time.Duration) string
+}
+
+// DialectFor returns the dialect for a project's engine (MySQL by default)
+func DialectFor(cfg *config.Config, engine models.DatabaseEngine) Dialect {
+	if engine == models.EnginePostgres {
+		return &postgresDialect{cfg: cfg}
+	}
+	return &mysqlDialect{cfg: cfg}
+}
+
+// ValidEngine reports whether engine is a supported database engine
+func ValidEngine(engine models.DatabaseEngine) bool {
+	return engine == models.EngineMySQL || engine == models.EnginePostgres
+}
+
+// ===========================================
+// MySQL
+// ===========================================
+
+type mysqlDialect struct {
+	cfg *config.Config
+}
+
+func (d *mysqlDialect) Engine() models.DatabaseEngine { return models.EngineMySQL }
+func (d *mysqlDialect) DriverName() string            { return "mysql" }
+func (d *mysqlDialect) LaravelConnection() string     { return "mysql" }
+func (d *mysqlDialect) Host() string                  { return d.cfg.DBHost }
+func (d *mysqlDialect) Placeholder(n int) string      { return "?" }
+
+func (d *mysqlDialect) Port() int {
+	port, _ := strconv.Atoi(d.cfg.DBPort)
+	return port
+}
+
+func (d *mysqlDialect) DSN(database, user, password string) string {
+	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true", user, password, d.cfg.DBHost, d.cfg.DBPort, database)
+}
+
+func (d *mysqlDialect) QuoteIdent(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+func (d *mysqlDialect) QuoteString(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, "'", `\'`)
+	value = strings.ReplaceAll(value, "\n", `\n`)
+	value = strings.ReplaceAll(value, "\r", `\r`)
+	return "'" + value + "'"
+}
+
+func (d *mysqlDialect) ListTablesQuery() string {
+	return `
+		SELECT
+			TABLE_NAME,
+			COALESCE(TABLE_ROWS, 0),
+			ROUND(((DATA_LENGTH + INDEX_LENGTH) / 1024), 2) AS size_kb,
+			COALESCE(ENGINE, ''),
+			CREATE_TIME
+		FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = ? AND TABLE_TYPE = 'BASE TABLE'
+		ORDER BY TABLE_NAME
+	`
+}
+
+// StatementTimeoutQuery uses MariaDB's max_statement_time (seconds)
+func (d *mysqlDialect) StatementTimeoutQuery(timeout time.Duration) string {
+	return fmt.Sprintf("SET SESSION max_statement_time = %g", timeout.Seconds())
+}
+
+func (d *mysqlDialect) ColumnsQuery() string {
+	return `
+		SELECT
+			COLUMN_NAME,
+			COLUMN_TYPE,
+			IS_NULLABLE,
+			COLUMN_KEY,
+			COLUMN_DEFAULT,
+			EXTRA
+		FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?
+		ORDER BY ORDINAL_POSITION
+	`
+}
+
+func (d *mysqlDialect) IndexesQuery() string {
+	return `
+		SELECT
+			INDEX_NAME,
+			COLUMN_NAME,
+			NON_UNIQUE = 0,
+			INDEX_NAME = 'PRIMARY'
+		FROM information_schema.STATISTICS
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?
+		ORDER BY INDEX_NAME, SEQ_IN_INDEX
+	`
+}
+
+func (d *mysqlDialect) ForeignKeysQuery() string {
+	return `
+		SELECT
+			k.CONSTRAINT_NAME,
+			k.COLUMN_NAME,
+			k.REFERENCED_TABLE_NAME,
+			k.REFERENCED_COLUMN_NAME,
+			r.DELETE_RULE,
+			r.UPDATE_RULE
+		FROM information_schema.KEY_COLUMN_USAGE k
+		JOIN information_schema.REFERENTIAL_CONSTRAINTS r
+			ON r.CONSTRAINT_SCHEMA = k.CONSTRAINT_SCHEMA AND r.CONSTRAINT_NAME = k.CONSTRAINT_NAME AND r.TABLE_NAME = k.TABLE_NAME
+		WHERE k.TABLE_SCHEMA = ? AND k.TABLE_NAME = ? AND k.REFERENCED_TABLE_NAME IS NOT NULL
+		ORDER BY k.CONSTRAINT_NAME, k.ORDINAL_POSITION
+	`
+}
+
+// ===========================================
+// PostgreSQL
+// ===========================================
+
+type postgresDialect struct {
+	cfg *config.Config
+}
+
+func (d *postgresDialect) Engine() models.DatabaseEngine { return models.EnginePostgres }
+func (d *postgresDialect) DriverName() string            { return "postgres" }
+func (d *postgresDialect) LaravelConnection() string     { return "pgsql" }
+func (d *postgresDialect) Host() string                  { return d.cfg.PostgresHost }
+
+func (d *postgresDialect) Port() int {
+	port, _ := strconv.Atoi(d.cfg.PostgresPort)
+	return port
+}
+
+func (d *postgresDialect) Placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+func (d *postgresDialect) DSN(database, user, password string) string {
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		d.cfg.PostgresHost, d.cfg.PostgresPort,
+		quoteConnValue(user), quoteConnValue(password), quoteConnValue(database))
+}
+
+func (d *postgresDialect) QuoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// QuoteString uses standard conforming strings, where only quotes are escaped
+func (d *postgresDialect) QuoteString(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+func (d *postgresDialect) ListTablesQuery() string {
+	return `
+		SELECT
+			c.relname,
+			GREATEST(c.reltuples, 0)::bigint,
+			ROUND(pg_total_relation_size(c.oid) / 1024.0, 2),
+			'heap',
+			NULL::timestamp
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE current_database() = $1 AND n.nspname = 'public' AND c.relkind IN ('r', 'p')
+		ORDER BY c.relname
+	`
+}
+
+func (d *postgresDialect) StatementTimeoutQuery(timeout time.Duration) string {
+	return fmt.Sprintf("SET statement_timeout = %d", timeout.Milliseconds())
+}
+
+func (d *postgresDialect) ColumnsQuery() string {
+	return `
+		SELECT
+			c.column_name,
+			CASE WHEN c.character_maximum_length IS NOT NULL
+				THEN c.data_type || '(' || c.character_maximum_length || ')'
+				ELSE c.data_type END,
+			c.is_nullable,
+			COALESCE((
+				SELECT CASE tc.constraint_type WHEN 'PRIMARY KEY' THEN 'PRI' WHEN 'UNIQUE' THEN 'UNI' ELSE 'MUL' END
+				FROM information_schema.key_column_usage k
+				JOIN information_schema.table_constraints tc
+					ON tc.constraint_name = k.constraint_name AND tc.table_schema = k.table_schema
+				WHERE k.table_schema = c.table_schema AND k.table_name = c.table_name AND k.column_name = c.column_name
+				ORDER BY CASE tc.constraint_type WHEN 'PRIMARY KEY' THEN 0 WHEN 'UNIQUE' THEN 1 ELSE 2 END
+				LIMIT 1
+			), ''),
+			c.column_default,
+			CASE WHEN c.is_identity = 'YES' OR c.column_default LIKE 'nextval(%' THEN 'auto_increment' ELSE '' END
+		FROM information_schema.columns c
+		WHERE c.table_catalog = $1 AND c.table_schema = 'public' AND c.table_name = $2
+		ORDER BY c.ordinal_position
+	`
+}
+
+func (d *postgresDialect) IndexesQuery() string {
+	return `
+		SELECT
+			i.relname,
+			a.attname,
+			ix.indisunique,
+			ix.indisprimary
+		FROM pg_index ix
+		JOIN pg_class t ON t.oid = ix.indrelid
+		JOIN pg_class i ON i.oid = ix.indexrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		JOIN LATERAL unnest(ix.indkey::int2[]) WITH ORDINALITY AS k(attnum, ord) ON true
+		JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+		WHERE current_database() = $1 AND n.nspname = 'public' AND t.relname = $2
+		ORDER BY i.relname, k.ord
+	`
+}
+
+func (d *postgresDialect) ForeignKeysQuery() string {
+	return `
+		SELECT
+			c.conname,
+			a.attname,
+			rt.relname,
+			ra.attname,
+			CASE c.confdeltype WHEN 'r' THEN 'RESTRICT' WHEN 'c' THEN 'CASCADE' WHEN 'n' THEN 'SET NULL' WHEN 'd' THEN 'SET DEFAULT' ELSE 'NO ACTION' END,
+			CASE c.confupdtype WHEN 'r' THEN 'RESTRICT' WHEN 'c' THEN 'CASCADE' WHEN 'n' THEN 'SET NULL' WHEN 'd' THEN 'SET DEFAULT' ELSE 'NO ACTION' END
+		FROM pg_constraint c
+		JOIN pg_class t ON t.oid = c.conrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		JOIN pg_class rt ON rt.oid = c.confrelid
+		JOIN LATERAL unnest(c.conkey, c.confkey) WITH ORDINALITY AS k(attnum, refnum, ord) ON true
+		JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = k.attnum
+		JOIN pg_attribute ra ON ra.attrelid = c.confrelid AND ra.attnum = k.refnum
+		WHERE c.contype = 'f' AND current_database() = $1 AND n.nspname = 'public' AND t.relname = $2
+		ORDER BY c.conname, k.ord
+	`
+}
+
+// quoteConnValue quotes a value for a libpq key/value connection string
+func quoteConnValue(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
@@ -7,7 +7,6 @@ package services
 
 import (
 	"bytes"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -26,12 +25,13 @@ import (
 
 // DockerService handles all Docker operations
 type DockerService struct {
-	cfg *config.Config
+	cfg     *config.Config
+	secrets *SecretBox
 }
 
 // NewDockerService creates a new Docker service
 func NewDockerService(cfg *config.Config) *DockerService {
-	return &DockerService{cfg: cfg}
+	return &DockerService{cfg: cfg, secrets: NewSecretBox(cfg)}
 }
 
 // ===========================================
@@ -150,47 +150,33 @@ func detectPHPVersion(laravelVersion, phpConstraint string) string {
 }
 
 // ===========================================
-// Database Operations
+// Database Credentials
 // ===========================================
 
-// CreateDatabase creates a MySQL database for a project
-func (s *DockerService) CreateDatabase(dbName string) error {
-	// Connect to MySQL container and create database
-	cmd := exec.Command("docker", "exec", "paas-mysql",
-		"mysql", "-uroot", "-p"+os.Getenv("MYSQL_ROOT_PASSWORD"),
-		"-e", fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s`;", dbName))
-
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to create database: %s", stderr.String())
+// EnsureDatabasePassword makes sure the project has a generated, encrypted
+// database password. Returns true when project.DBPassword needs to be saved.
+func (s *DockerService) EnsureDatabasePassword(project *models.Project) (bool, error) {
+	if project.DBPassword != "" {
+		return false, nil
 	}
 
-	// Create user and grant privileges
-	cmd = exec.Command("docker", "exec", "paas-mysql",
-		"mysql", "-uroot", "-p"+os.Getenv("MYSQL_ROOT_PASSWORD"),
-		"-e", fmt.Sprintf(
-			"CREATE USER IF NOT EXISTS '%s'@'%%' IDENTIFIED BY '%s'; GRANT ALL PRIVILEGES ON `%s`.* TO '%s'@'%%'; FLUSH PRIVILEGES;",
-			dbName, dbName, dbName, dbName,
-		))
+	password, err := GenerateDatabasePassword()
+	if err != nil {
+		return false, err
+	}
 
-	cmd.Stderr = &stderr
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to create database user: %s", stderr.String())
+	encrypted, err := s.secrets.Encrypt(password)
+	if err != nil {
+		return false, err
 	}
 
-	return nil
+	project.DBPassword = encrypted
+	return true, nil
 }
 
-// DropDatabase removes a MySQL database
-func (s *DockerService) DropDatabase(dbName string) error {
-	cmd := exec.Command("docker", "exec", "paas-mysql",
-		"mysql", "-uroot", "-p"+os.Getenv("MYSQL_ROOT_PASSWORD"),
-		"-e", fmt.Sprintf("DROP DATABASE IF EXISTS `%s`; DROP USER IF EXISTS '%s'@'%%';", dbName, dbName))
-
-	cmd.Run() // Ignore errors
-	return nil
+// DatabasePassword returns the plaintext database password of a project
+func (s *DockerService) DatabasePassword(project *models.Project) (string, error) {
+	return ProjectDatabasePassword(s.secrets, project)
 }
 
 // ===========================================
@@ -285,13 +271,22 @@ stdout_logfile_maxbytes=0
 	routerName := fmt.Sprintf("%s-%d", project.Subdomain, timestamp)
 	serviceName := project.Subdomain
 
+	// Limits come from the project, older projects keep the previous defaults
+	cpus, memory := "0.5", "512m"
+	if project.CPULimit != nil {
+		cpus = strconv.FormatFloat(*project.CPULimit, 'f', -1, 64)
+	}
+	if project.MemoryLimit != nil {
+		memory = *project.MemoryLimit
+	}
+
 	runArgs := []string{
 		"run", "-d",
 		"--name", containerName,
 		"--network", s.cfg.DockerNetwork,
 		"--restart", "unless-stopped",
-		"--cpus", "0.5",
-		"--memory", "512m",
+		"--cpus", cpus,
+		"--memory", memory,
 		
 		"--label", "traefik.enable=true",
 		"--label", fmt.Sprintf("traefik.http.routers.%s.rule=Host(`%s.%s`)",
@@ -326,46 +321,114 @@ stdout_logfile_maxbytes=0
 	return containerID, nil
 }
 
-// createEnvFile generates .env for Laravel project
+// EnsureAppKey makes sure the project has a persisted, encrypted APP_KEY.
+// Projects deployed before keys were persisted keep the key already in their .env.
+// Returns true when project.AppKey was set and needs to be saved.
+func (s *DockerService) EnsureAppKey(project *models.Project) (bool, error) {
+	if project.AppKey != "" {
+		return false, nil
+	}
+
+	appKey := ""
+	if content, err := s.GetEnvFile(project.Subdomain); err == nil {
+		if existing, ok := LookupEnv(content, "APP_KEY"); ok && strings.HasPrefix(existing, "base64:") {
+			appKey = existing
+		}
+	}
+
+	if appKey == "" {
+		generated, err := GenerateAppKey()
+		if err != nil {
+			return false, err
+		}
+		appKey = generated
+	}
+
+	encrypted, err := s.secrets.Encrypt(appKey)
+	if err != nil {
+		return false, err
+	}
+
+	project.AppKey = encrypted
+	return true, nil
+}
+
+// createEnvFile merges platform-managed settings into the project's .env
 func (s *DockerService) createEnvFile(project *models.Project, projectPath, projectDomain string) error {
-	// Generate random 32-byte key for APP_KEY
-	key := make([]byte, 32)
-	rand.Read(key)
-	appKey := base64.StdEncoding.EncodeToString(key)
-
-	envContent := fmt.Sprintf(`APP_NAME="%s"
-APP_ENV=production
-APP_KEY=base64:%s
-APP_DEBUG=true
-APP_URL=https://%s.%s
-
-DB_CONNECTION=mysql
-DB_HOST=paas-mysql
-DB_PORT=3306
-DB_DATABASE=%s
-DB_USERNAME=%s
-DB_PASSWORD=%s
-
-CACHE_DRIVER=file
-SESSION_DRIVER=file
-QUEUE_CONNECTION=sync
-`,
-		project.Name,
-		appKey,
-		project.Subdomain, projectDomain,
-		project.DatabaseName,
-		project.DatabaseName,
-		project.DatabaseName,
-	)
-	
-	// Set QUEUE_CONNECTION dynamically
+	if project.AppKey == "" {
+		return fmt.Errorf("project has no APP_KEY")
+	}
+
+	appKey, err := s.secrets.Decrypt(project.AppKey)
+	if err != nil {
+		return err
+	}
+
+	dbPassword, err := s.DatabasePassword(project)
+	if err != nil {
+		return err
+	}
+
+	envPath := filepath.Join(projectPath, ".env")
+
+	// Start from the user's .env (restored by CloneRepository), or the repo's example on first deploy
+	var content []byte
+	if data, err := os.ReadFile(envPath); err == nil {
+		content = data
+	} else if data, err := os.ReadFile(filepath.Join(projectPath, ".env.example")); err == nil {
+		content = data
+	}
+
+	appEnv := project.AppEnv
+	if appEnv == "" {
+		appEnv = "production"
+	}
+
+	// Project containers reach the database servers by their container names
+	dbConnection, dbHost, dbPort := "mysql", "paas-mysql", "3306"
+	if project.DatabaseEngine == models.EnginePostgres {
+		dbConnection, dbHost, dbPort = "pgsql", s.cfg.PostgresHost, s.cfg.PostgresPort
+	}
+
 	queueConn := "sync"
 	if project.QueueEnabled {
 		queueConn = "database"
 	}
-	envContent = strings.Replace(envContent, "QUEUE_CONNECTION=sync", "QUEUE_CONNECTION="+queueConn, 1)
 
-	return os.WriteFile(filepath.Join(projectPath, ".env"), []byte(envContent), 0644)
+	managed := []EnvVar{
+		{Key: "APP_ENV", Value: appEnv},
+		{Key: "APP_KEY", Value: appKey},
+		{Key: "APP_DEBUG", Value: strconv.FormatBool(project.AppDebug)},
+		{Key: "APP_URL", Value: fmt.Sprintf("https://%s.%s", project.Subdomain, projectDomain)},
+		{Key: "DB_CONNECTION", Value: dbConnection},
+		{Key: "DB_HOST", Value: dbHost},
+		{Key: "DB_PORT", Value: dbPort},
+		{Key: "DB_DATABASE", Value: project.DatabaseName},
+		{Key: "DB_USERNAME", Value: project.DatabaseName},
+		{Key: "DB_PASSWORD", Value: dbPassword},
+		{Key: "QUEUE_CONNECTION", Value: queueConn},
+	}
+
+	// Attached add-ons (loaded by the caller) point the app at their containers
+	for i := range project.Addons {
+		if project.Addons[i].Status == models.AddonStatusFailed {
+			continue
+		}
+		vars, err := addonEnvVars(s.secrets, &project.Addons[i])
+		if err != nil {
+			return err
+		}
+		managed = append(managed, vars...)
+	}
+
+	defaults := []EnvVar{
+		{Key: "APP_NAME", Value: project.Name},
+		{Key: "CACHE_DRIVER", Value: "file"},
+		{Key: "SESSION_DRIVER", Value: "file"},
+	}
+
+	merged := MergeEnv(string(content), managed, defaults)
+	return os.WriteFile(envPath, []byte(merged), 0644)
 }
 
 // StopContainer stops a running container
@@ -374,6 +437,17 @@ func (s *DockerService) StopContainer(containerID string) error {
 	return cmd.Run()
 }
 
+// RestartContainer restarts a container, dropping its open connections
+func (s *DockerService) RestartContainer(containerID string) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command("docker", "restart", containerID)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to restart container: %s", stderr.String())
+	}
+	return nil
+}
+
 // RemoveContainer stops and removes a container
 func (s *DockerService) RemoveContainer(containerID string) error {
 	exec.Command("docker", "stop", containerID).Run()
@@ -923,6 +997,37 @@ func (s *DockerService) SaveEnvFile(subdomain, content string) error {
 	return os.WriteFile(filepath.Join(projectPath, ".env"), []byte(content), 0644)
 }
 
+// UpdateEnvKeys merges the given keys into a project's .env on disk
+func (s *DockerService) UpdateEnvKeys(subdomain string, vars []EnvVar) error {
+	content, err := s.GetEnvFile(subdomain)
+	if err != nil {
+		return err
+	}
+	return s.SaveEnvFile(subdomain, MergeEnv(content, vars, nil))
+}
+
+// ApplyEnvFile copies the project's .env into its running container and restarts it.
+// The image bakes .env in at build time, so this avoids a full rebuild.
+func (s *DockerService) ApplyEnvFile(containerID, subdomain string) error {
+	envPath := filepath.Join(s.cfg.ProjectsPath, subdomain, ".env")
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("docker", "cp", envPath, containerID+":/var/www/html/.env")
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to copy .env into container: %s", stderr.String())
+	}
+
+	stderr.Reset()
+	cmd = exec.Command("docker", "restart", containerID)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to restart container: %s", stderr.String())
+	}
+
+	return nil
+}
+
 // ===========================================
 // Helpers
 // ===========================================
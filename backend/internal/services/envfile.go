@@ -0,0 +1,126 @@
+// ===========================================
+// Env File Helpers
+// ===========================================
+// Merges platform-managed keys into a Laravel .env
+// without discarding the user's own settings
+// ===========================================
+package services
+
+import (
+	"strings"
+)
+
+// EnvVar is a single KEY=value pair
+type EnvVar struct {
+	Key   string
+	Value string
+}
+
+// LookupEnv returns the value of key in .env content
+func LookupEnv(content, key string) (string, bool) {
+	for _, line := range strings.Split(content, "\n") {
+		k, v, ok := parseEnvLine(line)
+		if ok && k == key {
+			return unquoteEnvValue(v), true
+		}
+	}
+	return "", false
+}
+
+// MergeEnv overwrites managed keys and adds defaults that are missing.
+// Comments, ordering and all other user keys are preserved.
+func MergeEnv(content string, managed, defaults []EnvVar) string {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	if len(lines) == 1 && lines[0] == "" {
+		lines = nil
+	}
+
+	managedIndex := make(map[string]string, len(managed))
+	for _, v := range managed {
+		managedIndex[v.Key] = v.Value
+	}
+
+	present := make(map[string]bool)
+	for i, line := range lines {
+		key, _, ok := parseEnvLine(line)
+		if !ok {
+			continue
+		}
+		if value, isManaged := managedIndex[key]; isManaged {
+			lines[i] = key + "=" + formatEnvValue(value)
+		}
+		present[key] = true
+	}
+
+	var appended []string
+	for _, v := range managed {
+		if !present[v.Key] {
+			appended = append(appended, v.Key+"="+formatEnvValue(v.Value))
+			present[v.Key] = true
+		}
+	}
+	for _, v := range defaults {
+		if !present[v.Key] {
+			appended = append(appended, v.Key+"="+formatEnvValue(v.Value))
+			present[v.Key] = true
+		}
+	}
+
+	if len(appended) > 0 {
+		if len(lines) > 0 {
+			lines = append(lines, "", "# Managed by Laravel PaaS")
+		}
+		lines = append(lines, appended...)
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// parseEnvLine splits a KEY=value line, ignoring comments and blanks
+func parseEnvLine(line string) (string, string, bool) {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return "", "", false
+	}
+	trimmed = strings.TrimPrefix(trimmed, "export ")
+
+	key, value, found := strings.Cut(trimmed, "=")
+	if !found {
+		return "", "", false
+	}
+	return strings.TrimSpace(key), strings.TrimSpace(value), true
+}
+
+// unquoteEnvValue strips surrounding quotes from a value. Single-quoted values
+// are literal; double-quoted ones undo the \\ and \" escapes of formatEnvValue.
+func unquoteEnvValue(value string) string {
+	if len(value) < 2 {
+		return value
+	}
+	if value[0] == '\'' && value[len(value)-1] == '\'' {
+		return value[1 : len(value)-1]
+	}
+	if value[0] != '"' || value[len(value)-1] != '"' {
+		return value
+	}
+
+	inner := value[1 : len(value)-1]
+	var b strings.Builder
+	for i := 0; i < len(inner); i++ {
+		if inner[i] == '\\' && i+1 < len(inner) && (inner[i+1] == '\\' || inner[i+1] == '"') {
+			i++
+		}
+		b.WriteByte(inner[i])
+	}
+	return b.String()
+}
+
+// formatEnvValue quotes values that contain spaces or special characters
+func formatEnvValue(value string) string {
+	if value == "" || !strings.ContainsAny(value, " \t#\"'$\\") {
+		return value
+	}
+	escaped := strings.ReplaceAll(value, `\`, `\\`)
+	escaped = strings.ReplaceAll(escaped, `"`, `\"`)
+	return `"` + escaped + `"`
+}
@@ -0,0 +1,48 @@
+package services
+
+import "testing"
+
+func TestEnvValueRoundTrip(t *testing.T) {
+	values := []string{
+		"",
+		"plain",
+		"with space",
+		`back\slash`,
+		`trailing\`,
+		`\"`,
+		`say "hi"`,
+		`it's`,
+		"hash # sign",
+		"dollar $HOME",
+		`base64:a\\b"c'd`,
+	}
+
+	for _, value := range values {
+		content := MergeEnv("APP_NAME=Laravel\n", []EnvVar{{Key: "SECRET", Value: value}}, nil)
+		got, ok := LookupEnv(content, "SECRET")
+		if !ok {
+			t.Fatalf("LookupEnv(%q) did not find SECRET", content)
+		}
+		if got != value {
+			t.Errorf("round trip of %q = %q (written as %q)", value, got, content)
+		}
+	}
+}
+
+func TestUnquoteEnvValue(t *testing.T) {
+	tests := map[string]string{
+		`plain`:         `plain`,
+		`"quoted"`:      `quoted`,
+		`'C:\path'`:     `C:\path`,
+		`"a\\b"`:        `a\b`,
+		`"say \"hi\""`:  `say "hi"`,
+		`"keep \n raw"`: `keep \n raw`,
+		`"`:             `"`,
+	}
+
+	for in, want := range tests {
+		if got := unquoteEnvValue(in); got != want {
+			t.Errorf("unquoteEnvValue(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
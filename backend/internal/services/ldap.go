@@ -0,0 +1,109 @@
+// ===========================================
+// LDAP Authenticator
+// ===========================================
+// Finds the user with a service account and
+// verifies the password by binding as them
+// ===========================================
+package services
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/laravel-paas/backend/internal/config"
+)
+
+// ErrLDAPInvalidCredentials is returned when the user is unknown or the password is wrong
+var ErrLDAPInvalidCredentials = errors.New("invalid LDAP credentials")
+
+// ldapTimeout bounds dialing and each directory request
+const ldapTimeout = 10 * time.Second
+
+// LDAPAuthenticator checks logins against the configured directory
+type LDAPAuthenticator struct {
+	cfg *config.Config
+}
+
+// NewLDAPAuthenticator creates an authenticator for the configured directory
+func NewLDAPAuthenticator(cfg *config.Config) *LDAPAuthenticator {
+	return &LDAPAuthenticator{cfg: cfg}
+}
+
+// Configured reports whether a directory is set up
+func (a *LDAPAuthenticator) Configured() bool {
+	return a.cfg.LDAPURL != "" && a.cfg.LDAPBaseDN != ""
+}
+
+// Authenticate verifies a login name and password and returns the directory identity
+func (a *LDAPAuthenticator) Authenticate(username, password string) (*ExternalIdentity, error) {
+	// An empty password would be an unauthenticated bind, which many servers accept
+	if username == "" || password == "" {
+		return nil, ErrLDAPInvalidCredentials
+	}
+
+	conn, err := ldap.DialURL(a.cfg.LDAPURL, ldap.DialWithDialer(&net.Dialer{Timeout: ldapTimeout}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to LDAP: %w", err)
+	}
+	defer conn.Close()
+	conn.SetTimeout(ldapTimeout)
+
+	if a.cfg.LDAPStartTLS {
+		host := a.cfg.LDAPURL
+		if u, err := url.Parse(a.cfg.LDAPURL); err == nil {
+			host = u.Hostname()
+		}
+		if err := conn.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return nil, fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+
+	// Search as the service account, or anonymously when none is configured
+	if a.cfg.LDAPBindDN != "" {
+		if err := conn.Bind(a.cfg.LDAPBindDN, a.cfg.LDAPBindPassword); err != nil {
+			return nil, fmt.Errorf("LDAP service bind failed: %w", err)
+		}
+	}
+
+	filter := strings.ReplaceAll(a.cfg.LDAPUserFilter, "%s", ldap.EscapeFilter(username))
+	result, err := conn.Search(ldap.NewSearchRequest(
+		a.cfg.LDAPBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		2, int(ldapTimeout.Seconds()), false, filter,
+		[]string{"mail", "cn", "displayName", "memberOf"}, nil,
+	))
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+			return nil, ErrLDAPInvalidCredentials
+		}
+		return nil, fmt.Errorf("LDAP search failed: %w", err)
+	}
+	// Zero matches is an unknown user; several make the login ambiguous
+	if len(result.Entries) != 1 {
+		return nil, ErrLDAPInvalidCredentials
+	}
+	entry := result.Entries[0]
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrLDAPInvalidCredentials
+		}
+		return nil, fmt.Errorf("LDAP bind failed: %w", err)
+	}
+
+	name := entry.GetAttributeValue("displayName")
+	if name == "" {
+		name = entry.GetAttributeValue("cn")
+	}
+	return &ExternalIdentity{
+		Provider: "ldap",
+		Email:    entry.GetAttributeValue("mail"),
+		Name:     name,
+		Groups:   entry.GetAttributeValues("memberOf"),
+	}, nil
+}
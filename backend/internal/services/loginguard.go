@@ -0,0 +1,167 @@
+// ===========================================
+// Login Guard
+// ===========================================
+// Brute-force protection for sign-ins:
+// per IP and per email backoff, temporary
+// account lockout and failed attempt records
+// ===========================================
+package services
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/laravel-paas/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// failureWindow is how long failed logins are remembered after the last one
+	failureWindow = time.Hour
+	// maxLoginBackoff caps the doubling delay between attempts
+	maxLoginBackoff = 15 * time.Minute
+)
+
+// LoginGuard tracks failed logins in Redis and locks accounts that keep failing
+type LoginGuard struct {
+	db           *gorm.DB
+	redisService *RedisService
+}
+
+// NewLoginGuard creates a new login guard
+func NewLoginGuard(db *gorm.DB, redisService *RedisService) *LoginGuard {
+	return &LoginGuard{db: db, redisService: redisService}
+}
+
+// LoginAttemptInfo describes where a sign-in came from
+type LoginAttemptInfo struct {
+	Email     string
+	IPAddress string
+	UserAgent string
+}
+
+// Wait returns how long the email or IP address still has to wait before
+// the next attempt; zero means the attempt may go ahead
+func (g *LoginGuard) Wait(attempt LoginAttemptInfo) time.Duration {
+	wait := g.redisService.TTL(blockKey("email", normalizeEmail(attempt.Email)))
+	if ipWait := g.redisService.TTL(blockKey("ip", attempt.IPAddress)); ipWait > wait {
+		wait = ipWait
+	}
+	return wait
+}
+
+// Locked reports whether sign-ins for the email are locked. Unknown emails are
+// locked the same way so a lockout does not reveal which accounts exist.
+func (g *LoginGuard) Locked(email string) bool {
+	email = normalizeEmail(email)
+	if g.redisService.TTL(lockKey(email)) > 0 {
+		return true
+	}
+
+	var user models.User
+	if err := g.db.Select("id", "locked_until").Where("email = ?", email).First(&user).Error; err != nil {
+		return false
+	}
+	return user.LockedUntil != nil && user.LockedUntil.After(time.Now())
+}
+
+// Reasons a sign-in failed, as recorded in login attempts
+const (
+	FailureInvalidCredentials = "invalid_credentials"
+	FailureInvalidTwoFactor   = "invalid_2fa_code"
+)
+
+// RecordFailure counts a failed sign-in, delays further attempts and locks the
+// account once the threshold is reached. Wrong passwords and wrong two-factor
+// codes count alike. Every failure is kept for admins.
+func (g *LoginGuard) RecordFailure(attempt LoginAttemptInfo, userID *uint, reason string) {
+	email := normalizeEmail(attempt.Email)
+	g.saveAttempt(attempt, userID, reason)
+
+	emailFailures, err := g.redisService.IncrementCounter(failureKey("email", email), failureWindow)
+	if err != nil {
+		log.Printf("Failed to count login failure: %v", err)
+		return
+	}
+	ipFailures, _ := g.redisService.IncrementCounter(failureKey("ip", attempt.IPAddress), failureWindow)
+
+	g.backoff("email", email, emailFailures, getSettingInt(g.db, "login_backoff_after", 3))
+	g.backoff("ip", attempt.IPAddress, ipFailures, getSettingInt(g.db, "login_ip_backoff_after", 20))
+
+	threshold := getSettingInt(g.db, "login_lockout_threshold", 10)
+	if threshold <= 0 || emailFailures < int64(threshold) {
+		return
+	}
+
+	lockout := time.Duration(getSettingInt(g.db, "login_lockout_minutes", 15)) * time.Minute
+	g.redisService.SetCache(lockKey(email), true, lockout)
+	g.redisService.DeleteCache(failureKey("email", email))
+	g.redisService.DeleteCache(blockKey("email", email))
+	if userID != nil {
+		g.db.Model(&models.User{}).Where("id = ?", *userID).Update("locked_until", time.Now().Add(lockout))
+	}
+	g.saveAttempt(attempt, userID, "account_locked")
+}
+
+// RecordSuccess forgets the failures of the email. Failures of the IP address
+// are kept so one valid account cannot be used to reset them.
+func (g *LoginGuard) RecordSuccess(email string) {
+	email = normalizeEmail(email)
+	g.redisService.DeleteCache(failureKey("email", email))
+	g.redisService.DeleteCache(blockKey("email", email))
+}
+
+// Unlock lifts a lockout and forgets the failures of the user's email
+func (g *LoginGuard) Unlock(user *models.User) error {
+	email := normalizeEmail(user.Email)
+	g.redisService.DeleteCache(lockKey(email))
+	g.RecordSuccess(email)
+	return g.db.Model(user).Update("locked_until", nil).Error
+}
+
+// backoff blocks the key for a doubling delay once failures pass the threshold
+func (g *LoginGuard) backoff(kind, value string, failures int64, after int) {
+	if after <= 0 || failures < int64(after) {
+		return
+	}
+	exponent := failures - int64(after)
+	if exponent > 10 {
+		exponent = 10
+	}
+	delay := time.Second << exponent
+	if delay > maxLoginBackoff {
+		delay = maxLoginBackoff
+	}
+	g.redisService.SetCache(blockKey(kind, value), true, delay)
+}
+
+func (g *LoginGuard) saveAttempt(attempt LoginAttemptInfo, userID *uint, reason string) {
+	record := models.LoginAttempt{
+		Email:     truncate(normalizeEmail(attempt.Email), 255),
+		UserID:    userID,
+		IPAddress: attempt.IPAddress,
+		UserAgent: truncate(attempt.UserAgent, 255),
+		Reason:    reason,
+	}
+	if err := g.db.Create(&record).Error; err != nil {
+		log.Printf("Failed to record login attempt: %v", err)
+	}
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func failureKey(kind, value string) string {
+	return fmt.Sprintf("login_failures:%s:%s", kind, value)
+}
+
+func blockKey(kind, value string) string {
+	return fmt.Sprintf("login_block:%s:%s", kind, value)
+}
+
+func lockKey(email string) string {
+	return "login_lock:" + email
+}
@@ -0,0 +1,75 @@
+// ===========================================
+// Mailer
+// ===========================================
+// Sends plain-text email through SMTP, or
+// logs it when no mail server is configured
+// ===========================================
+package services
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/laravel-paas/backend/internal/config"
+)
+
+// Mailer delivers email
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// NewMailer returns the mailer selected by MAIL_DRIVER
+func NewMailer(cfg *config.Config) Mailer {
+	switch cfg.MailDriver {
+	case "smtp":
+		return &SMTPMailer{cfg: cfg}
+	default:
+		return &LogMailer{}
+	}
+}
+
+// LogMailer prints messages to the log instead of sending them
+type LogMailer struct{}
+
+// Send logs the message
+func (m *LogMailer) Send(to, subject, body string) error {
+	log.Printf("📧 Mail to %s: %s\n%s", to, subject, body)
+	return nil
+}
+
+// SMTPMailer sends messages through an SMTP server, using STARTTLS when offered
+type SMTPMailer struct {
+	cfg *config.Config
+}
+
+// Send delivers the message
+func (m *SMTPMailer) Send(to, subject, body string) error {
+	if m.cfg.MailHost == "" {
+		return fmt.Errorf("MAIL_HOST is not configured")
+	}
+	// Refuse header injection through user-supplied addresses
+	if strings.ContainsAny(to, "\r\n") {
+		return fmt.Errorf("invalid recipient")
+	}
+
+	var auth smtp.Auth
+	if m.cfg.MailUsername != "" {
+		auth = smtp.PlainAuth("", m.cfg.MailUsername, m.cfg.MailPassword, m.cfg.MailHost)
+	}
+
+	var msg strings.Builder
+	msg.WriteString("From: " + m.cfg.MailFrom + "\r\n")
+	msg.WriteString("To: " + to + "\r\n")
+	msg.WriteString("Subject: " + subject + "\r\n")
+	msg.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	addr := net.JoinHostPort(m.cfg.MailHost, m.cfg.MailPort)
+	return smtp.SendMail(addr, auth, m.cfg.MailFrom, []string{to}, []byte(msg.String()))
+}
@@ -0,0 +1,175 @@
+// ===========================================
+// OpenID Connect Provider
+// ===========================================
+// Authorization code flow with PKCE against
+// the configured issuer
+// ===========================================
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/laravel-paas/backend/internal/config"
+	"golang.org/x/oauth2"
+)
+
+// OIDCLogin is the state kept between redirecting to the provider and the callback
+type OIDCLogin struct {
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+}
+
+// OIDCProvider signs users in through an OpenID Connect issuer
+type OIDCProvider struct {
+	cfg *config.Config
+
+	// Discovery happens on first use so a down issuer does not block startup
+	mu       sync.Mutex
+	provider *oidc.Provider
+}
+
+// NewOIDCProvider creates a provider for the configured issuer
+func NewOIDCProvider(cfg *config.Config) *OIDCProvider {
+	return &OIDCProvider{cfg: cfg}
+}
+
+// Configured reports whether issuer and client are set
+func (p *OIDCProvider) Configured() bool {
+	return p.cfg.OIDCIssuer != "" && p.cfg.OIDCClientID != ""
+}
+
+// discover fetches and caches the issuer's metadata
+func (p *OIDCProvider) discover(ctx context.Context) (*oidc.Provider, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.provider == nil {
+		provider, err := oidc.NewProvider(ctx, p.cfg.OIDCIssuer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to discover OIDC issuer: %w", err)
+		}
+		p.provider = provider
+	}
+	return p.provider, nil
+}
+
+func (p *OIDCProvider) oauthConfig(provider *oidc.Provider) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     p.cfg.OIDCClientID,
+		ClientSecret: p.cfg.OIDCClientSecret,
+		RedirectURL:  p.cfg.OIDCRedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       []string{oidc.ScopeOpenID, "email", "profile"},
+	}
+}
+
+// Begin starts a login. It returns the provider URL to redirect to, and the
+// login to keep under the returned state until the callback.
+func (p *OIDCProvider) Begin(ctx context.Context) (string, string, *OIDCLogin, error) {
+	provider, err := p.discover(ctx)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	state, err := randomHex(16)
+	if err != nil {
+		return "", "", nil, err
+	}
+	nonce, err := randomHex(16)
+	if err != nil {
+		return "", "", nil, err
+	}
+	login := &OIDCLogin{Nonce: nonce, Verifier: oauth2.GenerateVerifier()}
+
+	url := p.oauthConfig(provider).AuthCodeURL(state,
+		oidc.Nonce(nonce),
+		oauth2.S256ChallengeOption(login.Verifier))
+	return url, state, login, nil
+}
+
+// Complete exchanges the authorization code and verifies the ID token
+func (p *OIDCProvider) Complete(ctx context.Context, code string, login *OIDCLogin) (*ExternalIdentity, error) {
+	provider, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := p.oauthConfig(provider).Exchange(ctx, code, oauth2.VerifierOption(login.Verifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange authorization code: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("provider did not return an ID token")
+	}
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: p.cfg.OIDCClientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+	if idToken.Nonce != login.Nonce {
+		return nil, errors.New("invalid ID token nonce")
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("invalid ID token claims: %w", err)
+	}
+
+	email, _ := claims["email"].(string)
+	if verified, ok := claims["email_verified"].(bool); ok && !verified {
+		return nil, errors.New("email address is not verified")
+	}
+	if !p.domainAllowed(email) {
+		return nil, errors.New("email domain is not allowed")
+	}
+
+	name, _ := claims["name"].(string)
+	return &ExternalIdentity{
+		Provider: "oidc",
+		Email:    email,
+		Name:     name,
+		Groups:   claimValues(claims[p.cfg.OIDCRoleClaim]),
+	}, nil
+}
+
+// domainAllowed checks the email against OIDC_ALLOWED_DOMAINS
+func (p *OIDCProvider) domainAllowed(email string) bool {
+	domains := splitList(p.cfg.OIDCAllowedDomains)
+	if len(domains) == 0 {
+		return true
+	}
+
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	for _, domain := range domains {
+		if strings.EqualFold(email[at+1:], domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// claimValues reads a claim that is either a string or a list of strings
+func claimValues(claim interface{}) []string {
+	switch v := claim.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
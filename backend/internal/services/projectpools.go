@@ -0,0 +1,171 @@
+// ===========================================
+// Project Database Connection Pools
+// ===========================================
+// Keeps one small connection pool per project
+// database, evicting pools that sit idle
+// ===========================================
+package services
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/laravel-paas/backend/internal/config"
+	"github.com/laravel-paas/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// poolEvictInterval is how often idle pools are looked for
+	poolEvictInterval = time.Minute
+	// poolConnMaxLifetime recycles connections so server-side changes are picked up
+	poolConnMaxLifetime = 30 * time.Minute
+)
+
+// projectPool is the pool of one project database
+type projectPool struct {
+	db *sql.DB
+	// fingerprint changes when the credentials or database do, making the pool stale
+	fingerprint string
+	lastUsed    time.Time
+}
+
+// ProjectPools shares connection pools to project databases between requests.
+// It must be created once and passed to every consumer.
+type ProjectPools struct {
+	db      *gorm.DB
+	cfg     *config.Config
+	secrets *SecretBox
+
+	mu    sync.Mutex
+	pools map[uint]*projectPool
+
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewProjectPools creates an empty pool manager
+func NewProjectPools(db *gorm.DB, cfg *config.Config) *ProjectPools {
+	return &ProjectPools{
+		db:       db,
+		cfg:      cfg,
+		secrets:  NewSecretBox(cfg),
+		pools:    make(map[uint]*projectPool),
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Start begins evicting idle pools
+func (p *ProjectPools) Start() {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(poolEvictInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				p.evictIdle()
+			case <-p.stopChan:
+				return
+			}
+		}
+	}()
+	log.Println("🔗 Project database pools started")
+}
+
+// Stop stops eviction and closes every pool
+func (p *ProjectPools) Stop() {
+	close(p.stopChan)
+	p.wg.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for id, pool := range p.pools {
+		pool.db.Close()
+		delete(p.pools, id)
+	}
+}
+
+// Get returns the shared pool of a project database. Callers must not close it.
+func (p *ProjectPools) Get(project *models.Project) (*sql.DB, error) {
+	fingerprint := string(project.DatabaseEngine) + "/" + project.DatabaseName + "/" + project.DBPassword
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if pool, ok := p.pools[project.ID]; ok {
+		if pool.fingerprint == fingerprint {
+			pool.lastUsed = time.Now()
+			return pool.db, nil
+		}
+		// Credentials changed since the pool was opened
+		pool.db.Close()
+		delete(p.pools, project.ID)
+	}
+
+	db, err := OpenProjectDatabase(p.cfg, p.secrets, project)
+	if err != nil {
+		return nil, err
+	}
+
+	// Requests beyond the limit wait for a free connection until their context expires
+	maxOpen := getSettingInt(p.db, "db_pool_max_open", 5)
+	if maxOpen < 1 {
+		maxOpen = 1
+	}
+	idle := time.Duration(getSettingInt(p.db, "db_pool_idle_minutes", 10)) * time.Minute
+
+	db.SetMaxOpenConns(maxOpen)
+	db.SetMaxIdleConns(maxOpen)
+	db.SetConnMaxIdleTime(idle)
+	db.SetConnMaxLifetime(poolConnMaxLifetime)
+
+	p.pools[project.ID] = &projectPool{db: db, fingerprint: fingerprint, lastUsed: time.Now()}
+	return db, nil
+}
+
+// Invalidate closes the pool of a project, e.g. before its database is dropped
+// or after its password changed. Running queries finish first.
+func (p *ProjectPools) Invalidate(projectID uint) {
+	p.mu.Lock()
+	pool, ok := p.pools[projectID]
+	delete(p.pools, projectID)
+	p.mu.Unlock()
+
+	if ok {
+		pool.db.Close()
+	}
+}
+
+// discardConn closes a pinned connection instead of returning it to the pool.
+// Statements run on it may have changed session settings (FOREIGN_KEY_CHECKS,
+// sql_mode, variables...) that must not leak into later requests.
+func discardConn(conn *sql.Conn) {
+	conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+}
+
+// evictIdle closes pools that have not been used within the idle timeout
+func (p *ProjectPools) evictIdle() {
+	idle := time.Duration(getSettingInt(p.db, "db_pool_idle_minutes", 10)) * time.Minute
+	cutoff := time.Now().Add(-idle)
+
+	var stale []*sql.DB
+	p.mu.Lock()
+	for id, pool := range p.pools {
+		// Keep pools with connections checked out, e.g. a running import
+		if pool.lastUsed.Before(cutoff) && pool.db.Stats().InUse == 0 {
+			stale = append(stale, pool.db)
+			delete(p.pools, id)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, db := range stale {
+		db.Close()
+	}
+}
@@ -0,0 +1,75 @@
+// ===========================================
+// Database Provisioner
+// ===========================================
+// Engine-independent interface for creating and
+// dropping per-project databases and users
+// ===========================================
+package services
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/laravel-paas/backend/internal/config"
+	"github.com/laravel-paas/backend/internal/models"
+)
+
+// databaseNamePattern restricts project database (and user) names
+var databaseNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,63}$`)
+
+// DatabaseProvisioner manages project databases on one engine.
+// All operations are idempotent and report errors.
+type DatabaseProvisioner interface {
+	Engine() models.DatabaseEngine
+	ValidateDatabaseName(name string) error
+	Create(name, password string) error
+	Drop(name string) error
+	SetPassword(name, password string) error
+	RevokeWrites(name string) error
+	RestoreWrites(name string) error
+	Exists(name string) (bool, error)
+	Size(name string) (int64, error)
+	ListOrphans(known []string) ([]OrphanDatabase, error)
+}
+
+// OrphanDatabase is a database on the server that no project references
+type OrphanDatabase struct {
+	Name      string                `json:"name"`
+	Engine    models.DatabaseEngine `json:"engine"`
+	SizeBytes int64                 `json:"size_bytes"`
+}
+
+// Provisioners holds one provisioner per supported engine
+type Provisioners struct {
+	mysql    DatabaseProvisioner
+	postgres DatabaseProvisioner
+}
+
+// NewProvisioners creates provisioners for all engines. Connections are opened lazily.
+func NewProvisioners(cfg *config.Config) *Provisioners {
+	return &Provisioners{
+		mysql:    NewMySQLProvisioner(cfg),
+		postgres: NewPostgresProvisioner(cfg),
+	}
+}
+
+// For returns the provisioner of an engine (MySQL by default)
+func (p *Provisioners) For(engine models.DatabaseEngine) DatabaseProvisioner {
+	if engine == models.EnginePostgres {
+		return p.postgres
+	}
+	return p.mysql
+}
+
+// All returns every provisioner
+func (p *Provisioners) All() []DatabaseProvisioner {
+	return []DatabaseProvisioner{p.mysql, p.postgres}
+}
+
+// validateDatabaseName applies the naming rules shared by all engines
+func validateDatabaseName(name string) error {
+	if !databaseNamePattern.MatchString(name) {
+		return fmt.Errorf("database name may only contain letters, numbers, dashes and underscores (max 63)")
+	}
+	return nil
+}
@@ -0,0 +1,262 @@
+// ===========================================
+// MySQL Provisioner
+// ===========================================
+// Creates and drops per-project MySQL databases
+// and users over a privileged connection
+// ===========================================
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/laravel-paas/backend/internal/config"
+	"github.com/laravel-paas/backend/internal/models"
+)
+
+// mysqlSystemSchemas are never treated as project databases
+var mysqlSystemSchemas = map[string]bool{
+	"information_schema": true,
+	"performance_schema": true,
+	"mysql":              true,
+	"sys":                true,
+}
+
+// mysqlSystemUsers are accounts of the server itself. Project users are named
+// after their database, so these names would hand the account to a student.
+var mysqlSystemUsers = map[string]bool{
+	"root":             true,
+	"mariadb.sys":      true,
+	"mysql.sys":        true,
+	"mysql.session":    true,
+	"mysql.infoschema": true,
+}
+
+// MySQLProvisioner manages MySQL project databases with a privileged connection
+type MySQLProvisioner struct {
+	cfg *config.Config
+	db  *sql.DB
+}
+
+// NewMySQLProvisioner creates a provisioner. The connection is opened lazily.
+func NewMySQLProvisioner(cfg *config.Config) *MySQLProvisioner {
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/?parseTime=true",
+		cfg.MySQLRootUser,
+		cfg.MySQLRootPassword,
+		cfg.DBHost,
+		cfg.DBPort,
+	)
+
+	// sql.Open only validates the DSN, errors surface on first use
+	db, _ := sql.Open("mysql", dsn)
+	db.SetMaxOpenConns(4)
+	db.SetMaxIdleConns(1)
+	db.SetConnMaxIdleTime(5 * time.Minute)
+
+	return &MySQLProvisioner{cfg: cfg, db: db}
+}
+
+// Engine returns the engine this provisioner manages
+func (p *MySQLProvisioner) Engine() models.DatabaseEngine {
+	return models.EngineMySQL
+}
+
+// ValidateDatabaseName checks that a name is safe to use as a project database
+func (p *MySQLProvisioner) ValidateDatabaseName(name string) error {
+	if err := validateDatabaseName(name); err != nil {
+		return err
+	}
+	if mysqlSystemSchemas[strings.ToLower(name)] || strings.EqualFold(name, p.cfg.DBName) {
+		return fmt.Errorf("database name %q is reserved", name)
+	}
+	if mysqlSystemUsers[strings.ToLower(name)] || strings.EqualFold(name, p.cfg.MySQLRootUser) || strings.EqualFold(name, p.cfg.DBUser) {
+		return fmt.Errorf("database name %q is reserved", name)
+	}
+	return nil
+}
+
+// Create creates the database and its user, or brings them up to date.
+// Safe to call repeatedly: the password and grants are always re-applied.
+func (p *MySQLProvisioner) Create(name, password string) error {
+	if err := p.ValidateDatabaseName(name); err != nil {
+		return err
+	}
+
+	account := quoteAccount(name)
+	statements := []string{
+		fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci", quoteIdentifier(name)),
+		fmt.Sprintf("CREATE USER IF NOT EXISTS %s IDENTIFIED BY %s", account, quoteString(password)),
+		fmt.Sprintf("ALTER USER %s IDENTIFIED BY %s", account, quoteString(password)),
+		mysqlGrantAll(name),
+		"FLUSH PRIVILEGES",
+	}
+
+	for _, stmt := range statements {
+		if _, err := p.db.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to provision database %s: %w", name, err)
+		}
+	}
+
+	// Older grants used the name unescaped and matched other databases too.
+	// REVOKE fails when there is no such grant, which is fine.
+	if strings.ContainsAny(name, "_%") {
+		p.db.Exec(fmt.Sprintf("REVOKE ALL PRIVILEGES ON %s.* FROM %s", quoteIdentifier(name), account))
+	}
+
+	return nil
+}
+
+// Drop removes the database and its user. Missing objects are not an error.
+func (p *MySQLProvisioner) Drop(name string) error {
+	if err := p.ValidateDatabaseName(name); err != nil {
+		return err
+	}
+
+	if _, err := p.db.Exec(fmt.Sprintf("DROP DATABASE IF EXISTS %s", quoteIdentifier(name))); err != nil {
+		return fmt.Errorf("failed to drop database %s: %w", name, err)
+	}
+
+	if _, err := p.db.Exec(fmt.Sprintf("DROP USER IF EXISTS %s", quoteAccount(name))); err != nil {
+		return fmt.Errorf("failed to drop user %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// SetPassword changes the password of a project's database user
+func (p *MySQLProvisioner) SetPassword(name, password string) error {
+	if err := p.ValidateDatabaseName(name); err != nil {
+		return err
+	}
+
+	if _, err := p.db.Exec(fmt.Sprintf("ALTER USER %s IDENTIFIED BY %s", quoteAccount(name), quoteString(password))); err != nil {
+		return fmt.Errorf("failed to change password for %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// RevokeWrites removes INSERT and UPDATE from the project user (quota exceeded)
+func (p *MySQLProvisioner) RevokeWrites(name string) error {
+	if err := p.ValidateDatabaseName(name); err != nil {
+		return err
+	}
+
+	if _, err := p.db.Exec(mysqlRevokeWrites(name)); err != nil {
+		return fmt.Errorf("failed to revoke writes for %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// RestoreWrites grants the project user full privileges again
+func (p *MySQLProvisioner) RestoreWrites(name string) error {
+	if err := p.ValidateDatabaseName(name); err != nil {
+		return err
+	}
+
+	if _, err := p.db.Exec(mysqlGrantAll(name)); err != nil {
+		return fmt.Errorf("failed to restore writes for %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// Exists reports whether the database exists
+func (p *MySQLProvisioner) Exists(name string) (bool, error) {
+	var count int
+	err := p.db.QueryRow(
+		"SELECT COUNT(*) FROM information_schema.SCHEMATA WHERE SCHEMA_NAME = ?", name,
+	).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to check database %s: %w", name, err)
+	}
+	return count > 0, nil
+}
+
+// Size returns the data + index size of the database in bytes
+func (p *MySQLProvisioner) Size(name string) (int64, error) {
+	var size sql.NullInt64
+	err := p.db.QueryRow(
+		"SELECT SUM(DATA_LENGTH + INDEX_LENGTH) FROM information_schema.TABLES WHERE TABLE_SCHEMA = ?", name,
+	).Scan(&size)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get size of %s: %w", name, err)
+	}
+	return size.Int64, nil
+}
+
+// ListOrphans returns databases that do not belong to any of the known projects
+func (p *MySQLProvisioner) ListOrphans(known []string) ([]OrphanDatabase, error) {
+	knownSet := make(map[string]bool, len(known))
+	for _, name := range known {
+		knownSet[name] = true
+	}
+
+	rows, err := p.db.Query(`
+		SELECT s.SCHEMA_NAME, COALESCE(SUM(t.DATA_LENGTH + t.INDEX_LENGTH), 0)
+		FROM information_schema.SCHEMATA s
+		LEFT JOIN information_schema.TABLES t ON t.TABLE_SCHEMA = s.SCHEMA_NAME
+		GROUP BY s.SCHEMA_NAME
+		ORDER BY s.SCHEMA_NAME
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list databases: %w", err)
+	}
+	defer rows.Close()
+
+	orphans := []OrphanDatabase{}
+	for rows.Next() {
+		o := OrphanDatabase{Engine: models.EngineMySQL}
+		if err := rows.Scan(&o.Name, &o.SizeBytes); err != nil {
+			return nil, fmt.Errorf("failed to read database list: %w", err)
+		}
+		if knownSet[o.Name] || mysqlSystemSchemas[strings.ToLower(o.Name)] || strings.EqualFold(o.Name, p.cfg.DBName) {
+			continue
+		}
+		orphans = append(orphans, o)
+	}
+
+	return orphans, rows.Err()
+}
+
+// ===========================================
+// Quoting Helpers
+// ===========================================
+
+// quoteIdentifier quotes a MySQL identifier with backticks
+func quoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// quoteGrantDatabase quotes the database of a GRANT or REVOKE. There _ and %
+// are wildcards, unescaped s_____ would match every six-letter database starting with s.
+func quoteGrantDatabase(name string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, "_", `\_`, "%", `\%`).Replace(name)
+	return quoteIdentifier(escaped)
+}
+
+// mysqlGrantAll grants the project user everything on its own database only
+func mysqlGrantAll(name string) string {
+	return fmt.Sprintf("GRANT ALL PRIVILEGES ON %s.* TO %s", quoteGrantDatabase(name), quoteAccount(name))
+}
+
+// mysqlRevokeWrites removes INSERT and UPDATE on the project database
+func mysqlRevokeWrites(name string) string {
+	return fmt.Sprintf("REVOKE INSERT, UPDATE ON %s.* FROM %s", quoteGrantDatabase(name), quoteAccount(name))
+}
+
+// quoteString quotes a MySQL string literal
+func quoteString(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
+
+// quoteAccount formats a project user account for any host
+func quoteAccount(user string) string {
+	return quoteString(user) + "@'%'"
+}
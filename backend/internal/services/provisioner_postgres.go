@@ -0,0 +1,272 @@
+// ===========================================
+// PostgreSQL Provisioner
+// ===========================================
+// Creates and drops per-project PostgreSQL
+// databases and roles on the paas-postgres service
+// ===========================================
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/laravel-paas/backend/internal/config"
+	"github.com/laravel-paas/backend/internal/models"
+	_ "github.com/lib/pq"
+)
+
+// postgresSystemDatabases are never treated as project databases
+var postgresSystemDatabases = map[string]bool{
+	"postgres":  true,
+	"template0": true,
+	"template1": true,
+}
+
+// PostgresProvisioner manages PostgreSQL project databases with a superuser connection
+type PostgresProvisioner struct {
+	cfg *config.Config
+	db  *sql.DB
+}
+
+// NewPostgresProvisioner creates a provisioner. The connection is opened lazily.
+func NewPostgresProvisioner(cfg *config.Config) *PostgresProvisioner {
+	db, _ := sql.Open("postgres", postgresAdminDSN(cfg, "postgres"))
+	db.SetMaxOpenConns(4)
+	db.SetMaxIdleConns(1)
+	db.SetConnMaxIdleTime(5 * time.Minute)
+
+	return &PostgresProvisioner{cfg: cfg, db: db}
+}
+
+// Engine returns the engine this provisioner manages
+func (p *PostgresProvisioner) Engine() models.DatabaseEngine {
+	return models.EnginePostgres
+}
+
+// ValidateDatabaseName checks that a name is safe to use as a project database
+func (p *PostgresProvisioner) ValidateDatabaseName(name string) error {
+	if err := validateDatabaseName(name); err != nil {
+		return err
+	}
+	if postgresSystemDatabases[strings.ToLower(name)] || strings.HasPrefix(strings.ToLower(name), "pg_") {
+		return fmt.Errorf("database name %q is reserved", name)
+	}
+	// The project role shares the name, it must never be the superuser
+	if strings.EqualFold(name, p.cfg.PostgresUser) {
+		return fmt.Errorf("database name %q is reserved", name)
+	}
+	return nil
+}
+
+// Create creates the role and database, or brings them up to date
+func (p *PostgresProvisioner) Create(name, password string) error {
+	if err := p.ValidateDatabaseName(name); err != nil {
+		return err
+	}
+
+	// Role (CREATE ROLE has no IF NOT EXISTS)
+	var roleExists bool
+	if err := p.db.QueryRow("SELECT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = $1)", name).Scan(&roleExists); err != nil {
+		return fmt.Errorf("failed to check role %s: %w", name, err)
+	}
+
+	roleStmt := "ALTER ROLE %s WITH LOGIN PASSWORD %s"
+	if !roleExists {
+		roleStmt = "CREATE ROLE %s WITH LOGIN PASSWORD %s"
+	}
+	if _, err := p.db.Exec(fmt.Sprintf(roleStmt, quotePgIdent(name), quotePgString(password))); err != nil {
+		return fmt.Errorf("failed to provision role %s: %w", name, err)
+	}
+
+	// Database owned by the project role (CREATE DATABASE cannot run in a transaction)
+	exists, err := p.Exists(name)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		if _, err := p.db.Exec(fmt.Sprintf("CREATE DATABASE %s OWNER %s ENCODING 'UTF8'", quotePgIdent(name), quotePgIdent(name))); err != nil {
+			return fmt.Errorf("failed to create database %s: %w", name, err)
+		}
+	}
+
+	// Other roles must not connect to a student's database
+	statements := []string{
+		fmt.Sprintf("REVOKE ALL ON DATABASE %s FROM PUBLIC", quotePgIdent(name)),
+		fmt.Sprintf("GRANT ALL PRIVILEGES ON DATABASE %s TO %s", quotePgIdent(name), quotePgIdent(name)),
+	}
+	for _, stmt := range statements {
+		if _, err := p.db.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to provision database %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// Drop removes the database and its role. Missing objects are not an error.
+func (p *PostgresProvisioner) Drop(name string) error {
+	if err := p.ValidateDatabaseName(name); err != nil {
+		return err
+	}
+
+	// Open connections would block DROP DATABASE
+	if _, err := p.db.Exec("SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE datname = $1 AND pid <> pg_backend_pid()", name); err != nil {
+		return fmt.Errorf("failed to disconnect sessions of %s: %w", name, err)
+	}
+
+	if _, err := p.db.Exec(fmt.Sprintf("DROP DATABASE IF EXISTS %s", quotePgIdent(name))); err != nil {
+		return fmt.Errorf("failed to drop database %s: %w", name, err)
+	}
+
+	if _, err := p.db.Exec(fmt.Sprintf("DROP ROLE IF EXISTS %s", quotePgIdent(name))); err != nil {
+		return fmt.Errorf("failed to drop role %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// SetPassword changes the password of a project's role
+func (p *PostgresProvisioner) SetPassword(name, password string) error {
+	if err := p.ValidateDatabaseName(name); err != nil {
+		return err
+	}
+
+	if _, err := p.db.Exec(fmt.Sprintf("ALTER ROLE %s WITH PASSWORD %s", quotePgIdent(name), quotePgString(password))); err != nil {
+		return fmt.Errorf("failed to change password for %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// pgQuotaSchema holds the superuser-owned guard of databases over quota
+const pgQuotaSchema = "paas_quota"
+
+// RevokeWrites removes INSERT and UPDATE on all tables from the project role.
+// The role owns its tables and could grant them back or create new ones, so an
+// event trigger owned by the superuser refuses GRANT and table creation until
+// writes are restored.
+func (p *PostgresProvisioner) RevokeWrites(name string) error {
+	return p.execInDatabase(name,
+		pgEachSchema(name, "REVOKE INSERT, UPDATE ON ALL TABLES IN SCHEMA %I FROM %I"),
+		fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s", pgQuotaSchema),
+		fmt.Sprintf("REVOKE ALL ON SCHEMA %s FROM PUBLIC", pgQuotaSchema),
+		fmt.Sprintf(`CREATE OR REPLACE FUNCTION %s.block_writes() RETURNS event_trigger LANGUAGE plpgsql AS $$
+BEGIN
+	RAISE EXCEPTION 'database is over its quota, %% is not allowed', tg_tag;
+END $$`, pgQuotaSchema),
+		"DROP EVENT TRIGGER IF EXISTS paas_quota_block",
+		fmt.Sprintf(`CREATE EVENT TRIGGER paas_quota_block ON ddl_command_start
+	WHEN TAG IN ('GRANT', 'CREATE TABLE', 'CREATE TABLE AS', 'SELECT INTO', 'CREATE MATERIALIZED VIEW')
+	EXECUTE FUNCTION %s.block_writes()`, pgQuotaSchema),
+	)
+}
+
+// RestoreWrites removes the guard and grants INSERT and UPDATE on all tables again
+func (p *PostgresProvisioner) RestoreWrites(name string) error {
+	return p.execInDatabase(name,
+		fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", pgQuotaSchema),
+		pgEachSchema(name, "GRANT INSERT, UPDATE ON ALL TABLES IN SCHEMA %I TO %I"),
+	)
+}
+
+// Exists reports whether the database exists
+func (p *PostgresProvisioner) Exists(name string) (bool, error) {
+	var exists bool
+	if err := p.db.QueryRow("SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = $1)", name).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check database %s: %w", name, err)
+	}
+	return exists, nil
+}
+
+// Size returns the on-disk size of the database in bytes
+func (p *PostgresProvisioner) Size(name string) (int64, error) {
+	var size sql.NullInt64
+	if err := p.db.QueryRow("SELECT pg_database_size(datname) FROM pg_database WHERE datname = $1", name).Scan(&size); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to get size of %s: %w", name, err)
+	}
+	return size.Int64, nil
+}
+
+// ListOrphans returns databases that do not belong to any of the known projects
+func (p *PostgresProvisioner) ListOrphans(known []string) ([]OrphanDatabase, error) {
+	knownSet := make(map[string]bool, len(known))
+	for _, name := range known {
+		knownSet[name] = true
+	}
+
+	rows, err := p.db.Query("SELECT datname, pg_database_size(datname) FROM pg_database WHERE NOT datistemplate ORDER BY datname")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list databases: %w", err)
+	}
+	defer rows.Close()
+
+	orphans := []OrphanDatabase{}
+	for rows.Next() {
+		o := OrphanDatabase{Engine: models.EnginePostgres}
+		if err := rows.Scan(&o.Name, &o.SizeBytes); err != nil {
+			return nil, fmt.Errorf("failed to read database list: %w", err)
+		}
+		if knownSet[o.Name] || postgresSystemDatabases[o.Name] {
+			continue
+		}
+		orphans = append(orphans, o)
+	}
+
+	return orphans, rows.Err()
+}
+
+// execInDatabase runs statements as superuser inside a project database
+func (p *PostgresProvisioner) execInDatabase(name string, statements ...string) error {
+	if err := p.ValidateDatabaseName(name); err != nil {
+		return err
+	}
+
+	db, err := sql.Open("postgres", postgresAdminDSN(p.cfg, name))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to update privileges for %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// pgEachSchema builds a block running a format() template with (schema, role)
+// for every user schema of the database, not only public
+func pgEachSchema(role, template string) string {
+	return fmt.Sprintf(`DO $$
+DECLARE s name;
+BEGIN
+	FOR s IN SELECT nspname FROM pg_namespace
+		WHERE nspname NOT LIKE 'pg\_%%' AND nspname NOT IN ('information_schema', %s)
+	LOOP
+		EXECUTE format(%s, s, %s);
+	END LOOP;
+END $$`, quotePgString(pgQuotaSchema), quotePgString(template), quotePgString(role))
+}
+
+// postgresAdminDSN builds a superuser DSN for the given database
+func postgresAdminDSN(cfg *config.Config, database string) string {
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		cfg.PostgresHost, cfg.PostgresPort,
+		quoteConnValue(cfg.PostgresUser), quoteConnValue(cfg.PostgresPassword), quoteConnValue(database))
+}
+
+// quotePgIdent quotes a PostgreSQL identifier
+func quotePgIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// quotePgString quotes a PostgreSQL string literal
+func quotePgString(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
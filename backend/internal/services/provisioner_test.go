@@ -0,0 +1,70 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/laravel-paas/backend/internal/config"
+)
+
+func TestValidateDatabaseNameReservesServerAccounts(t *testing.T) {
+	cfg := &config.Config{DBName: "paas", DBUser: "panel", MySQLRootUser: "admin", PostgresUser: "pgadmin"}
+	mysql := &MySQLProvisioner{cfg: cfg}
+	postgres := &PostgresProvisioner{cfg: cfg}
+
+	for _, name := range []string{"root", "ROOT", "panel", "admin", "paas", "mysql", "sys"} {
+		if err := mysql.ValidateDatabaseName(name); err == nil {
+			t.Errorf("MySQL accepted reserved name %q", name)
+		}
+	}
+	for _, name := range []string{"pgadmin", "postgres", "template1", "pg_monitor"} {
+		if err := postgres.ValidateDatabaseName(name); err == nil {
+			t.Errorf("PostgreSQL accepted reserved name %q", name)
+		}
+	}
+
+	for _, name := range []string{"shop01", "student_blog", "root2"} {
+		if err := mysql.ValidateDatabaseName(name); err != nil {
+			t.Errorf("MySQL rejected %q: %v", name, err)
+		}
+		if err := postgres.ValidateDatabaseName(name); err != nil {
+			t.Errorf("PostgreSQL rejected %q: %v", name, err)
+		}
+	}
+}
+
+func TestMySQLGrantsEscapeWildcards(t *testing.T) {
+	tests := []struct {
+		name   string
+		grant  string
+		revoke string
+	}{
+		{
+			"shop01",
+			"GRANT ALL PRIVILEGES ON `shop01`.* TO 'shop01'@'%'",
+			"REVOKE INSERT, UPDATE ON `shop01`.* FROM 'shop01'@'%'",
+		},
+		{
+			"s_____",
+			"GRANT ALL PRIVILEGES ON `s\\_\\_\\_\\_\\_`.* TO 's_____'@'%'",
+			"REVOKE INSERT, UPDATE ON `s\\_\\_\\_\\_\\_`.* FROM 's_____'@'%'",
+		},
+		{
+			"my_blog-2",
+			"GRANT ALL PRIVILEGES ON `my\\_blog-2`.* TO 'my_blog-2'@'%'",
+			"REVOKE INSERT, UPDATE ON `my\\_blog-2`.* FROM 'my_blog-2'@'%'",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := mysqlGrantAll(tt.name); got != tt.grant {
+			t.Errorf("mysqlGrantAll(%q) = %s, want %s", tt.name, got, tt.grant)
+		}
+		if got := mysqlRevokeWrites(tt.name); got != tt.revoke {
+			t.Errorf("mysqlRevokeWrites(%q) = %s, want %s", tt.name, got, tt.revoke)
+		}
+	}
+
+	if got := quoteGrantDatabase("a%b"); got != "`a\\%b`" {
+		t.Errorf("quoteGrantDatabase(a%%b) = %s", got)
+	}
+}
@@ -0,0 +1,224 @@
+// ===========================================
+// SQL Console Query Runner
+// ===========================================
+// Runs one or more statements from the SQL
+// console with limits on time and result size
+// ===========================================
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/laravel-paas/backend/internal/sqlparse"
+)
+
+// QueryOptions limits a console run
+type QueryOptions struct {
+	// ReadOnly runs everything in a read-only transaction that is rolled back
+	ReadOnly bool
+	Timeout  time.Duration
+	// MaxRows caps the rows returned per result set
+	MaxRows int
+}
+
+// QueryResult is the outcome of one statement (or one result set of a procedure)
+type QueryResult struct {
+	Statement    string                   `json:"statement"`
+	Kind         sqlparse.Kind            `json:"kind"`
+	Columns      []string                 `json:"columns"`
+	Rows         []map[string]interface{} `json:"rows"` // nil for statements without a result set
+	RowsAffected int64                    `json:"rows_affected"`
+	Truncated    bool                     `json:"truncated"`
+	Duration     string                   `json:"duration"`
+}
+
+// QueryError reports which statement of a run failed
+type QueryError struct {
+	Index     int // zero-based statement index, -1 when the script could not be parsed
+	Line      int // line on which the statement starts
+	Message   string
+	Forbidden bool // rejected before execution
+}
+
+func (e *QueryError) Error() string {
+	if e.Index < 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("Statement %d (line %d): %s", e.Index+1, e.Line, e.Message)
+}
+
+// queryer is implemented by both *sql.Conn and *sql.Tx
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// QueryRunner executes console queries against one project database
+type QueryRunner struct {
+	db      *sql.DB
+	dialect Dialect
+}
+
+// NewQueryRunner creates a runner over an open project database connection
+func NewQueryRunner(db *sql.DB, dialect Dialect) *QueryRunner {
+	return &QueryRunner{db: db, dialect: dialect}
+}
+
+// Run validates every statement of the script, then executes them in order on
+// one connection. It stops at the first failing statement and returns the
+// results collected so far together with a *QueryError.
+func (r *QueryRunner) Run(script string, opts QueryOptions) ([]QueryResult, error) {
+	lexOpts := splitOptions(r.dialect)
+
+	statements, err := sqlparse.Split(script, lexOpts)
+	if err != nil {
+		return nil, &QueryError{Index: -1, Message: err.Error()}
+	}
+	if len(statements) == 0 {
+		return nil, &QueryError{Index: -1, Message: "Query is required"}
+	}
+
+	classes := make([]sqlparse.Classification, len(statements))
+	for i, stmt := range statements {
+		classes[i] = sqlparse.Classify(stmt.SQL, lexOpts)
+
+		if classes[i].Forbidden != "" {
+			return nil, &QueryError{Index: i, Line: stmt.Line, Message: classes[i].Forbidden, Forbidden: true}
+		}
+		if opts.ReadOnly && !classes[i].ReadOnly() {
+			return nil, &QueryError{
+				Index:     i,
+				Line:      stmt.Line,
+				Message:   fmt.Sprintf("%s statements are not allowed in read-only mode", classes[i].Keyword),
+				Forbidden: true,
+			}
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return nil, &QueryError{Index: -1, Message: "Failed to connect"}
+	}
+	defer conn.Close()
+	defer discardConn(conn)
+
+	// Also stop the statement on the server, not only the client wait
+	conn.ExecContext(ctx, r.dialect.StatementTimeoutQuery(opts.Timeout))
+
+	var q queryer = conn
+	if opts.ReadOnly {
+		tx, err := conn.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+		if err != nil {
+			return nil, &QueryError{Index: -1, Message: "Failed to start read-only transaction: " + err.Error()}
+		}
+		defer tx.Rollback()
+		q = tx
+	}
+
+	var results []QueryResult
+	for i, stmt := range statements {
+		stmtResults, err := r.execute(ctx, q, stmt.SQL, classes[i], opts.MaxRows)
+		results = append(results, stmtResults...)
+
+		if err != nil {
+			message := err.Error()
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				message = fmt.Sprintf("Query timed out after %s", opts.Timeout)
+			}
+			return results, &QueryError{Index: i, Line: stmt.Line, Message: message}
+		}
+	}
+
+	return results, nil
+}
+
+// execute runs one statement; procedures may produce several result sets
+func (r *QueryRunner) execute(ctx context.Context, q queryer, query string, class sqlparse.Classification, maxRows int) ([]QueryResult, error) {
+	start := time.Now()
+
+	if !class.ReturnsRows {
+		result, err := q.ExecContext(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		affected, _ := result.RowsAffected()
+		return []QueryResult{{
+			Statement:    query,
+			Kind:         class.Kind,
+			RowsAffected: affected,
+			Duration:     time.Since(start).String(),
+		}}, nil
+	}
+
+	rows, err := q.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []QueryResult
+	for {
+		result, err := readResultSet(rows, maxRows)
+		if err != nil {
+			return results, err
+		}
+		result.Statement = query
+		result.Kind = class.Kind
+		result.Duration = time.Since(start).String()
+
+		// Procedures end with an empty status result set
+		if len(result.Columns) > 0 || len(results) == 0 {
+			results = append(results, result)
+		}
+
+		if !rows.NextResultSet() {
+			return results, rows.Err()
+		}
+	}
+}
+
+// readResultSet reads up to maxRows rows of the current result set
+func readResultSet(rows *sql.Rows, maxRows int) (QueryResult, error) {
+	result := QueryResult{Rows: []map[string]interface{}{}}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return result, err
+	}
+	result.Columns = columns
+
+	for rows.Next() {
+		if maxRows > 0 && len(result.Rows) >= maxRows {
+			result.Truncated = true
+			break
+		}
+
+		values := make([]interface{}, len(columns))
+		valuePtrs := make([]interface{}, len(columns))
+		for i := range values {
+			valuePtrs[i] = &values[i]
+		}
+		if err := rows.Scan(valuePtrs...); err != nil {
+			return result, err
+		}
+
+		row := make(map[string]interface{}, len(columns))
+		for i, col := range columns {
+			if b, ok := values[i].([]byte); ok {
+				row[col] = string(b)
+			} else {
+				row[col] = values[i]
+			}
+		}
+		result.Rows = append(result.Rows, row)
+	}
+
+	return result, rows.Err()
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/laravel-paas/backend/internal/config"
+	"github.com/laravel-paas/backend/internal/models"
+)
+
+// These scripts are all rejected before the runner connects, so no database is needed
+func TestQueryRunnerRejects(t *testing.T) {
+	tests := []struct {
+		name      string
+		engine    models.DatabaseEngine
+		script    string
+		readOnly  bool
+		index     int
+		line      int
+		forbidden bool
+	}{
+		{"empty script", models.EngineMySQL, " ;; -- nothing\n", false, -1, 0, false},
+		{"unterminated string", models.EngineMySQL, "SELECT 'open", false, -1, 0, false},
+		{"unterminated escape string", models.EnginePostgres, `SELECT E'it\'s`, false, -1, 0, false},
+		{"forbidden first statement", models.EngineMySQL, "DROP DATABASE other; SELECT 1", false, 0, 1, true},
+		{"forbidden later statement", models.EnginePostgres, "SELECT 1;\nSELECT 2;\nGRANT ALL ON t TO public", false, 2, 3, true},
+		{"forbidden inside executable comment", models.EngineMySQL, "SELECT 1; /*!50000 DROP USER root */", false, 1, 1, true},
+		{"outfile", models.EngineMySQL, "SELECT * FROM users INTO OUTFILE '/tmp/x'", false, 0, 1, true},
+		{"write in read-only mode", models.EngineMySQL, "SELECT 1; DELETE FROM users", true, 1, 1, true},
+		{"select into in read-only mode", models.EnginePostgres, "SELECT * INTO copy FROM users", true, 0, 1, true},
+		{"cte write in read-only mode", models.EnginePostgres, "WITH d AS (DELETE FROM t RETURNING *) SELECT * FROM d", true, 0, 1, true},
+		{"locking read in read-only mode", models.EnginePostgres, "SELECT * FROM t FOR UPDATE", true, 0, 1, true},
+		{"autocommit in read-only mode", models.EngineMySQL, "SET autocommit=0; SET autocommit=1; SELECT nextval(s)", true, 0, 1, true},
+		{"transaction_read_only in read-only mode", models.EnginePostgres, "SET transaction_read_only = off;\nSELECT nextval('s')", true, 0, 1, true},
+		{"session characteristics in read-only mode", models.EnginePostgres, "SELECT 1; SET SESSION CHARACTERISTICS AS TRANSACTION READ WRITE", true, 1, 1, true},
+		{"set names in read-only mode", models.EngineMySQL, "SET NAMES utf8mb4", true, 0, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := NewQueryRunner(nil, DialectFor(&config.Config{}, tt.engine))
+			results, err := runner.Run(tt.script, QueryOptions{ReadOnly: tt.readOnly})
+
+			var qerr *QueryError
+			if !errors.As(err, &qerr) {
+				t.Fatalf("Run() error = %v, want a *QueryError", err)
+			}
+			if len(results) != 0 {
+				t.Errorf("Run() returned %d results, want none", len(results))
+			}
+			if qerr.Index != tt.index || qerr.Forbidden != tt.forbidden {
+				t.Errorf("QueryError = %+v, want index %d forbidden %v", qerr, tt.index, tt.forbidden)
+			}
+			if tt.index >= 0 && qerr.Line != tt.line {
+				t.Errorf("QueryError.Line = %d, want %d", qerr.Line, tt.line)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+// ===========================================
+// Database Quota Service
+// ===========================================
+// Measures project database size against its
+// quota and revokes writes while over quota
+// ===========================================
+package services
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/laravel-paas/backend/internal/config"
+	"github.com/laravel-paas/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// QuotaUsage describes a project's database usage against its quota
+type QuotaUsage struct {
+	UsedBytes     int64   `json:"used_bytes"`
+	QuotaBytes    int64   `json:"quota_bytes"` // 0 = unlimited
+	Percent       float64 `json:"percent"`
+	WarnPercent   int     `json:"warn_percent"`
+	Warning       bool    `json:"warning"`
+	Exceeded      bool    `json:"exceeded"`
+	WritesRevoked bool    `json:"writes_revoked"`
+}
+
+// QuotaService checks and enforces per-project database quotas
+type QuotaService struct {
+	db           *gorm.DB
+	cfg          *config.Config
+	provisioners *Provisioners
+	docker       *DockerService
+	projectPools *ProjectPools
+	interval     time.Duration
+	running      bool
+}
+
+// NewQuotaService creates a new quota service
+func NewQuotaService(db *gorm.DB, cfg *config.Config, projectPools *ProjectPools) *QuotaService {
+	return &QuotaService{
+		db:           db,
+		cfg:          cfg,
+		provisioners: NewProvisioners(cfg),
+		docker:       NewDockerService(cfg),
+		projectPools: projectPools,
+		interval:     5 * time.Minute,
+	}
+}
+
+// Start periodically enforces quotas for all projects
+func (q *QuotaService) Start() {
+	if q.running {
+		return
+	}
+	q.running = true
+	log.Println("📏 Database quota enforcer started")
+
+	go func() {
+		for q.running {
+			q.EnforceAll()
+			time.Sleep(q.interval)
+		}
+	}()
+}
+
+// Stop stops the periodic enforcement
+func (q *QuotaService) Stop() {
+	q.running = false
+}
+
+// QuotaBytes returns the effective quota for a project (0 = unlimited)
+func (q *QuotaService) QuotaBytes(project *models.Project) int64 {
+	quotaMB := getSettingInt(q.db, "db_quota_mb", 0)
+	if project.DBQuotaMB != nil {
+		quotaMB = *project.DBQuotaMB
+	}
+	if quotaMB <= 0 {
+		return 0
+	}
+	return int64(quotaMB) * 1024 * 1024
+}
+
+// Usage measures a project's database without changing privileges
+func (q *QuotaService) Usage(project *models.Project) (*QuotaUsage, error) {
+	used, err := q.provisioners.For(project.DatabaseEngine).Size(project.DatabaseName)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &QuotaUsage{
+		UsedBytes:     used,
+		QuotaBytes:    q.QuotaBytes(project),
+		WarnPercent:   getSettingInt(q.db, "db_quota_warn_percent", 80),
+		WritesRevoked: project.DBWritesRevoked,
+	}
+
+	if usage.QuotaBytes > 0 {
+		usage.Percent = float64(used) * 100 / float64(usage.QuotaBytes)
+		usage.Exceeded = used > usage.QuotaBytes
+		usage.Warning = usage.Percent >= float64(usage.WarnPercent)
+	}
+
+	return usage, nil
+}
+
+// Enforce measures a project and revokes or restores INSERT/UPDATE accordingly
+func (q *QuotaService) Enforce(project *models.Project) (*QuotaUsage, error) {
+	usage, err := q.Usage(project)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case usage.Exceeded && !project.DBWritesRevoked:
+		if err := q.provisioners.For(project.DatabaseEngine).RevokeWrites(project.DatabaseName); err != nil {
+			return usage, err
+		}
+		q.db.Model(project).Update("db_writes_revoked", true)
+		project.DBWritesRevoked = true
+		log.Printf("⚠️  Project #%d database over quota (%d bytes), writes revoked", project.ID, usage.UsedBytes)
+		q.reconnect(project)
+
+	case !usage.Exceeded && project.DBWritesRevoked:
+		if err := q.provisioners.For(project.DatabaseEngine).RestoreWrites(project.DatabaseName); err != nil {
+			return usage, err
+		}
+		q.db.Model(project).Update("db_writes_revoked", false)
+		project.DBWritesRevoked = false
+		log.Printf("✅ Project #%d database back under quota, writes restored", project.ID)
+		q.reconnect(project)
+	}
+
+	usage.WritesRevoked = project.DBWritesRevoked
+	return usage, nil
+}
+
+// reconnect drops open connections to a project database after its privileges
+// changed: MySQL only rechecks database privileges when a session selects the database
+func (q *QuotaService) reconnect(project *models.Project) {
+	q.projectPools.Invalidate(project.ID)
+
+	if project.ContainerID == nil {
+		return
+	}
+	if err := q.docker.RestartContainer(*project.ContainerID); err != nil {
+		log.Printf("❌ Failed to restart project #%d after a quota change: %v", project.ID, err)
+	}
+}
+
+// EnforceAll enforces quotas for every project
+func (q *QuotaService) EnforceAll() {
+	var projects []models.Project
+	if err := q.db.Find(&projects).Error; err != nil {
+		log.Printf("❌ Quota check failed to load projects: %v", err)
+		return
+	}
+
+	for i := range projects {
+		if _, err := q.Enforce(&projects[i]); err != nil {
+			log.Printf("❌ Quota check failed for project #%d: %v", projects[i].ID, err)
+		}
+	}
+}
+
+// getSettingInt reads an integer setting, falling back to defaultValue
+func getSettingInt(db *gorm.DB, key string, defaultValue int) int {
+	var setting models.Setting
+	if err := db.Where("setting_key = ?", key).First(&setting).Error; err != nil {
+		return defaultValue
+	}
+	value, err := strconv.Atoi(setting.Value)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// getSettingBool reads a "true"/"false" setting, falling back to defaultValue
+func getSettingBool(db *gorm.DB, key string, defaultValue bool) bool {
+	var setting models.Setting
+	if err := db.Where("setting_key = ?", key).First(&setting).Error; err != nil {
+		return defaultValue
+	}
+	value, err := strconv.ParseBool(setting.Value)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
@@ -204,6 +204,26 @@ func (r *RedisService) DeleteCache(key string) error {
 	return r.client.Del(r.ctx, key).Err()
 }
 
+// IncrementCounter increments a counter and (re)starts its expiration window
+func (r *RedisService) IncrementCounter(key string, window time.Duration) (int64, error) {
+	pipe := r.client.TxPipeline()
+	incr := pipe.Incr(r.ctx, key)
+	pipe.Expire(r.ctx, key, window)
+	if _, err := pipe.Exec(r.ctx); err != nil {
+		return 0, fmt.Errorf("failed to increment counter: %w", err)
+	}
+	return incr.Val(), nil
+}
+
+// TTL returns how long a key has left to live, or 0 if it does not exist
+func (r *RedisService) TTL(key string) time.Duration {
+	ttl, err := r.client.TTL(r.ctx, key).Result()
+	if err != nil || ttl < 0 {
+		return 0
+	}
+	return ttl
+}
+
 // AddToBlacklist adds a token to the blacklist
 func (r *RedisService) AddToBlacklist(token string, expiration time.Duration) error {
 	key := fmt.Sprintf("blacklist:%s", token)
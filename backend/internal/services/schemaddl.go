@@ -0,0 +1,605 @@
+// ===========================================
+// Schema Designer DDL
+// ===========================================
+// Turns structured schema changes into DDL
+// statements for MySQL and PostgreSQL
+// ===========================================
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/laravel-paas/backend/internal/models"
+)
+
+// SchemaAction is the kind of a structured schema change
+type SchemaAction string
+
+const (
+	SchemaCreateTable    SchemaAction = "create_table"
+	SchemaAddColumn      SchemaAction = "add_column"
+	SchemaModifyColumn   SchemaAction = "modify_column"
+	SchemaDropColumn     SchemaAction = "drop_column"
+	SchemaAddIndex       SchemaAction = "add_index"
+	SchemaDropIndex      SchemaAction = "drop_index"
+	SchemaAddForeignKey  SchemaAction = "add_foreign_key"
+	SchemaDropForeignKey SchemaAction = "drop_foreign_key"
+)
+
+// ColumnSpec describes a column. Type is a Laravel blueprint type
+// (string, integer, id, dateTime...) so changes map onto migrations.
+type ColumnSpec struct {
+	Name      string  `json:"name"`
+	Type      string  `json:"type"`
+	Length    int     `json:"length,omitempty"`    // string, char
+	Precision int     `json:"precision,omitempty"` // decimal
+	Scale     int     `json:"scale,omitempty"`     // decimal
+	Unsigned  bool    `json:"unsigned,omitempty"`
+	Nullable  bool    `json:"nullable"`
+	Default   *string `json:"default,omitempty"` // CURRENT_TIMESTAMP and NULL are kept as keywords
+	Unique    bool    `json:"unique,omitempty"`
+	Primary   bool    `json:"primary,omitempty"`
+}
+
+// IndexSpec describes an index
+type IndexSpec struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+	Unique  bool     `json:"unique"`
+	Primary bool     `json:"primary,omitempty"`
+}
+
+// ForeignKeySpec describes a foreign key constraint
+type ForeignKeySpec struct {
+	Name              string   `json:"name"`
+	Columns           []string `json:"columns"`
+	ReferencedTable   string   `json:"referenced_table"`
+	ReferencedColumns []string `json:"referenced_columns"`
+	OnDelete          string   `json:"on_delete,omitempty"`
+	OnUpdate          string   `json:"on_update,omitempty"`
+}
+
+// SchemaChange is one structured change to a table
+type SchemaChange struct {
+	Action     SchemaAction    `json:"action"`
+	Table      string          `json:"table"`
+	Columns    []ColumnSpec    `json:"columns,omitempty"`     // create_table
+	Column     *ColumnSpec     `json:"column,omitempty"`      // add_column, modify_column
+	Index      *IndexSpec      `json:"index,omitempty"`       // add_index
+	ForeignKey *ForeignKeySpec `json:"foreign_key,omitempty"` // add_foreign_key
+	// Name is the column, index or foreign key to modify or drop
+	Name string `json:"name,omitempty"`
+
+	// Previous definitions, loaded from the catalog, for reversing the change
+	PreviousColumn     *ColumnSpec     `json:"-"`
+	PreviousIndex      *IndexSpec      `json:"-"`
+	PreviousForeignKey *ForeignKeySpec `json:"-"`
+}
+
+// columnType maps a blueprint type onto both engines
+type columnType struct {
+	mysql, postgres string
+	integer         bool // accepts unsigned
+	autoIncrement   bool // implies the primary key
+	length          bool
+	decimal         bool
+}
+
+var columnTypes = map[string]columnType{
+	"id":           {mysql: "BIGINT", postgres: "BIGSERIAL", integer: true, autoIncrement: true},
+	"increments":   {mysql: "INT", postgres: "SERIAL", integer: true, autoIncrement: true},
+	"tinyInteger":  {mysql: "TINYINT", postgres: "SMALLINT", integer: true},
+	"smallInteger": {mysql: "SMALLINT", postgres: "SMALLINT", integer: true},
+	"integer":      {mysql: "INT", postgres: "INTEGER", integer: true},
+	"bigInteger":   {mysql: "BIGINT", postgres: "BIGINT", integer: true},
+	"foreignId":    {mysql: "BIGINT", postgres: "BIGINT", integer: true},
+	"string":       {mysql: "VARCHAR", postgres: "VARCHAR", length: true},
+	"char":         {mysql: "CHAR", postgres: "CHAR", length: true},
+	"text":         {mysql: "TEXT", postgres: "TEXT"},
+	"mediumText":   {mysql: "MEDIUMTEXT", postgres: "TEXT"},
+	"longText":     {mysql: "LONGTEXT", postgres: "TEXT"},
+	"boolean":      {mysql: "TINYINT(1)", postgres: "BOOLEAN"},
+	"date":         {mysql: "DATE", postgres: "DATE"},
+	"dateTime":     {mysql: "DATETIME", postgres: "TIMESTAMP(0) WITHOUT TIME ZONE"},
+	"timestamp":    {mysql: "TIMESTAMP", postgres: "TIMESTAMP(0) WITHOUT TIME ZONE"},
+	"time":         {mysql: "TIME", postgres: "TIME(0) WITHOUT TIME ZONE"},
+	"decimal":      {mysql: "DECIMAL", postgres: "DECIMAL", decimal: true},
+	"double":       {mysql: "DOUBLE", postgres: "DOUBLE PRECISION"},
+	"json":         {mysql: "JSON", postgres: "JSON"},
+	"jsonb":        {mysql: "JSON", postgres: "JSONB"},
+	"uuid":         {mysql: "CHAR(36)", postgres: "UUID"},
+	"binary":       {mysql: "BLOB", postgres: "BYTEA"},
+}
+
+// referentialActions are the allowed ON DELETE / ON UPDATE rules
+var referentialActions = map[string]bool{
+	"CASCADE": true, "RESTRICT": true, "SET NULL": true, "NO ACTION": true,
+}
+
+// Normalize fills in default names the way Laravel does
+func (c *SchemaChange) Normalize() {
+	switch c.Action {
+	case SchemaModifyColumn:
+		if c.Column != nil && c.Column.Name == "" {
+			c.Column.Name = c.Name
+		}
+		if c.Name == "" && c.Column != nil {
+			c.Name = c.Column.Name
+		}
+
+	case SchemaAddIndex:
+		if c.Index != nil && c.Index.Name == "" {
+			suffix := "index"
+			if c.Index.Unique {
+				suffix = "unique"
+			}
+			c.Index.Name = strings.ToLower(c.Table + "_" + strings.Join(c.Index.Columns, "_") + "_" + suffix)
+		}
+
+	case SchemaAddForeignKey:
+		if fk := c.ForeignKey; fk != nil {
+			if fk.Name == "" {
+				fk.Name = strings.ToLower(c.Table + "_" + strings.Join(fk.Columns, "_") + "_foreign")
+			}
+			if len(fk.ReferencedColumns) == 0 {
+				fk.ReferencedColumns = []string{"id"}
+			}
+			fk.OnDelete = strings.ToUpper(strings.TrimSpace(fk.OnDelete))
+			fk.OnUpdate = strings.ToUpper(strings.TrimSpace(fk.OnUpdate))
+		}
+	}
+}
+
+// Validate checks that the change is complete; identifiers and existence are checked by the caller
+func (c *SchemaChange) Validate() error {
+	if c.Table == "" {
+		return fmt.Errorf("Table name is required")
+	}
+
+	switch c.Action {
+	case SchemaCreateTable:
+		if len(c.Columns) == 0 {
+			return fmt.Errorf("A table needs at least one column")
+		}
+		seen := make(map[string]bool)
+		autoIncrement := 0
+		for _, col := range c.Columns {
+			if err := validateColumn(col); err != nil {
+				return err
+			}
+			if seen[col.Name] {
+				return fmt.Errorf("Duplicate column: %s", col.Name)
+			}
+			seen[col.Name] = true
+			if columnTypes[col.Type].autoIncrement {
+				autoIncrement++
+			}
+		}
+		if autoIncrement > 1 {
+			return fmt.Errorf("A table can only have one auto-increment column")
+		}
+
+	case SchemaAddColumn, SchemaModifyColumn:
+		if c.Column == nil {
+			return fmt.Errorf("Column definition is required")
+		}
+		if err := validateColumn(*c.Column); err != nil {
+			return err
+		}
+		if c.Action == SchemaModifyColumn {
+			if c.Column.Name != c.Name {
+				return fmt.Errorf("Renaming columns is not supported")
+			}
+			if columnTypes[c.Column.Type].autoIncrement || c.Column.Primary {
+				return fmt.Errorf("Primary keys cannot be changed by modifying a column")
+			}
+		}
+
+	case SchemaDropColumn, SchemaDropIndex, SchemaDropForeignKey:
+		if c.Name == "" {
+			return fmt.Errorf("Name is required")
+		}
+
+	case SchemaAddIndex:
+		if c.Index == nil || len(c.Index.Columns) == 0 {
+			return fmt.Errorf("Index columns are required")
+		}
+
+	case SchemaAddForeignKey:
+		fk := c.ForeignKey
+		if fk == nil || len(fk.Columns) == 0 || fk.ReferencedTable == "" {
+			return fmt.Errorf("Foreign key columns and referenced table are required")
+		}
+		if len(fk.Columns) != len(fk.ReferencedColumns) {
+			return fmt.Errorf("Foreign key and referenced columns must have the same length")
+		}
+		for _, rule := range []string{fk.OnDelete, fk.OnUpdate} {
+			if rule != "" && !referentialActions[rule] {
+				return fmt.Errorf("Invalid referential action: %s", rule)
+			}
+		}
+
+	default:
+		return fmt.Errorf("Unknown action: %s", c.Action)
+	}
+
+	return nil
+}
+
+// Identifiers returns every name the change introduces or refers to
+func (c *SchemaChange) Identifiers() []string {
+	names := []string{c.Table}
+	if c.Name != "" {
+		names = append(names, c.Name)
+	}
+	for _, col := range c.Columns {
+		names = append(names, col.Name)
+	}
+	if c.Column != nil {
+		names = append(names, c.Column.Name)
+	}
+	if c.Index != nil {
+		names = append(names, c.Index.Name)
+		names = append(names, c.Index.Columns...)
+	}
+	if fk := c.ForeignKey; fk != nil {
+		names = append(names, fk.Name, fk.ReferencedTable)
+		names = append(names, fk.Columns...)
+		names = append(names, fk.ReferencedColumns...)
+	}
+	return names
+}
+
+func validateColumn(col ColumnSpec) error {
+	if col.Name == "" {
+		return fmt.Errorf("Column name is required")
+	}
+	t, ok := columnTypes[col.Type]
+	if !ok {
+		return fmt.Errorf("Unknown column type for %s: %s", col.Name, col.Type)
+	}
+	if col.Length < 0 || col.Length > 65535 || col.Precision < 0 || col.Precision > 65 || col.Scale < 0 || col.Scale > 30 {
+		return fmt.Errorf("Invalid size for column %s", col.Name)
+	}
+	if col.Default != nil {
+		if _, err := defaultLiteral(models.EngineMySQL, t, col.Type, *col.Default); err != nil {
+			return fmt.Errorf("Column %s: %s", col.Name, err.Error())
+		}
+	}
+	return nil
+}
+
+// SchemaDDL returns the statements that apply the change. The change must be
+// normalized and validated.
+func SchemaDDL(dialect Dialect, c *SchemaChange) ([]string, error) {
+	q := dialect.QuoteIdent
+	table := q(c.Table)
+	postgres := dialect.Engine() == models.EnginePostgres
+
+	switch c.Action {
+	case SchemaCreateTable:
+		var defs, primary []string
+		for _, col := range c.Columns {
+			def, err := columnDefinition(dialect, col, false)
+			if err != nil {
+				return nil, err
+			}
+			defs = append(defs, def)
+			if col.Primary && !columnTypes[col.Type].autoIncrement {
+				primary = append(primary, q(col.Name))
+			}
+		}
+		if len(primary) > 0 {
+			defs = append(defs, "PRIMARY KEY ("+strings.Join(primary, ", ")+")")
+		}
+		return []string{fmt.Sprintf("CREATE TABLE %s (\n  %s\n)", table, strings.Join(defs, ",\n  "))}, nil
+
+	case SchemaAddColumn:
+		def, err := columnDefinition(dialect, *c.Column, false)
+		if err != nil {
+			return nil, err
+		}
+		if c.Column.Primary && !columnTypes[c.Column.Type].autoIncrement {
+			def += " PRIMARY KEY"
+		}
+		return []string{fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", table, def)}, nil
+
+	case SchemaModifyColumn:
+		col := *c.Column
+		if !postgres {
+			def, err := columnDefinition(dialect, col, true)
+			if err != nil {
+				return nil, err
+			}
+			return []string{fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s", table, def)}, nil
+		}
+
+		// PostgreSQL changes type, nullability and default separately
+		t := columnTypes[col.Type]
+		name := q(col.Name)
+		sqlType := columnSQLType(models.EnginePostgres, t, col)
+		statements := []string{
+			fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s USING %s::%s", table, name, sqlType, name, sqlType),
+		}
+		if col.Nullable {
+			statements = append(statements, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP NOT NULL", table, name))
+		} else {
+			statements = append(statements, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET NOT NULL", table, name))
+		}
+		if col.Default != nil {
+			literal, err := defaultLiteral(models.EnginePostgres, t, col.Type, *col.Default)
+			if err != nil {
+				return nil, err
+			}
+			statements = append(statements, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET DEFAULT %s", table, name, literal))
+		} else {
+			statements = append(statements, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP DEFAULT", table, name))
+		}
+		return statements, nil
+
+	case SchemaDropColumn:
+		return []string{fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table, q(c.Name))}, nil
+
+	case SchemaAddIndex:
+		unique := ""
+		if c.Index.Unique {
+			unique = "UNIQUE "
+		}
+		return []string{fmt.Sprintf("CREATE %sINDEX %s ON %s (%s)", unique, q(c.Index.Name), table, quoteList(dialect, c.Index.Columns))}, nil
+
+	case SchemaDropIndex:
+		if postgres {
+			return []string{fmt.Sprintf("DROP INDEX %s", q(c.Name))}, nil
+		}
+		return []string{fmt.Sprintf("DROP INDEX %s ON %s", q(c.Name), table)}, nil
+
+	case SchemaAddForeignKey:
+		fk := c.ForeignKey
+		stmt := fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s)",
+			table, q(fk.Name), quoteList(dialect, fk.Columns), q(fk.ReferencedTable), quoteList(dialect, fk.ReferencedColumns))
+		if fk.OnDelete != "" {
+			stmt += " ON DELETE " + fk.OnDelete
+		}
+		if fk.OnUpdate != "" {
+			stmt += " ON UPDATE " + fk.OnUpdate
+		}
+		return []string{stmt}, nil
+
+	case SchemaDropForeignKey:
+		if postgres {
+			return []string{fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", table, q(c.Name))}, nil
+		}
+		return []string{fmt.Sprintf("ALTER TABLE %s DROP FOREIGN KEY %s", table, q(c.Name))}, nil
+	}
+
+	return nil, fmt.Errorf("Unknown action: %s", c.Action)
+}
+
+// columnDefinition renders "name TYPE [NOT NULL] [DEFAULT x] [UNIQUE]".
+// Unique constraints are left out when modifying, they are managed as indexes.
+func columnDefinition(dialect Dialect, col ColumnSpec, modify bool) (string, error) {
+	t, ok := columnTypes[col.Type]
+	if !ok {
+		return "", fmt.Errorf("Unknown column type: %s", col.Type)
+	}
+	engine := dialect.Engine()
+
+	def := dialect.QuoteIdent(col.Name) + " " + columnSQLType(engine, t, col)
+	if t.autoIncrement {
+		if engine == models.EnginePostgres {
+			return def + " PRIMARY KEY", nil
+		}
+		return def + " NOT NULL AUTO_INCREMENT PRIMARY KEY", nil
+	}
+
+	if col.Nullable {
+		def += " NULL"
+	} else {
+		def += " NOT NULL"
+	}
+	if col.Default != nil {
+		literal, err := defaultLiteral(engine, t, col.Type, *col.Default)
+		if err != nil {
+			return "", err
+		}
+		def += " DEFAULT " + literal
+	}
+	if col.Unique && !modify {
+		def += " UNIQUE"
+	}
+	return def, nil
+}
+
+// columnSQLType returns the engine type including size and sign
+func columnSQLType(engine models.DatabaseEngine, t columnType, col ColumnSpec) string {
+	sqlType := t.mysql
+	if engine == models.EnginePostgres {
+		sqlType = t.postgres
+	}
+
+	switch {
+	case t.length:
+		length := col.Length
+		if length == 0 {
+			length = 255
+		}
+		sqlType += fmt.Sprintf("(%d)", length)
+	case t.decimal:
+		precision, scale := col.Precision, col.Scale
+		if precision == 0 {
+			precision, scale = 8, 2
+		}
+		sqlType += fmt.Sprintf("(%d, %d)", precision, scale)
+	}
+
+	// Laravel makes IDs and foreign IDs unsigned on MySQL
+	if engine != models.EnginePostgres && t.integer && (col.Unsigned || t.autoIncrement || col.Type == "foreignId") {
+		sqlType += " UNSIGNED"
+	}
+	return sqlType
+}
+
+// defaultLiteral renders a default value for the column type
+func defaultLiteral(engine models.DatabaseEngine, t columnType, typeName, value string) (string, error) {
+	switch strings.ToUpper(value) {
+	case "NULL", "CURRENT_TIMESTAMP":
+		return strings.ToUpper(value), nil
+	}
+
+	switch {
+	case typeName == "boolean":
+		var b bool
+		switch strings.ToLower(value) {
+		case "true", "1":
+			b = true
+		case "false", "0":
+		default:
+			return "", fmt.Errorf("default must be true or false")
+		}
+		if engine == models.EnginePostgres {
+			return strings.ToUpper(strconv.FormatBool(b)), nil
+		}
+		if b {
+			return "1", nil
+		}
+		return "0", nil
+
+	case t.integer:
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return "", fmt.Errorf("default must be an integer")
+		}
+		return value, nil
+
+	case t.decimal || typeName == "double":
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return "", fmt.Errorf("default must be a number")
+		}
+		return value, nil
+	}
+
+	if engine == models.EnginePostgres {
+		return "'" + strings.ReplaceAll(value, "'", "''") + "'", nil
+	}
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	return "'" + strings.ReplaceAll(value, "'", `\'`) + "'", nil
+}
+
+// quoteList quotes and joins column names
+func quoteList(dialect Dialect, names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = dialect.QuoteIdent(name)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// ColumnSpecFromCatalog maps a column read from information_schema back onto a
+// blueprint type. Types without an equivalent keep their database type name.
+func ColumnSpecFromCatalog(name, dbType string, nullable bool, dflt *string, key, extra string) ColumnSpec {
+	col := ColumnSpec{Name: name, Nullable: nullable, Unique: key == "UNI"}
+
+	t := strings.ToLower(dbType)
+	col.Unsigned = strings.Contains(t, "unsigned")
+	base, args := t, ""
+	if i := strings.Index(t, "("); i >= 0 {
+		base = strings.TrimSpace(t[:i])
+		if j := strings.Index(t[i:], ")"); j >= 0 {
+			args = t[i+1 : i+j]
+		}
+	}
+	base = strings.TrimSpace(strings.TrimSuffix(base, " unsigned"))
+	sizes := strings.Split(args, ",")
+	size := func(i int) int {
+		if i >= len(sizes) {
+			return 0
+		}
+		n, _ := strconv.Atoi(strings.TrimSpace(sizes[i]))
+		return n
+	}
+
+	autoIncrement := extra == "auto_increment"
+	switch {
+	case autoIncrement && base == "bigint":
+		col.Type = "id"
+	case autoIncrement && (base == "int" || base == "integer"):
+		col.Type = "increments"
+	case base == "tinyint" && args == "1", base == "boolean":
+		col.Type = "boolean"
+	case base == "tinyint":
+		col.Type = "tinyInteger"
+	case base == "smallint":
+		col.Type = "smallInteger"
+	case base == "int", base == "integer", base == "mediumint":
+		col.Type = "integer"
+	case base == "bigint":
+		col.Type = "bigInteger"
+	case base == "varchar", base == "character varying":
+		col.Type, col.Length = "string", size(0)
+	case base == "char" && args == "36":
+		col.Type = "uuid"
+	case base == "char", base == "character":
+		col.Type, col.Length = "char", size(0)
+	case base == "text", base == "tinytext":
+		col.Type = "text"
+	case base == "mediumtext":
+		col.Type = "mediumText"
+	case base == "longtext":
+		col.Type = "longText"
+	case base == "date":
+		col.Type = "date"
+	case base == "datetime":
+		col.Type = "dateTime"
+	case strings.HasPrefix(base, "timestamp"):
+		col.Type = "timestamp"
+	case strings.HasPrefix(base, "time"):
+		col.Type = "time"
+	case base == "decimal", base == "numeric":
+		col.Type, col.Precision, col.Scale = "decimal", size(0), size(1)
+	case base == "double", base == "double precision", base == "float", base == "real":
+		col.Type = "double"
+	case base == "json":
+		col.Type = "json"
+	case base == "jsonb":
+		col.Type = "jsonb"
+	case base == "uuid":
+		col.Type = "uuid"
+	case base == "blob", base == "bytea", base == "longblob", base == "mediumblob":
+		col.Type = "binary"
+	default:
+		col.Type = dbType
+	}
+
+	if key == "PRI" && !columnTypes[col.Type].autoIncrement {
+		col.Primary = true
+	}
+	if !autoIncrement {
+		col.Default = catalogDefault(dflt)
+	}
+	return col
+}
+
+// catalogDefault strips quoting and casts from a catalog default expression
+func catalogDefault(dflt *string) *string {
+	if dflt == nil {
+		return nil
+	}
+	value := strings.TrimSpace(*dflt)
+
+	// PostgreSQL: 'text'::character varying
+	if i := strings.LastIndex(value, "::"); i > 0 && strings.HasPrefix(value, "'") {
+		value = value[:i]
+	}
+
+	switch strings.ToLower(value) {
+	case "null":
+		return nil
+	case "current_timestamp", "current_timestamp()", "now()":
+		value = "CURRENT_TIMESTAMP"
+	}
+	if len(value) >= 2 && strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'") {
+		value = strings.ReplaceAll(value[1:len(value)-1], "''", "'")
+	}
+	return &value
+}
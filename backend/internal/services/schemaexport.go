@@ -0,0 +1,337 @@
+// ===========================================
+// Database Schema Export
+// ===========================================
+// Describes tables and relationships of a
+// project database as JSON, DBML or Mermaid
+// ===========================================
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SchemaColumn is a column as read from the catalog
+type SchemaColumn struct {
+	Name          string  `json:"name"`
+	Type          string  `json:"type"`
+	Nullable      bool    `json:"nullable"`
+	Default       *string `json:"default"`
+	AutoIncrement bool    `json:"auto_increment"`
+}
+
+// SchemaTable is a table with its keys and indexes
+type SchemaTable struct {
+	Name        string           `json:"name"`
+	Columns     []SchemaColumn   `json:"columns"`
+	PrimaryKey  []string         `json:"primary_key"`
+	Indexes     []IndexSpec      `json:"indexes"`
+	ForeignKeys []ForeignKeySpec `json:"foreign_keys"`
+}
+
+// SchemaRelationship is a foreign key seen from both ends
+type SchemaRelationship struct {
+	Name        string   `json:"name"`
+	FromTable   string   `json:"from_table"`
+	FromColumns []string `json:"from_columns"`
+	ToTable     string   `json:"to_table"`
+	ToColumns   []string `json:"to_columns"`
+	// Cardinality is many-to-one, or one-to-one when the key columns are unique
+	Cardinality string `json:"cardinality"`
+	Optional    bool   `json:"optional"` // a key column is nullable
+	OnDelete    string `json:"on_delete,omitempty"`
+	OnUpdate    string `json:"on_update,omitempty"`
+}
+
+// DatabaseSchema is the whole schema of a project database
+type DatabaseSchema struct {
+	Database      string               `json:"database"`
+	Engine        string               `json:"engine"`
+	Tables        []SchemaTable        `json:"tables"`
+	Relationships []SchemaRelationship `json:"relationships"`
+}
+
+// BuildRelationships derives the relationships from the tables' foreign keys
+func (s *DatabaseSchema) BuildRelationships() {
+	s.Relationships = []SchemaRelationship{}
+	for _, table := range s.Tables {
+		for _, fk := range table.ForeignKeys {
+			rel := SchemaRelationship{
+				Name:        fk.Name,
+				FromTable:   table.Name,
+				FromColumns: fk.Columns,
+				ToTable:     fk.ReferencedTable,
+				ToColumns:   fk.ReferencedColumns,
+				Cardinality: "many-to-one",
+				OnDelete:    fk.OnDelete,
+				OnUpdate:    fk.OnUpdate,
+			}
+			if table.isUnique(fk.Columns) {
+				rel.Cardinality = "one-to-one"
+			}
+			for _, name := range fk.Columns {
+				if col := table.column(name); col != nil && col.Nullable {
+					rel.Optional = true
+				}
+			}
+			s.Relationships = append(s.Relationships, rel)
+		}
+	}
+}
+
+// column returns the named column, or nil
+func (t *SchemaTable) column(name string) *SchemaColumn {
+	for i := range t.Columns {
+		if t.Columns[i].Name == name {
+			return &t.Columns[i]
+		}
+	}
+	return nil
+}
+
+// isUnique reports whether the columns are exactly the primary key or a unique index
+func (t *SchemaTable) isUnique(columns []string) bool {
+	if sameColumns(t.PrimaryKey, columns) {
+		return true
+	}
+	for _, index := range t.Indexes {
+		if index.Unique && sameColumns(index.Columns, columns) {
+			return true
+		}
+	}
+	return false
+}
+
+// uniqueColumn reports single-column unique indexes other than the primary key
+func (t *SchemaTable) uniqueColumn(name string) bool {
+	for _, index := range t.Indexes {
+		if index.Unique && !index.Primary && len(index.Columns) == 1 && index.Columns[0] == name {
+			return true
+		}
+	}
+	return false
+}
+
+// foreignColumn reports whether the column is part of a foreign key
+func (t *SchemaTable) foreignColumn(name string) bool {
+	for _, fk := range t.ForeignKeys {
+		for _, col := range fk.Columns {
+			if col == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func sameColumns(a, b []string) bool {
+	if len(a) == 0 || len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// ===========================================
+// DBML
+// ===========================================
+
+var simpleName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// dbmlName quotes names that are not plain identifiers
+func dbmlName(name string) string {
+	if simpleName.MatchString(name) {
+		return name
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `\"`) + `"`
+}
+
+// DBML renders the schema in the DBML language used by dbdiagram.io
+func (s *DatabaseSchema) DBML() string {
+	var b strings.Builder
+
+	for i, table := range s.Tables {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(fmt.Sprintf("Table %s {\n", dbmlName(table.Name)))
+
+		singlePK := len(table.PrimaryKey) == 1
+		for _, col := range table.Columns {
+			var settings []string
+			if singlePK && table.PrimaryKey[0] == col.Name {
+				settings = append(settings, "pk")
+			}
+			if col.AutoIncrement {
+				settings = append(settings, "increment")
+			}
+			if table.uniqueColumn(col.Name) {
+				settings = append(settings, "unique")
+			}
+			if !col.Nullable {
+				settings = append(settings, "not null")
+			}
+			if col.Default != nil && !col.AutoIncrement {
+				settings = append(settings, "default: "+dbmlDefault(*col.Default))
+			}
+
+			colType := col.Type
+			if !simpleName.MatchString(colType) {
+				colType = `"` + strings.ReplaceAll(colType, `"`, `\"`) + `"`
+			}
+			line := fmt.Sprintf("  %s %s", dbmlName(col.Name), colType)
+			if len(settings) > 0 {
+				line += " [" + strings.Join(settings, ", ") + "]"
+			}
+			b.WriteString(line + "\n")
+		}
+
+		var indexes []string
+		if len(table.PrimaryKey) > 1 {
+			indexes = append(indexes, fmt.Sprintf("    (%s) [pk]", dbmlColumns(table.PrimaryKey)))
+		}
+		for _, index := range table.Indexes {
+			if index.Primary || (index.Unique && len(index.Columns) == 1) {
+				continue
+			}
+			settings := []string{fmt.Sprintf("name: '%s'", index.Name)}
+			if index.Unique {
+				settings = append([]string{"unique"}, settings...)
+			}
+			indexes = append(indexes, fmt.Sprintf("    (%s) [%s]", dbmlColumns(index.Columns), strings.Join(settings, ", ")))
+		}
+		if len(indexes) > 0 {
+			b.WriteString("\n  indexes {\n" + strings.Join(indexes, "\n") + "\n  }\n")
+		}
+
+		b.WriteString("}\n")
+	}
+
+	if len(s.Relationships) > 0 {
+		b.WriteString("\n")
+	}
+	for _, rel := range s.Relationships {
+		op := ">"
+		if rel.Cardinality == "one-to-one" {
+			op = "-"
+		}
+		line := fmt.Sprintf("Ref %s: %s.%s %s %s.%s", dbmlName(rel.Name),
+			dbmlName(rel.FromTable), dbmlRefColumns(rel.FromColumns), op,
+			dbmlName(rel.ToTable), dbmlRefColumns(rel.ToColumns))
+
+		var settings []string
+		if rel.OnDelete != "" && rel.OnDelete != "NO ACTION" {
+			settings = append(settings, "delete: "+strings.ToLower(rel.OnDelete))
+		}
+		if rel.OnUpdate != "" && rel.OnUpdate != "NO ACTION" {
+			settings = append(settings, "update: "+strings.ToLower(rel.OnUpdate))
+		}
+		if len(settings) > 0 {
+			line += " [" + strings.Join(settings, ", ") + "]"
+		}
+		b.WriteString(line + "\n")
+	}
+
+	return b.String()
+}
+
+func dbmlColumns(columns []string) string {
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = dbmlName(c)
+	}
+	return strings.Join(names, ", ")
+}
+
+// dbmlRefColumns renders col or (a, b) for composite keys
+func dbmlRefColumns(columns []string) string {
+	if len(columns) == 1 {
+		return dbmlName(columns[0])
+	}
+	return "(" + dbmlColumns(columns) + ")"
+}
+
+// dbmlDefault renders numbers as is, expressions in backticks and text quoted
+func dbmlDefault(value string) string {
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return value
+	}
+	switch strings.ToLower(value) {
+	case "true", "false", "null":
+		return strings.ToLower(value)
+	}
+	if strings.HasSuffix(value, ")") || strings.EqualFold(value, "CURRENT_TIMESTAMP") {
+		return "`" + value + "`"
+	}
+	return "'" + strings.ReplaceAll(value, "'", `\'`) + "'"
+}
+
+// ===========================================
+// Mermaid
+// ===========================================
+
+var mermaidUnsafe = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// mermaidWord turns a name or type into a single Mermaid token
+func mermaidWord(s string) string {
+	return strings.Trim(mermaidUnsafe.ReplaceAllString(s, "_"), "_")
+}
+
+// Mermaid renders the schema as a Mermaid erDiagram
+func (s *DatabaseSchema) Mermaid() string {
+	var b strings.Builder
+	b.WriteString("erDiagram\n")
+
+	for _, table := range s.Tables {
+		b.WriteString(fmt.Sprintf("    %s {\n", mermaidWord(table.Name)))
+		for _, col := range table.Columns {
+			// Drop sizes, Mermaid types are single words
+			colType := col.Type
+			if i := strings.Index(colType, "("); i > 0 {
+				colType = colType[:i]
+			}
+
+			var keys []string
+			for _, pk := range table.PrimaryKey {
+				if pk == col.Name {
+					keys = append(keys, "PK")
+				}
+			}
+			if table.foreignColumn(col.Name) {
+				keys = append(keys, "FK")
+			}
+			if table.uniqueColumn(col.Name) {
+				keys = append(keys, "UK")
+			}
+
+			line := fmt.Sprintf("        %s %s", mermaidWord(colType), mermaidWord(col.Name))
+			if len(keys) > 0 {
+				line += " " + strings.Join(keys, ", ")
+			}
+			b.WriteString(line + "\n")
+		}
+		b.WriteString("    }\n")
+	}
+
+	for _, rel := range s.Relationships {
+		// Referenced side first: exactly one (or zero or one when optional)
+		left := "||"
+		if rel.Optional {
+			left = "|o"
+		}
+		right := "o{"
+		if rel.Cardinality == "one-to-one" {
+			right = "o|"
+		}
+		b.WriteString(fmt.Sprintf("    %s %s--%s %s : \"%s\"\n",
+			mermaidWord(rel.ToTable), left, right, mermaidWord(rel.FromTable), strings.Join(rel.FromColumns, ", ")))
+	}
+
+	return b.String()
+}
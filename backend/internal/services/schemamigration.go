@@ -0,0 +1,248 @@
+// ===========================================
+// Laravel Migration Generator
+// ===========================================
+// Writes a structured schema change as a
+// Laravel migration students can commit
+// ===========================================
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LaravelMigration returns the file name and PHP source of a migration
+// equivalent to the change. The change must be normalized and validated;
+// previous definitions are used to write down().
+func LaravelMigration(c *SchemaChange, now time.Time) (string, string, error) {
+	var up, down []string
+	var name string
+	table := phpString(c.Table)
+	create := false
+
+	switch c.Action {
+	case SchemaCreateTable:
+		name = fmt.Sprintf("create_%s_table", c.Table)
+		create = true
+		for _, col := range c.Columns {
+			up = append(up, blueprintColumn(col, false))
+		}
+		var primary []string
+		for _, col := range c.Columns {
+			if col.Primary && !columnTypes[col.Type].autoIncrement {
+				primary = append(primary, col.Name)
+			}
+		}
+		if len(primary) > 1 {
+			// Composite keys are declared on the table rather than per column
+			for i, line := range up {
+				up[i] = strings.Replace(line, "->primary()", "", 1)
+			}
+			up = append(up, fmt.Sprintf("$table->primary(%s);", phpArray(primary)))
+		}
+
+	case SchemaAddColumn:
+		name = fmt.Sprintf("add_%s_to_%s_table", c.Column.Name, c.Table)
+		up = []string{blueprintColumn(*c.Column, false)}
+		down = []string{fmt.Sprintf("$table->dropColumn(%s);", phpString(c.Column.Name))}
+
+	case SchemaModifyColumn:
+		name = fmt.Sprintf("modify_%s_in_%s_table", c.Column.Name, c.Table)
+		up = []string{blueprintColumn(*c.Column, true)}
+		down = []string{restoreLine(c.PreviousColumn, true)}
+
+	case SchemaDropColumn:
+		name = fmt.Sprintf("drop_%s_from_%s_table", c.Name, c.Table)
+		up = []string{fmt.Sprintf("$table->dropColumn(%s);", phpString(c.Name))}
+		down = []string{restoreLine(c.PreviousColumn, false)}
+
+	case SchemaAddIndex:
+		name = fmt.Sprintf("add_%s_to_%s_table", c.Index.Name, c.Table)
+		up = []string{blueprintIndex(c.Index)}
+		down = []string{dropIndexLine(c.Index)}
+
+	case SchemaDropIndex:
+		name = fmt.Sprintf("drop_%s_from_%s_table", c.Name, c.Table)
+		if c.PreviousIndex != nil {
+			up = []string{dropIndexLine(c.PreviousIndex)}
+			down = []string{blueprintIndex(c.PreviousIndex)}
+		} else {
+			up = []string{fmt.Sprintf("$table->dropIndex(%s);", phpString(c.Name))}
+			down = []string{"// The previous index definition is unknown"}
+		}
+
+	case SchemaAddForeignKey:
+		name = fmt.Sprintf("add_%s_to_%s_table", c.ForeignKey.Name, c.Table)
+		up = []string{blueprintForeign(c.ForeignKey)}
+		down = []string{fmt.Sprintf("$table->dropForeign(%s);", phpString(c.ForeignKey.Name))}
+
+	case SchemaDropForeignKey:
+		name = fmt.Sprintf("drop_%s_from_%s_table", c.Name, c.Table)
+		up = []string{fmt.Sprintf("$table->dropForeign(%s);", phpString(c.Name))}
+		if c.PreviousForeignKey != nil {
+			down = []string{blueprintForeign(c.PreviousForeignKey)}
+		} else {
+			down = []string{"// The previous foreign key definition is unknown"}
+		}
+
+	default:
+		return "", "", fmt.Errorf("Unknown action: %s", c.Action)
+	}
+
+	var b strings.Builder
+	b.WriteString("<?php\n\n")
+	b.WriteString("use Illuminate\\Database\\Migrations\\Migration;\n")
+	b.WriteString("use Illuminate\\Database\\Schema\\Blueprint;\n")
+	b.WriteString("use Illuminate\\Support\\Facades\\Schema;\n\n")
+	b.WriteString("return new class extends Migration\n{\n")
+
+	b.WriteString("    public function up(): void\n    {\n")
+	if create {
+		writeSchemaBlock(&b, "create", table, up)
+	} else {
+		writeSchemaBlock(&b, "table", table, up)
+	}
+	b.WriteString("    }\n\n")
+
+	b.WriteString("    public function down(): void\n    {\n")
+	if create {
+		b.WriteString(fmt.Sprintf("        Schema::dropIfExists(%s);\n", table))
+	} else {
+		writeSchemaBlock(&b, "table", table, down)
+	}
+	b.WriteString("    }\n};\n")
+
+	filename := fmt.Sprintf("%s_%s.php", now.Format("2006_01_02_150405"), strings.ToLower(name))
+	return filename, b.String(), nil
+}
+
+// writeSchemaBlock writes Schema::create/table with a blueprint closure
+func writeSchemaBlock(b *strings.Builder, method, table string, lines []string) {
+	b.WriteString(fmt.Sprintf("        Schema::%s(%s, function (Blueprint $table) {\n", method, table))
+	for _, line := range lines {
+		b.WriteString("            " + line + "\n")
+	}
+	b.WriteString("        });\n")
+}
+
+// blueprintColumn renders "$table->string('name', 100)->nullable();"
+func blueprintColumn(col ColumnSpec, change bool) string {
+	t, known := columnTypes[col.Type]
+	if !known {
+		return fmt.Sprintf("// Column %s has type %s, which has no blueprint equivalent", col.Name, col.Type)
+	}
+
+	args := []string{phpString(col.Name)}
+	switch {
+	case col.Type == "id" && col.Name == "id":
+		args = nil
+	case t.length && col.Length > 0:
+		args = append(args, strconv.Itoa(col.Length))
+	case t.decimal && col.Precision > 0:
+		args = append(args, strconv.Itoa(col.Precision), strconv.Itoa(col.Scale))
+	}
+
+	line := fmt.Sprintf("$table->%s(%s)", col.Type, strings.Join(args, ", "))
+	if t.autoIncrement {
+		return line + ";"
+	}
+
+	if col.Unsigned && t.integer && col.Type != "foreignId" {
+		line += "->unsigned()"
+	}
+	if col.Nullable {
+		line += "->nullable()"
+	}
+	if col.Default != nil {
+		line += phpDefault(col.Type, t, *col.Default)
+	}
+	if col.Unique && !change {
+		line += "->unique()"
+	}
+	if col.Primary {
+		line += "->primary()"
+	}
+	if change {
+		line += "->change()"
+	}
+	return line + ";"
+}
+
+// restoreLine re-creates a previous column definition in down()
+func restoreLine(prev *ColumnSpec, change bool) string {
+	if prev == nil {
+		return "// The previous column definition is unknown"
+	}
+	return blueprintColumn(*prev, change)
+}
+
+// phpDefault renders ->default(...) or ->useCurrent()
+func phpDefault(typeName string, t columnType, value string) string {
+	switch {
+	case strings.EqualFold(value, "CURRENT_TIMESTAMP"):
+		return "->useCurrent()"
+	case strings.EqualFold(value, "NULL"):
+		return "->default(null)"
+	case typeName == "boolean":
+		return fmt.Sprintf("->default(%t)", value == "1" || strings.EqualFold(value, "true"))
+	case t.integer || t.decimal || typeName == "double":
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			return fmt.Sprintf("->default(%s)", value)
+		}
+	}
+	return fmt.Sprintf("->default(%s)", phpString(value))
+}
+
+// blueprintIndex renders "$table->index(['a', 'b'], 'name');"
+func blueprintIndex(index *IndexSpec) string {
+	method := "index"
+	switch {
+	case index.Primary:
+		method = "primary"
+	case index.Unique:
+		method = "unique"
+	}
+	return fmt.Sprintf("$table->%s(%s, %s);", method, phpArray(index.Columns), phpString(index.Name))
+}
+
+// dropIndexLine renders the drop matching blueprintIndex
+func dropIndexLine(index *IndexSpec) string {
+	method := "dropIndex"
+	switch {
+	case index.Primary:
+		method = "dropPrimary"
+	case index.Unique:
+		method = "dropUnique"
+	}
+	return fmt.Sprintf("$table->%s(%s);", method, phpString(index.Name))
+}
+
+// blueprintForeign renders a $table->foreign(...) chain
+func blueprintForeign(fk *ForeignKeySpec) string {
+	line := fmt.Sprintf("$table->foreign(%s, %s)->references(%s)->on(%s)",
+		phpArray(fk.Columns), phpString(fk.Name), phpArray(fk.ReferencedColumns), phpString(fk.ReferencedTable))
+	if fk.OnDelete != "" {
+		line += fmt.Sprintf("->onDelete(%s)", phpString(strings.ToLower(fk.OnDelete)))
+	}
+	if fk.OnUpdate != "" {
+		line += fmt.Sprintf("->onUpdate(%s)", phpString(strings.ToLower(fk.OnUpdate)))
+	}
+	return line + ";"
+}
+
+// phpString quotes a single-quoted PHP string
+func phpString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return "'" + strings.ReplaceAll(s, "'", `\'`) + "'"
+}
+
+// phpArray renders ['a', 'b']
+func phpArray(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = phpString(v)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
@@ -0,0 +1,110 @@
+// ===========================================
+// Secret Box
+// ===========================================
+// Encrypts per-project secrets (APP_KEY, passwords)
+// before they are stored in the database
+// ===========================================
+package services
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/laravel-paas/backend/internal/config"
+	"github.com/laravel-paas/backend/internal/models"
+)
+
+// SecretBox encrypts and decrypts secrets with AES-256-GCM
+type SecretBox struct {
+	aead cipher.AEAD
+}
+
+// NewSecretBox creates a secret box keyed from the configured encryption key
+func NewSecretBox(cfg *config.Config) *SecretBox {
+	key := sha256.Sum256([]byte(cfg.EncryptionKey))
+
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		// Only possible with an invalid key size, which sha256 rules out
+		panic(fmt.Sprintf("failed to create cipher: %v", err))
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create GCM: %v", err))
+	}
+
+	return &SecretBox{aead: aead}
+}
+
+// Encrypt returns the base64 encoded nonce and ciphertext
+func (b *SecretBox) Encrypt(plaintext string) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := b.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt reverses Encrypt
+func (b *SecretBox) Decrypt(encoded string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode secret: %w", err)
+	}
+
+	nonceSize := b.aead.NonceSize()
+	if len(data) < nonceSize {
+		return "", fmt.Errorf("secret is too short")
+	}
+
+	plaintext, err := b.aead.Open(nil, data[:nonceSize], data[nonceSize:], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt secret: %w", err)
+	}
+
+	return string(plaintext), nil
+}
+
+// GenerateAppKey creates a Laravel compatible APP_KEY using crypto/rand
+func GenerateAppKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return "", fmt.Errorf("failed to generate app key: %w", err)
+	}
+	return "base64:" + base64.StdEncoding.EncodeToString(key), nil
+}
+
+// GenerateDatabasePassword creates a strong random alphanumeric password
+func GenerateDatabasePassword() (string, error) {
+	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	const length = 32
+
+	password := make([]byte, length)
+	max := big.NewInt(int64(len(charset)))
+	for i := range password {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate password: %w", err)
+		}
+		password[i] = charset[n.Int64()]
+	}
+	return string(password), nil
+}
+
+// ProjectDatabasePassword decrypts a project's database password.
+// Projects created before per-project passwords still use the database name.
+func ProjectDatabasePassword(secrets *SecretBox, project *models.Project) (string, error) {
+	if project.DBPassword == "" {
+		return project.DatabaseName, nil
+	}
+	return secrets.Decrypt(project.DBPassword)
+}
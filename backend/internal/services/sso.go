@@ -0,0 +1,110 @@
+// ===========================================
+// Single Sign-On Provisioning
+// ===========================================
+// Links identities from external providers to
+// users, creating accounts on first sign-in
+// ===========================================
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/laravel-paas/backend/internal/models"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// ExternalIdentity is a user as asserted by an identity provider
+type ExternalIdentity struct {
+	Provider string // oidc, ldap
+	Email    string
+	Name     string
+	Groups   []string
+}
+
+// ProvisionUser returns the user for an identity, linking by email to an existing
+// account or creating one just in time. The role mapped from the provider is given
+// to new accounts; existing accounts are only ever promoted from student to admin,
+// never demoted, and the superadmin is left alone. The second result reports
+// whether an existing user's role changed.
+func ProvisionUser(db *gorm.DB, identity *ExternalIdentity, role models.Role) (*models.User, bool, error) {
+	email := strings.ToLower(strings.TrimSpace(identity.Email))
+	if email == "" {
+		return nil, false, errors.New("identity provider did not return an email address")
+	}
+
+	var user models.User
+	err := db.Where("email = ?", email).First(&user).Error
+	if err == nil {
+		if role == models.RoleAdmin && user.Role == models.RoleStudent {
+			if err := db.Model(&user).Update("role", models.RoleAdmin).Error; err != nil {
+				return nil, false, err
+			}
+			return &user, true, nil
+		}
+		return &user, false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, err
+	}
+
+	// A deleted account keeps its email; do not silently bring it back
+	var deleted int64
+	db.Unscoped().Model(&models.User{}).Where("email = ? AND deleted_at IS NOT NULL", email).Count(&deleted)
+	if deleted > 0 {
+		return nil, false, errors.New("this account has been deleted, contact your administrator")
+	}
+
+	// Nobody knows this password; the account signs in through its provider
+	secret, err := randomHex(32)
+	if err != nil {
+		return nil, false, err
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, false, err
+	}
+
+	name := strings.TrimSpace(identity.Name)
+	if name == "" {
+		name = strings.SplitN(email, "@", 2)[0]
+	}
+
+	user = models.User{
+		Email:        email,
+		Password:     string(hashed),
+		Name:         name,
+		Role:         role,
+		AuthProvider: identity.Provider,
+	}
+	if err := db.Create(&user).Error; err != nil {
+		return nil, false, fmt.Errorf("failed to create user: %w", err)
+	}
+	return &user, false, nil
+}
+
+// MapRole returns admin when any of the identity's groups is listed in adminValues
+// (comma-separated, case-insensitive), student otherwise
+func MapRole(groups []string, adminValues string) models.Role {
+	for _, value := range splitList(adminValues) {
+		for _, group := range groups {
+			if strings.EqualFold(strings.TrimSpace(group), value) {
+				return models.RoleAdmin
+			}
+		}
+	}
+	return models.RoleStudent
+}
+
+// splitList splits a comma-separated setting, dropping empty entries
+func splitList(list string) []string {
+	var values []string
+	for _, v := range strings.Split(list, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
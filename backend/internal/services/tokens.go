@@ -0,0 +1,447 @@
+// ===========================================
+// Token Service
+// ===========================================
+// Issues short-lived access tokens and the
+// rotating refresh tokens of user sessions
+// ===========================================
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/laravel-paas/backend/internal/config"
+	"github.com/laravel-paas/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// tokenVersionTTL is how long a user's token version is cached in Redis
+	tokenVersionTTL = time.Hour
+	// refreshReuseGrace lets concurrent refreshes from one browser race without
+	// being mistaken for a stolen token
+	refreshReuseGrace = 10 * time.Second
+)
+
+var (
+	// ErrInvalidRefreshToken is returned for unknown, expired or revoked refresh tokens
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	// ErrRefreshTokenReused is returned when a rotated-out refresh token is presented again
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected, session revoked")
+	// ErrInvalidResetToken is returned for unknown, expired or used password reset tokens
+	ErrInvalidResetToken = errors.New("invalid or expired password reset token")
+)
+
+// SessionInfo describes the client signing in or refreshing
+type SessionInfo struct {
+	IPAddress string
+	UserAgent string
+}
+
+// TokenPair is an access token with the refresh token that renews it
+type TokenPair struct {
+	AccessToken string
+	// RefreshToken is empty when the current refresh token stays valid
+	RefreshToken string
+	AccessExpiry time.Time
+	Session      *models.Session
+}
+
+// TokenService issues and revokes access tokens
+type TokenService struct {
+	db           *gorm.DB
+	cfg          *config.Config
+	redisService *RedisService
+}
+
+// NewTokenService creates a new token service
+func NewTokenService(db *gorm.DB, cfg *config.Config, redisService *RedisService) *TokenService {
+	return &TokenService{db: db, cfg: cfg, redisService: redisService}
+}
+
+// AccessTokenTTL is the lifetime of access tokens
+func (t *TokenService) AccessTokenTTL() time.Duration {
+	return time.Duration(t.cfg.AccessTokenMinutes) * time.Minute
+}
+
+// RefreshTokenTTL is the lifetime of a session without activity
+func (t *TokenService) RefreshTokenTTL() time.Duration {
+	return time.Duration(t.cfg.RefreshTokenDays) * 24 * time.Hour
+}
+
+// Issue signs an access token for the user within a session. It carries a unique
+// jti, the session id, the user's token version and whether the account must still
+// change its password or enroll in two-factor authentication.
+func (t *TokenService) Issue(user *models.User, sessionID string) (string, time.Time, error) {
+	jti, err := randomHex(16)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	expiresAt := time.Now().Add(t.AccessTokenTTL())
+
+	claims := jwt.MapClaims{
+		"user_id": user.ID,
+		"email":   user.Email,
+		"role":    user.Role,
+		"ver":     user.TokenVersion,
+		"mcp":     user.MustChangePassword,
+		"tfa":     TwoFactorSetupRequired(t.db, user),
+		"sid":     sessionID,
+		"jti":     jti,
+		"exp":     expiresAt.Unix(),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signed, err := token.SignedString([]byte(t.cfg.JWTSecret))
+	return signed, expiresAt, err
+}
+
+// StartSession creates a session for a user who just signed in
+func (t *TokenService) StartSession(user *models.User, info SessionInfo) (*TokenPair, error) {
+	tokenID, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := models.Session{
+		UserID:      user.ID,
+		TokenID:     tokenID,
+		RefreshHash: hashToken(secret),
+		Device:      DescribeDevice(info.UserAgent),
+		IPAddress:   info.IPAddress,
+		UserAgent:   truncate(info.UserAgent, 500),
+		LastUsedAt:  now,
+		ExpiresAt:   now.Add(t.RefreshTokenTTL()),
+	}
+	if err := t.db.Create(&session).Error; err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	// Forget the user's sessions that ended a while ago
+	t.db.Where("user_id = ? AND (expires_at < ? OR revoked_at < ?)", user.ID, now, now.Add(-24*time.Hour)).
+		Delete(&models.Session{})
+
+	access, expiry, err := t.Issue(user, tokenID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  access,
+		RefreshToken: tokenID + "." + secret,
+		AccessExpiry: expiry,
+		Session:      &session,
+	}, nil
+}
+
+// Refresh exchanges a refresh token for a new access token and a rotated refresh token.
+// Presenting a refresh token that was already rotated out revokes the whole session,
+// since either the legitimate client or an attacker holds a stolen copy.
+func (t *TokenService) Refresh(refreshToken string, info SessionInfo) (*TokenPair, error) {
+	tokenID, secret, ok := strings.Cut(refreshToken, ".")
+	if !ok || tokenID == "" || secret == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	var session models.Session
+	if err := t.db.Where("token_id = ?", tokenID).First(&session).Error; err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	now := time.Now()
+	if session.RevokedAt != nil || now.After(session.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	var user models.User
+	if err := t.db.First(&user, session.UserID).Error; err != nil {
+		t.RevokeSession(&session)
+		return nil, ErrInvalidRefreshToken
+	}
+
+	presented := hashToken(secret)
+	if !sameHash(presented, session.RefreshHash) {
+		// Another request of the same client rotated the token a moment ago
+		if sameHash(presented, session.PreviousHash) && session.RotatedAt != nil &&
+			now.Sub(*session.RotatedAt) < refreshReuseGrace {
+			return t.renewAccess(&user, &session, info, now)
+		}
+
+		t.RevokeSession(&session)
+		return nil, ErrRefreshTokenReused
+	}
+
+	newSecret, err := randomHex(32)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rotate only if nobody else did in between
+	result := t.db.Model(&models.Session{}).
+		Where("id = ? AND refresh_hash = ?", session.ID, session.RefreshHash).
+		Updates(map[string]interface{}{
+			"refresh_hash":  hashToken(newSecret),
+			"previous_hash": session.RefreshHash,
+			"rotated_at":    now,
+			"last_used_at":  now,
+			"ip_address":    info.IPAddress,
+			"expires_at":    now.Add(t.RefreshTokenTTL()),
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to rotate refresh token: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return t.renewAccess(&user, &session, info, now)
+	}
+
+	access, expiry, err := t.Issue(&user, session.TokenID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  access,
+		RefreshToken: session.TokenID + "." + newSecret,
+		AccessExpiry: expiry,
+		Session:      &session,
+	}, nil
+}
+
+// renewAccess issues an access token without rotating the refresh token
+func (t *TokenService) renewAccess(user *models.User, session *models.Session, info SessionInfo, now time.Time) (*TokenPair, error) {
+	t.db.Model(session).Updates(map[string]interface{}{
+		"last_used_at": now,
+		"ip_address":   info.IPAddress,
+	})
+
+	access, expiry, err := t.Issue(user, session.TokenID)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{AccessToken: access, AccessExpiry: expiry, Session: session}, nil
+}
+
+// RevokeSession ends a session. Access tokens already issued for it stop working too.
+func (t *TokenService) RevokeSession(session *models.Session) error {
+	now := time.Now()
+	if err := t.db.Model(session).Update("revoked_at", now).Error; err != nil {
+		return err
+	}
+	return t.redisService.AddToBlacklist(sessionBlacklistKey(session.TokenID), t.AccessTokenTTL())
+}
+
+// EndSession revokes a session by the id carried in its tokens, if it is still active
+func (t *TokenService) EndSession(tokenID string) error {
+	if tokenID == "" {
+		return nil
+	}
+
+	var session models.Session
+	if err := t.db.Where("token_id = ? AND revoked_at IS NULL", tokenID).First(&session).Error; err != nil {
+		return nil
+	}
+	return t.RevokeSession(&session)
+}
+
+// ActiveSessions lists the sessions of a user that can still be refreshed
+func (t *TokenService) ActiveSessions(userID uint) ([]models.Session, error) {
+	var sessions []models.Session
+	err := t.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").Find(&sessions).Error
+	return sessions, err
+}
+
+// CreatePasswordReset issues a single-use password reset token, replacing
+// any reset the user requested before
+func (t *TokenService) CreatePasswordReset(user *models.User) (string, time.Time, error) {
+	token, err := randomHex(32)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	minutes := getSettingInt(t.db, "password_reset_minutes", 60)
+	reset := models.PasswordReset{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(time.Duration(minutes) * time.Minute),
+	}
+
+	err = t.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND used_at IS NULL", user.ID).Delete(&models.PasswordReset{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&reset).Error
+	})
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to create password reset: %w", err)
+	}
+	return token, reset.ExpiresAt, nil
+}
+
+// ConsumePasswordReset marks a reset token used and returns its user
+func (t *TokenService) ConsumePasswordReset(token string) (*models.User, error) {
+	var reset models.PasswordReset
+	if err := t.db.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", hashToken(token), time.Now()).
+		First(&reset).Error; err != nil {
+		return nil, ErrInvalidResetToken
+	}
+
+	// Two requests racing with the same token: only one marks it used
+	result := t.db.Model(&models.PasswordReset{}).Where("id = ? AND used_at IS NULL", reset.ID).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrInvalidResetToken
+	}
+
+	var user models.User
+	if err := t.db.First(&user, reset.UserID).Error; err != nil {
+		return nil, ErrInvalidResetToken
+	}
+	return &user, nil
+}
+
+// Revoke blacklists a single token until it would have expired anyway
+func (t *TokenService) Revoke(jti string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if jti == "" || ttl <= 0 {
+		return nil
+	}
+	return t.redisService.AddToBlacklist(jti, ttl)
+}
+
+// RevokeAllForUser invalidates every token issued to the user so far and ends their sessions
+func (t *TokenService) RevokeAllForUser(userID uint) error {
+	if err := t.db.Model(&models.User{}).Unscoped().Where("id = ?", userID).
+		UpdateColumn("token_version", gorm.Expr("token_version + 1")).Error; err != nil {
+		return err
+	}
+
+	if err := t.db.Model(&models.Session{}).Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return err
+	}
+
+	// Drop the cached version; the next request reloads it from the database
+	return t.redisService.DeleteCache(tokenVersionKey(userID))
+}
+
+// IsRevoked reports whether an access token was revoked by jti, by its session
+// or by a newer token version. Tokens of users that no longer exist count as revoked.
+func (t *TokenService) IsRevoked(userID uint, jti, sessionID string, version uint) bool {
+	if jti != "" && t.redisService.IsBlacklisted(jti) {
+		return true
+	}
+	if sessionID != "" && t.redisService.IsBlacklisted(sessionBlacklistKey(sessionID)) {
+		return true
+	}
+
+	current, err := t.currentVersion(userID)
+	if err != nil {
+		return true
+	}
+	return version < current
+}
+
+// currentVersion returns the user's token version, cached in Redis
+func (t *TokenService) currentVersion(userID uint) (uint, error) {
+	var version uint
+	if err := t.redisService.GetCache(tokenVersionKey(userID), &version); err == nil {
+		return version, nil
+	}
+
+	var user models.User
+	if err := t.db.Select("id", "token_version").First(&user, userID).Error; err != nil {
+		return 0, err
+	}
+
+	t.redisService.SetCache(tokenVersionKey(userID), user.TokenVersion, tokenVersionTTL)
+	return user.TokenVersion, nil
+}
+
+// DescribeDevice turns a user agent into a short label like "Firefox on Linux"
+func DescribeDevice(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+
+	browser := "Unknown browser"
+	switch {
+	case strings.Contains(ua, "edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "opr/") || strings.Contains(ua, "opera"):
+		browser = "Opera"
+	case strings.Contains(ua, "firefox/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "chrome/") || strings.Contains(ua, "crios/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "safari/"):
+		browser = "Safari"
+	case strings.Contains(ua, "curl/"):
+		browser = "curl"
+	}
+
+	os := ""
+	switch {
+	case strings.Contains(ua, "android"):
+		os = "Android"
+	case strings.Contains(ua, "iphone") || strings.Contains(ua, "ipad"):
+		os = "iOS"
+	case strings.Contains(ua, "windows"):
+		os = "Windows"
+	case strings.Contains(ua, "mac os"):
+		os = "macOS"
+	case strings.Contains(ua, "linux"):
+		os = "Linux"
+	}
+
+	if os == "" {
+		return browser
+	}
+	return browser + " on " + os
+}
+
+func tokenVersionKey(userID uint) string {
+	return fmt.Sprintf("token_version:%d", userID)
+}
+
+func sessionBlacklistKey(sessionID string) string {
+	return "session:" + sessionID
+}
+
+// hashToken digests a refresh token secret; secrets are random so no salt is needed
+func hashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func sameHash(a, b string) bool {
+	return b != "" && subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
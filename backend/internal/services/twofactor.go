@@ -0,0 +1,316 @@
+// ===========================================
+// Two-Factor Authentication
+// ===========================================
+// TOTP enrollment, recovery codes and the
+// login challenge between password and code
+// ===========================================
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image/png"
+	"strings"
+	"time"
+
+	"github.com/laravel-paas/backend/internal/config"
+	"github.com/laravel-paas/backend/internal/models"
+	"github.com/pquerna/otp/totp"
+	"gorm.io/gorm"
+)
+
+const (
+	// totpIssuer is the account label shown in authenticator apps
+	totpIssuer = "Laravel PaaS"
+	// recoveryCodeCount is how many recovery codes are issued at once
+	recoveryCodeCount = 10
+	// challengeTTL is how long a user has to enter their code after the password
+	challengeTTL = 5 * time.Minute
+	// challengeMaxAttempts ends a challenge after this many wrong codes
+	challengeMaxAttempts = 5
+)
+
+var (
+	// ErrInvalidTwoFactorCode is returned for wrong, expired or reused codes
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+	// ErrInvalidChallenge is returned for unknown or expired login challenges
+	ErrInvalidChallenge = errors.New("login challenge expired, please sign in again")
+)
+
+// TwoFactorSetup is what the user needs to add the account to an authenticator app
+type TwoFactorSetup struct {
+	Secret     string `json:"secret"`
+	OTPAuthURL string `json:"otpauth_url"`
+	QRCode     string `json:"qr_code"` // PNG data URL
+}
+
+// loginChallenge is the pending second step of a login
+type loginChallenge struct {
+	UserID    uint      `json:"user_id"`
+	Attempts  int       `json:"attempts"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// TwoFactorService manages TOTP secrets, recovery codes and login challenges
+type TwoFactorService struct {
+	db           *gorm.DB
+	secrets      *SecretBox
+	redisService *RedisService
+}
+
+// NewTwoFactorService creates a new two-factor service
+func NewTwoFactorService(db *gorm.DB, cfg *config.Config, redisService *RedisService) *TwoFactorService {
+	return &TwoFactorService{db: db, secrets: NewSecretBox(cfg), redisService: redisService}
+}
+
+// TwoFactorSetupRequired reports whether the user must enroll before using the panel
+func TwoFactorSetupRequired(db *gorm.DB, user *models.User) bool {
+	return !user.TwoFactorEnabled && user.IsAdmin() && getSettingBool(db, "require_2fa_admins", false)
+}
+
+// Setup generates a new secret for the user. It only takes effect once Enable
+// confirms the user's app produces matching codes.
+func (s *TwoFactorService) Setup(user *models.User) (*TwoFactorSetup, error) {
+	if user.TwoFactorEnabled {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{Issuer: totpIssuer, AccountName: user.Email})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate secret: %w", err)
+	}
+
+	encrypted, err := s.secrets.Encrypt(key.Secret())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(user).Update("totp_secret", encrypted).Error; err != nil {
+		return nil, err
+	}
+
+	img, err := key.Image(240, 240)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render QR code: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to render QR code: %w", err)
+	}
+
+	return &TwoFactorSetup{
+		Secret:     key.Secret(),
+		OTPAuthURL: key.URL(),
+		QRCode:     "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()),
+	}, nil
+}
+
+// Enable turns two-factor authentication on after checking a code from the
+// pending secret, and returns fresh recovery codes to show once
+func (s *TwoFactorService) Enable(user *models.User, code string) ([]string, error) {
+	if user.TwoFactorEnabled {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+	if user.TOTPSecret == "" {
+		return nil, errors.New("two-factor setup has not been started")
+	}
+	if !s.validateTOTP(user, code) {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	var codes []string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Update("two_factor_enabled", true).Error; err != nil {
+			return err
+		}
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable turns two-factor authentication off and forgets the secret and recovery codes
+func (s *TwoFactorService) Disable(user *models.User) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"two_factor_enabled": false,
+			"totp_secret":        "",
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error
+	})
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes of an enrolled user
+func (s *TwoFactorService) RegenerateRecoveryCodes(user *models.User) ([]string, error) {
+	if !user.TwoFactorEnabled {
+		return nil, errors.New("two-factor authentication is not enabled")
+	}
+	return replaceRecoveryCodes(s.db, user.ID)
+}
+
+// Verify checks a TOTP code or, failing that, consumes a recovery code
+func (s *TwoFactorService) Verify(user *models.User, code string) bool {
+	if !user.TwoFactorEnabled {
+		return false
+	}
+	if s.validateTOTP(user, code) {
+		return true
+	}
+	return s.useRecoveryCode(user.ID, code)
+}
+
+// validateTOTP checks a code against the user's secret, allowing one step of
+// clock drift. Each code is accepted once.
+func (s *TwoFactorService) validateTOTP(user *models.User, code string) bool {
+	code = strings.TrimSpace(code)
+	if len(code) != 6 || user.TOTPSecret == "" {
+		return false
+	}
+
+	secret, err := s.secrets.Decrypt(user.TOTPSecret)
+	if err != nil || !totp.Validate(code, secret) {
+		return false
+	}
+
+	// A code stays valid for up to 90 seconds; remember it that long
+	usedKey := fmt.Sprintf("totp_used:%d:%s", user.ID, code)
+	var used bool
+	if s.redisService.GetCache(usedKey, &used) == nil {
+		return false
+	}
+	s.redisService.SetCache(usedKey, true, 90*time.Second)
+	return true
+}
+
+// useRecoveryCode marks a matching unused recovery code as used
+func (s *TwoFactorService) useRecoveryCode(userID uint, code string) bool {
+	normalized := normalizeRecoveryCode(code)
+	if normalized == "" {
+		return false
+	}
+
+	result := s.db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hashToken(normalized)).
+		Update("used_at", time.Now())
+	return result.Error == nil && result.RowsAffected == 1
+}
+
+// RemainingRecoveryCodes counts the unused recovery codes of a user
+func (s *TwoFactorService) RemainingRecoveryCodes(userID uint) int64 {
+	var count int64
+	s.db.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&count)
+	return count
+}
+
+// CreateChallenge starts the second step of a login for a user whose password checked out
+func (s *TwoFactorService) CreateChallenge(userID uint) (string, error) {
+	token, err := randomHex(32)
+	if err != nil {
+		return "", err
+	}
+
+	challenge := loginChallenge{UserID: userID, ExpiresAt: time.Now().Add(challengeTTL)}
+	if err := s.redisService.SetCache(challengeKey(token), challenge, challengeTTL); err != nil {
+		return "", fmt.Errorf("failed to store login challenge: %w", err)
+	}
+	return token, nil
+}
+
+// ChallengeUser returns the user of a pending login challenge without using it up
+func (s *TwoFactorService) ChallengeUser(token string) (*models.User, error) {
+	var challenge loginChallenge
+	if token == "" || s.redisService.GetCache(challengeKey(token), &challenge) != nil {
+		return nil, ErrInvalidChallenge
+	}
+
+	var user models.User
+	if err := s.db.First(&user, challenge.UserID).Error; err != nil {
+		return nil, ErrInvalidChallenge
+	}
+	return &user, nil
+}
+
+// ResolveChallenge checks the code for a login challenge and returns its user.
+// A challenge is used up by success or by too many wrong codes.
+func (s *TwoFactorService) ResolveChallenge(token, code string) (*models.User, error) {
+	var challenge loginChallenge
+	if token == "" || s.redisService.GetCache(challengeKey(token), &challenge) != nil {
+		return nil, ErrInvalidChallenge
+	}
+
+	var user models.User
+	if err := s.db.First(&user, challenge.UserID).Error; err != nil {
+		s.redisService.DeleteCache(challengeKey(token))
+		return nil, ErrInvalidChallenge
+	}
+
+	if !s.Verify(&user, code) {
+		challenge.Attempts++
+		remaining := time.Until(challenge.ExpiresAt)
+		if challenge.Attempts >= challengeMaxAttempts || remaining <= 0 {
+			s.redisService.DeleteCache(challengeKey(token))
+			return nil, ErrInvalidChallenge
+		}
+		s.redisService.SetCache(challengeKey(token), challenge, remaining)
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	s.redisService.DeleteCache(challengeKey(token))
+	return &user, nil
+}
+
+// replaceRecoveryCodes deletes a user's recovery codes and stores new ones
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	records := make([]models.RecoveryCode, recoveryCodeCount)
+	for i := range codes {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+		records[i] = models.RecoveryCode{UserID: userID, CodeHash: hashToken(normalizeRecoveryCode(code))}
+	}
+
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// recoveryAlphabet leaves out characters that are easily confused
+const recoveryAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// generateRecoveryCode returns a code like "k7rm2-x9qfd"
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate recovery code: %w", err)
+	}
+	for i := range b {
+		b[i] = recoveryAlphabet[int(b[i])%len(recoveryAlphabet)]
+	}
+	return string(b[:5]) + "-" + string(b[5:]), nil
+}
+
+// normalizeRecoveryCode ignores case, spaces and dashes
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+func challengeKey(token string) string {
+	return "2fa_challenge:" + token
+}
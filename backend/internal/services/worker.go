@@ -19,6 +19,8 @@ type DeploymentWorker struct {
 	db            *gorm.DB
 	cfg           *config.Config
 	dockerService *DockerService
+	provisioners  *Provisioners
+	backupService *BackupService
 	redisService  *RedisService
 	running       bool
 }
@@ -29,6 +31,8 @@ func NewDeploymentWorker(db *gorm.DB, cfg *config.Config, redisService *RedisSer
 		db:            db,
 		cfg:           cfg,
 		dockerService: NewDockerService(cfg),
+		provisioners:  NewProvisioners(cfg),
+		backupService: NewBackupService(db, cfg),
 		redisService:  redisService,
 		running:       false,
 	}
@@ -105,7 +109,7 @@ func (w *DeploymentWorker) processDeployment(job *DeploymentJob) {
 
 	// Fetch project from database
 	var project models.Project
-	if err := w.db.First(&project, job.ProjectID).Error; err != nil {
+	if err := w.db.Preload("Addons").First(&project, job.ProjectID).Error; err != nil {
 		log.Printf("❌ Failed to find project #%d: %v", job.ProjectID, err)
 		w.redisService.IncrementDeploymentCounter("failed_not_found")
 		return
@@ -158,20 +162,57 @@ func (w *DeploymentWorker) deployProject(project *models.Project) {
 		"php_version":     finalPHPVersion,
 	})
 
-	// Step 3: Create database
-	if err := w.dockerService.CreateDatabase(project.DatabaseName); err != nil {
+	// Step 3: Create database with a per-project random password
+	passwordChanged, err := w.dockerService.EnsureDatabasePassword(project)
+	if err != nil {
+		w.updateProjectError(project, "Failed to generate database password: "+err.Error())
+		return
+	}
+
+	dbPassword, err := w.dockerService.DatabasePassword(project)
+	if err != nil {
+		w.updateProjectError(project, "Failed to read database password: "+err.Error())
+		return
+	}
+
+	if err := w.provisioners.For(project.DatabaseEngine).Create(project.DatabaseName, dbPassword); err != nil {
 		w.updateProjectError(project, "Failed to create database: "+err.Error())
 		return
 	}
 
+	if passwordChanged {
+		w.db.Model(project).Update("db_password", project.DBPassword)
+	}
+
+	// Provisioning re-grants all privileges, keep writes revoked while over quota
+	if project.DBWritesRevoked {
+		if err := w.provisioners.For(project.DatabaseEngine).RevokeWrites(project.DatabaseName); err != nil {
+			w.updateProjectError(project, "Failed to apply database quota: "+err.Error())
+			return
+		}
+	}
+
 	// Capture old container ID for cleanup after successful deployment
 	var oldContainerID *string
 	if project.ContainerID != nil {
 		oldHelp := *project.ContainerID
 		oldContainerID = &oldHelp
+
+		// Snapshot the database before the new release runs its migrations
+		if _, err := w.backupService.Backup(project, models.BackupPreDeploy); err != nil {
+			log.Printf("⚠️  Pre-deploy backup failed for project #%d: %v", project.ID, err)
+		}
 	}
 
 	// Step 4: Build and run container
+	// Persist the APP_KEY once so redeploys don't invalidate sessions and encrypted data
+	if changed, err := w.dockerService.EnsureAppKey(project); err != nil {
+		w.updateProjectError(project, "Failed to prepare APP_KEY: "+err.Error())
+		return
+	} else if changed {
+		w.db.Model(project).Update("app_key", project.AppKey)
+	}
+
 	projectDomain := w.getProjectDomain()
 	containerID, err := w.dockerService.BuildAndRun(project, finalPHPVersion, projectDomain)
 
@@ -0,0 +1,261 @@
+// ===========================================
+// SQL Statement Classifier
+// ===========================================
+// Decides what a statement does (read, write,
+// schema change...) from its tokens
+// ===========================================
+package sqlparse
+
+// Kind groups statements by their effect
+type Kind string
+
+const (
+	KindRead        Kind = "read"        // SELECT, SHOW, EXPLAIN...
+	KindWrite       Kind = "write"       // INSERT, UPDATE, DELETE, CALL...
+	KindSchema      Kind = "schema"      // CREATE, ALTER, DROP, TRUNCATE...
+	KindTransaction Kind = "transaction" // BEGIN, COMMIT, SET TRANSACTION...
+	KindSession     Kind = "session"     // SET
+	KindOther       Kind = "other"
+)
+
+// Classification describes a single statement
+type Classification struct {
+	Keyword     string `json:"keyword"`
+	Kind        Kind   `json:"kind"`
+	ReturnsRows bool   `json:"returns_rows"`
+	// Forbidden explains why the statement may not run; empty when allowed
+	Forbidden string `json:"forbidden,omitempty"`
+}
+
+// ReadOnly reports whether the statement can run in a read-only transaction.
+// SET is excluded: autocommit or transaction_read_only would end the read-only
+// transaction for the statements that follow.
+func (c Classification) ReadOnly() bool {
+	return c.Kind == KindRead
+}
+
+// forbiddenObjects are server-level objects students may not create, alter or drop
+var forbiddenObjects = map[string]bool{
+	"DATABASE": true, "USER": true, "ROLE": true, "TABLESPACE": true, "SERVER": true,
+}
+
+// createModifiers may appear between CREATE/DROP/ALTER and the object type
+var createModifiers = map[string]bool{
+	"OR": true, "REPLACE": true, "TEMPORARY": true, "TEMP": true, "GLOBAL": true,
+	"LOCAL": true, "UNLOGGED": true, "UNIQUE": true, "ONLINE": true, "OFFLINE": true,
+}
+
+// Classify determines what a single statement does
+func Classify(stmt string, opts Options) Classification {
+	return classifyTokens(Tokenize(stmt, opts), opts)
+}
+
+func classifyTokens(tokens []Token, opts Options) Classification {
+	words := wordsOf(tokens)
+	if len(words) == 0 {
+		return Classification{Kind: KindOther}
+	}
+
+	c := Classification{Keyword: words[0].Text}
+	top := topLevelWords(tokens)
+
+	switch c.Keyword {
+	case "SELECT", "TABLE", "VALUES":
+		c.Kind, c.ReturnsRows = KindRead, true
+		if i := indexOf(top, "INTO"); i >= 0 {
+			if next := wordAt(top, i+1); next == "OUTFILE" || next == "DUMPFILE" {
+				c.Forbidden = "Writing files on the database server is not allowed"
+			}
+			// SELECT ... INTO creates a table (PostgreSQL) or sets variables (MySQL)
+			c.Kind, c.ReturnsRows = KindWrite, false
+		} else if isLockingRead(top) {
+			// Row locks are refused in read-only transactions
+			c.Kind = KindWrite
+		}
+
+	case "SHOW", "DESCRIBE", "DESC":
+		c.Kind, c.ReturnsRows = KindRead, true
+
+	case "EXPLAIN":
+		c.Kind, c.ReturnsRows = KindRead, true
+		// EXPLAIN ANALYZE executes the statement it explains
+		if wordAt(words, 1) == "ANALYZE" {
+			for i := 2; i < len(words); i++ {
+				if isStatementKeyword(words[i].Text) {
+					if inner := classifyTokens(words[i:], opts); !inner.ReadOnly() {
+						c.Kind = inner.Kind
+					}
+					break
+				}
+			}
+		}
+
+	case "WITH":
+		c = classifyWith(tokens, top, opts)
+
+	case "INSERT", "UPDATE", "DELETE", "REPLACE", "MERGE", "UPSERT":
+		c.Kind = KindWrite
+		c.ReturnsRows = indexOf(top, "RETURNING") >= 0
+
+	case "CALL", "DO", "EXECUTE", "HANDLER":
+		// Procedures may both modify data and return result sets
+		c.Kind, c.ReturnsRows = KindWrite, c.Keyword == "CALL" || c.Keyword == "EXECUTE"
+
+	case "LOAD", "COPY":
+		c.Kind = KindWrite
+		c.Forbidden = "Loading files on the database server is not allowed, use the import instead"
+
+	case "CREATE", "ALTER", "DROP":
+		c.Kind = KindSchema
+		object := wordAt(top, 1)
+		for i := 2; createModifiers[object]; i++ {
+			object = wordAt(top, i)
+		}
+		if forbiddenObjects[object] || (object == "SCHEMA" && opts.SchemaIsDatabase) {
+			c.Forbidden = "Creating, altering or dropping databases, users and roles is not allowed"
+		}
+
+	case "RENAME", "TRUNCATE", "COMMENT", "REINDEX", "CLUSTER", "REFRESH":
+		c.Kind = KindSchema
+		if wordAt(top, 1) == "USER" {
+			c.Forbidden = "Managing users is not allowed"
+		}
+
+	case "GRANT", "REVOKE":
+		c.Kind = KindSchema
+		c.Forbidden = "Managing privileges is not allowed"
+
+	case "BEGIN", "START", "COMMIT", "ROLLBACK", "SAVEPOINT", "RELEASE", "END", "ABORT", "XA":
+		c.Kind = KindTransaction
+
+	case "SET":
+		c.Kind = KindSession
+		switch wordAt(top, 1) {
+		case "TRANSACTION":
+			c.Kind = KindTransaction
+		case "SESSION":
+			if next := wordAt(top, 2); next == "CHARACTERISTICS" || next == "TRANSACTION" {
+				c.Kind = KindTransaction
+			} else if next == "AUTHORIZATION" {
+				c.Forbidden = "Changing the session user is not allowed"
+			}
+		case "PASSWORD", "ROLE", "DEFAULT":
+			c.Forbidden = "Changing credentials or roles is not allowed"
+		case "GLOBAL", "PERSIST", "PERSIST_ONLY":
+			c.Forbidden = "Changing server settings is not allowed"
+		}
+
+	case "USE":
+		c.Kind = KindSession
+		c.Forbidden = "Switching databases is not allowed"
+
+	case "KILL", "SHUTDOWN", "INSTALL", "UNINSTALL", "PURGE":
+		c.Kind = KindOther
+		c.Forbidden = "Server administration commands are not allowed"
+
+	default:
+		c.Kind = KindOther
+	}
+
+	return c
+}
+
+// classifyWith finds the main statement of a WITH query. Data-modifying
+// CTEs (PostgreSQL) make the whole statement a write.
+func classifyWith(tokens []Token, top []Token, opts Options) Classification {
+	c := Classification{Keyword: "WITH", Kind: KindRead, ReturnsRows: true}
+
+	for i, t := range top {
+		if i == 0 || !isStatementKeyword(t.Text) {
+			continue
+		}
+		main := classifyTokens(top[i:], opts)
+		c.Kind, c.ReturnsRows, c.Forbidden = main.Kind, main.ReturnsRows, main.Forbidden
+		break
+	}
+
+	// Look for INSERT/UPDATE/DELETE opening a CTE body
+	for i := 1; i < len(tokens); i++ {
+		if tokens[i].Kind != TokenWord || tokens[i-1].Text != "(" {
+			continue
+		}
+		switch tokens[i].Text {
+		case "INSERT", "UPDATE", "DELETE", "MERGE":
+			c.Kind = KindWrite
+		}
+	}
+
+	return c
+}
+
+// isLockingRead reports SELECT ... FOR UPDATE/SHARE and LOCK IN SHARE MODE (MySQL)
+func isLockingRead(top []Token) bool {
+	for i, t := range top {
+		switch {
+		case t.Text == "FOR":
+			switch wordAt(top, i+1) {
+			case "UPDATE", "SHARE", "NO", "KEY":
+				return true
+			}
+		case t.Text == "LOCK" && wordAt(top, i+1) == "IN":
+			return true
+		}
+	}
+	return false
+}
+
+// isStatementKeyword reports keywords that start the main part of a statement
+func isStatementKeyword(word string) bool {
+	switch word {
+	case "SELECT", "INSERT", "UPDATE", "DELETE", "REPLACE", "MERGE", "VALUES", "TABLE", "WITH":
+		return true
+	}
+	return false
+}
+
+// wordsOf returns the word tokens
+func wordsOf(tokens []Token) []Token {
+	var words []Token
+	for _, t := range tokens {
+		if t.Kind == TokenWord {
+			words = append(words, t)
+		}
+	}
+	return words
+}
+
+// topLevelWords returns the word tokens at the nesting level of the first word
+func topLevelWords(tokens []Token) []Token {
+	var words []Token
+	depth := -1
+	for _, t := range tokens {
+		if t.Kind != TokenWord {
+			continue
+		}
+		if depth < 0 {
+			depth = t.Depth
+		}
+		if t.Depth == depth {
+			words = append(words, t)
+		}
+	}
+	return words
+}
+
+// wordAt returns the text of the i-th token, or "" when out of range
+func wordAt(tokens []Token, i int) string {
+	if i < 0 || i >= len(tokens) {
+		return ""
+	}
+	return tokens[i].Text
+}
+
+// indexOf returns the index of the first token with the given text, or -1
+func indexOf(tokens []Token, text string) int {
+	for i, t := range tokens {
+		if t.Text == text {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,180 @@
+package sqlparse
+
+import "testing"
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        Options
+		stmt        string
+		kind        Kind
+		returnsRows bool
+		forbidden   bool
+	}{
+		// Plain statements
+		{"select", MySQL(), "SELECT * FROM users", KindRead, true, false},
+		{"parenthesized select", MySQL(), "(SELECT 1) UNION (SELECT 2)", KindRead, true, false},
+		{"show", MySQL(), "SHOW TABLES", KindRead, true, false},
+		{"insert", MySQL(), "INSERT INTO users (name) VALUES ('a')", KindWrite, false, false},
+		{"insert returning", Postgres(), "INSERT INTO users (name) VALUES ('a') RETURNING id", KindWrite, true, false},
+		{"update", Postgres(), "update users set name = 'b'", KindWrite, false, false},
+		{"create table", MySQL(), "CREATE TABLE t (id INT)", KindSchema, false, false},
+		{"create temporary table", Postgres(), "CREATE TEMPORARY TABLE t (id INT)", KindSchema, false, false},
+		{"set", MySQL(), "SET NAMES utf8mb4", KindSession, false, false},
+		{"set transaction", Postgres(), "SET TRANSACTION ISOLATION LEVEL SERIALIZABLE", KindTransaction, false, false},
+		{"begin", Postgres(), "BEGIN", KindTransaction, false, false},
+		{"empty", MySQL(), "  ", KindOther, false, false},
+
+		// Comments
+		{"leading line comment", MySQL(), "-- DROP DATABASE x\nSELECT 1", KindRead, true, false},
+		{"leading hash comment", MySQL(), "# DELETE FROM t\nSELECT 1", KindRead, true, false},
+		{"leading block comment", Postgres(), "/* DELETE */ SELECT 1", KindRead, true, false},
+		{"hash is not a comment on postgres", Postgres(), "SELECT 1 # 2", KindRead, true, false},
+		{"mysql executable comment", MySQL(), "/*!40101 SET NAMES utf8 */", KindSession, false, false},
+		{"executable comment hides a drop", MySQL(), "/*!50000 DROP DATABASE other */", KindSchema, false, true},
+
+		// Quoting
+		{"keyword in string", MySQL(), "SELECT 'DELETE FROM t INTO OUTFILE'", KindRead, true, false},
+		{"keyword in identifier", MySQL(), "SELECT `into` FROM t", KindRead, true, false},
+		{"mysql backslash escape", MySQL(), `SELECT 'it\'s INTO OUTFILE' FROM t`, KindRead, true, false},
+		{"mysql double quoted string", MySQL(), `SELECT "a INTO b" FROM t`, KindRead, true, false},
+		{"postgres double quoted identifier", Postgres(), `SELECT "into" FROM t`, KindRead, true, false},
+		{"postgres escape string", Postgres(), `SELECT E'it\'s INTO t' FROM t`, KindRead, true, false},
+		{"postgres dollar quoted", Postgres(), "SELECT $body$ INTO t $body$", KindRead, true, false},
+		{"postgres doubled quote", Postgres(), "SELECT 'it''s INTO t'", KindRead, true, false},
+
+		// SELECT ... INTO and locking reads
+		{"select into table", Postgres(), "SELECT * INTO copy FROM users", KindWrite, false, false},
+		{"select into variable", MySQL(), "SELECT COUNT(*) INTO @n FROM users", KindWrite, false, false},
+		{"select into outfile", MySQL(), "SELECT * FROM users INTO OUTFILE '/tmp/x'", KindWrite, false, true},
+		{"select into dumpfile", MySQL(), "SELECT 1 INTO DUMPFILE '/tmp/x'", KindWrite, false, true},
+		{"subquery into is not top level", Postgres(), "SELECT (SELECT 1) AS n", KindRead, true, false},
+		{"for update", Postgres(), "SELECT * FROM users WHERE id = 1 FOR UPDATE", KindWrite, true, false},
+		{"for share", Postgres(), "SELECT * FROM users FOR SHARE", KindWrite, true, false},
+		{"for no key update", Postgres(), "SELECT * FROM users FOR NO KEY UPDATE SKIP LOCKED", KindWrite, true, false},
+		{"lock in share mode", MySQL(), "SELECT * FROM users LOCK IN SHARE MODE", KindWrite, true, false},
+		{"for update in string", MySQL(), "SELECT 'FOR UPDATE' FROM users", KindRead, true, false},
+
+		// CTEs
+		{"cte select", Postgres(), "WITH a AS (SELECT 1) SELECT * FROM a", KindRead, true, false},
+		{"cte with insert body", Postgres(), "WITH a AS (INSERT INTO t VALUES (1) RETURNING id) SELECT * FROM a", KindWrite, true, false},
+		{"cte with delete body", Postgres(), "WITH gone AS (DELETE FROM t RETURNING *) SELECT count(*) FROM gone", KindWrite, true, false},
+		{"cte feeding update", Postgres(), "WITH a AS (SELECT 1 AS id) UPDATE t SET x = 1 FROM a", KindWrite, false, false},
+		{"recursive cte", MySQL(), "WITH RECURSIVE n AS (SELECT 1 UNION ALL SELECT 1 FROM n) SELECT * FROM n", KindRead, true, false},
+
+		// EXPLAIN
+		{"explain", MySQL(), "EXPLAIN SELECT * FROM users", KindRead, true, false},
+		{"explain analyze delete", Postgres(), "EXPLAIN ANALYZE DELETE FROM users", KindWrite, true, false},
+
+		// Forbidden statements
+		{"drop database", MySQL(), "DROP DATABASE other", KindSchema, false, true},
+		{"create or replace role", Postgres(), "CREATE ROLE admin", KindSchema, false, true},
+		{"create schema on mysql", MySQL(), "CREATE SCHEMA other", KindSchema, false, true},
+		{"create schema on postgres", Postgres(), "CREATE SCHEMA reports", KindSchema, false, false},
+		{"grant", Postgres(), "GRANT ALL ON t TO public", KindSchema, false, true},
+		{"use", MySQL(), "USE other", KindSession, false, true},
+		{"set global", MySQL(), "SET GLOBAL max_connections = 1", KindSession, false, true},
+		{"set role", Postgres(), "SET ROLE postgres", KindSession, false, true},
+		{"set session authorization", Postgres(), "SET SESSION AUTHORIZATION postgres", KindSession, false, true},
+		{"load data", MySQL(), "LOAD DATA INFILE '/etc/passwd' INTO TABLE t", KindWrite, false, true},
+		{"copy", Postgres(), "COPY t FROM '/etc/passwd'", KindWrite, false, true},
+		{"kill", MySQL(), "KILL 42", KindOther, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Classify(tt.stmt, tt.opts)
+			if c.Kind != tt.kind {
+				t.Errorf("Kind = %q, want %q", c.Kind, tt.kind)
+			}
+			if c.ReturnsRows != tt.returnsRows {
+				t.Errorf("ReturnsRows = %v, want %v", c.ReturnsRows, tt.returnsRows)
+			}
+			if (c.Forbidden != "") != tt.forbidden {
+				t.Errorf("Forbidden = %q, want forbidden %v", c.Forbidden, tt.forbidden)
+			}
+		})
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   Options
+		script string
+		want   []Statement
+	}{
+		{"single without delimiter", MySQL(), "SELECT 1", []Statement{{"SELECT 1", 1}}},
+		{"multiple statements", MySQL(), "SELECT 1; SELECT 2;\nSELECT 3", []Statement{
+			{"SELECT 1", 1}, {"SELECT 2", 1}, {"SELECT 3", 2},
+		}},
+		{"empty statements are dropped", Postgres(), ";;\n; SELECT 1;;", []Statement{{"SELECT 1", 2}}},
+		{"delimiter in string", MySQL(), "INSERT INTO t VALUES ('a;b'); SELECT 2", []Statement{
+			{"INSERT INTO t VALUES ('a;b')", 1}, {"SELECT 2", 1},
+		}},
+		{"delimiter in identifier", MySQL(), "SELECT `a;b` FROM t; SELECT 2", []Statement{
+			{"SELECT `a;b` FROM t", 1}, {"SELECT 2", 1},
+		}},
+		{"mysql backslash escaped quote", MySQL(), `SELECT 'it\'s; fine'; SELECT 2`, []Statement{
+			{`SELECT 'it\'s; fine'`, 1}, {"SELECT 2", 1},
+		}},
+		{"postgres backslash is literal", Postgres(), `SELECT 'C:\'; SELECT 2`, []Statement{
+			{`SELECT 'C:\'`, 1}, {"SELECT 2", 1},
+		}},
+		{"postgres escape string", Postgres(), `SELECT E'it\'s; fine'; SELECT 2`, []Statement{
+			{`SELECT E'it\'s; fine'`, 1}, {"SELECT 2", 1},
+		}},
+		{"postgres lower-case escape string", Postgres(), `SELECT e'\'; x'; SELECT 2`, []Statement{
+			{`SELECT e'\'; x'`, 1}, {"SELECT 2", 1},
+		}},
+		{"identifier ending in e is not an escape string", Postgres(), `SELECT name'C:\'; SELECT 2`, []Statement{
+			{`SELECT name'C:\'`, 1}, {"SELECT 2", 1},
+		}},
+		{"postgres dollar quoted body", Postgres(), "CREATE FUNCTION f() RETURNS int AS $fn$ SELECT 1; $fn$ LANGUAGE sql; SELECT 2", []Statement{
+			{"CREATE FUNCTION f() RETURNS int AS $fn$ SELECT 1; $fn$ LANGUAGE sql", 1}, {"SELECT 2", 1},
+		}},
+		{"positional parameters are not dollar quotes", Postgres(), "PREPARE p AS SELECT $1; SELECT 2", []Statement{
+			{"PREPARE p AS SELECT $1", 1}, {"SELECT 2", 1},
+		}},
+		{"line comment", MySQL(), "SELECT 1; -- a; b\nSELECT 2", []Statement{{"SELECT 1", 1}, {"SELECT 2", 2}}},
+		{"hash comment", MySQL(), "# a; b\nSELECT 1", []Statement{{"SELECT 1", 2}}},
+		{"block comment only", Postgres(), "SELECT 1; /* a; b */", []Statement{{"SELECT 1", 1}}},
+		{"mysql dash needs a space", MySQL(), "SELECT 1--1; SELECT 2", []Statement{{"SELECT 1--1", 1}, {"SELECT 2", 1}}},
+		{"delimiter command", MySQL(), "DELIMITER $$\nCREATE PROCEDURE p() BEGIN SELECT 1; END$$\nDELIMITER ;\nCALL p();", []Statement{
+			{"CREATE PROCEDURE p() BEGIN SELECT 1; END", 2}, {"CALL p()", 4},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Split(tt.script, tt.opts)
+			if err != nil {
+				t.Fatalf("Split() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Split() = %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("statement %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSplitUnterminated(t *testing.T) {
+	scripts := map[string]Options{
+		"SELECT 'open":                MySQL(),
+		`SELECT 'it\'s`:               MySQL(),
+		`SELECT E'it\'s`:              Postgres(),
+		"SELECT 1 /* open":            Postgres(),
+		"SELECT $tag$ never closed $": Postgres(),
+	}
+
+	for script, opts := range scripts {
+		if _, err := Split(script, opts); err == nil {
+			t.Errorf("Split(%q) succeeded, want an unterminated error", script)
+		}
+	}
+}
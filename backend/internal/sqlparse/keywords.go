@@ -0,0 +1,49 @@
+// ===========================================
+// SQL Keywords
+// ===========================================
+// Lightweight inspection of statements without
+// a full SQL grammar
+// ===========================================
+package sqlparse
+
+import (
+	"strings"
+	"unicode"
+)
+
+// FirstKeyword returns the upper-cased first keyword of a statement,
+// skipping whitespace, comments and opening parentheses. The contents of
+// MySQL executable comments (/*! ... */) are treated as regular SQL.
+func FirstKeyword(stmt string) string {
+	s := stmt
+	for {
+		s = strings.TrimLeftFunc(s, unicode.IsSpace)
+
+		switch {
+		case strings.HasPrefix(s, "/*!"):
+			s = strings.TrimLeft(s[3:], "0123456789")
+		case strings.HasPrefix(s, "/*"):
+			end := strings.Index(s, "*/")
+			if end < 0 {
+				return ""
+			}
+			s = s[end+2:]
+		case strings.HasPrefix(s, "--") || strings.HasPrefix(s, "#"):
+			end := strings.IndexByte(s, '\n')
+			if end < 0 {
+				return ""
+			}
+			s = s[end+1:]
+		case strings.HasPrefix(s, "("):
+			s = s[1:]
+		default:
+			end := strings.IndexFunc(s, func(r rune) bool {
+				return !(r == '_' || unicode.IsLetter(r))
+			})
+			if end < 0 {
+				end = len(s)
+			}
+			return strings.ToUpper(s[:end])
+		}
+	}
+}
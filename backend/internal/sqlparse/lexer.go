@@ -0,0 +1,176 @@
+// ===========================================
+// SQL Lexer
+// ===========================================
+// Tokenizes a single statement so keywords can
+// be told apart from strings and identifiers
+// ===========================================
+package sqlparse
+
+import (
+	"strings"
+	"unicode"
+)
+
+// TokenKind is the lexical category of a token
+type TokenKind int
+
+const (
+	TokenWord   TokenKind = iota // keyword or unquoted identifier
+	TokenString                  // '...', E'...', $tag$...$tag$ (and "..." on MySQL)
+	TokenIdent                   // `...` or "..." (PostgreSQL)
+	TokenNumber
+	TokenPunct
+)
+
+// Token is a lexical token of a statement
+type Token struct {
+	Kind  TokenKind
+	Text  string // upper-cased for words, verbatim otherwise
+	Depth int    // parenthesis nesting level
+}
+
+// Tokenize splits a statement into tokens, dropping whitespace and comments.
+// The contents of MySQL executable comments (/*! ... */) are tokenized as SQL.
+func Tokenize(stmt string, opts Options) []Token {
+	var tokens []Token
+	depth := 0
+	s := []rune(stmt)
+
+	for i := 0; i < len(s); {
+		r := s[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '-' && isDashComment(s, i, opts),
+			r == '#' && opts.HashComments:
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+
+		case r == '/' && i+1 < len(s) && s[i+1] == '*':
+			if i+2 < len(s) && s[i+2] == '!' {
+				// Executable comment: skip the marker and version, keep the contents
+				i += 3
+				for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+					i++
+				}
+				continue
+			}
+			end := indexRunes(s, i+2, []rune("*/"))
+			if end < 0 {
+				return tokens
+			}
+			i = end + 2
+
+		case r == '*' && i+1 < len(s) && s[i+1] == '/':
+			// End of an executable comment
+			i += 2
+
+		case r == '\'' || (r == '"' && opts.BackslashEscapes):
+			end := scanQuoted(s, i, opts.BackslashEscapes)
+			tokens = append(tokens, Token{Kind: TokenString, Text: string(s[i:end]), Depth: depth})
+			i = end
+
+		case (r == 'E' || r == 'e') && opts.EscapeStrings && i+1 < len(s) && s[i+1] == '\'':
+			end := scanQuoted(s, i+1, true)
+			tokens = append(tokens, Token{Kind: TokenString, Text: string(s[i:end]), Depth: depth})
+			i = end
+
+		case r == '`' || r == '"':
+			end := scanQuoted(s, i, false)
+			tokens = append(tokens, Token{Kind: TokenIdent, Text: string(s[i:end]), Depth: depth})
+			i = end
+
+		case r == '$' && opts.DollarQuotes && dollarTag(s, i) != "":
+			start := i
+			tag := []rune(dollarTag(s, i))
+			end := indexRunes(s, i+len(tag), tag)
+			if end < 0 {
+				i = len(s)
+			} else {
+				i = end + len(tag)
+			}
+			tokens = append(tokens, Token{Kind: TokenString, Text: string(s[start:i]), Depth: depth})
+
+		case r == '_' || unicode.IsLetter(r):
+			start := i
+			for i < len(s) && (isIdentRune(s[i]) || s[i] == '$') {
+				i++
+			}
+			tokens = append(tokens, Token{Kind: TokenWord, Text: strings.ToUpper(string(s[start:i])), Depth: depth})
+
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(s) && (unicode.IsDigit(s[i]) || s[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, Token{Kind: TokenNumber, Text: string(s[start:i]), Depth: depth})
+
+		default:
+			if r == ')' && depth > 0 {
+				depth--
+			}
+			tokens = append(tokens, Token{Kind: TokenPunct, Text: string(r), Depth: depth})
+			if r == '(' {
+				depth++
+			}
+			i++
+		}
+	}
+
+	return tokens
+}
+
+// scanQuoted returns the index after the quoted section starting at i
+func scanQuoted(s []rune, i int, backslash bool) int {
+	quote := s[i]
+	for j := i + 1; j < len(s); j++ {
+		switch {
+		case s[j] == '\\' && backslash:
+			j++
+		case s[j] == quote:
+			if j+1 < len(s) && s[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return len(s)
+}
+
+// isDashComment reports whether a -- comment starts at i (MySQL requires whitespace after it)
+func isDashComment(s []rune, i int, opts Options) bool {
+	if i+1 >= len(s) || s[i+1] != '-' {
+		return false
+	}
+	return !opts.HashComments || i+2 >= len(s) || unicode.IsSpace(s[i+2])
+}
+
+// indexRunes returns the index of sub in s at or after from, or -1
+func indexRunes(s []rune, from int, sub []rune) int {
+	for i := from; i+len(sub) <= len(s); i++ {
+		if string(s[i:i+len(sub)]) == string(sub) {
+			return i
+		}
+	}
+	return -1
+}
+
+// dollarTag returns the $tag$ opening at i, or "" if there is none
+func dollarTag(s []rune, i int) string {
+	if i > 0 && isIdentRune(s[i-1]) {
+		return ""
+	}
+	for j := i + 1; j < len(s); j++ {
+		if s[j] == '$' {
+			return string(s[i : j+1])
+		}
+		if !(s[j] == '_' || unicode.IsLetter(s[j]) || (j > i+1 && unicode.IsDigit(s[j]))) {
+			return ""
+		}
+	}
+	return ""
+}
@@ -0,0 +1,388 @@
+// ===========================================
+// SQL Statement Splitter
+// ===========================================
+// Splits SQL scripts into statements, aware of
+// quotes, comments and DELIMITER commands
+// ===========================================
+package sqlparse
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// maxStatementBytes guards against unterminated statements eating memory
+const maxStatementBytes = 64 * 1024 * 1024
+
+// Options describes the lexical rules of an engine
+type Options struct {
+	// BackslashEscapes allows \' inside strings (MySQL)
+	BackslashEscapes bool
+	// HashComments treats # as a line comment (MySQL)
+	HashComments bool
+	// DollarQuotes enables $tag$ ... $tag$ strings (PostgreSQL)
+	DollarQuotes bool
+	// EscapeStrings allows \' inside E'...' strings only (PostgreSQL)
+	EscapeStrings bool
+	// SchemaIsDatabase makes CREATE/DROP SCHEMA act on databases (MySQL)
+	SchemaIsDatabase bool
+}
+
+// MySQL returns the lexical rules of MySQL/MariaDB
+func MySQL() Options {
+	return Options{BackslashEscapes: true, HashComments: true, SchemaIsDatabase: true}
+}
+
+// Postgres returns the lexical rules of PostgreSQL
+func Postgres() Options {
+	return Options{DollarQuotes: true, EscapeStrings: true}
+}
+
+// Statement is a single SQL statement without its delimiter
+type Statement struct {
+	SQL  string
+	Line int // line on which the statement starts
+}
+
+// Splitter reads statements one at a time from a stream
+type Splitter struct {
+	r         *bufio.Reader
+	opts      Options
+	delimiter string
+	line      int
+	bytesRead int64
+	recent    [3]rune // last runes read, most recent first
+}
+
+// NewSplitter creates a splitter reading from r
+func NewSplitter(r io.Reader, opts Options) *Splitter {
+	return &Splitter{
+		r:         bufio.NewReaderSize(r, 64*1024),
+		opts:      opts,
+		delimiter: ";",
+		line:      1,
+	}
+}
+
+// Split returns all statements of a script
+func Split(script string, opts Options) ([]Statement, error) {
+	s := NewSplitter(strings.NewReader(script), opts)
+
+	var statements []Statement
+	for {
+		stmt, err := s.Next()
+		if err == io.EOF {
+			return statements, nil
+		}
+		if err != nil {
+			return statements, err
+		}
+		statements = append(statements, stmt)
+	}
+}
+
+// BytesRead returns how many bytes of input have been consumed
+func (s *Splitter) BytesRead() int64 {
+	return s.bytesRead
+}
+
+// Next returns the next non-empty statement, or io.EOF at the end of input
+func (s *Splitter) Next() (Statement, error) {
+	var buf strings.Builder
+	content := false
+	startLine := s.line
+
+	for {
+		// DELIMITER is a client command, only valid at the start of a statement
+		if !content && s.peekKeyword("delimiter") {
+			if err := s.readDelimiterCommand(); err != nil {
+				return Statement{}, err
+			}
+			buf.Reset()
+			startLine = s.line
+			continue
+		}
+
+		if s.peekString(s.delimiter) {
+			s.discard(len(s.delimiter))
+			if content {
+				return Statement{SQL: strings.TrimSpace(buf.String()), Line: startLine}, nil
+			}
+			buf.Reset()
+			startLine = s.line
+			continue
+		}
+
+		r, err := s.readRune()
+		if err == io.EOF {
+			if content {
+				return Statement{SQL: strings.TrimSpace(buf.String()), Line: startLine}, nil
+			}
+			return Statement{}, io.EOF
+		}
+		if err != nil {
+			return Statement{}, err
+		}
+
+		if buf.Len() > maxStatementBytes {
+			return Statement{}, fmt.Errorf("statement starting on line %d is too large", startLine)
+		}
+
+		switch {
+		case r == '\'' || r == '"' || r == '`':
+			if !content {
+				startLine = s.line
+			}
+			content = true
+			buf.WriteRune(r)
+			backslash := (s.opts.BackslashEscapes && r != '`') || (r == '\'' && s.afterEscapePrefix())
+			if err := s.readQuoted(r, backslash, &buf); err != nil {
+				return Statement{}, err
+			}
+
+		case r == '-' && s.isDashComment():
+			if err := s.skipLine(); err != nil {
+				return Statement{}, err
+			}
+			buf.WriteByte('\n')
+
+		case r == '#' && s.opts.HashComments:
+			if err := s.skipLine(); err != nil {
+				return Statement{}, err
+			}
+			buf.WriteByte('\n')
+
+		case r == '/' && s.peekString("*"):
+			// /*! ... */ and /*+ ... */ are executed by MySQL, plain comments are kept verbatim
+			executable := s.peekString("*!") || s.peekString("*+")
+			buf.WriteRune(r)
+			if err := s.readBlockComment(&buf); err != nil {
+				return Statement{}, err
+			}
+			if executable {
+				if !content {
+					startLine = s.line
+				}
+				content = true
+			}
+
+		case r == '$' && s.opts.DollarQuotes && !isIdentRune(s.recent[1]):
+			if !content {
+				startLine = s.line
+			}
+			content = true
+			buf.WriteRune(r)
+			if err := s.readDollarQuoted(&buf); err != nil {
+				return Statement{}, err
+			}
+
+		default:
+			if !content && !unicode.IsSpace(r) {
+				content = true
+				startLine = s.line
+			}
+			buf.WriteRune(r)
+		}
+	}
+}
+
+// readRune reads one rune and tracks position
+func (s *Splitter) readRune() (rune, error) {
+	r, size, err := s.r.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	s.bytesRead += int64(size)
+	if r == '\n' {
+		s.line++
+	}
+	s.recent[2], s.recent[1], s.recent[0] = s.recent[1], s.recent[0], r
+	return r, nil
+}
+
+// afterEscapePrefix reports whether the quote just read opens an E'...' string
+func (s *Splitter) afterEscapePrefix() bool {
+	return s.opts.EscapeStrings && (s.recent[1] == 'E' || s.recent[1] == 'e') && !isIdentRune(s.recent[2])
+}
+
+// peekString reports whether the input continues with str
+func (s *Splitter) peekString(str string) bool {
+	b, err := s.r.Peek(len(str))
+	return err == nil && string(b) == str
+}
+
+// peekKeyword reports whether the input continues with a keyword followed by whitespace
+func (s *Splitter) peekKeyword(keyword string) bool {
+	b, _ := s.r.Peek(len(keyword) + 1)
+	if len(b) < len(keyword)+1 {
+		return false
+	}
+	return strings.EqualFold(string(b[:len(keyword)]), keyword) && (b[len(keyword)] == ' ' || b[len(keyword)] == '\t')
+}
+
+// discard skips n bytes that were already peeked
+func (s *Splitter) discard(n int) {
+	for i := 0; i < n; i++ {
+		s.readRune()
+	}
+}
+
+// readDelimiterCommand consumes "DELIMITER x" and switches the delimiter
+func (s *Splitter) readDelimiterCommand() error {
+	line := s.line
+	var cmd strings.Builder
+	for {
+		r, err := s.readRune()
+		if err == io.EOF || r == '\n' {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		cmd.WriteRune(r)
+	}
+
+	fields := strings.Fields(cmd.String())
+	if len(fields) < 2 {
+		return fmt.Errorf("line %d: DELIMITER requires a value", line)
+	}
+	s.delimiter = fields[1]
+	return nil
+}
+
+// readQuoted copies a quoted string or identifier including the closing quote.
+// With backslash, \ escapes the next rune.
+func (s *Splitter) readQuoted(quote rune, backslash bool, buf *strings.Builder) error {
+	line := s.line
+	for {
+		r, err := s.readRune()
+		if err == io.EOF {
+			return fmt.Errorf("line %d: unterminated %c quote", line, quote)
+		}
+		if err != nil {
+			return err
+		}
+		buf.WriteRune(r)
+
+		if r == '\\' && backslash {
+			next, err := s.readRune()
+			if err != nil {
+				return fmt.Errorf("line %d: unterminated %c quote", line, quote)
+			}
+			buf.WriteRune(next)
+			continue
+		}
+
+		if r == quote {
+			// A doubled quote is an escaped quote
+			if s.peekString(string(quote)) {
+				next, _ := s.readRune()
+				buf.WriteRune(next)
+				continue
+			}
+			return nil
+		}
+	}
+}
+
+// readBlockComment copies a /* */ comment; the opening slash was already written
+func (s *Splitter) readBlockComment(buf *strings.Builder) error {
+	line := s.line
+	star, _ := s.readRune()
+	buf.WriteRune(star)
+
+	for {
+		r, err := s.readRune()
+		if err == io.EOF {
+			return fmt.Errorf("line %d: unterminated comment", line)
+		}
+		if err != nil {
+			return err
+		}
+		buf.WriteRune(r)
+		if r == '*' && s.peekString("/") {
+			slash, _ := s.readRune()
+			buf.WriteRune(slash)
+			return nil
+		}
+	}
+}
+
+// readDollarQuoted copies a $tag$...$tag$ string; the first $ was already written.
+// A $ not followed by a valid tag (e.g. $1 parameters) is left as is.
+func (s *Splitter) readDollarQuoted(buf *strings.Builder) error {
+	line := s.line
+
+	// Read the tag up to the closing $ of the opening delimiter
+	var tag strings.Builder
+	for i := 0; ; i++ {
+		b, err := s.r.Peek(i + 1)
+		if err != nil || len(b) <= i {
+			return nil
+		}
+		c := b[i]
+		if c == '$' {
+			break
+		}
+		if !(c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (i > 0 && c >= '0' && c <= '9')) {
+			return nil
+		}
+		tag.WriteByte(c)
+	}
+
+	opening := tag.String() + "$"
+	s.discard(len(opening))
+	buf.WriteString(opening)
+
+	closing := "$" + opening
+	for {
+		if s.peekString(closing) {
+			s.discard(len(closing))
+			buf.WriteString(closing)
+			return nil
+		}
+
+		r, err := s.readRune()
+		if err == io.EOF {
+			return fmt.Errorf("line %d: unterminated dollar-quoted string", line)
+		}
+		if err != nil {
+			return err
+		}
+		buf.WriteRune(r)
+	}
+}
+
+// isDashComment reports whether a '-' just read starts a -- comment.
+// MySQL requires whitespace after the dashes, so 1--1 stays arithmetic.
+func (s *Splitter) isDashComment() bool {
+	if !s.opts.HashComments {
+		return s.peekString("-")
+	}
+	b, _ := s.r.Peek(2)
+	if len(b) == 0 || b[0] != '-' {
+		return false
+	}
+	return len(b) == 1 || b[1] == ' ' || b[1] == '\t' || b[1] == '\n' || b[1] == '\r'
+}
+
+// skipLine discards input up to and including the end of the line
+func (s *Splitter) skipLine() error {
+	for {
+		r, err := s.readRune()
+		if err == io.EOF || r == '\n' {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// isIdentRune reports runes that can be part of an unquoted identifier
+func isIdentRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
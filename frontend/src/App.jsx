@@ -5,8 +5,8 @@
 // ===========================================
 
 import { useEffect, lazy, Suspense } from 'react'
-import { Routes, Route, Navigate } from 'react-router-dom'
-import useAuthStore from './stores/authStore'
+import { Routes, Route, Navigate, Outlet, useLocation } from 'react-router-dom'
+import useAuthStore, { PANEL_PERMISSIONS, hasPermission, homePath } from './stores/authStore'
 
 // Layouts
 import DashboardLayout from './components/DashboardLayout'
@@ -31,11 +31,18 @@ const AdminVolumes = lazy(() => import('./pages/admin/Volumes'))
 const StudentDatabases = lazy(() => import('./pages/student/Databases'))
 const StudentFeedback = lazy(() => import('./pages/student/Feedback'))
 const AdminFeedback = lazy(() => import('./pages/admin/Feedback'))
+const AdminAudit = lazy(() => import('./pages/admin/Audit'))
+const AdminClasses = lazy(() => import('./pages/admin/Classes'))
+const AdminRoles = lazy(() => import('./pages/admin/Roles'))
+const Account = lazy(() => import('./pages/Account'))
+const PasswordReset = lazy(() => import('./pages/PasswordReset'))
 
 // Protected Route Component
-function ProtectedRoute({ children, requireAdmin = false }) {
+// permissions lists what opens the page; any one of them is enough
+function ProtectedRoute({ children, permissions }) {
   const { token, user, isLoading } = useAuthStore()
-  const isAdmin = user?.role === 'superadmin' || user?.role === 'admin'
+  const location = useLocation()
+  const home = homePath(user)
   
   if (isLoading) {
     return <LoadingScreen />
@@ -45,13 +52,18 @@ function ProtectedRoute({ children, requireAdmin = false }) {
     return <Navigate to="/login" replace />
   }
   
-  if (requireAdmin && !isAdmin) {
-    return <Navigate to="/dashboard" replace />
+  // Accounts with a temporary password or missing required 2FA must fix that first
+  if ((user?.must_change_password || user?.two_factor_setup_required) && location.pathname !== '/account') {
+    return <Navigate to="/account" replace />
+  }
+  
+  if (permissions && !hasPermission(user, ...permissions)) {
+    return <Navigate to={home} replace />
   }
 
-  // If user is admin but accessing student dashboard, redirect them to admin dashboard
-  if (!requireAdmin && isAdmin && window.location.pathname === '/dashboard') {
-    return <Navigate to="/admin/dashboard" replace />
+  // Users with an admin page land there instead of the student dashboard
+  if (!permissions && home !== '/dashboard' && location.pathname === '/dashboard') {
+    return <Navigate to={home} replace />
   }
   
   return children
@@ -81,6 +93,8 @@ function App() {
         {/* Public Routes */}
         <Route path="/" element={<Landing />} />
         <Route path="/login" element={<Login />} />
+        <Route path="/forgot-password" element={<PasswordReset />} />
+        <Route path="/reset-password" element={<PasswordReset />} />
         
         {/* Student Routes */}
         <Route element={
@@ -95,24 +109,32 @@ function App() {
           <Route path="/databases" element={<StudentDatabases />} />
           <Route path="/projects/:id/database" element={<DatabaseManager />} />
           <Route path="/feedback" element={<StudentFeedback />} />
+          <Route path="/account" element={<Account />} />
         </Route>
         
         {/* Admin Routes */}
         <Route path="/admin" element={
-          <ProtectedRoute requireAdmin>
+          <ProtectedRoute permissions={Object.values(PANEL_PERMISSIONS).flat()}>
             <DashboardLayout isAdmin />
           </ProtectedRoute>
         }>
-          <Route index element={<Navigate to="/admin/dashboard" replace />} />
-          <Route path="dashboard" element={<AdminDashboard />} />
-          <Route path="users" element={<AdminUsers />} />
-          <Route path="projects" element={<AdminProjects />} />
-          <Route path="containers" element={<AdminContainers />} />
-          <Route path="images" element={<AdminImages />} />
-          <Route path="networks" element={<AdminNetworks />} />
-          <Route path="volumes" element={<AdminVolumes />} />
-          <Route path="settings" element={<AdminSettings />} />
-          <Route path="feedback" element={<AdminFeedback />} />
+          <Route index element={<Navigate to={homePath(user)} replace />} />
+          <Route path="classes" element={<ProtectedRoute permissions={PANEL_PERMISSIONS.classes}><AdminClasses /></ProtectedRoute>} />
+          <Route path="users" element={<ProtectedRoute permissions={PANEL_PERMISSIONS.users}><AdminUsers /></ProtectedRoute>} />
+          <Route path="projects" element={<ProtectedRoute permissions={PANEL_PERMISSIONS.projects}><AdminProjects /></ProtectedRoute>} />
+          <Route path="settings" element={<ProtectedRoute permissions={PANEL_PERMISSIONS.settings}><AdminSettings /></ProtectedRoute>} />
+          <Route path="feedback" element={<ProtectedRoute permissions={PANEL_PERMISSIONS.feedback}><AdminFeedback /></ProtectedRoute>} />
+          <Route path="audit" element={<ProtectedRoute permissions={PANEL_PERMISSIONS.audit}><AdminAudit /></ProtectedRoute>} />
+          <Route path="roles" element={<ProtectedRoute permissions={PANEL_PERMISSIONS.roles}><AdminRoles /></ProtectedRoute>} />
+
+          {/* Docker host pages */}
+          <Route element={<ProtectedRoute permissions={PANEL_PERMISSIONS.dashboard}><Outlet /></ProtectedRoute>}>
+            <Route path="dashboard" element={<AdminDashboard />} />
+            <Route path="containers" element={<AdminContainers />} />
+            <Route path="images" element={<AdminImages />} />
+            <Route path="networks" element={<AdminNetworks />} />
+            <Route path="volumes" element={<AdminVolumes />} />
+          </Route>
         </Route>
         
         {/* Fallback */}
@@ -5,7 +5,7 @@
 // ===========================================
 
 import { Outlet, NavLink, useNavigate } from 'react-router-dom'
-import useAuthStore from '../stores/authStore'
+import useAuthStore, { PANEL_PERMISSIONS, hasPermission, homePath } from '../stores/authStore'
 
 // Icons (inline SVG for simplicity)
 const Icons = {
@@ -30,6 +30,21 @@ const Icons = {
       <circle cx="12" cy="12" r="3" />
     </svg>
   ),
+  Audit: () => (
+    <svg className="w-5 h-5" fill="none" stroke="currentColor" strokeWidth={1.5} viewBox="0 0 24 24">
+      <path strokeLinecap="round" strokeLinejoin="round" d="M9 12h3.75M9 15h3.75M9 18h3.75m3 .75H18a2.25 2.25 0 002.25-2.25V6.108c0-1.135-.845-2.098-1.976-2.192a48.424 48.424 0 00-1.123-.08m-5.801 0c-.065.21-.1.433-.1.664 0 .414.336.75.75.75h4.5a.75.75 0 00.75-.75 2.25 2.25 0 00-.1-.664m-5.8 0A2.251 2.251 0 0113.5 2.25H15c1.012 0 1.867.668 2.15 1.586m-5.8 0c-.376.023-.75.05-1.124.08C9.095 4.01 8.25 4.973 8.25 6.108V8.25m0 0H4.875c-.621 0-1.125.504-1.125 1.125v11.25c0 .621.504 1.125 1.125 1.125h9.75c.621 0 1.125-.504 1.125-1.125V9.375c0-.621-.504-1.125-1.125-1.125H8.25z" />
+    </svg>
+  ),
+  Roles: () => (
+    <svg className="w-5 h-5" fill="none" stroke="currentColor" strokeWidth={1.5} viewBox="0 0 24 24">
+      <path strokeLinecap="round" strokeLinejoin="round" d="M9 12.75L11.25 15 15 9.75m-3-7.036A11.959 11.959 0 013.598 6 11.99 11.99 0 003 9.749c0 5.592 3.824 10.29 9 11.623 5.176-1.332 9-6.03 9-11.622 0-1.31-.21-2.571-.598-3.751h-.152c-3.196 0-6.1-1.248-8.25-3.285z" />
+    </svg>
+  ),
+  Classes: () => (
+    <svg className="w-5 h-5" fill="none" stroke="currentColor" strokeWidth={1.5} viewBox="0 0 24 24">
+      <path strokeLinecap="round" strokeLinejoin="round" d="M4.26 10.147a60.436 60.436 0 00-.491 6.347A48.627 48.627 0 0112 20.904a48.627 48.627 0 018.232-4.41 60.46 60.46 0 00-.491-6.347m-15.482 0a50.57 50.57 0 00-2.658-.813A59.905 59.905 0 0112 3.493a59.902 59.902 0 0110.399 5.84c-.896.248-1.783.52-2.658.814m-15.482 0A50.697 50.697 0 0112 13.489a50.702 50.702 0 017.74-3.342M6.75 15a.75.75 0 100-1.5.75.75 0 000 1.5zm0 0v-3.675A55.378 55.378 0 0112 8.443m-7.007 11.55A5.981 5.981 0 006.75 15.75v-1.5" />
+    </svg>
+  ),
   Logout: () => (
     <svg className="w-5 h-5" fill="none" stroke="currentColor" strokeWidth={1.5} viewBox="0 0 24 24">
       <path strokeLinecap="round" strokeLinejoin="round" d="M15.75 9V5.25A2.25 2.25 0 0013.5 3h-6a2.25 2.25 0 00-2.25 2.25v13.5A2.25 2.25 0 007.5 21h6a2.25 2.25 0 002.25-2.25V15m3 0l3-3m0 0l-3-3m3 3H9" />
@@ -82,16 +97,22 @@ function DashboardLayout({ isAdmin = false }) {
     navigate('/login')
   }
   
-  // Navigation items based on role
+  const can = (page) => hasPermission(user, ...PANEL_PERMISSIONS[page])
+  const hasAdminPanel = homePath(user) !== '/dashboard'
+  
+  // Navigation items based on the permissions of the user's role
   const navItems = isAdmin
     ? {
         management: [
-          { to: '/admin/dashboard', icon: Icons.Dashboard, label: 'Dashboard' },
-          { to: '/admin/users', icon: Icons.Users, label: 'Users' },
-          { to: '/admin/projects', icon: Icons.Projects, label: 'Projects' },
-          { to: '/admin/settings', icon: Icons.Settings, label: 'Settings' },
-        ],
-        resources: [
+          can('dashboard') && { to: '/admin/dashboard', icon: Icons.Dashboard, label: 'Dashboard' },
+          can('classes') && { to: '/admin/classes', icon: Icons.Classes, label: 'Classes' },
+          can('users') && { to: '/admin/users', icon: Icons.Users, label: hasPermission(user, 'user.read.any') ? 'Users' : 'Students' },
+          can('projects') && { to: '/admin/projects', icon: Icons.Projects, label: 'Projects' },
+          can('roles') && { to: '/admin/roles', icon: Icons.Roles, label: 'Roles' },
+          can('settings') && { to: '/admin/settings', icon: Icons.Settings, label: 'Settings' },
+          can('audit') && { to: '/admin/audit', icon: Icons.Audit, label: 'Audit Log' },
+        ].filter(Boolean),
+        resources: can('dashboard') && [
           { to: '/admin/containers', icon: Icons.Containers, label: 'Containers' },
           { to: '/admin/images', icon: Icons.Images, label: 'Images' },
           { to: '/admin/networks', icon: Icons.Networks, label: 'Networks' },
@@ -204,7 +225,7 @@ function DashboardLayout({ isAdmin = false }) {
           
           <div className="space-y-1">
             {/* Switch to Admin (if admin viewing student dashboard) */}
-            {!isAdmin && (user?.role === 'superadmin' || user?.role === 'admin') && (
+            {!isAdmin && hasAdminPanel && (
               <NavLink
                 to="/admin"
                 className="flex items-center gap-3 px-4 py-2 rounded-lg text-slate-500 hover:text-slate-300 transition-colors text-xs font-bold uppercase tracking-widest"
@@ -225,6 +246,14 @@ function DashboardLayout({ isAdmin = false }) {
               </NavLink>
             )}
             
+            <NavLink
+              to="/account"
+              className="flex items-center gap-3 px-4 py-2 rounded-lg text-slate-500 hover:text-slate-300 transition-colors text-xs font-bold uppercase tracking-widest"
+            >
+              <Icons.Users />
+              Account
+            </NavLink>
+            
             <button
               onClick={handleLogout}
               className="flex items-center gap-3 px-4 py-2 rounded-lg text-slate-600 hover:text-red-400 transition-all w-full text-xs font-bold uppercase tracking-widest group"
@@ -243,7 +272,7 @@ function DashboardLayout({ isAdmin = false }) {
         {/* Top bar with Inbox icon */}
         <header className="h-16 flex items-center justify-end px-8 bg-transparent border-b border-white/[0.02] backdrop-blur-sm sticky top-0 z-40">
            <NavLink 
-            to={isAdmin ? "/admin/feedback" : "/feedback"}
+            to={isAdmin && can('feedback') ? "/admin/feedback" : "/feedback"}
             className={({ isActive }) => 
               `relative p-2.5 rounded-xl transition-all duration-300 ${
                 isActive 
@@ -251,7 +280,7 @@ function DashboardLayout({ isAdmin = false }) {
                 : 'text-slate-500 hover:text-white hover:bg-white/5 border border-transparent'
               }`
             }
-            title={isAdmin ? "Feedback Inbox" : "Contact Support"}
+            title={isAdmin && can('feedback') ? "Feedback Inbox" : "Contact Support"}
            >
               <Icons.Feedback />
               {/* Pulsing notification dot */}
@@ -0,0 +1,370 @@
+// ===========================================
+// Account Page
+// ===========================================
+// Password, two-factor and signed-in devices of the current user
+// ===========================================
+
+import { useState, useEffect } from 'react'
+import toast from 'react-hot-toast'
+import { authAPI, classesAPI } from '../services/api'
+import useAuthStore, { hasPermission } from '../stores/authStore'
+
+function Account() {
+  const { user, logout, changePassword, enableTwoFactor, fetchUser } = useAuthStore()
+  const [sessions, setSessions] = useState([])
+  const [isLoading, setIsLoading] = useState(true)
+  const [isSaving, setIsSaving] = useState(false)
+  const [passwords, setPasswords] = useState({ current: '', next: '', confirm: '' })
+  const [twoFactor, setTwoFactor] = useState(null)
+  const [setup, setSetup] = useState(null)
+  const [code, setCode] = useState('')
+  const [recoveryCodes, setRecoveryCodes] = useState(null)
+  const [classes, setClasses] = useState(null)
+  const [joinCode, setJoinCode] = useState('')
+  const canJoin = hasPermission(user, 'class.join')
+
+  useEffect(() => {
+    fetchSessions()
+    fetchTwoFactor()
+  }, [])
+
+  useEffect(() => {
+    if (canJoin && !user?.must_change_password && !user?.two_factor_setup_required) {
+      fetchClasses()
+    }
+  }, [canJoin, user?.must_change_password, user?.two_factor_setup_required])
+
+  const fetchClasses = async () => {
+    try {
+      const res = await classesAPI.mine()
+      setClasses(res.data)
+    } catch (error) {
+    }
+  }
+
+  const handleJoinClass = async (e) => {
+    e.preventDefault()
+    try {
+      const res = await classesAPI.join(joinCode)
+      toast.success(res.data.message)
+      setJoinCode('')
+      fetchClasses()
+    } catch (error) {
+      toast.error(error.response?.data?.error || 'Failed to join class')
+    }
+  }
+
+  const fetchTwoFactor = async () => {
+    try {
+      const res = await authAPI.twoFactorStatus()
+      setTwoFactor(res.data)
+    } catch (error) {
+      toast.error('Failed to load two-factor status')
+    }
+  }
+
+  const handleTwoFactorSetup = async () => {
+    try {
+      const res = await authAPI.twoFactorSetup()
+      setSetup(res.data)
+      setCode('')
+    } catch (error) {
+      toast.error(error.response?.data?.error || 'Failed to start setup')
+    }
+  }
+
+  const handleTwoFactorEnable = async (e) => {
+    e.preventDefault()
+    setIsSaving(true)
+    try {
+      setRecoveryCodes(await enableTwoFactor(code))
+      setSetup(null)
+      setCode('')
+      toast.success('Two-factor authentication enabled')
+      fetchTwoFactor()
+    } catch (error) {
+      toast.error(error.response?.data?.error || 'Failed to enable two-factor authentication')
+    } finally {
+      setIsSaving(false)
+    }
+  }
+
+  const handleTwoFactorDisable = async () => {
+    if (!code) return toast.error('Enter a current code first')
+    if (!confirm('Turn off two-factor authentication?')) return
+    try {
+      await authAPI.twoFactorDisable(code)
+      setCode('')
+      setRecoveryCodes(null)
+      toast.success('Two-factor authentication disabled')
+      fetchTwoFactor()
+      fetchUser()
+    } catch (error) {
+      toast.error(error.response?.data?.error || 'Failed to disable two-factor authentication')
+    }
+  }
+
+  const handleRegenerateCodes = async () => {
+    if (!code) return toast.error('Enter a current code first')
+    try {
+      const res = await authAPI.twoFactorRecoveryCodes(code)
+      setRecoveryCodes(res.data.recovery_codes)
+      setCode('')
+      toast.success('New recovery codes generated')
+      fetchTwoFactor()
+    } catch (error) {
+      toast.error(error.response?.data?.error || 'Failed to generate recovery codes')
+    }
+  }
+
+  const fetchSessions = async () => {
+    try {
+      const res = await authAPI.sessions()
+      setSessions(res.data || [])
+    } catch (error) {
+      toast.error('Failed to load sessions')
+    } finally {
+      setIsLoading(false)
+    }
+  }
+
+  const handlePasswordChange = async (e) => {
+    e.preventDefault()
+    if (passwords.next !== passwords.confirm) {
+      return toast.error('Passwords do not match')
+    }
+
+    setIsSaving(true)
+    try {
+      await changePassword(passwords.current, passwords.next)
+      setPasswords({ current: '', next: '', confirm: '' })
+      toast.success('Password changed, other devices were signed out')
+      fetchSessions()
+    } catch (error) {
+      toast.error(error.response?.data?.error || 'Failed to change password')
+    } finally {
+      setIsSaving(false)
+    }
+  }
+
+  const handleRevoke = async (session) => {
+    if (!confirm(session.current ? 'Sign out of this device?' : `Sign out ${session.device}?`)) return
+    try {
+      await authAPI.revokeSession(session.id)
+      if (session.current) {
+        await logout()
+        return
+      }
+      toast.success('Session revoked')
+      fetchSessions()
+    } catch (error) {
+      toast.error(error.response?.data?.error || 'Failed to revoke session')
+    }
+  }
+
+  return (
+    <div className="max-w-4xl mx-auto space-y-8">
+      <div>
+        <h1 className="text-3xl font-bold text-white">Account</h1>
+        <p className="text-slate-400 mt-2">{user?.name} · {user?.email}</p>
+      </div>
+
+      <form onSubmit={handlePasswordChange} className="card space-y-4">
+        <h2 className="text-lg font-semibold text-white">Change Password</h2>
+        {user?.must_change_password && (
+          <p className="p-3 rounded-lg bg-yellow-500/10 border border-yellow-500/30 text-yellow-400 text-sm">
+            Your password was set by an administrator. Choose your own password to continue.
+          </p>
+        )}
+        <input
+          type="password"
+          value={passwords.current}
+          onChange={(e) => setPasswords({ ...passwords, current: e.target.value })}
+          className="input w-full"
+          placeholder="Current password"
+          autoComplete="current-password"
+          required
+        />
+        <input
+          type="password"
+          value={passwords.next}
+          onChange={(e) => setPasswords({ ...passwords, next: e.target.value })}
+          className="input w-full"
+          placeholder="New password"
+          autoComplete="new-password"
+          required
+        />
+        <input
+          type="password"
+          value={passwords.confirm}
+          onChange={(e) => setPasswords({ ...passwords, confirm: e.target.value })}
+          className="input w-full"
+          placeholder="Confirm new password"
+          autoComplete="new-password"
+          required
+        />
+        <button type="submit" disabled={isSaving} className="btn btn-primary text-sm disabled:opacity-50">
+          {isSaving ? 'Saving...' : 'Change Password'}
+        </button>
+      </form>
+
+      <div className="card space-y-4">
+        <div className="flex items-center justify-between">
+          <h2 className="text-lg font-semibold text-white">Two-Factor Authentication</h2>
+          {twoFactor && (
+            <span className={`badge ${twoFactor.enabled ? 'bg-green-500/20 text-green-400' : 'bg-slate-500/20 text-slate-400'}`}>
+              {twoFactor.enabled ? 'Enabled' : 'Disabled'}
+            </span>
+          )}
+        </div>
+        {user?.two_factor_setup_required && (
+          <p className="p-3 rounded-lg bg-yellow-500/10 border border-yellow-500/30 text-yellow-400 text-sm">
+            Two-factor authentication is required for your role. Set it up to continue.
+          </p>
+        )}
+
+        {recoveryCodes && (
+          <div className="p-4 rounded-lg bg-slate-900 border border-slate-700 space-y-2">
+            <p className="text-sm text-slate-300">
+              Save these recovery codes somewhere safe. Each can be used once if you lose your device, and they will not be shown again.
+            </p>
+            <div className="grid grid-cols-2 gap-2 font-mono text-white">
+              {recoveryCodes.map((recoveryCode) => (
+                <span key={recoveryCode}>{recoveryCode}</span>
+              ))}
+            </div>
+          </div>
+        )}
+
+        {twoFactor && !twoFactor.enabled && !setup && (
+          <button onClick={handleTwoFactorSetup} className="btn btn-primary text-sm">
+            Set Up Authenticator App
+          </button>
+        )}
+
+        {setup && (
+          <form onSubmit={handleTwoFactorEnable} className="space-y-4">
+            <p className="text-sm text-slate-300">
+              Scan this QR code with your authenticator app, then enter the code it shows.
+            </p>
+            <img src={setup.qr_code} alt="Authenticator QR code" className="w-48 h-48 bg-white rounded-lg" />
+            <p className="text-xs text-slate-400">
+              Can't scan it? Enter this key manually: <span className="font-mono text-slate-200">{setup.secret}</span>
+            </p>
+            <input
+              type="text"
+              inputMode="numeric"
+              autoComplete="one-time-code"
+              value={code}
+              onChange={(e) => setCode(e.target.value)}
+              className="input w-full"
+              placeholder="123456"
+              required
+            />
+            <button type="submit" disabled={isSaving} className="btn btn-primary text-sm disabled:opacity-50">
+              {isSaving ? 'Verifying...' : 'Enable'}
+            </button>
+          </form>
+        )}
+
+        {twoFactor?.enabled && (
+          <div className="space-y-4">
+            <p className="text-sm text-slate-400">
+              {twoFactor.recovery_codes_remaining} recovery codes left.
+            </p>
+            <input
+              type="text"
+              value={code}
+              onChange={(e) => setCode(e.target.value)}
+              className="input w-full"
+              placeholder="Current code"
+            />
+            <div className="flex gap-2">
+              <button onClick={handleRegenerateCodes} className="btn btn-secondary text-sm">
+                New Recovery Codes
+              </button>
+              {!twoFactor.required && (
+                <button onClick={handleTwoFactorDisable} className="btn btn-secondary text-sm">
+                  Disable
+                </button>
+              )}
+            </div>
+          </div>
+        )}
+      </div>
+
+      {classes && (
+        <div className="card space-y-4">
+          <h2 className="text-lg font-semibold text-white">Classes</h2>
+          {classes.classes?.length > 0 ? (
+            <div className="divide-y divide-slate-700">
+              {classes.classes.map((cls) => (
+                <div key={cls.id} className="py-3">
+                  <p className="text-white font-medium">{cls.name}</p>
+                  <p className="text-slate-400 text-xs">
+                    {(cls.teachers || []).map((t) => t.name).join(', ') || 'No teacher assigned'}
+                  </p>
+                </div>
+              ))}
+            </div>
+          ) : (
+            <p className="text-slate-400 text-sm">You are not in any class yet</p>
+          )}
+          {classes.limits && (
+            <p className="text-slate-400 text-xs">
+              Up to {classes.limits.max_projects} projects · {classes.limits.cpu_limit_percent}% CPU · {classes.limits.memory_limit_mb} MB memory
+              {classes.limits.project_expiry_days > 0 && ` · projects expire after ${classes.limits.project_expiry_days} days`}
+            </p>
+          )}
+          <form onSubmit={handleJoinClass} className="flex gap-2">
+            <input
+              type="text"
+              value={joinCode}
+              onChange={(e) => setJoinCode(e.target.value.toUpperCase())}
+              className="input flex-1 font-mono tracking-widest"
+              placeholder="Join code"
+              maxLength={20}
+              required
+            />
+            <button type="submit" className="btn btn-primary text-sm">Join Class</button>
+          </form>
+        </div>
+      )}
+
+      <div className="card">
+        <h2 className="text-lg font-semibold text-white mb-4">Active Sessions</h2>
+        {isLoading ? (
+          <p className="text-slate-400 text-sm">Loading...</p>
+        ) : sessions.length === 0 ? (
+          <p className="text-slate-400 text-sm">No active sessions</p>
+        ) : (
+          <div className="divide-y divide-slate-700">
+            {sessions.map((session) => (
+              <div key={session.id} className="flex items-center justify-between py-3 gap-4">
+                <div className="min-w-0">
+                  <p className="text-white font-medium">
+                    {session.device}
+                    {session.current && (
+                      <span className="badge bg-green-500/20 text-green-400 ml-2">This device</span>
+                    )}
+                  </p>
+                  <p className="text-slate-400 text-xs truncate" title={session.user_agent}>
+                    {session.ip_address} · last active {new Date(session.last_used_at).toLocaleString()}
+                  </p>
+                </div>
+                <button
+                  onClick={() => handleRevoke(session)}
+                  className="btn btn-secondary text-sm"
+                >
+                  Sign out
+                </button>
+              </div>
+            ))}
+          </div>
+        )}
+      </div>
+    </div>
+  )
+}
+
+export default Account
@@ -6,7 +6,7 @@
 
 import { useEffect } from 'react'
 import { Link, useNavigate } from 'react-router-dom'
-import useAuthStore from '../stores/authStore'
+import useAuthStore, { homePath } from '../stores/authStore'
 
 const features = [
   {
@@ -79,8 +79,7 @@ export default function Landing() {
   // If already logged in, redirect to dashboard
   useEffect(() => {
     if (token) {
-      const isAdmin = user?.role === 'superadmin' || user?.role === 'admin'
-      navigate(isAdmin ? '/admin/dashboard' : '/dashboard', { replace: true })
+      navigate(homePath(user), { replace: true })
     }
   }, [token, user, navigate])
 
@@ -3,41 +3,72 @@
 // ===========================================
 
 import { useState, useEffect } from 'react'
-import { useNavigate } from 'react-router-dom'
+import { useNavigate, useSearchParams, Link } from 'react-router-dom'
 import toast from 'react-hot-toast'
-import useAuthStore from '../stores/authStore'
+import useAuthStore, { homePath } from '../stores/authStore'
+import { authAPI } from '../services/api'
 
 function Login() {
   const [email, setEmail] = useState('')
   const [password, setPassword] = useState('')
   const [isLoading, setIsLoading] = useState(false)
+  const [providers, setProviders] = useState({ password: true, oidc: false, ldap: false })
+  const [challengeToken, setChallengeToken] = useState('')
+  const [code, setCode] = useState('')
+  const [searchParams] = useSearchParams()
   
   const login = useAuthStore((state) => state.login)
+  const completeChallenge = useAuthStore((state) => state.completeChallenge)
+  const completeSSO = useAuthStore((state) => state.completeSSO)
   const token = useAuthStore((state) => state.token)
   const user = useAuthStore((state) => state.user)
   const navigate = useNavigate()
 
   useEffect(() => {
     if (token && user) {
-      const isAdmin = user.role === 'superadmin' || user.role === 'admin'
-      navigate(isAdmin ? '/admin/dashboard' : '/dashboard', { replace: true })
+      navigate(homePath(user), { replace: true })
     }
   }, [token, user, navigate])
+
+  useEffect(() => {
+    authAPI.providers()
+      .then((response) => setProviders(response.data))
+      .catch(() => {})
+
+    // Back from the identity provider
+    if (searchParams.get('sso_error')) {
+      toast.error(searchParams.get('sso_error'))
+    } else if (searchParams.get('challenge')) {
+      setChallengeToken(searchParams.get('challenge'))
+    } else if (searchParams.get('sso') === 'success') {
+      completeSSO()
+        .then((user) => toast.success(`Welcome back, ${user.name}!`))
+        .catch(() => toast.error('Single sign-on failed'))
+    }
+  }, [])
+  
+  const finishLogin = (user) => {
+    toast.success(`Welcome back, ${user.name}!`)
+    
+    // Redirect based on role
+    if (user.must_change_password || user.two_factor_setup_required) {
+      navigate('/account')
+    } else {
+      navigate(homePath(user))
+    }
+  }
   
   const handleSubmit = async (e) => {
     e.preventDefault()
     setIsLoading(true)
     
     try {
-      const user = await login(email, password)
-      toast.success(`Welcome back, ${user.name}!`)
-      
-      // Redirect based on role
-      if (user.role === 'superadmin' || user.role === 'admin') {
-        navigate('/admin/dashboard')
-      } else {
-        navigate('/dashboard')
+      const result = await login(email, password)
+      if (result.two_factor_required) {
+        setChallengeToken(result.challenge_token)
+        return
       }
+      finishLogin(result)
     } catch (error) {
       toast.error(error.response?.data?.error || 'Login failed')
     } finally {
@@ -45,6 +76,24 @@ function Login() {
     }
   }
   
+  const handleChallenge = async (e) => {
+    e.preventDefault()
+    setIsLoading(true)
+    
+    try {
+      finishLogin(await completeChallenge(challengeToken, code))
+    } catch (error) {
+      toast.error(error.response?.data?.error || 'Verification failed')
+      // The challenge is gone after too many attempts or when it expires
+      if (error.response?.data?.error !== 'invalid two-factor code') {
+        setChallengeToken('')
+        setCode('')
+      }
+    } finally {
+      setIsLoading(false)
+    }
+  }
+  
   return (
     <div className="min-h-screen flex items-center justify-center p-4 bg-gradient-to-br from-slate-900 via-slate-800 to-slate-900">
       {/* Background decoration */}
@@ -63,15 +112,55 @@ function Login() {
           <p className="text-slate-400 mt-2">Student Hosting Platform</p>
         </div>
         
-        {/* Login Form */}
+        {challengeToken ? (
+        <form onSubmit={handleChallenge} className="card p-8 space-y-6">
+          <div>
+            <label htmlFor="code" className="block text-sm font-medium text-slate-300 mb-2">
+              Authentication Code
+            </label>
+            <input
+              id="code"
+              type="text"
+              inputMode="numeric"
+              autoComplete="one-time-code"
+              value={code}
+              onChange={(e) => setCode(e.target.value)}
+              className="w-full px-4 py-3 border"
+              placeholder="123456"
+              required
+              autoFocus
+            />
+            <p className="text-sm text-slate-400 mt-2">
+              Enter the code from your authenticator app, or one of your recovery codes.
+            </p>
+          </div>
+          
+          <button
+            type="submit"
+            disabled={isLoading}
+            className="btn btn-primary w-full py-3 text-lg font-semibold disabled:opacity-50 disabled:cursor-not-allowed"
+          >
+            {isLoading ? 'Verifying...' : 'Verify'}
+          </button>
+          
+          <button
+            type="button"
+            onClick={() => { setChallengeToken(''); setCode('') }}
+            className="btn btn-secondary w-full py-3"
+          >
+            Back to sign in
+          </button>
+        </form>
+        ) : (
+        /* Login Form */
         <form onSubmit={handleSubmit} className="card p-8 space-y-6">
           <div>
             <label htmlFor="email" className="block text-sm font-medium text-slate-300 mb-2">
-              Email Address
+              {providers.ldap ? 'Email or Username' : 'Email Address'}
             </label>
             <input
               id="email"
-              type="email"
+              type={providers.ldap ? 'text' : 'email'}
               value={email}
               onChange={(e) => setEmail(e.target.value)}
               className="w-full px-4 py-3 border"
@@ -94,6 +183,11 @@ function Login() {
               placeholder="••••••••"
               required
             />
+            <div className="text-right mt-2">
+              <Link to="/forgot-password" className="text-sm text-primary-400 hover:text-primary-300">
+                Forgot password?
+              </Link>
+            </div>
           </div>
           
           <button
@@ -113,7 +207,17 @@ function Login() {
               'Sign In'
             )}
           </button>
+          
+          {providers.oidc && (
+            <a
+              href="/api/auth/oidc/login"
+              className="btn btn-secondary w-full py-3 text-center block"
+            >
+              Sign in with SSO
+            </a>
+          )}
         </form>
+        )}
         
         {/* Footer */}
         <p className="text-center mt-6 text-slate-500 text-sm">
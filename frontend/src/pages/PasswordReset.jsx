@@ -0,0 +1,119 @@
+// ===========================================
+// Password Reset Page
+// ===========================================
+// Requests a reset link, or sets a new
+// password when opened from the link
+// ===========================================
+
+import { useState } from 'react'
+import { Link, useNavigate, useSearchParams } from 'react-router-dom'
+import toast from 'react-hot-toast'
+import { authAPI } from '../services/api'
+
+function PasswordReset() {
+  const [searchParams] = useSearchParams()
+  const token = searchParams.get('token')
+  const navigate = useNavigate()
+
+  const [email, setEmail] = useState('')
+  const [password, setPassword] = useState('')
+  const [confirm, setConfirm] = useState('')
+  const [isLoading, setIsLoading] = useState(false)
+  const [sent, setSent] = useState(false)
+
+  const handleRequest = async (e) => {
+    e.preventDefault()
+    setIsLoading(true)
+    try {
+      await authAPI.forgotPassword(email)
+      setSent(true)
+    } catch (error) {
+      toast.error(error.response?.data?.error || 'Request failed')
+    } finally {
+      setIsLoading(false)
+    }
+  }
+
+  const handleReset = async (e) => {
+    e.preventDefault()
+    if (password !== confirm) {
+      return toast.error('Passwords do not match')
+    }
+
+    setIsLoading(true)
+    try {
+      await authAPI.resetPassword(token, password)
+      toast.success('Password reset, please sign in')
+      navigate('/login')
+    } catch (error) {
+      toast.error(error.response?.data?.error || 'Reset failed')
+    } finally {
+      setIsLoading(false)
+    }
+  }
+
+  return (
+    <div className="min-h-screen flex items-center justify-center p-4 bg-gradient-to-br from-slate-900 via-slate-800 to-slate-900">
+      <div className="relative w-full max-w-md">
+        <div className="text-center mb-8">
+          <h1 className="text-3xl font-bold text-white">{token ? 'Choose a New Password' : 'Reset Password'}</h1>
+          <p className="text-slate-400 mt-2">
+            {token ? 'The link can be used once.' : 'We will email you a link to choose a new password.'}
+          </p>
+        </div>
+
+        {token ? (
+          <form onSubmit={handleReset} className="card p-8 space-y-6">
+            <input
+              type="password"
+              value={password}
+              onChange={(e) => setPassword(e.target.value)}
+              className="w-full px-4 py-3 border"
+              placeholder="New password"
+              autoComplete="new-password"
+              required
+              autoFocus
+            />
+            <input
+              type="password"
+              value={confirm}
+              onChange={(e) => setConfirm(e.target.value)}
+              className="w-full px-4 py-3 border"
+              placeholder="Confirm new password"
+              autoComplete="new-password"
+              required
+            />
+            <button type="submit" disabled={isLoading} className="btn btn-primary w-full py-3 disabled:opacity-50">
+              {isLoading ? 'Saving...' : 'Set Password'}
+            </button>
+          </form>
+        ) : sent ? (
+          <div className="card p-8 text-center text-slate-300">
+            If an account exists for {email}, a reset link is on its way. Check your inbox.
+          </div>
+        ) : (
+          <form onSubmit={handleRequest} className="card p-8 space-y-6">
+            <input
+              type="email"
+              value={email}
+              onChange={(e) => setEmail(e.target.value)}
+              className="w-full px-4 py-3 border"
+              placeholder="you@example.com"
+              required
+              autoFocus
+            />
+            <button type="submit" disabled={isLoading} className="btn btn-primary w-full py-3 disabled:opacity-50">
+              {isLoading ? 'Sending...' : 'Send Reset Link'}
+            </button>
+          </form>
+        )}
+
+        <p className="text-center mt-6 text-slate-500 text-sm">
+          <Link to="/login" className="text-primary-400 hover:text-primary-300">Back to sign in</Link>
+        </p>
+      </div>
+    </div>
+  )
+}
+
+export default PasswordReset
@@ -0,0 +1,223 @@
+// ===========================================
+// Admin Audit Log Page
+// ===========================================
+
+import { useState, useEffect, Fragment } from 'react'
+import toast from 'react-hot-toast'
+import { auditAPI } from '../../services/api'
+
+const PAGE_SIZE = 50
+
+function AdminAudit() {
+  const [events, setEvents] = useState([])
+  const [total, setTotal] = useState(0)
+  const [page, setPage] = useState(1)
+  const [isLoading, setIsLoading] = useState(true)
+  const [expanded, setExpanded] = useState(null)
+  const [filters, setFilters] = useState({
+    actor: '',
+    action: '',
+    target_type: '',
+    result: '',
+    from: '',
+    to: '',
+  })
+
+  useEffect(() => {
+    fetchEvents()
+  }, [page, filters])
+
+  // Empty filters are left out of the query string
+  const activeFilters = () =>
+    Object.fromEntries(Object.entries(filters).filter(([, value]) => value !== ''))
+
+  const fetchEvents = async () => {
+    setIsLoading(true)
+    try {
+      const response = await auditAPI.list({ ...activeFilters(), page, limit: PAGE_SIZE })
+      setEvents(response.data.data || [])
+      setTotal(response.data.total || 0)
+    } catch (error) {
+      toast.error(error.response?.data?.error || 'Failed to load audit log')
+    } finally {
+      setIsLoading(false)
+    }
+  }
+
+  const handleFilter = (key, value) => {
+    setPage(1)
+    setFilters((f) => ({ ...f, [key]: value }))
+  }
+
+  const handleExport = async () => {
+    try {
+      const res = await auditAPI.export(activeFilters())
+      const blob = new Blob([res.data], { type: 'text/csv' })
+      const url = window.URL.createObjectURL(blob)
+      const a = document.createElement('a')
+      a.href = url
+      a.download = `audit_${new Date().toISOString().slice(0, 10)}.csv`
+      a.click()
+      window.URL.revokeObjectURL(url)
+    } catch (error) {
+      toast.error('Export failed')
+    }
+  }
+
+  const totalPages = Math.ceil(total / PAGE_SIZE)
+
+  return (
+    <div className="space-y-6">
+      <div className="flex justify-between items-center">
+        <div>
+          <h1 className="text-2xl font-bold text-white">Audit Log</h1>
+          <p className="text-slate-400">Administrative and destructive actions</p>
+        </div>
+        <button onClick={handleExport} className="btn btn-secondary">
+          Export CSV
+        </button>
+      </div>
+
+      {/* Filters */}
+      <div className="grid grid-cols-2 md:grid-cols-6 gap-4">
+        <input
+          type="text"
+          placeholder="Actor email..."
+          value={filters.actor}
+          onChange={(e) => handleFilter('actor', e.target.value)}
+          className="px-4 py-2 border"
+        />
+        <input
+          type="text"
+          placeholder="Action..."
+          value={filters.action}
+          onChange={(e) => handleFilter('action', e.target.value)}
+          className="px-4 py-2 border"
+        />
+        <select
+          value={filters.target_type}
+          onChange={(e) => handleFilter('target_type', e.target.value)}
+          className="px-4 py-2 border"
+        >
+          <option value="">All Targets</option>
+          <option value="project">Projects</option>
+          <option value="user">Users</option>
+          <option value="setting">Settings</option>
+          <option value="database">Databases</option>
+          <option value="feedback">Feedback</option>
+          <option value="system">System</option>
+        </select>
+        <select
+          value={filters.result}
+          onChange={(e) => handleFilter('result', e.target.value)}
+          className="px-4 py-2 border"
+        >
+          <option value="">All Results</option>
+          <option value="success">Success</option>
+          <option value="failure">Failure</option>
+        </select>
+        <input
+          type="date"
+          value={filters.from}
+          onChange={(e) => handleFilter('from', e.target.value)}
+          className="px-4 py-2 border"
+          title="From"
+        />
+        <input
+          type="date"
+          value={filters.to}
+          onChange={(e) => handleFilter('to', e.target.value)}
+          className="px-4 py-2 border"
+          title="To"
+        />
+      </div>
+
+      {/* Events Table */}
+      <div className="card overflow-hidden">
+        {isLoading ? (
+          <div className="p-12 text-center">
+            <div className="animate-spin rounded-full h-8 w-8 border-t-2 border-b-2 border-primary-500 mx-auto"></div>
+          </div>
+        ) : events.length === 0 ? (
+          <p className="p-12 text-center text-slate-400">No audit events found</p>
+        ) : (
+          <table>
+            <thead>
+              <tr className="border-b border-slate-700">
+                <th>Time</th>
+                <th>Actor</th>
+                <th>Action</th>
+                <th>Target</th>
+                <th>Result</th>
+                <th>IP Address</th>
+              </tr>
+            </thead>
+            <tbody>
+              {events.map((event) => (
+                <Fragment key={event.id}>
+                  <tr
+                    onClick={() => setExpanded(expanded === event.id ? null : event.id)}
+                    className="cursor-pointer"
+                  >
+                    <td className="text-slate-400 whitespace-nowrap">{new Date(event.created_at).toLocaleString()}</td>
+                    <td>
+                      {event.actor_email}
+                      <span className="text-slate-500 text-xs ml-2">{event.actor_role}</span>
+                    </td>
+                    <td className="font-mono text-sm">{event.action}</td>
+                    <td className="text-slate-400">
+                      {event.target_type}{event.target_id && ` #${event.target_id}`}
+                    </td>
+                    <td>
+                      <span className={`badge ${
+                        event.result === 'success' ? 'bg-green-500/20 text-green-400' : 'bg-red-500/20 text-red-400'
+                      }`}>
+                        {event.result} ({event.status_code})
+                      </span>
+                    </td>
+                    <td className="font-mono text-sm">{event.ip_address}</td>
+                  </tr>
+                  {expanded === event.id && (
+                    <tr>
+                      <td colSpan={6} className="bg-slate-900/50">
+                        <pre className="text-xs text-slate-300 whitespace-pre-wrap break-all">{event.summary || 'No details'}</pre>
+                        <p className="text-xs text-slate-500 mt-2">{event.user_agent}</p>
+                      </td>
+                    </tr>
+                  )}
+                </Fragment>
+              ))}
+            </tbody>
+          </table>
+        )}
+
+        {/* Pagination */}
+        {totalPages > 1 && (
+          <div className="p-4 border-t border-slate-700 flex justify-between items-center">
+            <p className="text-slate-400 text-sm">
+              Showing {(page - 1) * PAGE_SIZE + 1} to {Math.min(page * PAGE_SIZE, total)} of {total}
+            </p>
+            <div className="flex gap-2">
+              <button
+                onClick={() => setPage(p => Math.max(1, p - 1))}
+                disabled={page === 1}
+                className="btn btn-secondary text-sm disabled:opacity-50"
+              >
+                Previous
+              </button>
+              <button
+                onClick={() => setPage(p => Math.min(totalPages, p + 1))}
+                disabled={page === totalPages}
+                className="btn btn-secondary text-sm disabled:opacity-50"
+              >
+                Next
+              </button>
+            </div>
+          </div>
+        )}
+      </div>
+    </div>
+  )
+}
+
+export default AdminAudit
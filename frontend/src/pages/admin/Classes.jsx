@@ -0,0 +1,443 @@
+// ===========================================
+// Admin Classes Page
+// ===========================================
+// Classes, their students, teachers, join
+// codes and limit overrides
+// ===========================================
+
+import { useState, useEffect } from 'react'
+import toast from 'react-hot-toast'
+import { classesAPI, usersAPI } from '../../services/api'
+import useAuthStore from '../../stores/authStore'
+
+// Limits a class can override; empty means the global setting applies
+const LIMIT_FIELDS = [
+  { key: 'max_projects', label: 'Max projects per student' },
+  { key: 'project_expiry_days', label: 'Project expiry (days, 0 = never)' },
+  { key: 'cpu_limit_percent', label: 'CPU limit (%)' },
+  { key: 'memory_limit_mb', label: 'Memory limit (MB)' },
+]
+
+const emptyForm = {
+  name: '',
+  description: '',
+  join_enabled: true,
+  max_projects: '',
+  project_expiry_days: '',
+  cpu_limit_percent: '',
+  memory_limit_mb: '',
+}
+
+function AdminClasses() {
+  const canManage = useAuthStore((state) => state.can('class.manage'))
+  const [classes, setClasses] = useState([])
+  const [isLoading, setIsLoading] = useState(true)
+  const [selected, setSelected] = useState(null)
+  const [limits, setLimits] = useState(null)
+  const [teachers, setTeachers] = useState([])
+  const [showModal, setShowModal] = useState(false)
+  const [editing, setEditing] = useState(null)
+  const [formData, setFormData] = useState(emptyForm)
+  const [studentEmails, setStudentEmails] = useState('')
+
+  useEffect(() => {
+    fetchClasses()
+    if (canManage) {
+      usersAPI.list({ role: 'teacher', limit: 100 })
+        .then((response) => setTeachers(response.data.data || []))
+        .catch(() => {})
+    }
+  }, [canManage])
+
+  const fetchClasses = async () => {
+    setIsLoading(true)
+    try {
+      const response = await classesAPI.list()
+      setClasses(response.data || [])
+    } catch (error) {
+      toast.error('Failed to fetch classes')
+    } finally {
+      setIsLoading(false)
+    }
+  }
+
+  const openClass = async (id) => {
+    try {
+      const response = await classesAPI.get(id)
+      setSelected(response.data.class)
+      setLimits(response.data.limits)
+    } catch (error) {
+      toast.error(error.response?.data?.error || 'Failed to load class')
+    }
+  }
+
+  const handleCreate = () => {
+    setEditing(null)
+    setFormData(emptyForm)
+    setShowModal(true)
+  }
+
+  const handleEdit = (cls) => {
+    setEditing(cls)
+    setFormData({
+      name: cls.name,
+      description: cls.description || '',
+      join_enabled: cls.join_enabled,
+      max_projects: cls.max_projects ?? '',
+      project_expiry_days: cls.project_expiry_days ?? '',
+      cpu_limit_percent: cls.cpu_limit_percent ?? '',
+      memory_limit_mb: cls.memory_limit_mb ?? '',
+    })
+    setShowModal(true)
+  }
+
+  const handleSubmit = async (e) => {
+    e.preventDefault()
+
+    const data = {
+      name: formData.name,
+      description: formData.description,
+      join_enabled: formData.join_enabled,
+    }
+    // Cleared fields are sent as -1 so the class falls back to the global setting
+    if (canManage) {
+      LIMIT_FIELDS.forEach(({ key }) => {
+        data[key] = formData[key] === '' ? -1 : parseInt(formData[key], 10)
+      })
+    }
+
+    try {
+      if (editing) {
+        await classesAPI.update(editing.id, data)
+        toast.success('Class updated')
+      } else {
+        await classesAPI.create(data)
+        toast.success('Class created')
+      }
+      setShowModal(false)
+      fetchClasses()
+      if (editing && selected?.id === editing.id) {
+        openClass(editing.id)
+      }
+    } catch (error) {
+      toast.error(error.response?.data?.error || 'Failed to save class')
+    }
+  }
+
+  const handleDelete = async (cls) => {
+    if (!confirm(`Delete class ${cls.name}? Students and their projects are kept.`)) return
+
+    try {
+      await classesAPI.delete(cls.id)
+      toast.success('Class deleted')
+      if (selected?.id === cls.id) {
+        setSelected(null)
+      }
+      fetchClasses()
+    } catch (error) {
+      toast.error(error.response?.data?.error || 'Failed to delete class')
+    }
+  }
+
+  const handleRegenerateCode = async () => {
+    if (!confirm('Generate a new join code? The current code stops working.')) return
+
+    try {
+      const response = await classesAPI.regenerateJoinCode(selected.id)
+      setSelected((s) => ({ ...s, join_code: response.data.join_code }))
+      fetchClasses()
+    } catch (error) {
+      toast.error(error.response?.data?.error || 'Failed to generate join code')
+    }
+  }
+
+  const handleAddStudents = async (e) => {
+    e.preventDefault()
+    const emails = studentEmails.split(/[\s,;]+/).map((email) => email.trim()).filter(Boolean)
+    if (emails.length === 0) return
+
+    try {
+      const response = await classesAPI.addStudents(selected.id, emails)
+      toast.success(response.data.message)
+      setStudentEmails('')
+      openClass(selected.id)
+      fetchClasses()
+    } catch (error) {
+      toast.error(error.response?.data?.error || 'Failed to add students')
+    }
+  }
+
+  const handleRemoveStudent = async (student) => {
+    if (!confirm(`Remove ${student.email} from ${selected.name}?`)) return
+
+    try {
+      await classesAPI.removeStudent(selected.id, student.id)
+      openClass(selected.id)
+      fetchClasses()
+    } catch (error) {
+      toast.error(error.response?.data?.error || 'Failed to remove student')
+    }
+  }
+
+  const handleToggleTeacher = async (teacher) => {
+    const current = (selected.teachers || []).map((t) => t.id)
+    const ids = current.includes(teacher.id)
+      ? current.filter((id) => id !== teacher.id)
+      : [...current, teacher.id]
+
+    try {
+      await classesAPI.setTeachers(selected.id, ids)
+      openClass(selected.id)
+      fetchClasses()
+    } catch (error) {
+      toast.error(error.response?.data?.error || 'Failed to update teachers')
+    }
+  }
+
+  return (
+    <div className="space-y-6">
+      {/* Header */}
+      <div className="flex items-center justify-between">
+        <div>
+          <h1 className="text-2xl font-bold text-white">Classes</h1>
+          <p className="text-slate-400">
+            {canManage ? 'Cohorts, their teachers and limits' : 'The classes you teach'}
+          </p>
+        </div>
+        {canManage && (
+          <button onClick={handleCreate} className="btn btn-primary">
+            + New Class
+          </button>
+        )}
+      </div>
+
+      {/* Classes Table */}
+      <div className="card overflow-hidden">
+        {isLoading ? (
+          <div className="p-8 text-center">
+            <div className="animate-spin rounded-full h-8 w-8 border-b-2 border-primary-500 mx-auto"></div>
+          </div>
+        ) : classes.length === 0 ? (
+          <p className="p-8 text-center text-slate-400">No classes yet</p>
+        ) : (
+          <table>
+            <thead>
+              <tr className="border-b border-slate-700">
+                <th>Name</th>
+                <th>Teachers</th>
+                <th>Students</th>
+                <th>Join Code</th>
+                <th>Actions</th>
+              </tr>
+            </thead>
+            <tbody>
+              {classes.map((cls) => (
+                <tr key={cls.id} className={selected?.id === cls.id ? 'bg-white/[0.02]' : ''}>
+                  <td className="font-medium text-white">{cls.name}</td>
+                  <td className="text-slate-400">
+                    {(cls.teachers || []).map((t) => t.name).join(', ') || '—'}
+                  </td>
+                  <td>{cls.student_count}</td>
+                  <td>
+                    <span className="font-mono text-primary-400">{cls.join_code}</span>
+                    {!cls.join_enabled && (
+                      <span className="badge bg-slate-500/20 text-slate-400 ml-2">closed</span>
+                    )}
+                  </td>
+                  <td>
+                    <div className="flex gap-2">
+                      <button onClick={() => openClass(cls.id)} className="text-primary-400 hover:text-primary-300">
+                        Manage
+                      </button>
+                      <button onClick={() => handleEdit(cls)} className="text-yellow-400 hover:text-yellow-300">
+                        Edit
+                      </button>
+                      {canManage && (
+                        <button onClick={() => handleDelete(cls)} className="text-red-400 hover:text-red-300">
+                          Delete
+                        </button>
+                      )}
+                    </div>
+                  </td>
+                </tr>
+              ))}
+            </tbody>
+          </table>
+        )}
+      </div>
+
+      {/* Selected Class */}
+      {selected && (
+        <div className="grid grid-cols-1 lg:grid-cols-3 gap-6">
+          <div className="card p-6 lg:col-span-2 space-y-4">
+            <div className="flex justify-between items-start">
+              <div>
+                <h2 className="text-lg font-semibold text-white">{selected.name}</h2>
+                {selected.description && <p className="text-slate-400 text-sm">{selected.description}</p>}
+              </div>
+              <button onClick={() => setSelected(null)} className="text-slate-400 hover:text-white">
+                ✕
+              </button>
+            </div>
+
+            {/* Teachers enroll students through the join code only */}
+            {canManage && (
+            <form onSubmit={handleAddStudents} className="flex gap-2">
+              <input
+                type="text"
+                placeholder="Student emails, separated by commas or spaces"
+                value={studentEmails}
+                onChange={(e) => setStudentEmails(e.target.value)}
+                className="flex-1 px-4 py-2 border"
+              />
+              <button type="submit" className="btn btn-primary">Add</button>
+            </form>
+            )}
+
+            {(selected.students || []).length === 0 ? (
+              <p className="text-slate-400 text-sm">No students yet. Share the join code{canManage && ' or add them by email'}.</p>
+            ) : (
+              <table>
+                <thead>
+                  <tr className="border-b border-slate-700">
+                    <th>Name</th>
+                    <th>Email</th>
+                    <th></th>
+                  </tr>
+                </thead>
+                <tbody>
+                  {selected.students.map((student) => (
+                    <tr key={student.id}>
+                      <td className="text-white">{student.name}</td>
+                      <td>{student.email}</td>
+                      <td className="text-right">
+                        <button onClick={() => handleRemoveStudent(student)} className="text-red-400 hover:text-red-300">
+                          Remove
+                        </button>
+                      </td>
+                    </tr>
+                  ))}
+                </tbody>
+              </table>
+            )}
+          </div>
+
+          <div className="space-y-6">
+            <div className="card p-6 space-y-3">
+              <h3 className="text-white font-semibold">Join Code</h3>
+              <p className="font-mono text-2xl text-primary-400 tracking-widest">{selected.join_code}</p>
+              <p className="text-slate-400 text-sm">
+                {selected.join_enabled ? 'Students enter this code on their account page.' : 'Joining is closed.'}
+              </p>
+              <button onClick={handleRegenerateCode} className="btn btn-secondary text-sm">
+                Generate new code
+              </button>
+            </div>
+
+            {limits && (
+              <div className="card p-6 space-y-2">
+                <h3 className="text-white font-semibold">Limits</h3>
+                {LIMIT_FIELDS.map(({ key, label }) => (
+                  <div key={key} className="flex justify-between text-sm">
+                    <span className="text-slate-400">{label}</span>
+                    <span className={selected[key] != null ? 'text-primary-400' : 'text-slate-300'}>
+                      {limits[key]}
+                    </span>
+                  </div>
+                ))}
+                <p className="text-slate-500 text-xs">Highlighted values override the global settings.</p>
+              </div>
+            )}
+
+            {canManage && (
+              <div className="card p-6 space-y-2">
+                <h3 className="text-white font-semibold">Teachers</h3>
+                {teachers.length === 0 ? (
+                  <p className="text-slate-400 text-sm">No users with the teacher role</p>
+                ) : (
+                  teachers.map((teacher) => (
+                    <label key={teacher.id} className="flex items-center gap-2 text-sm text-slate-300">
+                      <input
+                        type="checkbox"
+                        checked={(selected.teachers || []).some((t) => t.id === teacher.id)}
+                        onChange={() => handleToggleTeacher(teacher)}
+                      />
+                      {teacher.name} <span className="text-slate-500">{teacher.email}</span>
+                    </label>
+                  ))
+                )}
+              </div>
+            )}
+          </div>
+        </div>
+      )}
+
+      {/* Modal */}
+      {showModal && (
+        <div className="fixed inset-0 bg-black/50 flex items-center justify-center z-50">
+          <div className="card p-6 w-full max-w-md">
+            <h2 className="text-xl font-semibold text-white mb-4">
+              {editing ? 'Edit Class' : 'New Class'}
+            </h2>
+            <form onSubmit={handleSubmit} className="space-y-4">
+              <div>
+                <label className="block text-sm text-slate-300 mb-1">Name</label>
+                <input
+                  type="text"
+                  value={formData.name}
+                  onChange={(e) => setFormData((f) => ({ ...f, name: e.target.value }))}
+                  className="w-full px-4 py-2 border"
+                  required
+                />
+              </div>
+              <div>
+                <label className="block text-sm text-slate-300 mb-1">Description</label>
+                <input
+                  type="text"
+                  value={formData.description}
+                  onChange={(e) => setFormData((f) => ({ ...f, description: e.target.value }))}
+                  className="w-full px-4 py-2 border"
+                />
+              </div>
+              <label className="flex items-center gap-2 text-sm text-slate-300">
+                <input
+                  type="checkbox"
+                  checked={formData.join_enabled}
+                  onChange={(e) => setFormData((f) => ({ ...f, join_enabled: e.target.checked }))}
+                />
+                Students can join with the join code
+              </label>
+              {canManage && (
+                <div className="space-y-3 pt-2 border-t border-slate-700">
+                  <p className="text-slate-400 text-sm">Leave empty to use the global setting</p>
+                  {LIMIT_FIELDS.map(({ key, label }) => (
+                    <div key={key}>
+                      <label className="block text-sm text-slate-300 mb-1">{label}</label>
+                      <input
+                        type="number"
+                        min="0"
+                        value={formData[key]}
+                        onChange={(e) => setFormData((f) => ({ ...f, [key]: e.target.value }))}
+                        className="w-full px-4 py-2 border"
+                      />
+                    </div>
+                  ))}
+                </div>
+              )}
+              <div className="flex gap-2 justify-end">
+                <button type="button" onClick={() => setShowModal(false)} className="btn btn-secondary">
+                  Cancel
+                </button>
+                <button type="submit" className="btn btn-primary">
+                  {editing ? 'Save' : 'Create'}
+                </button>
+              </div>
+            </form>
+          </div>
+        </div>
+      )}
+    </div>
+  )
+}
+
+export default AdminClasses
@@ -0,0 +1,241 @@
+// ===========================================
+// Admin Roles Page
+// ===========================================
+// Roles and the permissions they grant
+// ===========================================
+
+import { useState, useEffect } from 'react'
+import toast from 'react-hot-toast'
+import { rolesAPI } from '../../services/api'
+import useAuthStore from '../../stores/authStore'
+
+const emptyForm = {
+  name: '',
+  description: '',
+  permissions: [],
+}
+
+// Permissions are grouped by the part before the first dot
+const groupPermissions = (permissions) =>
+  permissions.reduce((groups, permission) => {
+    const group = permission.name.split('.')[0]
+    return { ...groups, [group]: [...(groups[group] || []), permission] }
+  }, {})
+
+function AdminRoles() {
+  const fetchUser = useAuthStore((state) => state.fetchUser)
+  const [roles, setRoles] = useState([])
+  const [permissions, setPermissions] = useState([])
+  const [isLoading, setIsLoading] = useState(true)
+  const [showModal, setShowModal] = useState(false)
+  const [editing, setEditing] = useState(null)
+  const [formData, setFormData] = useState(emptyForm)
+
+  useEffect(() => {
+    fetchRoles()
+  }, [])
+
+  const fetchRoles = async () => {
+    setIsLoading(true)
+    try {
+      const response = await rolesAPI.list()
+      setRoles(response.data.data || [])
+      setPermissions(response.data.permissions || [])
+    } catch (error) {
+      toast.error(error.response?.data?.error || 'Failed to load roles')
+    } finally {
+      setIsLoading(false)
+    }
+  }
+
+  const handleCreate = () => {
+    setEditing(null)
+    setFormData(emptyForm)
+    setShowModal(true)
+  }
+
+  const handleEdit = (role) => {
+    setEditing(role)
+    setFormData({
+      name: role.name,
+      description: role.description || '',
+      permissions: role.permissions || [],
+    })
+    setShowModal(true)
+  }
+
+  const togglePermission = (name) => {
+    setFormData((f) => ({
+      ...f,
+      permissions: f.permissions.includes(name)
+        ? f.permissions.filter((p) => p !== name)
+        : [...f.permissions, name],
+    }))
+  }
+
+  const handleSubmit = async (e) => {
+    e.preventDefault()
+    try {
+      if (editing) {
+        await rolesAPI.update(editing.id, formData)
+        toast.success('Role updated')
+      } else {
+        await rolesAPI.create(formData)
+        toast.success('Role created')
+      }
+      setShowModal(false)
+      fetchRoles()
+      // The signed-in user's own role may have changed
+      fetchUser()
+    } catch (error) {
+      toast.error(error.response?.data?.error || 'Failed to save role')
+    }
+  }
+
+  const handleDelete = async (role) => {
+    if (!confirm(`Delete role ${role.name}?`)) return
+
+    try {
+      await rolesAPI.delete(role.id)
+      toast.success('Role deleted')
+      fetchRoles()
+    } catch (error) {
+      toast.error(error.response?.data?.error || 'Failed to delete role')
+    }
+  }
+
+  const groups = groupPermissions(permissions)
+
+  return (
+    <div className="space-y-6">
+      {/* Header */}
+      <div className="flex items-center justify-between">
+        <div>
+          <h1 className="text-2xl font-bold text-white">Roles</h1>
+          <p className="text-slate-400">What each role is allowed to do</p>
+        </div>
+        <button onClick={handleCreate} className="btn btn-primary">
+          + New Role
+        </button>
+      </div>
+
+      {/* Roles Table */}
+      <div className="card overflow-hidden">
+        {isLoading ? (
+          <div className="p-8 text-center">
+            <div className="animate-spin rounded-full h-8 w-8 border-b-2 border-primary-500 mx-auto"></div>
+          </div>
+        ) : (
+          <table>
+            <thead>
+              <tr className="border-b border-slate-700">
+                <th>Name</th>
+                <th>Description</th>
+                <th>Permissions</th>
+                <th>Users</th>
+                <th>Actions</th>
+              </tr>
+            </thead>
+            <tbody>
+              {roles.map((role) => (
+                <tr key={role.id}>
+                  <td className="font-medium text-white">
+                    {role.name}
+                    {role.built_in && (
+                      <span className="badge bg-slate-500/20 text-slate-400 ml-2">built-in</span>
+                    )}
+                  </td>
+                  <td className="text-slate-400">{role.description || '—'}</td>
+                  <td>
+                    {(role.permissions || []).includes('*') ? 'All' : (role.permissions || []).length}
+                  </td>
+                  <td>{role.user_count}</td>
+                  <td>
+                    <div className="flex gap-2">
+                      {role.name !== 'superadmin' && (
+                        <button onClick={() => handleEdit(role)} className="text-yellow-400 hover:text-yellow-300">
+                          Edit
+                        </button>
+                      )}
+                      {!role.built_in && (
+                        <button onClick={() => handleDelete(role)} className="text-red-400 hover:text-red-300">
+                          Delete
+                        </button>
+                      )}
+                    </div>
+                  </td>
+                </tr>
+              ))}
+            </tbody>
+          </table>
+        )}
+      </div>
+
+      {/* Modal */}
+      {showModal && (
+        <div className="fixed inset-0 bg-black/50 flex items-center justify-center z-50">
+          <div className="card p-6 w-full max-w-2xl max-h-[90vh] overflow-y-auto">
+            <h2 className="text-xl font-semibold text-white mb-4">
+              {editing ? `Edit Role ${editing.name}` : 'New Role'}
+            </h2>
+            <form onSubmit={handleSubmit} className="space-y-4">
+              {!editing && (
+                <div>
+                  <label className="block text-sm text-slate-300 mb-1">Name</label>
+                  <input
+                    type="text"
+                    value={formData.name}
+                    onChange={(e) => setFormData((f) => ({ ...f, name: e.target.value }))}
+                    className="w-full px-4 py-2 border"
+                    placeholder="assistant"
+                    required
+                  />
+                </div>
+              )}
+              <div>
+                <label className="block text-sm text-slate-300 mb-1">Description</label>
+                <input
+                  type="text"
+                  value={formData.description}
+                  onChange={(e) => setFormData((f) => ({ ...f, description: e.target.value }))}
+                  className="w-full px-4 py-2 border"
+                />
+              </div>
+              {Object.entries(groups).map(([group, items]) => (
+                <div key={group} className="pt-2 border-t border-slate-700">
+                  <p className="text-slate-400 text-sm uppercase tracking-wider mb-2">{group}</p>
+                  <div className="space-y-1">
+                    {items.map((permission) => (
+                      <label key={permission.name} className="flex items-start gap-2 text-sm text-slate-300">
+                        <input
+                          type="checkbox"
+                          className="mt-1"
+                          checked={formData.permissions.includes(permission.name)}
+                          onChange={() => togglePermission(permission.name)}
+                        />
+                        <span>
+                          <span className="font-mono text-primary-400">{permission.name}</span>
+                          <span className="text-slate-500 ml-2">{permission.description}</span>
+                        </span>
+                      </label>
+                    ))}
+                  </div>
+                </div>
+              ))}
+              <div className="flex gap-2 pt-2">
+                <button type="button" onClick={() => setShowModal(false)} className="btn btn-secondary flex-1">
+                  Cancel
+                </button>
+                <button type="submit" className="btn btn-primary flex-1">
+                  {editing ? 'Update' : 'Create'}
+                </button>
+              </div>
+            </form>
+          </div>
+        </div>
+      )}
+    </div>
+  )
+}
+
+export default AdminRoles
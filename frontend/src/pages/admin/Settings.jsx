@@ -152,6 +152,58 @@ function AdminSettings() {
         </div>
       </div>
       
+      {/* Security */}
+      <div className="card p-6">
+        <h2 className="text-lg font-semibold text-white mb-4">Security</h2>
+        <div className="grid grid-cols-1 md:grid-cols-2 gap-4">
+          {[
+            ['login_backoff_after', 'Delay after failures per email', 3, 'Each further attempt waits twice as long'],
+            ['login_ip_backoff_after', 'Delay after failures per IP', 20, 'Keep high when a class shares one IP address'],
+            ['login_lockout_threshold', 'Lock account after failures', 10, '0 = never lock'],
+            ['login_lockout_minutes', 'Lockout duration (minutes)', 15, 'Admins can unlock users earlier'],
+            ['audit_retention_days', 'Audit log retention (days)', 180, 'Also applies to failed logins, 0 = keep forever'],
+          ].map(([key, label, fallback, hint]) => (
+            <div key={key}>
+              <label className="block text-sm text-slate-300 mb-1">{label}</label>
+              <input
+                type="number"
+                min="0"
+                value={settings[key] || fallback}
+                onChange={(e) => handleChange(key, e.target.value)}
+                className="w-full px-4 py-2 border"
+              />
+              <p className="text-sm text-slate-500 mt-1">{hint}</p>
+            </div>
+          ))}
+        </div>
+      </div>
+      
+      {/* Authentication */}
+      <div className="card p-6">
+        <h2 className="text-lg font-semibold text-white mb-4">Authentication</h2>
+        <div className="space-y-3">
+          {[
+            ['auth_password_enabled', 'Password login', 'The superadmin can always sign in with a password'],
+            ['auth_oidc_enabled', 'Single sign-on (OpenID Connect)', 'Configured with the OIDC_* environment variables'],
+            ['auth_ldap_enabled', 'LDAP login', 'Configured with the LDAP_* environment variables'],
+            ['require_2fa_admins', 'Require two-factor for admins', 'Admins without an authenticator app must set one up before using the panel'],
+          ].map(([key, label, hint]) => (
+            <label key={key} className="flex items-start gap-3 cursor-pointer">
+              <input
+                type="checkbox"
+                checked={settings[key] === 'true'}
+                onChange={(e) => handleChange(key, e.target.checked ? 'true' : 'false')}
+                className="mt-1"
+              />
+              <span>
+                <span className="block text-sm text-slate-300">{label}</span>
+                <span className="block text-sm text-slate-500">{hint}</span>
+              </span>
+            </label>
+          ))}
+        </div>
+      </div>
+      
       {/* Save Button */}
       <div className="flex justify-end">
         <button
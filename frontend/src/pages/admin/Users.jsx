@@ -4,9 +4,16 @@
 
 import { useState, useEffect, useRef } from 'react'
 import toast from 'react-hot-toast'
-import { usersAPI } from '../../services/api'
+import { usersAPI, rolesAPI } from '../../services/api'
+import useAuthStore from '../../stores/authStore'
 
 function AdminUsers() {
+  // Roles with only class permissions manage the students of their classes
+  const can = useAuthStore((state) => state.can)
+  const canCreate = can('user.create')
+  const canDelete = can('user.delete')
+  const canSeeAll = can('user.read.any')
+  const [roles, setRoles] = useState([])
   const [users, setUsers] = useState([])
   const [total, setTotal] = useState(0)
   const [page, setPage] = useState(1)
@@ -16,6 +23,7 @@ function AdminUsers() {
   const [showModal, setShowModal] = useState(false)
   const [editingUser, setEditingUser] = useState(null)
   const [importResults, setImportResults] = useState(null)
+  const [attempts, setAttempts] = useState([])
   const fileInputRef = useRef(null)
   
   const [formData, setFormData] = useState({
@@ -29,6 +37,32 @@ function AdminUsers() {
     fetchUsers()
   }, [page, search, roleFilter])
   
+  useEffect(() => {
+    if (canSeeAll) {
+      fetchAttempts()
+    }
+  }, [canSeeAll])
+  
+  useEffect(() => {
+    rolesAPI.list()
+      .then((response) => setRoles((response.data.data || []).map((role) => role.name)))
+      .catch(() => {})
+  }, [])
+  
+  // Roles the form offers; the current role stays selectable when roles cannot be listed
+  const roleOptions = (current) => {
+    const options = roles.filter((role) => role !== 'superadmin')
+    return current && !options.includes(current) ? [current, ...options] : options
+  }
+  
+  const fetchAttempts = async () => {
+    try {
+      const response = await usersAPI.loginAttempts({ limit: 20 })
+      setAttempts(response.data.data || [])
+    } catch (error) {
+    }
+  }
+  
   const fetchUsers = async () => {
     setIsLoading(true)
     try {
@@ -83,6 +117,39 @@ function AdminUsers() {
     }
   }
   
+  const handleSignOut = async (id) => {
+    if (!confirm('Sign this user out of all sessions?')) return
+    try {
+      await usersAPI.signOut(id)
+      toast.success('User signed out')
+    } catch (error) {
+      toast.error(error.response?.data?.error || 'Sign out failed')
+    }
+  }
+  
+  const handleUnlock = async (id) => {
+    try {
+      await usersAPI.unlock(id)
+      toast.success('User unlocked')
+      fetchUsers()
+    } catch (error) {
+      toast.error(error.response?.data?.error || 'Unlock failed')
+    }
+  }
+  
+  const isLocked = (user) => user.locked_until && new Date(user.locked_until) > new Date()
+  
+  const handleResetTwoFactor = async (id) => {
+    if (!confirm('Turn off two-factor authentication for this user? They will be signed out.')) return
+    try {
+      await usersAPI.resetTwoFactor(id)
+      toast.success('Two-factor authentication reset')
+      fetchUsers()
+    } catch (error) {
+      toast.error(error.response?.data?.error || 'Reset failed')
+    }
+  }
+  
   const handleImport = async (e) => {
     const file = e.target.files[0]
     if (!file) return
@@ -107,8 +174,11 @@ function AdminUsers() {
       <div className="flex items-center justify-between">
         <div>
           <h1 className="text-2xl font-bold text-white">User Management</h1>
-          <p className="text-slate-400">Manage students and administrators</p>
+          <p className="text-slate-400">
+            {canSeeAll ? 'Manage students, teachers and administrators' : 'Students of your classes'}
+          </p>
         </div>
+        {canCreate && (
         <div className="flex gap-2">
           <input
             type="file"
@@ -134,6 +204,7 @@ function AdminUsers() {
             + Add User
           </button>
         </div>
+        )}
       </div>
       
       {/* Import Results */}
@@ -189,9 +260,9 @@ function AdminUsers() {
           className="px-4 py-2 border"
         >
           <option value="">All Roles</option>
-          <option value="student">Students</option>
-          <option value="admin">Admins</option>
-          <option value="superadmin">Superadmin</option>
+          {roles.map((role) => (
+            <option key={role} value={role}>{role}</option>
+          ))}
         </select>
       </div>
       
@@ -221,10 +292,16 @@ function AdminUsers() {
                     <span className={`badge ${
                       user.role === 'superadmin' ? 'bg-purple-500/20 text-purple-400' :
                       user.role === 'admin' ? 'bg-blue-500/20 text-blue-400' :
+                      user.role === 'teacher' ? 'bg-emerald-500/20 text-emerald-400' :
                       'bg-slate-500/20 text-slate-400'
                     }`}>
                       {user.role}
                     </span>
+                    {isLocked(user) && (
+                      <span className="badge bg-red-500/20 text-red-400 ml-2" title={`Until ${new Date(user.locked_until).toLocaleString()}`}>
+                        locked
+                      </span>
+                    )}
                   </td>
                   <td className="text-slate-400">
                     {new Date(user.created_at).toLocaleDateString()}
@@ -237,7 +314,29 @@ function AdminUsers() {
                       >
                         Edit
                       </button>
-                      {user.role !== 'superadmin' && (
+                      <button 
+                        onClick={() => handleSignOut(user.id)}
+                        className="text-yellow-400 hover:text-yellow-300"
+                      >
+                        Sign out
+                      </button>
+                      {isLocked(user) && (
+                        <button 
+                          onClick={() => handleUnlock(user.id)}
+                          className="text-green-400 hover:text-green-300"
+                        >
+                          Unlock
+                        </button>
+                      )}
+                      {user.two_factor_enabled && (
+                        <button 
+                          onClick={() => handleResetTwoFactor(user.id)}
+                          className="text-yellow-400 hover:text-yellow-300"
+                        >
+                          Reset 2FA
+                        </button>
+                      )}
+                      {canDelete && user.role !== 'superadmin' && (
                         <button 
                           onClick={() => handleDelete(user.id)}
                           className="text-red-400 hover:text-red-300"
@@ -279,6 +378,47 @@ function AdminUsers() {
         )}
       </div>
       
+      {/* Failed Logins */}
+      {canSeeAll && (
+      <div className="card overflow-hidden">
+        <h2 className="text-lg font-semibold text-white p-4 border-b border-slate-700">Recent Failed Logins</h2>
+        {attempts.length === 0 ? (
+          <p className="p-4 text-slate-400 text-sm">No failed logins</p>
+        ) : (
+          <table>
+            <thead>
+              <tr className="border-b border-slate-700">
+                <th>Time</th>
+                <th>Email</th>
+                <th>IP Address</th>
+                <th>Result</th>
+              </tr>
+            </thead>
+            <tbody>
+              {attempts.map((attempt) => (
+                <tr key={attempt.id}>
+                  <td className="text-slate-400">{new Date(attempt.created_at).toLocaleString()}</td>
+                  <td>
+                    {attempt.email}
+                    {!attempt.user_id && <span className="text-slate-500 text-xs ml-2">(no account)</span>}
+                  </td>
+                  <td className="font-mono text-sm">{attempt.ip_address}</td>
+                  <td>
+                    <span className={`badge ${
+                      attempt.reason === 'account_locked' ? 'bg-red-500/20 text-red-400' : 'bg-yellow-500/20 text-yellow-400'
+                    }`}>
+                      {attempt.reason === 'account_locked' ? 'account locked' :
+                        attempt.reason === 'invalid_2fa_code' ? 'wrong 2FA code' : 'wrong password'}
+                    </span>
+                  </td>
+                </tr>
+              ))}
+            </tbody>
+          </table>
+        )}
+      </div>
+      )}
+      
       {/* Modal */}
       {showModal && (
         <div className="fixed inset-0 bg-black/50 flex items-center justify-center z-50">
@@ -314,8 +454,9 @@ function AdminUsers() {
                   onChange={(e) => setFormData(f => ({ ...f, role: e.target.value }))}
                   className="w-full px-4 py-2 border"
                 >
-                  <option value="student">Student</option>
-                  <option value="admin">Admin</option>
+                  {roleOptions(formData.role).map((role) => (
+                    <option key={role} value={role}>{role}</option>
+                  ))}
                 </select>
               </div>
               <div>
@@ -20,17 +20,31 @@ export default function DatabaseManager({ embedded = false, projectId = null })
   const [selectedTable, setSelectedTable] = useState(null)
   const [tableData, setTableData] = useState(null)
   const [tableStructure, setTableStructure] = useState(null)
+  const [tableSort, setTableSort] = useState('')
+  const [tableSearch, setTableSearch] = useState('')
   const [loading, setLoading] = useState(true)
   const [credentials, setCredentials] = useState(null)
+  const [usage, setUsage] = useState(null)
   
   // Query state
   const [query, setQuery] = useState('')
   const [queryResult, setQueryResult] = useState(null)
   const [queryLoading, setQueryLoading] = useState(false)
+  const [readOnly, setReadOnly] = useState(true)
   
   // Import state
   const [importSQL, setImportSQL] = useState('')
   const [importing, setImporting] = useState(false)
+  const [importFile, setImportFile] = useState(null)
+  const [importJob, setImportJob] = useState(null)
+
+  // Backup state
+  const [backups, setBackups] = useState([])
+  const [backingUp, setBackingUp] = useState(false)
+
+  // Schema state
+  const [schemaFormat, setSchemaFormat] = useState('mermaid')
+  const [schemaText, setSchemaText] = useState('')
   
   // Modals
   const [showCredentials, setShowCredentials] = useState(false)
@@ -48,6 +62,8 @@ export default function DatabaseManager({ embedded = false, projectId = null })
     fetchProject()
     fetchTables()
     fetchCredentials()
+    fetchUsage()
+    fetchBackups()
   }, [id])
 
   const fetchProject = async () => {
@@ -69,6 +85,39 @@ export default function DatabaseManager({ embedded = false, projectId = null })
     }
   }
 
+  const fetchUsage = async () => {
+    try {
+      const res = await databaseAPI.getUsage(id)
+      setUsage(res.data)
+    } catch (err) {
+      console.error('Failed to fetch database usage')
+    }
+  }
+
+  const fetchBackups = async () => {
+    try {
+      const res = await databaseAPI.listBackups(id)
+      setBackups(res.data.backups || [])
+    } catch (err) {
+      console.error('Failed to fetch backups')
+    }
+  }
+
+  const fetchSchema = async (format) => {
+    setSchemaFormat(format)
+    try {
+      const res = await databaseAPI.getSchema(id, format)
+      setSchemaText(res.data)
+    } catch (err) {
+      toast.error('Failed to load schema')
+    }
+  }
+
+  const formatBytes = (bytes) => {
+    if (bytes >= 1024 * 1024) return `${(bytes / 1024 / 1024).toFixed(1)} MB`
+    return `${(bytes / 1024).toFixed(1)} KB`
+  }
+
   const fetchTables = async () => {
     setLoading(true)
     try {
@@ -83,6 +132,8 @@ export default function DatabaseManager({ embedded = false, projectId = null })
 
   const selectTable = async (tableName) => {
     setSelectedTable(tableName)
+    setTableSort('')
+    setTableSearch('')
     setLoading(true)
     setTableData(null)
     try {
@@ -99,15 +150,38 @@ export default function DatabaseManager({ embedded = false, projectId = null })
     }
   }
 
+  const loadTableData = async (sort, search) => {
+    setLoading(true)
+    try {
+      const res = await databaseAPI.getData(id, selectedTable, 1, 50, { sort, search })
+      setTableData(res.data)
+    } catch (err) {
+      toast.error(err.response?.data?.error || 'Failed to load table data')
+    } finally {
+      setLoading(false)
+    }
+  }
+
+  // Cycle a column through ascending, descending and unsorted
+  const toggleSort = (col) => {
+    const next = tableSort === `${col}:asc` ? `${col}:desc` : tableSort === `${col}:desc` ? '' : `${col}:asc`
+    setTableSort(next)
+    loadTableData(next, tableSearch)
+  }
+
   const executeQuery = async () => {
     if (!query.trim()) return
     setQueryLoading(true)
     setQueryResult(null)
     try {
-      const res = await databaseAPI.query(id, query)
+      const res = await databaseAPI.query(id, query, readOnly)
       setQueryResult(res.data)
       toast.success(`Query executed in ${res.data.duration}`)
     } catch (err) {
+      // Results of the statements before the failing one are still shown
+      if (err.response?.data?.results?.length) {
+        setQueryResult({ results: err.response.data.results })
+      }
       toast.error(err.response?.data?.error || 'Query failed')
     } finally {
       setQueryLoading(false)
@@ -142,6 +216,7 @@ export default function DatabaseManager({ embedded = false, projectId = null })
         toast.success(`Imported ${res.data.statements} statements`)
         setImportSQL('')
         fetchTables()
+        fetchUsage()
       } else {
         toast.error(`Import completed with errors: ${res.data.errors?.length || 0} errors`)
       }
@@ -152,6 +227,49 @@ export default function DatabaseManager({ embedded = false, projectId = null })
     }
   }
 
+  const handleImportFile = async () => {
+    if (!importFile) {
+      toast.error('Please choose a .sql or .sql.gz file')
+      return
+    }
+    setImporting(true)
+    try {
+      const res = await databaseAPI.importFile(id, importFile)
+      setImportJob(res.data.job)
+      pollImport(res.data.job_id)
+    } catch (err) {
+      toast.error(err.response?.data?.error || 'Upload failed')
+      setImporting(false)
+    }
+  }
+
+  const pollImport = async (jobId) => {
+    try {
+      const res = await databaseAPI.importStatus(id, jobId)
+      const job = res.data
+      setImportJob(job)
+
+      if (job.status === 'completed' || job.status === 'failed') {
+        setImporting(false)
+        setImportFile(null)
+        fetchTables()
+        fetchUsage()
+        if (job.status === 'failed') {
+          toast.error(`Import failed: ${job.error}`)
+        } else if (job.errors?.length > 0) {
+          toast.error(`Import completed with errors: ${job.errors.length} errors`)
+        } else {
+          toast.success(`Imported ${job.executed} statements`)
+        }
+        return
+      }
+      setTimeout(() => pollImport(jobId), 2000)
+    } catch (err) {
+      toast.error('Failed to fetch import status')
+      setImporting(false)
+    }
+  }
+
   const confirmReset = () => {
     setConfirmModal({
       isOpen: true,
@@ -166,7 +284,7 @@ export default function DatabaseManager({ embedded = false, projectId = null })
   const handleReset = async () => {
     try {
       const res = await databaseAPI.reset(id)
-      toast.success(`Database reset! Dropped ${res.data.dropped} tables`)
+      toast.success(`Database reset! Dropped ${res.data.dropped} tables (backup saved)`)
       setSelectedTable(null)
       setTableData(null)
       fetchTables()
@@ -175,6 +293,57 @@ export default function DatabaseManager({ embedded = false, projectId = null })
     }
   }
 
+  const handleCreateBackup = async () => {
+    setBackingUp(true)
+    try {
+      await databaseAPI.createBackup(id)
+      toast.success('Backup created')
+      fetchBackups()
+    } catch (err) {
+      toast.error(err.response?.data?.error || 'Backup failed')
+    } finally {
+      setBackingUp(false)
+    }
+  }
+
+  const handleDownloadBackup = async (backup) => {
+    try {
+      const res = await databaseAPI.downloadBackup(id, backup.id)
+      const url = window.URL.createObjectURL(new Blob([res.data], { type: 'application/gzip' }))
+      const a = document.createElement('a')
+      a.href = url
+      a.download = `${project?.database_name}_backup_${backup.id}.sql.gz`
+      a.click()
+      window.URL.revokeObjectURL(url)
+    } catch (err) {
+      toast.error('Download failed')
+    }
+  }
+
+  const confirmRestore = (backup) => {
+    setConfirmModal({
+      isOpen: true,
+      title: 'Restore Backup?',
+      message: `This will replace all tables with the backup from ${new Date(backup.created_at).toLocaleString()}. A snapshot of the current database is taken first.`,
+      type: 'warning',
+      confirmText: 'Yes, Restore',
+      onConfirm: () => handleRestore(backup)
+    })
+  }
+
+  const handleRestore = async (backup) => {
+    setImporting(true)
+    try {
+      const res = await databaseAPI.restoreBackup(id, backup.id)
+      setImportJob(res.data.job)
+      pollImport(res.data.job_id)
+      fetchBackups()
+    } catch (err) {
+      toast.error(err.response?.data?.error || 'Restore failed')
+      setImporting(false)
+    }
+  }
+
   const copyToClipboard = (text) => {
     navigator.clipboard.writeText(text)
     toast.success('Copied')
@@ -222,6 +391,33 @@ export default function DatabaseManager({ embedded = false, projectId = null })
         </div>
       )}
 
+      {/* Storage Usage */}
+      {usage && usage.quota_bytes > 0 && (
+        <div className={`card p-4 border ${usage.exceeded ? 'border-red-500/40' : usage.warning ? 'border-amber-500/40' : 'border-slate-800'}`}>
+          <div className="flex justify-between items-center text-sm mb-2">
+            <span className="text-slate-300 font-medium">Storage</span>
+            <span className="text-slate-400 font-mono text-xs">
+              {formatBytes(usage.used_bytes)} / {formatBytes(usage.quota_bytes)} ({usage.percent.toFixed(0)}%)
+            </span>
+          </div>
+          <div className="w-full h-2 bg-slate-800 rounded-full overflow-hidden">
+            <div
+              className={`h-full ${usage.exceeded ? 'bg-red-500' : usage.warning ? 'bg-amber-500' : 'bg-primary-500'}`}
+              style={{ width: `${Math.min(usage.percent, 100)}%` }}
+            />
+          </div>
+          {usage.writes_revoked ? (
+            <p className="text-red-400 text-xs mt-2">
+              Database is over quota. INSERT and UPDATE are disabled until you free up space (DELETE / DROP still work).
+            </p>
+          ) : usage.warning && (
+            <p className="text-amber-400 text-xs mt-2">
+              Database is close to its quota. Writes will be blocked once it is exceeded.
+            </p>
+          )}
+        </div>
+      )}
+
       {/* Tabs */}
       <div>
          <div className="flex gap-1 bg-slate-800/50 p-1 rounded-lg w-fit mb-6">
@@ -229,10 +425,15 @@ export default function DatabaseManager({ embedded = false, projectId = null })
              { id: 'tables', label: 'Tables' },
              { id: 'query', label: 'SQL Query' },
              { id: 'import', label: 'Import / Export' },
+             { id: 'backups', label: 'Backups' },
+             { id: 'schema', label: 'Schema' },
            ].map(tab => (
              <button
                key={tab.id}
-               onClick={() => setActiveTab(tab.id)}
+               onClick={() => {
+                 setActiveTab(tab.id)
+                 if (tab.id === 'schema') fetchSchema(schemaFormat)
+               }}
                className={`px-6 py-2 rounded-md text-sm font-medium transition-all ${
                  activeTab === tab.id 
                  ? 'bg-slate-700 text-white shadow-sm' 
@@ -292,7 +493,18 @@ export default function DatabaseManager({ embedded = false, projectId = null })
                         </span>
                       )}
                     </div>
-                    {/* Could add structure toggle here if needed */}
+                    <form
+                      onSubmit={(e) => { e.preventDefault(); loadTableData(tableSort, tableSearch) }}
+                      className="flex items-center gap-2"
+                    >
+                      <input
+                        type="text"
+                        value={tableSearch}
+                        onChange={(e) => setTableSearch(e.target.value)}
+                        placeholder="Search text columns..."
+                        className="input text-sm py-1"
+                      />
+                    </form>
                   </div>
                   
                   <div className="flex-1 overflow-auto bg-slate-900/50">
@@ -303,8 +515,14 @@ export default function DatabaseManager({ embedded = false, projectId = null })
                         <thead className="bg-slate-800 sticky top-0 z-10 text-slate-300">
                           <tr>
                             {tableData.columns?.map(col => (
-                              <th key={col} className="px-4 py-3 font-mono text-xs uppercase tracking-wider border-b border-slate-700 whitespace-nowrap">
+                              <th
+                                key={col}
+                                onClick={() => toggleSort(col)}
+                                className="px-4 py-3 font-mono text-xs uppercase tracking-wider border-b border-slate-700 whitespace-nowrap cursor-pointer hover:text-white"
+                              >
                                 {col}
+                                {tableSort === `${col}:asc` && ' ▲'}
+                                {tableSort === `${col}:desc` && ' ▼'}
                               </th>
                             ))}
                           </tr>
@@ -328,7 +546,7 @@ export default function DatabaseManager({ embedded = false, projectId = null })
                     ) : (
                       <div className="flex flex-col items-center justify-center h-full text-slate-500 gap-2">
                         <div className="text-4xl opacity-20">📭</div>
-                        <div>Table is empty</div>
+                        <div>{tableSearch ? 'No matching rows' : 'Table is empty'}</div>
                       </div>
                     )}
                   </div>
@@ -349,7 +567,11 @@ export default function DatabaseManager({ embedded = false, projectId = null })
             <div className="card p-0 overflow-hidden flex flex-col h-1/2 border-slate-800">
               <div className="p-3 bg-slate-800 border-b border-slate-700 flex justify-between items-center">
                  <h3 className="font-semibold text-white text-sm">SQL Editor</h3>
-                 <div className="flex gap-2">
+                 <div className="flex gap-2 items-center">
+                    <label className="flex items-center gap-1 text-xs text-slate-400 mr-2" title="Run inside a read-only transaction; writes are rejected">
+                      <input type="checkbox" checked={readOnly} onChange={(e) => setReadOnly(e.target.checked)} />
+                      Read-only
+                    </label>
                     <button onClick={() => setQuery('')} className="text-xs text-slate-400 hover:text-white px-2 py-1 rounded hover:bg-slate-700">Clear</button>
                     <button 
                       onClick={executeQuery}
@@ -375,46 +597,58 @@ export default function DatabaseManager({ embedded = false, projectId = null })
                </div>
                <div className="flex-1 overflow-auto bg-slate-900/50 p-4">
                   {queryResult ? (
-                    <div>
-                      <div className="mb-4 flex items-center gap-2 text-xs">
-                        {queryResult.rows ? (
-                          <span className="px-2 py-0.5 bg-emerald-500/10 text-emerald-400 rounded border border-emerald-500/20">
-                            {queryResult.rows.length} rows retrieved
-                          </span>
-                        ) : (
-                           <span className="px-2 py-0.5 bg-blue-500/10 text-blue-400 rounded border border-blue-500/20">
-                            {queryResult.rows_affected} rows affected
-                          </span>
-                        )}
-                        <span className="text-slate-500">in {queryResult.duration}</span>
-                      </div>
+                    <div className="space-y-6">
+                      {queryResult.results.map((result, r) => (
+                        <div key={r}>
+                          <div className="mb-2 flex items-center gap-2 text-xs">
+                            {queryResult.results.length > 1 && (
+                              <span className="text-slate-400 font-mono truncate max-w-md">{result.statement}</span>
+                            )}
+                            {result.rows ? (
+                              <span className="px-2 py-0.5 bg-emerald-500/10 text-emerald-400 rounded border border-emerald-500/20">
+                                {result.rows.length} rows retrieved
+                              </span>
+                            ) : (
+                               <span className="px-2 py-0.5 bg-blue-500/10 text-blue-400 rounded border border-blue-500/20">
+                                {result.rows_affected} rows affected
+                              </span>
+                            )}
+                            {result.truncated && (
+                              <span className="px-2 py-0.5 bg-amber-500/10 text-amber-400 rounded border border-amber-500/20">
+                                truncated, add a LIMIT to see other rows
+                              </span>
+                            )}
+                            <span className="text-slate-500">in {result.duration}</span>
+                          </div>
 
-                      {queryResult.rows && queryResult.rows.length > 0 && (
-                        <div className="overflow-x-auto rounded border border-slate-700">
-                           <table className="w-full text-left text-sm border-collapse">
-                            <thead className="bg-slate-800 text-slate-300">
-                              <tr>
-                                {queryResult.columns?.map(col => (
-                                  <th key={col} className="px-4 py-2 font-mono text-xs border-b border-slate-600 whitespace-nowrap">
-                                    {col}
-                                  </th>
-                                ))}
-                              </tr>
-                            </thead>
-                            <tbody className="divide-y divide-slate-700 text-slate-300 bg-slate-900">
-                              {queryResult.rows.map((row, i) => (
-                                <tr key={i} className="hover:bg-slate-800/50">
-                                  {queryResult.columns?.map(col => (
-                                    <td key={col} className="px-4 py-2 max-w-xs truncate font-mono text-xs border-r border-slate-800 last:border-0">
-                                      {row[col] !== null ? String(row[col]) : <span className="text-slate-600">NULL</span>}
-                                    </td>
+                          {result.rows && result.rows.length > 0 && (
+                            <div className="overflow-x-auto rounded border border-slate-700">
+                               <table className="w-full text-left text-sm border-collapse">
+                                <thead className="bg-slate-800 text-slate-300">
+                                  <tr>
+                                    {result.columns?.map(col => (
+                                      <th key={col} className="px-4 py-2 font-mono text-xs border-b border-slate-600 whitespace-nowrap">
+                                        {col}
+                                      </th>
+                                    ))}
+                                  </tr>
+                                </thead>
+                                <tbody className="divide-y divide-slate-700 text-slate-300 bg-slate-900">
+                                  {result.rows.map((row, i) => (
+                                    <tr key={i} className="hover:bg-slate-800/50">
+                                      {result.columns?.map(col => (
+                                        <td key={col} className="px-4 py-2 max-w-xs truncate font-mono text-xs border-r border-slate-800 last:border-0">
+                                          {row[col] !== null ? String(row[col]) : <span className="text-slate-600">NULL</span>}
+                                        </td>
+                                      ))}
+                                    </tr>
                                   ))}
-                                </tr>
-                              ))}
-                            </tbody>
-                           </table>
+                                </tbody>
+                               </table>
+                            </div>
+                          )}
                         </div>
-                      )}
+                      ))}
                     </div>
                   ) : (
                     <div className="h-full flex items-center justify-center text-slate-600 text-sm italic">
@@ -457,7 +691,133 @@ export default function DatabaseManager({ embedded = false, projectId = null })
                >
                  {importing ? 'Importing...' : 'Run Import'}
                </button>
+
+               <div className="border-t border-slate-800 mt-6 pt-6">
+                 <p className="text-slate-400 text-sm mb-3">
+                   Or upload a dump file (.sql or .sql.gz). Large files are imported in the background.
+                 </p>
+                 <input
+                   type="file"
+                   accept=".sql,.gz"
+                   onChange={(e) => setImportFile(e.target.files[0] || null)}
+                   className="w-full text-slate-400 text-sm mb-4"
+                 />
+                 <button
+                   onClick={handleImportFile}
+                   disabled={importing || !importFile}
+                   className="btn btn-secondary w-full justify-center py-2"
+                 >
+                   {importing ? 'Importing...' : 'Upload & Import'}
+                 </button>
+
+                 {importJob && (
+                   <div className="mt-4">
+                     <div className="flex justify-between text-xs text-slate-400 mb-1">
+                       <span>{importJob.filename} · {importJob.status}</span>
+                       <span>{Math.round(importJob.progress || 0)}%</span>
+                     </div>
+                     <div className="w-full h-2 bg-slate-800 rounded-full overflow-hidden">
+                       <div
+                         className={`h-full ${importJob.status === 'failed' ? 'bg-red-500' : 'bg-primary-500'}`}
+                         style={{ width: `${Math.min(importJob.progress || 0, 100)}%` }}
+                       />
+                     </div>
+                     {importJob.errors?.slice(0, 5).map((e, i) => (
+                       <p key={i} className="text-red-400 text-xs font-mono mt-2">Line {e.line}: {e.error}</p>
+                     ))}
+                   </div>
+                 )}
+               </div>
+            </div>
+          </div>
+        )}
+
+        {/* Backups Tab */}
+        {activeTab === 'backups' && (
+          <div className="card border-slate-800 p-6">
+            <div className="flex justify-between items-center mb-4">
+              <div>
+                <h3 className="font-bold text-white text-lg">🗄️ Backups</h3>
+                <p className="text-slate-400 text-sm">
+                  Taken automatically on a schedule, before a reset and before each deploy's migrations.
+                </p>
+              </div>
+              <button onClick={handleCreateBackup} disabled={backingUp} className="btn btn-secondary">
+                {backingUp ? 'Backing up...' : 'Back Up Now'}
+              </button>
+            </div>
+
+            {importJob && importing && (
+              <p className="text-slate-400 text-sm mb-4">
+                Restoring… {Math.round(importJob.progress || 0)}%
+              </p>
+            )}
+
+            {backups.length === 0 ? (
+              <p className="text-slate-500 text-sm">No backups yet.</p>
+            ) : (
+              <table className="w-full text-sm">
+                <thead>
+                  <tr className="text-left text-slate-500 border-b border-slate-800">
+                    <th className="py-2">Created</th>
+                    <th className="py-2">Reason</th>
+                    <th className="py-2">Size</th>
+                    <th className="py-2"></th>
+                  </tr>
+                </thead>
+                <tbody>
+                  {backups.map((backup) => (
+                    <tr key={backup.id} className="border-b border-slate-800/50 text-slate-300">
+                      <td className="py-2">{new Date(backup.created_at).toLocaleString()}</td>
+                      <td className="py-2">{backup.reason}</td>
+                      <td className="py-2">{formatBytes(backup.size_bytes)}</td>
+                      <td className="py-2 text-right space-x-2">
+                        <button onClick={() => handleDownloadBackup(backup)} className="btn btn-secondary text-sm">
+                          Download
+                        </button>
+                        <button onClick={() => confirmRestore(backup)} disabled={importing} className="btn btn-primary text-sm disabled:opacity-50">
+                          Restore
+                        </button>
+                      </td>
+                    </tr>
+                  ))}
+                </tbody>
+              </table>
+            )}
+          </div>
+        )}
+
+        {/* Schema Tab */}
+        {activeTab === 'schema' && (
+          <div className="card border-slate-800 p-6">
+            <div className="flex justify-between items-center mb-4">
+              <div>
+                <h3 className="font-bold text-white text-lg">🧩 Schema</h3>
+                <p className="text-slate-400 text-sm">
+                  Tables and relationships as a Mermaid diagram or DBML, ready to paste into reports or dbdiagram.io.
+                </p>
+              </div>
+              <div className="flex gap-2">
+                {['mermaid', 'dbml'].map(format => (
+                  <button
+                    key={format}
+                    onClick={() => fetchSchema(format)}
+                    className={`btn text-sm ${schemaFormat === format ? 'btn-primary' : 'btn-secondary'}`}
+                  >
+                    {format === 'mermaid' ? 'Mermaid' : 'DBML'}
+                  </button>
+                ))}
+                <button
+                  onClick={() => { navigator.clipboard.writeText(schemaText); toast.success('Copied') }}
+                  className="btn btn-secondary text-sm"
+                >
+                  Copy
+                </button>
+              </div>
             </div>
+            <pre className="bg-slate-950 rounded-lg p-4 text-xs text-slate-300 font-mono overflow-auto max-h-[600px]">
+              {schemaText}
+            </pre>
           </div>
         )}
       </div>
@@ -15,6 +15,7 @@ function StudentNewProject() {
     github_url: '',
     branch: '',
     database_name: '',
+    database_engine: 'mysql',
     queue_enabled: false,
   })
   
@@ -142,6 +143,23 @@ function StudentNewProject() {
           </p>
         </div>
 
+        {/* Database Engine */}
+        <div>
+          <label htmlFor="database_engine" className="block text-sm font-medium text-slate-300 mb-2">
+            Database Engine
+          </label>
+          <select
+            id="database_engine"
+            name="database_engine"
+            value={formData.database_engine}
+            onChange={handleChange}
+            className="w-full px-4 py-3 border"
+          >
+            <option value="mysql">MySQL (MariaDB)</option>
+            <option value="postgres">PostgreSQL</option>
+          </select>
+        </div>
+
         {/* Queue Worker Checkbox */}
         <div className="flex items-start gap-3 p-4 bg-slate-800 rounded-lg border border-slate-700">
            <div className="flex items-center h-5">
@@ -46,6 +46,7 @@ function MetricCard({ title, value, subtext, color = 'primary' }) {
 }
 
 import DatabaseManager from './DatabaseManager'
+import ProjectMembers from './ProjectMembers'
 import ConfirmationModal from '../../components/ConfirmationModal'
 
 function StudentProjectDetail() {
@@ -284,6 +285,13 @@ function StudentProjectDetail() {
   
   if (!project) return null
   const projectUrl = project.url
+
+  // Viewers only look and never see the .env secrets; only the owner deletes.
+  // Staff without a membership are checked by the API.
+  const canWrite = project.member_role !== 'viewer'
+  const canDelete = !project.member_role || project.member_role === 'owner'
+  const tabs = ['workload', 'console', 'environment', 'database', 'logs', 'settings', 'members']
+    .filter((tab) => canWrite || !['console', 'environment', 'settings'].includes(tab))
   
   return (
     <div className="space-y-6 max-w-7xl mx-auto pb-20">
@@ -318,6 +326,9 @@ function StudentProjectDetail() {
           <div className="flex items-center gap-4 mb-2">
              <h1 className="text-3xl font-bold text-white tracking-tight">{project.name}</h1>
              <StatusIndicator status={project.status} />
+             {project.member_role && project.member_role !== 'owner' && (
+               <span className="badge bg-slate-500/20 text-slate-400">shared · {project.member_role}</span>
+             )}
           </div>
           <div className="flex items-center gap-2 text-slate-400 font-mono text-sm">
              <span>{project.subdomain}</span>
@@ -332,13 +343,17 @@ function StudentProjectDetail() {
         </div>
         
         <div className="flex gap-3">
+           {canWrite && (
            <button onClick={handleRedeploy} className="btn btn-secondary flex items-center gap-2">
              <svg xmlns="http://www.w3.org/2000/svg" width="16" height="16" viewBox="0 0 24 24" fill="none" stroke="currentColor" strokeWidth="2" strokeLinecap="round" strokeLinejoin="round"><path d="M3 12a9 9 0 0 1 9-9 9.75 9.75 0 0 1 6.74 2.74L21 8"/><path d="M21 3v5h-5"/><path d="M21 12a9 9 0 0 1-9 9 9.75 9.75 0 0 1-6.74-2.74L3 16"/><path d="M3 21v-5h5"/></svg>
              Redeploy
            </button>
+           )}
+           {canDelete && (
            <button onClick={handleDelete} className="btn btn-danger-outline flex items-center gap-2 px-3">
              <svg xmlns="http://www.w3.org/2000/svg" width="16" height="16" viewBox="0 0 24 24" fill="none" stroke="currentColor" strokeWidth="2" strokeLinecap="round" strokeLinejoin="round"><path d="M3 6h18"/><path d="M19 6v14c0 1-1 2-2 2H7c-1 0-2-1-2-2V6"/><path d="M8 6V4c0-1 1-2 2-2h4c1 0 2 1 2 2v2"/><line x1="10" x2="10" y1="11" y2="17"/><line x1="14" x2="14" y1="11" y2="17"/></svg>
            </button>
+           )}
         </div>
       </div>
 
@@ -379,7 +394,7 @@ function StudentProjectDetail() {
       {/* Tabs Layout */}
       <div>
         <div className="flex gap-1 bg-slate-800/50 p-1 rounded-lg w-fit mb-6 overflow-x-auto">
-           {['workload', 'console', 'environment', 'database', 'logs', 'settings'].map(tab => (
+           {tabs.map(tab => (
              <button
                key={tab}
                onClick={() => setActiveTab(tab)}
@@ -562,6 +577,11 @@ function StudentProjectDetail() {
             </div>
           )}
 
+          {/* Members Tab */}
+          {activeTab === 'members' && (
+             <ProjectMembers projectId={id} onLeave={() => navigate('/projects')} />
+          )}
+
           {/* Settings Tab */}
           {activeTab === 'settings' && (
              <div className="grid grid-cols-1 md:grid-cols-2 gap-6">
@@ -0,0 +1,199 @@
+// ===========================================
+// Project Members
+// ===========================================
+// Collaborators of a project and their
+// invitations
+// ===========================================
+
+import { useState, useEffect } from 'react'
+import toast from 'react-hot-toast'
+import { projectsAPI } from '../../services/api'
+import useAuthStore from '../../stores/authStore'
+
+const ROLE_OPTIONS = [
+  { value: 'developer', label: 'Developer', hint: 'Redeploys, runs commands, edits env and writes to the database' },
+  { value: 'viewer', label: 'Viewer', hint: 'Sees the project, its logs, stats and database' },
+]
+
+const roleBadge = {
+  owner: 'bg-purple-500/20 text-purple-400',
+  developer: 'bg-emerald-500/20 text-emerald-400',
+  viewer: 'bg-slate-500/20 text-slate-400',
+}
+
+function ProjectMembers({ projectId, onLeave }) {
+  const currentUser = useAuthStore((state) => state.user)
+  const [owner, setOwner] = useState(null)
+  const [members, setMembers] = useState([])
+  const [canManage, setCanManage] = useState(false)
+  const [email, setEmail] = useState('')
+  const [role, setRole] = useState('developer')
+
+  useEffect(() => {
+    fetchMembers()
+  }, [projectId])
+
+  const fetchMembers = async () => {
+    try {
+      const response = await projectsAPI.members(projectId)
+      setOwner(response.data.owner)
+      setMembers(response.data.data || [])
+      setCanManage(response.data.can_manage)
+    } catch (error) {
+      toast.error(error.response?.data?.error || 'Failed to load members')
+    }
+  }
+
+  const handleInvite = async (e) => {
+    e.preventDefault()
+    try {
+      await projectsAPI.inviteMember(projectId, email.trim(), role)
+      toast.success(`Invitation sent to ${email.trim()}`)
+      setEmail('')
+      fetchMembers()
+    } catch (error) {
+      toast.error(error.response?.data?.error || 'Failed to invite member')
+    }
+  }
+
+  const handleRoleChange = async (member, newRole) => {
+    try {
+      await projectsAPI.updateMember(projectId, member.id, newRole)
+      toast.success('Role updated')
+      fetchMembers()
+    } catch (error) {
+      toast.error(error.response?.data?.error || 'Failed to update role')
+    }
+  }
+
+  const handleRemove = async (member) => {
+    const leaving = member.user_id === currentUser?.id
+    if (!confirm(leaving ? 'Leave this project?' : `Remove ${member.email} from this project?`)) return
+
+    try {
+      await projectsAPI.removeMember(projectId, member.id)
+      if (leaving) {
+        toast.success('You left the project')
+        onLeave?.()
+        return
+      }
+      toast.success('Member removed')
+      fetchMembers()
+    } catch (error) {
+      toast.error(error.response?.data?.error || 'Failed to remove member')
+    }
+  }
+
+  return (
+    <div className="grid grid-cols-1 lg:grid-cols-3 gap-6">
+      <div className="lg:col-span-2 card p-0 overflow-hidden">
+        <div className="p-4 border-b border-slate-700 bg-slate-800/50">
+          <h3 className="font-semibold text-white">Members</h3>
+        </div>
+        <table>
+          <thead>
+            <tr className="border-b border-slate-700">
+              <th>Member</th>
+              <th>Role</th>
+              <th>Status</th>
+              <th></th>
+            </tr>
+          </thead>
+          <tbody>
+            {owner && (
+              <tr>
+                <td>
+                  <div className="text-white">{owner.name}</div>
+                  <div className="text-xs text-slate-500">{owner.email}</div>
+                </td>
+                <td><span className={`badge ${roleBadge.owner}`}>owner</span></td>
+                <td className="text-slate-400">—</td>
+                <td></td>
+              </tr>
+            )}
+            {members.map((member) => (
+              <tr key={member.id}>
+                <td>
+                  <div className="text-white">{member.user?.name || member.email}</div>
+                  {member.user && <div className="text-xs text-slate-500">{member.email}</div>}
+                </td>
+                <td>
+                  {canManage ? (
+                    <select
+                      value={member.role}
+                      onChange={(e) => handleRoleChange(member, e.target.value)}
+                      className="bg-slate-900 border border-slate-700 rounded px-2 py-1 text-sm text-white"
+                    >
+                      {ROLE_OPTIONS.map((option) => (
+                        <option key={option.value} value={option.value}>{option.label}</option>
+                      ))}
+                    </select>
+                  ) : (
+                    <span className={`badge ${roleBadge[member.role]}`}>{member.role}</span>
+                  )}
+                </td>
+                <td className="text-slate-400">
+                  {member.accepted_at ? 'Active' : 'Invited'}
+                </td>
+                <td className="text-right">
+                  {(canManage || member.user_id === currentUser?.id) && (
+                    <button onClick={() => handleRemove(member)} className="text-red-400 hover:text-red-300 text-sm">
+                      {member.user_id === currentUser?.id ? 'Leave' : 'Remove'}
+                    </button>
+                  )}
+                </td>
+              </tr>
+            ))}
+          </tbody>
+        </table>
+        {members.length === 0 && (
+          <p className="p-4 text-slate-400 text-sm">Nobody else works on this project yet</p>
+        )}
+      </div>
+
+      {canManage && (
+        <div className="card p-6">
+          <h3 className="font-semibold text-white mb-4">Invite a Collaborator</h3>
+          <form onSubmit={handleInvite} className="space-y-4">
+            <div>
+              <label className="block text-sm text-slate-400 mb-1">Email</label>
+              <input
+                type="email"
+                value={email}
+                onChange={(e) => setEmail(e.target.value)}
+                className="w-full px-4 py-2 border"
+                placeholder="classmate@school.edu"
+                required
+              />
+            </div>
+            <div className="space-y-2">
+              {ROLE_OPTIONS.map((option) => (
+                <label key={option.value} className="flex items-start gap-2 text-sm text-slate-300">
+                  <input
+                    type="radio"
+                    name="member-role"
+                    className="mt-1"
+                    checked={role === option.value}
+                    onChange={() => setRole(option.value)}
+                  />
+                  <span>
+                    {option.label}
+                    <span className="block text-xs text-slate-500">{option.hint}</span>
+                  </span>
+                </label>
+              ))}
+            </div>
+            <button type="submit" className="btn btn-primary w-full">
+              Send Invitation
+            </button>
+            <p className="text-xs text-slate-500">
+              Collaborators do not count against their own project limit.
+            </p>
+          </form>
+        </div>
+      )}
+    </div>
+  )
+}
+
+export default ProjectMembers
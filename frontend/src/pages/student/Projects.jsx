@@ -5,7 +5,7 @@
 import { useState, useEffect } from 'react'
 import { Link } from 'react-router-dom'
 import toast from 'react-hot-toast'
-import { projectsAPI } from '../../services/api'
+import { projectsAPI, invitationsAPI } from '../../services/api'
 
 function StatusDot({ status }) {
   const styles = {
@@ -22,6 +22,7 @@ import ConfirmationModal from '../../components/ConfirmationModal'
 
 function StudentProjects() {
   const [projects, setProjects] = useState([])
+  const [invitations, setInvitations] = useState([])
   const [isLoading, setIsLoading] = useState(true)
   
   // Modal State
@@ -40,8 +41,33 @@ function StudentProjects() {
   
   useEffect(() => {
     fetchProjects()
+    fetchInvitations()
   }, [])
   
+  const fetchInvitations = async () => {
+    try {
+      const response = await invitationsAPI.list()
+      setInvitations(response.data.data || [])
+    } catch (error) {
+    }
+  }
+  
+  const handleInvitation = async (invitation, accept) => {
+    try {
+      if (accept) {
+        await invitationsAPI.accept(invitation.id)
+        toast.success(`You joined ${invitation.project?.name}`)
+        fetchProjects()
+      } else {
+        await invitationsAPI.decline(invitation.id)
+        toast.success('Invitation declined')
+      }
+      fetchInvitations()
+    } catch (error) {
+      toast.error(error.response?.data?.error || 'Failed to answer invitation')
+    }
+  }
+  
   const fetchProjects = async () => {
     try {
       const response = await projectsAPI.listOwn()
@@ -113,6 +139,33 @@ function StudentProjects() {
         </Link>
       </div>
       
+      {/* Invitations */}
+      {invitations.length > 0 && (
+        <div className="card p-0 overflow-hidden border-primary-500/30">
+          <div className="p-4 border-b border-slate-700 bg-slate-800/50">
+            <h3 className="font-semibold text-white">Invitations</h3>
+          </div>
+          {invitations.map((invitation) => (
+            <div key={invitation.id} className="flex items-center justify-between p-4 border-b border-slate-800 last:border-0">
+              <div>
+                <div className="text-white">{invitation.project?.name}</div>
+                <div className="text-xs text-slate-500">
+                  {invitation.invited_by?.name || 'Someone'} invited you as {invitation.role}
+                </div>
+              </div>
+              <div className="flex gap-2">
+                <button onClick={() => handleInvitation(invitation, false)} className="btn btn-secondary text-sm py-1.5">
+                  Decline
+                </button>
+                <button onClick={() => handleInvitation(invitation, true)} className="btn btn-primary text-sm py-1.5">
+                  Accept
+                </button>
+              </div>
+            </div>
+          ))}
+        </div>
+      )}
+      
       {/* Projects Grid */}
       {isLoading ? (
         <div className="flex justify-center p-12">
@@ -142,6 +195,9 @@ function StudentProjects() {
                      <div>
                        <h3 className="font-bold text-white text-lg group-hover:text-primary-400 transition-colors">{project.name}</h3>
                        <div className="text-xs text-slate-500 font-mono">{project.subdomain}</div>
+                       {project.member_role && project.member_role !== 'owner' && (
+                         <div className="text-xs text-primary-400 mt-0.5">shared · {project.member_role}</div>
+                       )}
                      </div>
                   </div>
                   {project.status === 'running' && (
@@ -176,6 +232,7 @@ function StudentProjects() {
                       {new Date(project.created_at).toLocaleDateString()}
                    </div>
                    <div className="flex gap-2 opacity-0 group-hover:opacity-100 transition-opacity">
+                      {project.member_role !== 'viewer' && (
                       <button 
                         onClick={(e) => handleRedeploy(project.id, e)}
                         className="p-1.5 hover:bg-slate-700 rounded text-slate-400 hover:text-white"
@@ -183,6 +240,8 @@ function StudentProjects() {
                       >
                          <svg xmlns="http://www.w3.org/2000/svg" width="14" height="14" viewBox="0 0 24 24" fill="none" stroke="currentColor" strokeWidth="2" strokeLinecap="round" strokeLinejoin="round"><path d="M21 12a9 9 0 0 0-9-9 9.75 9.75 0 0 0-6.74 2.74L3 8"/><path d="M3 3v5h5"/><path d="M3 12a9 9 0 0 0 9 9 9.75 9.75 0 0 0 6.74-2.74L21 16"/><path d="M21 21v-5h-5"/></svg>
                       </button>
+                      )}
+                      {project.member_role === 'owner' && (
                       <button 
                         onClick={(e) => handleDelete(project.id, e)}
                         className="p-1.5 hover:bg-slate-700 rounded text-slate-400 hover:text-red-400"
@@ -190,6 +249,7 @@ function StudentProjects() {
                       >
                          <svg xmlns="http://www.w3.org/2000/svg" width="14" height="14" viewBox="0 0 24 24" fill="none" stroke="currentColor" strokeWidth="2" strokeLinecap="round" strokeLinejoin="round"><polyline points="3 6 5 6 21 6"/><path d="M19 6v14a2 2 0 0 1-2 2H7a2 2 0 0 1-2-2V6m3 0V4a2 2 0 0 1 2-2h4a2 2 0 0 1 2 2v2"/><line x1="10" y1="11" x2="10" y2="17"/><line x1="14" y1="11" x2="14" y2="17"/></svg>
                       </button>
+                      )}
                    </div>
                 </div>
               </div>
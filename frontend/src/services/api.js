@@ -23,12 +23,41 @@ api.interceptors.request.use((config) => {
   return config
 })
 
-// Response interceptor - handle errors
+// Refresh the access token from the refresh token cookie.
+// Concurrent 401s share one refresh so the token rotates only once.
+let refreshPromise = null
+
+const refreshAccessToken = () => {
+  if (!refreshPromise) {
+    refreshPromise = axios.post('/api/auth/refresh')
+      .then((response) => {
+        localStorage.setItem('token', response.data.token)
+        return response.data.token
+      })
+      .finally(() => {
+        refreshPromise = null
+      })
+  }
+  return refreshPromise
+}
+
+// Response interceptor - refresh expired tokens, handle errors
 api.interceptors.response.use(
   (response) => response,
-  (error) => {
-    const wasAuthenticated = !!error.config?.headers?.Authorization
+  async (error) => {
+    const config = error.config
+    const wasAuthenticated = !!config?.headers?.Authorization
     if (error.response?.status === 401 && wasAuthenticated) {
+      if (!config._retried) {
+        config._retried = true
+        try {
+          const token = await refreshAccessToken()
+          config.headers.Authorization = `Bearer ${token}`
+          return api(config)
+        } catch (refreshError) {
+          // Fall through: the session is over
+        }
+      }
       localStorage.removeItem('token')
       window.dispatchEvent(new Event('auth:expired'))
     }
@@ -49,6 +78,45 @@ export const authAPI = {
   
   me: () => 
     api.get('/auth/me'),
+  
+  refresh: () => 
+    api.post('/auth/refresh'),
+  
+  providers: () => 
+    api.get('/auth/providers'),
+  
+  changePassword: (currentPassword, newPassword) => 
+    api.post('/auth/password', { current_password: currentPassword, new_password: newPassword }),
+  
+  forgotPassword: (email) => 
+    api.post('/auth/password/forgot', { email }),
+  
+  resetPassword: (token, password) => 
+    api.post('/auth/password/reset', { token, password }),
+  
+  sessions: () => 
+    api.get('/auth/sessions'),
+  
+  revokeSession: (id) => 
+    api.delete(`/auth/sessions/${id}`),
+  
+  twoFactorStatus: () => 
+    api.get('/auth/2fa'),
+  
+  twoFactorSetup: () => 
+    api.post('/auth/2fa/setup'),
+  
+  twoFactorVerify: (code) => 
+    api.post('/auth/2fa/verify', { code }),
+  
+  twoFactorDisable: (code) => 
+    api.post('/auth/2fa/disable', { code }),
+  
+  twoFactorRecoveryCodes: (code) => 
+    api.post('/auth/2fa/recovery-codes', { code }),
+  
+  twoFactorChallenge: (challengeToken, code) => 
+    api.post('/auth/2fa/challenge', { challenge_token: challengeToken, code }),
 }
 
 // ===========================================
@@ -71,6 +139,18 @@ export const usersAPI = {
   delete: (id) => 
     api.delete(`/admin/users/${id}`),
   
+  signOut: (id) => 
+    api.post(`/admin/users/${id}/sign-out`),
+  
+  resetTwoFactor: (id) => 
+    api.delete(`/admin/users/${id}/2fa`),
+  
+  unlock: (id) => 
+    api.post(`/admin/users/${id}/unlock`),
+  
+  loginAttempts: (params = {}) => 
+    api.get('/admin/login-attempts', { params }),
+  
   importExcel: (file) => {
     const formData = new FormData()
     formData.append('file', file)
@@ -80,6 +160,79 @@ export const usersAPI = {
   },
 }
 
+// ===========================================
+// Classes API (Admin, Teachers)
+// ===========================================
+
+export const classesAPI = {
+  list: () => 
+    api.get('/admin/classes'),
+  
+  get: (id) => 
+    api.get(`/admin/classes/${id}`),
+  
+  create: (data) => 
+    api.post('/admin/classes', data),
+  
+  update: (id, data) => 
+    api.put(`/admin/classes/${id}`, data),
+  
+  delete: (id) => 
+    api.delete(`/admin/classes/${id}`),
+  
+  setTeachers: (id, userIds) => 
+    api.put(`/admin/classes/${id}/teachers`, { user_ids: userIds }),
+  
+  addStudents: (id, emails) => 
+    api.post(`/admin/classes/${id}/students`, { emails }),
+  
+  removeStudent: (id, userId) => 
+    api.delete(`/admin/classes/${id}/students/${userId}`),
+  
+  regenerateJoinCode: (id) => 
+    api.post(`/admin/classes/${id}/join-code`),
+  
+  // Students
+  mine: () => 
+    api.get('/classes'),
+  
+  join: (code) => 
+    api.post('/classes/join', { code }),
+}
+
+// ===========================================
+// Project Invitations API
+// ===========================================
+
+export const invitationsAPI = {
+  list: () => 
+    api.get('/invitations'),
+  
+  accept: (id) => 
+    api.post(`/invitations/${id}/accept`),
+  
+  decline: (id) => 
+    api.delete(`/invitations/${id}`),
+}
+
+// ===========================================
+// Roles API (Admin)
+// ===========================================
+
+export const rolesAPI = {
+  list: () => 
+    api.get('/admin/roles'),
+  
+  create: (data) => 
+    api.post('/admin/roles', data),
+  
+  update: (id, data) => 
+    api.put(`/admin/roles/${id}`, data),
+  
+  delete: (id) => 
+    api.delete(`/admin/roles/${id}`),
+}
+
 // ===========================================
 // Settings API (Admin)
 // ===========================================
@@ -92,6 +245,18 @@ export const settingsAPI = {
     api.put('/admin/settings', { settings }),
 }
 
+// ===========================================
+// Audit API (Admin)
+// ===========================================
+
+export const auditAPI = {
+  list: (params = {}) => 
+    api.get('/admin/audit', { params }),
+  
+  export: (params = {}) => 
+    api.get('/admin/audit', { params: { ...params, format: 'csv' }, responseType: 'blob' }),
+}
+
 // ===========================================
 // Projects API
 // ===========================================
@@ -130,6 +295,19 @@ export const projectsAPI = {
 
   updateEnv: (id, content) =>
     api.put(`/projects/${id}/env`, { content }),
+
+  // Collaborators
+  members: (id) =>
+    api.get(`/projects/${id}/members`),
+
+  inviteMember: (id, email, role) =>
+    api.post(`/projects/${id}/members`, { email, role }),
+
+  updateMember: (id, memberId, role) =>
+    api.put(`/projects/${id}/members/${memberId}`, { role }),
+
+  removeMember: (id, memberId) =>
+    api.delete(`/projects/${id}/members/${memberId}`),
   
   // Admin endpoints
   listAll: (params = {}) => 
@@ -142,6 +320,21 @@ export const projectsAPI = {
     api.get('/admin/stats'),
 }
 
+// ===========================================
+// Add-ons API (Redis, Meilisearch, Mailpit)
+// ===========================================
+
+export const addonsAPI = {
+  list: (projectId) =>
+    api.get(`/projects/${projectId}/addons`),
+
+  attach: (projectId, type) =>
+    api.post(`/projects/${projectId}/addons`, { type }),
+
+  detach: (projectId, addonId) =>
+    api.delete(`/projects/${projectId}/addons/${addonId}`),
+}
+
 // ===========================================
 // Database API (Student Database Management)
 // ===========================================
@@ -150,6 +343,14 @@ export const databaseAPI = {
   // Get database credentials
   getCredentials: (projectId) => 
     api.get(`/projects/${projectId}/database/credentials`),
+
+  // Generate a new database password and restart the app
+  rotatePassword: (projectId) =>
+    api.post(`/projects/${projectId}/database/rotate-password`),
+
+  // Get database size against quota
+  getUsage: (projectId) =>
+    api.get(`/projects/${projectId}/database/usage`),
   
   // List all tables
   listTables: (projectId) => 
@@ -160,26 +361,96 @@ export const databaseAPI = {
     api.get(`/projects/${projectId}/database/tables/${tableName}`),
   
   // Get table data with pagination
-  getData: (projectId, tableName, page = 1, limit = 50) => 
-    api.get(`/projects/${projectId}/database/tables/${tableName}/data`, { 
-      params: { page, limit } 
-    }),
+  // options: { sort: 'col:asc', filters: ['col:eq:value'], search, cursor }
+  getData: (projectId, tableName, page = 1, limit = 50, options = {}) => {
+    const params = new URLSearchParams({ page, limit })
+    if (options.sort) params.append('sort', options.sort)
+    if (options.search) params.append('search', options.search)
+    if (options.cursor !== undefined) params.append('cursor', options.cursor)
+    ;(options.filters || []).forEach((f) => params.append('filter', f))
+    return api.get(`/projects/${projectId}/database/tables/${tableName}/data`, { params })
+  },
+  
+  // Insert a row (values: column -> value)
+  insertRow: (projectId, tableName, values) => 
+    api.post(`/projects/${projectId}/database/tables/${tableName}/rows`, { values }),
   
-  // Execute SQL query
-  query: (projectId, sql) => 
-    api.post(`/projects/${projectId}/database/query`, { query: sql }),
+  // Update the row identified by its primary key
+  updateRow: (projectId, tableName, key, values) => 
+    api.put(`/projects/${projectId}/database/tables/${tableName}/rows`, { key, values }),
   
-  // Export database as SQL file
-  export: (projectId) => 
-    api.get(`/projects/${projectId}/database/export`, { responseType: 'blob' }),
+  // Delete the row identified by its primary key
+  deleteRow: (projectId, tableName, key) => 
+    api.delete(`/projects/${projectId}/database/tables/${tableName}/rows`, { data: { key } }),
+  
+  // Schema designer (dryRun returns the DDL and migration without executing)
+  createTable: (projectId, name, columns, dryRun = false) => 
+    api.post(`/projects/${projectId}/database/tables`, { name, columns }, { params: { dry_run: dryRun } }),
+  addColumn: (projectId, tableName, column, dryRun = false) => 
+    api.post(`/projects/${projectId}/database/tables/${tableName}/columns`, column, { params: { dry_run: dryRun } }),
+  modifyColumn: (projectId, tableName, columnName, column, dryRun = false) => 
+    api.put(`/projects/${projectId}/database/tables/${tableName}/columns/${columnName}`, column, { params: { dry_run: dryRun } }),
+  dropColumn: (projectId, tableName, columnName, dryRun = false) => 
+    api.delete(`/projects/${projectId}/database/tables/${tableName}/columns/${columnName}`, { params: { dry_run: dryRun } }),
+  addIndex: (projectId, tableName, index, dryRun = false) => 
+    api.post(`/projects/${projectId}/database/tables/${tableName}/indexes`, index, { params: { dry_run: dryRun } }),
+  dropIndex: (projectId, tableName, indexName, dryRun = false) => 
+    api.delete(`/projects/${projectId}/database/tables/${tableName}/indexes/${indexName}`, { params: { dry_run: dryRun } }),
+  addForeignKey: (projectId, tableName, foreignKey, dryRun = false) => 
+    api.post(`/projects/${projectId}/database/tables/${tableName}/foreign-keys`, foreignKey, { params: { dry_run: dryRun } }),
+  dropForeignKey: (projectId, tableName, name, dryRun = false) => 
+    api.delete(`/projects/${projectId}/database/tables/${tableName}/foreign-keys/${name}`, { params: { dry_run: dryRun } }),
+  
+  // Whole schema with relationships (format: json | dbml | mermaid)
+  getSchema: (projectId, format = 'json') => 
+    api.get(`/projects/${projectId}/database/schema`, { params: { format } }),
+  
+  // Laravel migration for a schema change ({ action, table, ... })
+  generateMigration: (projectId, change) => 
+    api.post(`/projects/${projectId}/database/migration`, change),
+  
+  // Execute SQL statements (one result per statement)
+  query: (projectId, sql, readOnly = false) => 
+    api.post(`/projects/${projectId}/database/query`, { query: sql, read_only: readOnly }),
+  
+  // Export database (format: sql | sql.gz | csv-zip, tables: comma separated)
+  export: (projectId, params = {}) => 
+    api.get(`/projects/${projectId}/database/export`, { params, responseType: 'blob' }),
   
   // Import SQL
   import: (projectId, sql) => 
     api.post(`/projects/${projectId}/database/import`, { sql }),
+
+  // Upload a .sql or .sql.gz dump, imported in the background
+  importFile: (projectId, file) => {
+    const formData = new FormData()
+    formData.append('file', file)
+    return api.post(`/projects/${projectId}/database/import`, formData, {
+      headers: { 'Content-Type': 'multipart/form-data' },
+    })
+  },
+
+  // Poll the progress of an uploaded import
+  importStatus: (projectId, jobId) =>
+    api.get(`/projects/${projectId}/database/import/${jobId}`),
   
-  // Reset database (drop all tables)
+  // Reset database (drop all tables, a backup is taken first)
   reset: (projectId) => 
     api.post(`/projects/${projectId}/database/reset`),
+
+  // Backups (scheduled, manual and automatic snapshots)
+  listBackups: (projectId) =>
+    api.get(`/projects/${projectId}/database/backups`),
+
+  createBackup: (projectId) =>
+    api.post(`/projects/${projectId}/database/backups`),
+
+  downloadBackup: (projectId, backupId) =>
+    api.get(`/projects/${projectId}/database/backups/${backupId}/download`, { responseType: 'blob' }),
+
+  // Restore runs in the background, poll with importStatus
+  restoreBackup: (projectId, backupId) =>
+    api.post(`/projects/${projectId}/database/backups/${backupId}/restore`),
 }
 
 // ===========================================
@@ -210,6 +481,12 @@ export const systemAPI = {
   
   prune: () => 
     api.post('/admin/system/prune'),
+
+  listOrphanDatabases: () =>
+    api.get('/admin/databases/orphans'),
+
+  dropOrphanDatabase: (name, engine = 'mysql') =>
+    api.delete(`/admin/databases/orphans/${name}`, { params: { engine } }),
 }
 
 export default api
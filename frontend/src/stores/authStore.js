@@ -7,6 +7,30 @@
 import { create } from 'zustand'
 import { authAPI } from '../services/api'
 
+// Permissions that open the admin panel, by the page they unlock
+export const PANEL_PERMISSIONS = {
+  dashboard: ['system.read'],
+  classes: ['class.read.own', 'class.read.any'],
+  users: ['user.read.class', 'user.read.any'],
+  projects: ['project.read.class', 'project.read.any'],
+  settings: ['settings.read'],
+  feedback: ['feedback.manage'],
+  audit: ['audit.read'],
+  roles: ['roles.manage'],
+}
+
+// hasPermission reports whether the user's role grants any of the permissions
+export const hasPermission = (user, ...permissions) => {
+  const granted = user?.permissions || []
+  return permissions.some((permission) => granted.includes(permission))
+}
+
+// homePath is the first page the user may open, the student dashboard otherwise
+export const homePath = (user) => {
+  const page = Object.keys(PANEL_PERMISSIONS).find((key) => hasPermission(user, ...PANEL_PERMISSIONS[key]))
+  return page ? `/admin/${page}` : '/dashboard'
+}
+
 const useAuthStore = create((set, get) => ({
   // State
   user: null,
@@ -15,15 +39,64 @@ const useAuthStore = create((set, get) => ({
   
   // Computed
   isAuthenticated: () => !!get().token,
-  isAdmin: () => {
-    const user = get().user
-    return user?.role === 'superadmin' || user?.role === 'admin'
-  },
-  isSuperAdmin: () => get().user?.role === 'superadmin',
+  can: (...permissions) => hasPermission(get().user, ...permissions),
   
   // Actions
+  // Returns the user, or { two_factor_required, challenge_token } when a code is still needed
   login: async (email, password) => {
     const response = await authAPI.login(email, password)
+    if (response.data.two_factor_required) {
+      return response.data
+    }
+    const { token, user } = response.data
+    
+    localStorage.setItem('token', token)
+    set({ token, user })
+    
+    return user
+  },
+  
+  // Second login step with a code from the authenticator app or a recovery code
+  completeChallenge: async (challengeToken, code) => {
+    const response = await authAPI.twoFactorChallenge(challengeToken, code)
+    const { token, user } = response.data
+    
+    localStorage.setItem('token', token)
+    set({ token, user })
+    
+    return user
+  },
+  
+  // Enabling 2FA reissues the access token without the enrollment restriction
+  enableTwoFactor: async (code) => {
+    const response = await authAPI.twoFactorVerify(code)
+    const { token, recovery_codes } = response.data
+    
+    localStorage.setItem('token', token)
+    set((state) => ({
+      token,
+      user: state.user && { ...state.user, two_factor_enabled: true, two_factor_setup_required: false },
+    }))
+    
+    return recovery_codes
+  },
+  
+  // Single sign-on leaves a refresh cookie; exchange it for an access token
+  completeSSO: async () => {
+    const response = await authAPI.refresh()
+    const { token } = response.data
+    
+    localStorage.setItem('token', token)
+    set({ token })
+    
+    const me = await authAPI.me()
+    set({ user: me.data })
+    return me.data
+  },
+  
+  // Changing the password signs out every session and returns a fresh token
+  changePassword: async (currentPassword, newPassword) => {
+    const response = await authAPI.changePassword(currentPassword, newPassword)
     const { token, user } = response.data
     
     localStorage.setItem('token', token)
@@ -3,13 +3,14 @@ set -e
 
 # ==============================================================================
 # Laravel PaaS Start Script
-# Starts all infrastructure containers (MariaDB, Redis, Traefik, Backend, Frontend)
+# Starts all infrastructure containers (MariaDB, PostgreSQL, Redis, Traefik, Backend, Frontend)
 # ==============================================================================
 
 # 1. Environment & Paths
 SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
 PROJECT_ROOT="$(dirname "$SCRIPT_DIR")"
 DB_DATA_DIR="${PROJECT_ROOT}/storage/mysql"
+PG_DATA_DIR="${PROJECT_ROOT}/storage/postgres"
 
 # Colors for output
 RED='\033[0;31m'
@@ -39,12 +40,22 @@ MYSQL_DATABASE=${MYSQL_DATABASE:-"paas"}
 BASE_DOMAIN=${BASE_DOMAIN:-"localhost"}
 ACME_EMAIL=${ACME_EMAIL:-"admin@localhost"}
 JWT_SECRET=${JWT_SECRET:-"change-me-please-12345"}
+if [ -z "$ENCRYPTION_KEY" ]; then
+    echo -e "${RED}Error: ENCRYPTION_KEY is not set${NC}"
+    echo -e "Installs created before it existed must set it to their JWT_SECRET."
+    exit 1
+fi
 MYSQL_PASSWORD=${MYSQL_PASSWORD:-"$MYSQL_ROOT_PASSWORD"}
+POSTGRES_PASSWORD=${POSTGRES_PASSWORD:-"$MYSQL_ROOT_PASSWORD"}
 
 # 3. Preparation
 echo -e "${YELLOW}Preparing environment...${NC}"
 docker network create paas-network 2>/dev/null || true
+# Only Traefik on this network may report client addresses to the backend
+TRUSTED_PROXIES=$(docker network inspect paas-network -f '{{range .IPAM.Config}}{{.Subnet}},{{end}}' 2>/dev/null | sed 's/,$//')
 mkdir -p "$DB_DATA_DIR"
+mkdir -p "$PG_DATA_DIR"
+mkdir -p "${PROJECT_ROOT}/storage/backups"
 mkdir -p "${PROJECT_ROOT}/storage/projects"
 
 # 4. Smart Backup Logic (Logical or Physical)
@@ -98,6 +109,18 @@ docker run -d \
     -v "${DB_DATA_DIR}:/var/lib/mysql" \
     mariadb:10.11
 
+# 5b. Infrastructure: PostgreSQL (projects using the postgres engine)
+echo -e "${YELLOW}Starting PostgreSQL...${NC}"
+docker rm -f paas-postgres 2>/dev/null || true
+
+docker run -d \
+    --name paas-postgres \
+    --network paas-network \
+    --restart unless-stopped \
+    -e POSTGRES_PASSWORD="$POSTGRES_PASSWORD" \
+    -v "${PG_DATA_DIR}:/var/lib/postgresql/data" \
+    postgres:16-alpine
+
 # 6. Infrastructure: Redis
 echo -e "${YELLOW}Starting Redis...${NC}"
 docker rm -f paas-redis 2>/dev/null || true
@@ -160,18 +183,27 @@ docker run -d \
     -v /var/run/docker.sock:/var/run/docker.sock \
     -v "${PROJECT_ROOT}/.env:/app/.env:ro" \
     -v "${PROJECT_ROOT}/storage/projects:/app/storage/projects" \
+    -v "${PROJECT_ROOT}/storage/backups:/app/storage/backups" \
     -v "${PROJECT_ROOT}/docker/templates:/app/docker/templates:ro" \
     -e MYSQL_HOST=paas-mysql \
     -e MYSQL_USER="$MYSQL_USER" \
     -e MYSQL_PASSWORD="$MYSQL_PASSWORD" \
     -e MYSQL_DATABASE="$MYSQL_DATABASE" \
+    -e POSTGRES_HOST=paas-postgres \
+    -e POSTGRES_PASSWORD="$POSTGRES_PASSWORD" \
     -e REDIS_HOST=paas-redis \
     -e REDIS_PORT="${REDIS_PORT:-6379}" \
     -e REDIS_PASSWORD="$REDIS_PASSWORD" \
     -e JWT_SECRET="$JWT_SECRET" \
+    -e ENCRYPTION_KEY="$ENCRYPTION_KEY" \
     -e BASE_DOMAIN="$BASE_DOMAIN" \
     -e PROJECT_DOMAIN="${PROJECT_DOMAIN:-$BASE_DOMAIN}" \
     -e DOCKER_NETWORK=paas-network \
+    -e TRUSTED_PROXIES="${TRUSTED_PROXIES:-172.16.0.0/12}" \
+    -e BACKUP_S3_ENDPOINT="$BACKUP_S3_ENDPOINT" \
+    -e BACKUP_S3_BUCKET="$BACKUP_S3_BUCKET" \
+    -e BACKUP_S3_ACCESS_KEY="$BACKUP_S3_ACCESS_KEY" \
+    -e BACKUP_S3_SECRET_KEY="$BACKUP_S3_SECRET_KEY" \
     --label "traefik.enable=true" \
     --label "traefik.http.routers.backend.rule=Host(\`$BASE_DOMAIN\`) && PathPrefix(\`/api\`)" \
     --label "traefik.http.services.backend.loadbalancer.server.port=8080" \
@@ -12,6 +12,7 @@ docker stop paas-frontend 2>/dev/null || true
 docker stop paas-backend 2>/dev/null || true
 docker stop paas-traefik 2>/dev/null || true
 docker stop paas-redis 2>/dev/null || true
+docker stop paas-postgres 2>/dev/null || true
 docker stop paas-mysql 2>/dev/null || true
 
 echo "✅ All containers stopped"
@@ -19,13 +20,13 @@ echo "✅ All containers stopped"
 # Optionally remove containers
 if [ "$1" == "--clean" ]; then
     echo "🗑️  Removing containers..."
-    docker rm paas-frontend paas-backend paas-traefik paas-redis paas-mysql 2>/dev/null || true
+    docker rm paas-frontend paas-backend paas-traefik paas-redis paas-postgres paas-mysql 2>/dev/null || true
     echo "✅ Containers removed"
 fi
 
 if [ "$1" == "--purge" ]; then
     echo "🗑️  Removing containers and volumes..."
-    docker rm paas-frontend paas-backend paas-traefik paas-redis paas-mysql 2>/dev/null || true
+    docker rm paas-frontend paas-backend paas-traefik paas-redis paas-postgres paas-mysql 2>/dev/null || true
     docker volume rm paas-redis-data paas-letsencrypt 2>/dev/null || true
-    echo "✅ Containers removed; Redis and TLS volumes purged (MySQL data preserved in storage/mysql)"
+    echo "✅ Containers removed; Redis and TLS volumes purged (database data preserved in storage/mysql and storage/postgres)"
 fi